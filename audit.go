@@ -0,0 +1,197 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/openfga/go-sdk/client"
+)
+
+const (
+	// auditActorHeader optionally names who made the change a message
+	// carries, for the audit trail.
+	auditActorHeader = "Fga-Actor"
+	// auditPublishTimeout bounds the wait for the audit stream to
+	// acknowledge the records of one message.
+	auditPublishTimeout = 5 * time.Second
+)
+
+// auditSubject, when set, receives an audit record for every object whose
+// access a message changed. It is empty when AUDIT_SUBJECT is unset.
+var auditSubject string
+
+// publishAudit publishes one audit record to the JetStream stream capturing
+// subject and waits for it to be stored. It is a variable so tests can
+// capture records without a NATS server.
+var publishAudit = func(ctx context.Context, subject string, data []byte) error {
+	_, err := jetstreamConn.Publish(ctx, subject, data)
+	return err
+}
+
+// auditPublishFailures counts audit records that could not be stored.
+var auditPublishFailures = expvar.NewInt("fga_sync_audit_publish_failures_total")
+
+// auditTrailKey is the context key for the audit trail of a message.
+type auditTrailKey struct{}
+
+// auditTrail collects the tuples a message wrote and deleted, by object,
+// until they are published.
+type auditTrail struct {
+	operation     string
+	actor         string
+	correlationID string
+
+	mu      sync.Mutex
+	records []*types.AuditRecord
+	byObj   map[string]*types.AuditRecord
+}
+
+// withAuditTrail returns a context collecting the changes made while
+// handling msg, received on subject, for the audit trail. It returns ctx
+// unchanged when auditing is disabled.
+func withAuditTrail(ctx context.Context, subject string, msg INatsMsg) context.Context {
+	if auditSubject == "" {
+		return ctx
+	}
+	operation := subject[strings.LastIndex(subject, ".")+1:]
+	correlationID := msg.Header().Get("Nats-Msg-Id")
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+		correlationID = spanCtx.TraceID().String()
+	}
+	if correlationID == "" {
+		correlationID = newCorrelationID()
+	}
+	return context.WithValue(ctx, auditTrailKey{}, &auditTrail{
+		operation:     operation,
+		actor:         msg.Header().Get(auditActorHeader),
+		correlationID: correlationID,
+		byObj:         make(map[string]*types.AuditRecord),
+	})
+}
+
+// auditOrigin is what the changes a message made are attributed to in the
+// audit trail. It is kept with work deferred past the message, such as
+// deferred deletes, so that the changes are attributed the same way when
+// they are made.
+type auditOrigin struct {
+	Operation     string `json:"operation"`
+	Actor         string `json:"actor,omitempty"`
+	CorrelationID string `json:"correlation_id"`
+}
+
+// auditOriginOf returns the origin of the audit trail of ctx, or nil if it
+// has none.
+func auditOriginOf(ctx context.Context) *auditOrigin {
+	trail, ok := ctx.Value(auditTrailKey{}).(*auditTrail)
+	if !ok {
+		return nil
+	}
+	return &auditOrigin{Operation: trail.operation, Actor: trail.actor, CorrelationID: trail.correlationID}
+}
+
+// withAuditOrigin returns a context collecting changes for the audit trail,
+// attributed to origin. It returns ctx unchanged when auditing is disabled or
+// origin is nil.
+func withAuditOrigin(ctx context.Context, origin *auditOrigin) context.Context {
+	if auditSubject == "" || origin == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, auditTrailKey{}, &auditTrail{
+		operation:     origin.Operation,
+		actor:         origin.Actor,
+		correlationID: origin.CorrelationID,
+		byObj:         make(map[string]*types.AuditRecord),
+	})
+}
+
+// recordAuditChanges adds tuples just written and deleted to the audit trail
+// of ctx, if it has one.
+func recordAuditChanges(ctx context.Context, writes []ClientTupleKey, deletes []ClientTupleKeyWithoutCondition) {
+	trail, ok := ctx.Value(auditTrailKey{}).(*auditTrail)
+	if !ok {
+		return
+	}
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+	for _, tuple := range writes {
+		record := trail.recordFor(tuple.Object)
+		record.Writes = append(record.Writes, tuple.Object+"#"+tuple.Relation+"@"+tuple.User)
+	}
+	for _, tuple := range deletes {
+		record := trail.recordFor(tuple.Object)
+		record.Deletes = append(record.Deletes, tuple.Object+"#"+tuple.Relation+"@"+tuple.User)
+	}
+}
+
+// recordFor returns the record of object, creating it first if needed. The
+// caller must hold t.mu.
+func (t *auditTrail) recordFor(object string) *types.AuditRecord {
+	if record, ok := t.byObj[object]; ok {
+		return record
+	}
+	record := &types.AuditRecord{
+		Object:        object,
+		Operation:     t.operation,
+		Writes:        []string{},
+		Deletes:       []string{},
+		Actor:         t.actor,
+		Timestamp:     time.Now().UTC(),
+		CorrelationID: t.correlationID,
+	}
+	t.byObj[object] = record
+	t.records = append(t.records, record)
+	return record
+}
+
+// publishAuditTrail publishes the records collected in the audit trail of
+// ctx, one per object, in the order the objects were first changed. Records
+// that can't be stored are logged and counted; the changes they describe
+// have already been applied, so they don't fail the message.
+func publishAuditTrail(ctx context.Context) {
+	trail, ok := ctx.Value(auditTrailKey{}).(*auditTrail)
+	if !ok {
+		return
+	}
+	trail.mu.Lock()
+	records := trail.records
+	trail.records, trail.byObj = nil, make(map[string]*types.AuditRecord)
+	trail.mu.Unlock()
+	if len(records) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditPublishTimeout)
+	defer cancel()
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err == nil {
+			err = publishAudit(ctx, auditSubject, data)
+		}
+		if err != nil {
+			auditPublishFailures.Add(1)
+			logger.With(errKey, err, "object", record.Object, "operation", record.Operation).
+				ErrorContext(ctx, "failed to publish audit record")
+		}
+	}
+}
+
+// newCorrelationID returns a random correlation ID for messages carrying no
+// trace or message ID.
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
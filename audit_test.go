@@ -0,0 +1,94 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/nats-io/nats.go"
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// captureAudit enables auditing to subject for the duration of the test and
+// returns the records published.
+func captureAudit(t *testing.T, subject string) *[]fgatypes.AuditRecord {
+	t.Helper()
+	origSubject, origPublish := auditSubject, publishAudit
+	t.Cleanup(func() { auditSubject, publishAudit = origSubject, origPublish })
+
+	var records []fgatypes.AuditRecord
+	auditSubject = subject
+	publishAudit = func(_ context.Context, published string, data []byte) error {
+		assert.Equal(t, subject, published)
+		var record fgatypes.AuditRecord
+		assert.NoError(t, json.Unmarshal(data, &record))
+		records = append(records, record)
+		return nil
+	}
+	return &records
+}
+
+// TestDispatchMessage_AuditTrail asserts that a sync publishes an audit
+// record of its object with every field filled in.
+func TestDispatchMessage_AuditTrail(t *testing.T) {
+	records := captureAudit(t, "audit.fga-sync")
+
+	service := setupService()
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{
+		Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:bob", Relation: "writer", Object: "committee:c1"}},
+		},
+	}, nil)
+	fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+	msg := buildGenericMessage(t, "committee", "update_access", fgatypes.GenericAccessData{
+		UID:       "c1",
+		Relations: map[string][]string{"writer": {"alice"}},
+	})
+	msg.subject = constants.GenericUpdateAccessSubject
+	msg.header = nats.Header{}
+	msg.header.Set(auditActorHeader, "user:admin")
+	msg.header.Set("Nats-Msg-Id", "msg-42")
+
+	dispatchMessage(context.Background(), msg.subject, "generic update access", constants.FgaSyncQueue,
+		service.genericUpdateAccessHandler, msg)
+
+	if assert.Len(t, *records, 1) {
+		record := (*records)[0]
+		assert.Equal(t, "committee:c1", record.Object)
+		assert.Equal(t, "update_access", record.Operation)
+		assert.Contains(t, record.Writes, "committee:c1#writer@user:alice")
+		assert.Equal(t, []string{"committee:c1#writer@user:bob"}, record.Deletes)
+		assert.Equal(t, "user:admin", record.Actor)
+		assert.Equal(t, "msg-42", record.CorrelationID)
+		assert.False(t, record.Timestamp.IsZero())
+	}
+}
+
+// TestDispatchMessage_AuditTrailNoChanges asserts that messages that change
+// nothing, and dispatch with auditing disabled, publish no records.
+func TestDispatchMessage_AuditTrailNoChanges(t *testing.T) {
+	records := captureAudit(t, "audit.fga-sync")
+	noop := func(_ context.Context, _ INatsMsg) error { return nil }
+	dispatchMessage(context.Background(), constants.GenericMemberPutSubject, "generic member put",
+		constants.FgaSyncQueue, noop, CreateMockNatsMsg([]byte(`{}`)))
+	assert.Empty(t, *records)
+
+	auditSubject = ""
+	writing := func(ctx context.Context, _ INatsMsg) error {
+		recordAuditChanges(ctx, []client.ClientTupleKey{{User: "user:alice", Relation: "member", Object: "committee:c1"}}, nil)
+		return nil
+	}
+	dispatchMessage(context.Background(), constants.GenericMemberPutSubject, "generic member put",
+		constants.FgaSyncQueue, writing, CreateMockNatsMsg([]byte(`{}`)))
+	assert.Empty(t, *records)
+}
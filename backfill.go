@@ -0,0 +1,102 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// defaultBackfillWorkers is the default number of workers processing
+// backfill messages.
+const defaultBackfillWorkers = 1
+
+// backfillWorkers processes messages received on backfill subjects. It is
+// nil when no backfill subjects are configured.
+var backfillWorkers *partitionedWorkers
+
+// backfillRoutes maps backfill subjects to the live subject whose handler
+// processes their messages.
+type backfillRoutes map[string]string
+
+// String formats the routes as they are configured, for the effective
+// configuration.
+func (r backfillRoutes) String() string {
+	entries := make([]string, 0, len(r))
+	for _, backfill := range slices.Sorted(maps.Keys(r)) {
+		entries = append(entries, backfill+"="+r[backfill])
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseBackfillRoutes parses a comma-separated list of backfill=live subject
+// entries, e.g. lfx.fga-sync-backfill.update_access=lfx.fga-sync.update_access.
+// Live subjects are given without the subject prefix, which is applied like
+// for every other subject.
+func parseBackfillRoutes(v string) (backfillRoutes, error) {
+	routes := make(backfillRoutes)
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		backfill, live, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("expected backfill=live subject, got %q", entry)
+		}
+		if err := validateSubjectPrefix(backfill); err != nil {
+			return nil, fmt.Errorf("backfill subject %q %w", backfill, err)
+		}
+		if err := validateSubjectPrefix(live); err != nil {
+			return nil, fmt.Errorf("live subject %q %w", live, err)
+		}
+		if _, dup := routes[backfill]; dup {
+			return nil, errors.New(backfill + " is listed more than once")
+		}
+		routes[backfill] = live
+	}
+	return routes, nil
+}
+
+// subscribeToBackfill subscribes to the backfill subject of each route, in
+// the queue group, processing its messages with the handler of the live
+// subscription it names. Backfill messages run on backfillWorkers rather than
+// on the live subscriptions, so a large backfill is processed at its own
+// bounded concurrency and cannot delay live messages.
+func subscribeToBackfill(routes backfillRoutes, subjects subjectSet, queue string, live []subscriptionConfig) error {
+	for _, backfill := range slices.Sorted(maps.Keys(routes)) {
+		liveSubject := subjects.of(routes[backfill])
+		i := slices.IndexFunc(live, func(config subscriptionConfig) bool { return config.subject == liveSubject })
+		if i < 0 {
+			return fmt.Errorf("backfill subject %s names unknown live subject %s", backfill, liveSubject)
+		}
+		config := live[i]
+		config.subject = backfill
+		config.description = "backfill " + config.description
+		if err := queueSubscribe(backfill, queue, func(ctx context.Context, msg INatsMsg) {
+			done := inflight.track(msg.Subject(), msg.Timestamp())
+			backfillWorkers.submit(partitionKey(msg), func() {
+				defer done()
+				dispatchSubscription(ctx, config, queue, msg)
+			})
+		}); err != nil {
+			logger.Error("error subscribing to NATS subject",
+				errKey, err,
+				"subject", backfill,
+				"queue", queue,
+			)
+			return err
+		}
+		logger.Info("subscribed to NATS subject",
+			"subject", backfill,
+			"live_subject", liveSubject,
+			"queue", queue,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSubscribeToBackfill_ConstrainedPool asserts that backfill messages are
+// processed by the live handler on the backfill pool, one at a time with a
+// single worker, while live messages are handled regardless.
+func TestSubscribeToBackfill_ConstrainedPool(t *testing.T) {
+	processes := make(map[string]func(context.Context, INatsMsg))
+	original := queueSubscribe
+	queueSubscribe = func(subject, queue string, process func(context.Context, INatsMsg)) error {
+		assert.Equal(t, "lfx.fga-sync.queue", queue)
+		processes[subject] = process
+		return nil
+	}
+	t.Cleanup(func() { queueSubscribe = original })
+	backfillWorkers = newPartitionedWorkers(1)
+	t.Cleanup(func() { backfillWorkers = nil })
+
+	release := make(chan struct{})
+	started := make(chan string, 3)
+	var running, maxRunning atomic.Int32
+	live := []subscriptionConfig{{
+		subject:     "lfx.fga-sync.update_access",
+		description: "generic update access",
+		handler: func(_ context.Context, msg INatsMsg) error {
+			started <- string(msg.Data())
+			if msg.Subject() == "lfx.fga-sync.update_access" {
+				return nil
+			}
+			if n := running.Add(1); n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		},
+	}}
+	routes := backfillRoutes{"lfx.backfill.update_access": "lfx.fga-sync.update_access"}
+	assert.NoError(t, subscribeToBackfill(routes, newSubjectSet(""), "lfx.fga-sync.queue", live))
+	process := processes["lfx.backfill.update_access"]
+	if !assert.NotNil(t, process) {
+		return
+	}
+
+	for _, data := range []string{
+		`{"object_type":"meeting","data":{"uid":"m1"}}`,
+		`{"object_type":"meeting","data":{"uid":"m2"}}`,
+	} {
+		msg := CreateMockNatsMsg([]byte(data))
+		msg.subject = "lfx.backfill.update_access"
+		process(context.Background(), msg)
+	}
+	first := <-started
+
+	// The second backfill message waits for the only backfill worker, even
+	// though it is for another object; a live message does not.
+	liveMsg := CreateMockNatsMsg([]byte(`live`))
+	liveMsg.subject = "lfx.fga-sync.update_access"
+	assert.NoError(t, live[0].handler(context.Background(), liveMsg))
+	assert.Equal(t, "live", <-started)
+	select {
+	case data := <-started:
+		t.Fatalf("backfill message %s started before %s finished", data, first)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-started
+	backfillWorkers.stop()
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestSubscribeToBackfill_UnknownLiveSubject(t *testing.T) {
+	original := queueSubscribe
+	queueSubscribe = func(string, string, func(context.Context, INatsMsg)) error {
+		t.Error("unexpected subscription")
+		return nil
+	}
+	t.Cleanup(func() { queueSubscribe = original })
+
+	routes := backfillRoutes{"lfx.backfill.update_access": "lfx.fga-sync.missing"}
+	err := subscribeToBackfill(routes, newSubjectSet("staging.lfx"), "staging.lfx.fga-sync.queue", nil)
+	assert.ErrorContains(t, err, "unknown live subject staging.lfx.fga-sync.missing")
+}
+
+func TestParseBackfillRoutes(t *testing.T) {
+	routes, err := parseBackfillRoutes(" lfx.backfill.update_access=lfx.fga-sync.update_access, ")
+	assert.NoError(t, err)
+	assert.Equal(t, backfillRoutes{"lfx.backfill.update_access": "lfx.fga-sync.update_access"}, routes)
+	assert.Equal(t, "lfx.backfill.update_access=lfx.fga-sync.update_access", routes.String())
+
+	for _, v := range []string{
+		"lfx.backfill.update_access",
+		"lfx.backfill.*=lfx.fga-sync.update_access",
+		"lfx.backfill.update_access=",
+		"a.b=lfx.fga-sync.update_access,a.b=lfx.fga-sync.delete_access",
+	} {
+		_, err := parseBackfillRoutes(v)
+		assert.Error(t, err, v)
+	}
+}
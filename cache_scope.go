@@ -0,0 +1,69 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+const (
+	// cacheScopeHeader names the cache scope of an access check request.
+	// Checks in a scope are cached separately from other scopes and from
+	// unscoped checks.
+	cacheScopeHeader = "Fga-Cache-Scope"
+	// cacheRefreshHeader, set to "true", invalidates the request's cache
+	// scope before its checks are served.
+	cacheRefreshHeader = "Fga-Cache-Refresh"
+)
+
+// cacheScopePattern matches a valid cache scope. Scopes become part of cache
+// keys, so they are limited to characters valid in a key token.
+var cacheScopePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// cacheScopeKey carries the cache scope of a request's checks.
+type cacheScopeKey struct{}
+
+// withCacheScope returns a context whose checks are cached in scope.
+func withCacheScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, cacheScopeKey{}, scope)
+}
+
+// cacheScopeFrom returns the cache scope set by [withCacheScope], or "" for
+// the shared, unscoped cache.
+func cacheScopeFrom(ctx context.Context) string {
+	scope, _ := ctx.Value(cacheScopeKey{}).(string)
+	return scope
+}
+
+// validateCacheScope checks a cache scope sent by a client.
+func validateCacheScope(scope string) error {
+	if !cacheScopePattern.MatchString(scope) {
+		return errors.New("cache scope must be 1-64 letters, digits, '_' or '-'")
+	}
+	return nil
+}
+
+// checkCacheKey returns the cache key of the check result for relationKey in
+// scope. The relation is encoded using base32 without padding to conform to
+// the allowed characters for NATS subjects.
+func checkCacheKey(scope, relationKey string) string {
+	encoded := cacheKeyEncoder.EncodeToString([]byte(relationKey))
+	if scope == "" {
+		return "rel." + encoded
+	}
+	return "scope." + scope + ".rel." + encoded
+}
+
+// cacheInvalidationKey returns the key of the invalidation marker of scope.
+// The unscoped marker invalidates every scope, while a scope's own marker
+// only invalidates it.
+func cacheInvalidationKey(scope string) string {
+	if scope == "" {
+		return "inv"
+	}
+	return "inv." + scope
+}
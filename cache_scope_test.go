@@ -0,0 +1,104 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	openfga "github.com/openfga/go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestAccessCheckHandler_CacheScopes tests that two cache scopes cache the
+// same check independently, and that refreshing one scope leaves the other's
+// cached result in place.
+func TestAccessCheckHandler_CacheScopes(t *testing.T) {
+	service := setupService()
+	service.fgaService.useCache = true
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	kv := service.fgaService.cacheBucket.(*MockKeyValue)
+	fgaClient.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
+		Result: &map[string]openfga.BatchCheckSingleResult{"1": {Allowed: openfga.PtrBool(true)}},
+	}, nil)
+
+	const relationKey = "project:123#writer@user:456"
+	check := func(scope string, refresh bool) error {
+		msg := CreateMockNatsMsg([]byte(relationKey))
+		msg.header = nats.Header{}
+		if scope != "" {
+			msg.header.Set(cacheScopeHeader, scope)
+		}
+		if refresh {
+			msg.header.Set(cacheRefreshHeader, trueString)
+		}
+		return service.accessCheckHandler(context.Background(), msg)
+	}
+	// age backdates the cached result of scope, so a refresh marker written
+	// afterwards is strictly newer.
+	age := func(scope string) {
+		kv.mu.Lock()
+		defer kv.mu.Unlock()
+		kv.createdTimes[checkCacheKey(scope, relationKey)] = time.Now().Add(-time.Minute)
+	}
+
+	// Each scope misses the cache once.
+	assert.NoError(t, check("admin", false))
+	assert.NoError(t, check("editor", false))
+	fgaClient.AssertNumberOfCalls(t, "BatchCheck", 2)
+
+	// Both scopes now hit their own entries.
+	assert.NoError(t, check("admin", false))
+	assert.NoError(t, check("editor", false))
+	fgaClient.AssertNumberOfCalls(t, "BatchCheck", 2)
+
+	// Refreshing the editor scope re-checks it, while admin still hits.
+	age("admin")
+	age("editor")
+	assert.NoError(t, check("editor", true))
+	fgaClient.AssertNumberOfCalls(t, "BatchCheck", 3)
+	assert.NoError(t, check("admin", false))
+	fgaClient.AssertNumberOfCalls(t, "BatchCheck", 3)
+
+	// The unscoped cache is separate from both scopes.
+	assert.NoError(t, check("", false))
+	fgaClient.AssertNumberOfCalls(t, "BatchCheck", 4)
+}
+
+// TestAccessCheckHandler_InvalidCacheScope tests that malformed cache scope
+// headers are rejected before anything is checked.
+func TestAccessCheckHandler_InvalidCacheScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		refresh bool
+	}{
+		{name: "refresh without scope", refresh: true},
+		{name: "scope with key separator", scope: "admin.view"},
+		{name: "scope with wildcard", scope: "admin*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.fgaService.useCache = true
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+
+			msg := CreateMockNatsMsg([]byte("project:123#writer@user:456"))
+			msg.header = nats.Header{}
+			if tt.scope != "" {
+				msg.header.Set(cacheScopeHeader, tt.scope)
+			}
+			if tt.refresh {
+				msg.header.Set(cacheRefreshHeader, trueString)
+			}
+
+			assert.Error(t, service.accessCheckHandler(context.Background(), msg))
+			fgaClient.AssertNotCalled(t, "BatchCheck", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
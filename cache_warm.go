@@ -0,0 +1,53 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+)
+
+// cacheWarmTimeout bounds how long startup cache warming may delay
+// subscribing to NATS.
+const cacheWarmTimeout = 30 * time.Second
+
+// cacheWarmSetFromEnv returns the tuples to pre-populate the cache with, in
+// the access check payload format (`object#relation@user`, one per line).
+// CACHE_WARM_TUPLES takes a comma-separated list; CACHE_WARM_FILE names a
+// file with one tuple per line. Both may be set.
+func cacheWarmSetFromEnv() ([]byte, error) {
+	var warmSet []byte
+	if v := os.Getenv("CACHE_WARM_TUPLES"); v != "" {
+		warmSet = append(warmSet, strings.ReplaceAll(v, ",", "\n")...)
+		warmSet = append(warmSet, '\n')
+	}
+	if path := os.Getenv("CACHE_WARM_FILE"); path != "" {
+		data, err := os.ReadFile(path) //nolint:gosec // path is operator-provided configuration
+		if err != nil {
+			return nil, err
+		}
+		warmSet = append(warmSet, data...)
+	}
+	return warmSet, nil
+}
+
+// WarmCache checks each tuple in warmSet against OpenFGA so the results are
+// cached before the first access check arrives. It returns the number of
+// tuples warmed.
+func (s FgaService) WarmCache(ctx context.Context, warmSet []byte) (int, error) {
+	checkRequests, err := s.ExtractCheckRequests(warmSet)
+	if err != nil {
+		return 0, err
+	}
+	if len(checkRequests) == 0 {
+		return 0, nil
+	}
+	if _, err = s.CheckRelationships(ctx, checkRequests); err != nil {
+		return 0, err
+	}
+	return len(checkRequests), nil
+}
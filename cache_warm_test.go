@@ -0,0 +1,62 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWarmCache(t *testing.T) {
+	warmFile := filepath.Join(t.TempDir(), "warm.txt")
+	if err := os.WriteFile(warmFile, []byte("project:public#viewer@user:*\n"), 0o600); err != nil {
+		t.Fatalf("failed to write warm file: %v", err)
+	}
+	t.Setenv("CACHE_WARM_TUPLES", "project:123#viewer@user:alice,project:123#writer@user:alice")
+	t.Setenv("CACHE_WARM_FILE", warmFile)
+
+	client := new(MockFgaClient)
+	result := map[string]openfga.BatchCheckSingleResult{
+		"1": {Allowed: openfga.PtrBool(true)},
+		"2": {Allowed: openfga.PtrBool(false)},
+		"3": {Allowed: openfga.PtrBool(true)},
+	}
+	client.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{Result: &result}, nil).Once()
+	kv := NewMockKeyValue()
+	service := FgaService{client: client, cacheBucket: kv}
+
+	warmCache(context.Background(), service)
+
+	expected := map[string]string{
+		"project:123#viewer@user:alice": "true",
+		"project:123#writer@user:alice": "false",
+		"project:public#viewer@user:*":  "true",
+	}
+	for relationKey, allowed := range expected {
+		entry, err := kv.Get(context.Background(), "rel."+cacheKeyEncoder.EncodeToString([]byte(relationKey)))
+		if assert.NoError(t, err, "expected warm entry for %s", relationKey) {
+			assert.Equal(t, allowed, string(entry.Value()))
+		}
+	}
+	client.AssertExpectations(t)
+}
+
+func TestWarmCache_Empty(t *testing.T) {
+	t.Setenv("CACHE_WARM_TUPLES", "")
+	t.Setenv("CACHE_WARM_FILE", "")
+
+	client := new(MockFgaClient)
+	service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+	warmCache(context.Background(), service)
+
+	client.AssertNotCalled(t, "BatchCheck", mock.Anything, mock.Anything, mock.Anything)
+}
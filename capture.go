@@ -0,0 +1,86 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+
+	. "github.com/openfga/go-sdk/client"
+)
+
+// captureSampler selects the messages to capture, 1 in every
+// CAPTURE_SAMPLE_RATE. It is nil when capture is disabled.
+var captureSampler *logSampler
+
+// messageCaptureKey is the context key for the capture of a message.
+type messageCaptureKey struct{}
+
+// messageCapture collects the tuples a sampled message wrote and deleted
+// until it is logged.
+type messageCapture struct {
+	mu     sync.Mutex
+	record types.CaptureRecord
+}
+
+// withMessageCapture returns a context capturing msg, received on subject,
+// and whether msg was sampled for capture. It returns ctx unchanged when
+// capture is disabled or msg was not sampled.
+func withMessageCapture(ctx context.Context, subject string, msg INatsMsg) (context.Context, bool) {
+	if captureSampler == nil || !captureSampler.sample() {
+		return ctx, false
+	}
+	return context.WithValue(ctx, messageCaptureKey{}, &messageCapture{record: types.CaptureRecord{
+		Subject: subject,
+		Headers: maps.Clone(msg.Header()),
+		Payload: string(msg.Data()),
+		Writes:  []types.CapturedTuple{},
+		Deletes: []types.CapturedTuple{},
+	}}), true
+}
+
+// recordCapturedChanges adds tuples just written and deleted to the capture
+// of ctx, if it has one.
+func recordCapturedChanges(ctx context.Context, writes []ClientTupleKey, deletes []ClientTupleKeyWithoutCondition) {
+	capture, ok := ctx.Value(messageCaptureKey{}).(*messageCapture)
+	if !ok {
+		return
+	}
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	for _, tuple := range writes {
+		captured := types.CapturedTuple{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object}
+		if tuple.Condition != nil {
+			captured.Condition = &types.CapturedCondition{Name: tuple.Condition.Name}
+			if tuple.Condition.Context != nil {
+				captured.Condition.Context = *tuple.Condition.Context
+			}
+		}
+		capture.record.Writes = append(capture.record.Writes, captured)
+	}
+	for _, tuple := range deletes {
+		capture.record.Deletes = append(capture.record.Deletes,
+			types.CapturedTuple{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object})
+	}
+}
+
+// logMessageCapture logs the capture of ctx, if it has one, once its
+// handler has returned errHandler.
+func logMessageCapture(ctx context.Context, errHandler error) {
+	capture, ok := ctx.Value(messageCaptureKey{}).(*messageCapture)
+	if !ok {
+		return
+	}
+	capture.mu.Lock()
+	record := capture.record
+	capture.mu.Unlock()
+	if errHandler != nil {
+		record.Error = errHandler.Error()
+	}
+	logger.With("capture", record).InfoContext(ctx, "captured message")
+}
@@ -0,0 +1,134 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/nats-io/nats.go"
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// captureMessages enables capture of 1 in every rate messages for the
+// duration of the test and returns the records logged.
+func captureMessages(t *testing.T, rate uint64) func() []fgatypes.CaptureRecord {
+	t.Helper()
+	origSampler, origLogger := captureSampler, logger
+	t.Cleanup(func() { captureSampler, logger = origSampler, origLogger })
+
+	var buf bytes.Buffer
+	captureSampler = &logSampler{rate: rate}
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	return func() []fgatypes.CaptureRecord {
+		var records []fgatypes.CaptureRecord
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var entry struct {
+				Msg     string                 `json:"msg"`
+				Capture fgatypes.CaptureRecord `json:"capture"`
+			}
+			if assert.NoError(t, json.Unmarshal(line, &entry)) && entry.Msg == "captured message" {
+				records = append(records, entry.Capture)
+			}
+		}
+		return records
+	}
+}
+
+// TestDispatchMessage_Capture asserts that the capture of a message holds
+// the message as received and exactly the mutations applied for it.
+func TestDispatchMessage_Capture(t *testing.T) {
+	records := captureMessages(t, 1)
+
+	service := setupService()
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{
+		Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:bob", Relation: "writer", Object: "committee:c1"}},
+		},
+	}, nil)
+	var applied []client.ClientWriteRequest
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		applied = append(applied, args.Get(1).(client.ClientWriteRequest))
+	}).Return(&client.ClientWriteResponse{}, nil)
+
+	msg := buildGenericMessage(t, "committee", "update_access", fgatypes.GenericAccessData{
+		UID:       "c1",
+		Relations: map[string][]string{"writer": {"alice"}},
+	})
+	msg.subject = constants.GenericUpdateAccessSubject
+	msg.header = nats.Header{}
+	msg.header.Set(auditActorHeader, "user:admin")
+
+	dispatchMessage(context.Background(), msg.subject, "generic update access", constants.FgaSyncQueue,
+		service.genericUpdateAccessHandler, msg)
+
+	captured := records()
+	if !assert.Len(t, captured, 1) || !assert.Len(t, applied, 1) {
+		return
+	}
+	record := captured[0]
+	assert.Equal(t, constants.GenericUpdateAccessSubject, record.Subject)
+	assert.Equal(t, string(msg.Data()), record.Payload)
+	assert.Equal(t, []string{"user:admin"}, record.Headers[auditActorHeader])
+	assert.Empty(t, record.Error)
+
+	var wantWrites, wantDeletes []fgatypes.CapturedTuple
+	for _, tuple := range applied[0].Writes {
+		wantWrites = append(wantWrites, fgatypes.CapturedTuple{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object})
+	}
+	for _, tuple := range applied[0].Deletes {
+		wantDeletes = append(wantDeletes, fgatypes.CapturedTuple{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object})
+	}
+	assert.Equal(t, wantWrites, record.Writes)
+	assert.Equal(t, wantDeletes, record.Deletes)
+	assert.Contains(t, record.Writes, fgatypes.CapturedTuple{User: "user:alice", Relation: "writer", Object: "committee:c1"})
+}
+
+// TestDispatchMessage_CaptureSampling asserts that only sampled messages are
+// captured, and none when capture is disabled.
+func TestDispatchMessage_CaptureSampling(t *testing.T) {
+	records := captureMessages(t, 3)
+	handler := func(context.Context, INatsMsg) error { return nil }
+	for range 6 {
+		msg := CreateMockNatsMsg([]byte("project:p1#viewer@user:alice"))
+		dispatchMessage(context.Background(), constants.AccessCheckSubject, "access check", constants.FgaSyncQueue,
+			handler, msg)
+	}
+	assert.Len(t, records(), 2)
+
+	captureSampler = nil
+	dispatchMessage(context.Background(), constants.AccessCheckSubject, "access check", constants.FgaSyncQueue,
+		handler, CreateMockNatsMsg([]byte("project:p1#viewer@user:alice")))
+	assert.Len(t, records(), 2)
+}
+
+// TestRecordCapturedChanges_Condition asserts that conditions are captured
+// with their context, so a replay writes the same conditional tuple.
+func TestRecordCapturedChanges_Condition(t *testing.T) {
+	captureSampler = &logSampler{rate: 1}
+	t.Cleanup(func() { captureSampler = nil })
+
+	ctx, captured := withMessageCapture(context.Background(), "subject", CreateMockNatsMsg([]byte("{}")))
+	assert.True(t, captured)
+	conditionContext := map[string]any{"start": "09:00"}
+	recordCapturedChanges(ctx, []client.ClientTupleKey{{
+		User: "user:alice", Relation: "viewer", Object: "meeting:m1",
+		Condition: &openfga.RelationshipCondition{Name: "in_business_hours", Context: &conditionContext},
+	}}, nil)
+
+	capture := ctx.Value(messageCaptureKey{}).(*messageCapture)
+	assert.Equal(t, []fgatypes.CapturedTuple{{
+		User: "user:alice", Relation: "viewer", Object: "meeting:m1",
+		Condition: &fgatypes.CapturedCondition{Name: "in_business_hours", Context: conditionContext},
+	}}, capture.record.Writes)
+}
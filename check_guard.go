@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"slices"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+
+	. "github.com/openfga/go-sdk/client"
+)
+
+// checkGuardFlag marks the result line of a check denied by the check guard.
+const checkGuardFlag = "guarded"
+
+// checkGuardTripped counts OpenFGA check batches abandoned by the check
+// guard.
+var checkGuardTripped = expvar.NewInt("fga_sync_check_guard_tripped_total")
+
+// errCheckGuardTripped is returned for checks abandoned by the check guard
+// when it is not configured to deny them.
+var errCheckGuardTripped = errors.New("check exceeded the check guard timeout")
+
+// checkGuard bounds how long checks may take to resolve in OpenFGA, so that
+// an outlier object graph, such as a public project whose committee has a
+// huge membership, cannot hold up the callers of the access check subject.
+type checkGuard struct {
+	// timeout bounds the OpenFGA checks of one batch. Zero disables the
+	// guard.
+	timeout time.Duration
+	// deny answers checks abandoned by the guard as denied and flagged,
+	// instead of failing the request.
+	deny bool
+}
+
+// guardedBatchCheck checks items in OpenFGA within the check guard timeout.
+// It reports whether the guard tripped and denied the checks, in which case
+// no results are returned. The items are resolved in one OpenFGA call, so a
+// single slow object trips the guard for every check of the batch, including
+// those of other objects; the log names all of the batch's objects.
+func (s FgaService) guardedBatchCheck(
+	ctx context.Context,
+	items []ClientBatchCheckItem,
+) (map[string]openfga.BatchCheckSingleResult, bool, error) {
+	if s.checkGuard.timeout <= 0 {
+		checked, err := s.batchCheckByConsistency(ctx, items)
+		return checked, false, err
+	}
+	guardCtx, cancel := context.WithTimeout(ctx, s.checkGuard.timeout)
+	defer cancel()
+	checked, err := s.batchCheckByConsistency(guardCtx, items)
+	if err == nil || ctx.Err() != nil || !errors.Is(guardCtx.Err(), context.DeadlineExceeded) {
+		return checked, false, err
+	}
+
+	checkGuardTripped.Add(1)
+	objects := make([]string, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(objects, item.Object) {
+			objects = append(objects, item.Object)
+		}
+	}
+	logger.With(errKey, err, "timeout", s.checkGuard.timeout, "checks", len(items), "objects", objects).
+		WarnContext(ctx, "checks exceeded the check guard timeout")
+	if !s.checkGuard.deny {
+		return nil, false, fmt.Errorf("%w of %s: %w", errCheckGuardTripped, s.checkGuard.timeout, err)
+	}
+	return nil, true, nil
+}
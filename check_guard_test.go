@@ -0,0 +1,88 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockSlowBatchCheck makes checks of object take until their context is done,
+// as OpenFGA does resolving an oversized object graph, and answers other
+// checks as allowed.
+func mockSlowBatchCheck(m *MockFgaClient, object string) {
+	m.On("BatchCheck", mock.Anything, mock.MatchedBy(func(req client.ClientBatchCheckRequest) bool {
+		return len(req.Checks) > 0 && req.Checks[0].Object == object
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return((*openfga.BatchCheckResponse)(nil), context.DeadlineExceeded)
+	result := map[string]openfga.BatchCheckSingleResult{"1": {Allowed: openfga.PtrBool(true)}}
+	m.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).
+		Return(&openfga.BatchCheckResponse{Result: &result}, nil)
+}
+
+func TestCheckRelationships_CheckGuard(t *testing.T) {
+	oversized := []client.ClientCheckRequest{{Object: "project:huge", Relation: "viewer", User: "user:alice"}}
+
+	t.Run("deny flags the checks", func(t *testing.T) {
+		fgaClient := new(MockFgaClient)
+		mockSlowBatchCheck(fgaClient, "project:huge")
+		kv := NewMockKeyValue()
+		service := FgaService{
+			client:      fgaClient,
+			cacheBucket: kv,
+			checkGuard:  checkGuard{timeout: 10 * time.Millisecond, deny: true},
+		}
+		tripped := checkGuardTripped.Value()
+
+		response, err := service.CheckRelationships(context.Background(), oversized)
+		assert.NoError(t, err)
+		assert.Equal(t, "project:huge#viewer@user:alice\tfalse\tguarded", string(response))
+		assert.Equal(t, tripped+1, checkGuardTripped.Value())
+		assert.NotContains(t, kv.data, checkCacheKey("", "project:huge#viewer@user:alice"))
+
+		// Checks of other objects are resolved as usual.
+		response, err = service.CheckRelationships(context.Background(), []client.ClientCheckRequest{
+			{Object: "project:small", Relation: "viewer", User: "user:alice"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "project:small#viewer@user:alice\ttrue", string(response))
+	})
+
+	t.Run("without deny the request fails", func(t *testing.T) {
+		fgaClient := new(MockFgaClient)
+		mockSlowBatchCheck(fgaClient, "project:huge")
+		service := FgaService{
+			client:      fgaClient,
+			cacheBucket: NewMockKeyValue(),
+			checkGuard:  checkGuard{timeout: 10 * time.Millisecond},
+		}
+
+		_, err := service.CheckRelationships(context.Background(), oversized)
+		assert.ErrorIs(t, err, errCheckGuardTripped)
+		assert.ErrorContains(t, err, "check exceeded the check guard timeout of 10ms")
+	})
+
+	t.Run("caller deadline is not the guard", func(t *testing.T) {
+		fgaClient := new(MockFgaClient)
+		mockSlowBatchCheck(fgaClient, "project:huge")
+		service := FgaService{
+			client:      fgaClient,
+			cacheBucket: NewMockKeyValue(),
+			checkGuard:  checkGuard{timeout: time.Minute, deny: true},
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := service.CheckRelationships(ctx, oversized)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, errCheckGuardTripped)
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// checkPolicy is how checks on objects of one type are served.
+type checkPolicy struct {
+	// consistency is the consistency preference sent with checks that miss
+	// the cache. Empty uses the OpenFGA server default.
+	consistency openfga.ConsistencyPreference
+	// cacheTTL is how long a cached check result is served. Zero serves it
+	// until the cache is next invalidated.
+	cacheTTL time.Duration
+}
+
+// checkPolicies maps object types to their check policy, falling back to a
+// default for types without one.
+type checkPolicies struct {
+	fallback checkPolicy
+	byType   map[string]checkPolicy
+}
+
+// forObject returns the policy for checks on object, an OpenFGA object ID of
+// the form type:id.
+func (p checkPolicies) forObject(object string) checkPolicy {
+	objectType, _, _ := strings.Cut(object, ":")
+	if policy, ok := p.byType[objectType]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+// String formats the policies as they are configured, for the effective
+// configuration.
+func (p checkPolicies) String() string {
+	entries := make([]string, 0, len(p.byType))
+	for _, objectType := range slices.Sorted(maps.Keys(p.byType)) {
+		policy := p.byType[objectType]
+		entries = append(entries, fmt.Sprintf("%s=%s/%s",
+			objectType, strings.ToLower(string(policy.consistency)), policy.cacheTTL))
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseConsistency parses a consistency preference as configured, e.g.
+// higher_consistency. Empty leaves the choice to the OpenFGA server.
+func parseConsistency(v string) (openfga.ConsistencyPreference, error) {
+	if v == "" {
+		return "", nil
+	}
+	consistency := openfga.ConsistencyPreference(strings.ToUpper(v))
+	switch consistency {
+	case openfga.CONSISTENCYPREFERENCE_MINIMIZE_LATENCY, openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY:
+		return consistency, nil
+	}
+	return "", fmt.Errorf("consistency must be minimize_latency or higher_consistency, got %q", v)
+}
+
+// parseCheckPolicies parses per-type check policies from a comma-separated
+// list of type=consistency/ttl entries, e.g.
+// meeting=higher_consistency/30s,project=/1h. Either part of an entry may be
+// left empty to use the fallback's.
+func parseCheckPolicies(v string, fallback checkPolicy) (map[string]checkPolicy, error) {
+	policies := make(map[string]checkPolicy)
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		objectType, spec, found := strings.Cut(entry, "=")
+		if !found || objectType == "" {
+			return nil, fmt.Errorf("expected type=consistency/ttl, got %q", entry)
+		}
+		consistencyStr, ttlStr, _ := strings.Cut(spec, "/")
+		policy := fallback
+		if consistencyStr != "" {
+			consistency, err := parseConsistency(consistencyStr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", objectType, err)
+			}
+			policy.consistency = consistency
+		}
+		if ttlStr != "" {
+			ttl, err := time.ParseDuration(ttlStr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", objectType, err)
+			}
+			if ttl < 0 {
+				return nil, fmt.Errorf("%s: cache ttl must not be negative", objectType)
+			}
+			policy.cacheTTL = ttl
+		}
+		if _, dup := policies[objectType]; dup {
+			return nil, errors.New(objectType + " is listed more than once")
+		}
+		policies[objectType] = policy
+	}
+	return policies, nil
+}
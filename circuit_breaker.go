@@ -0,0 +1,222 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+
+	. "github.com/openfga/go-sdk/client"
+)
+
+// defaultBreakerCooldown is how long an open breaker fast-fails before it
+// lets a probe call through.
+const defaultBreakerCooldown = 30 * time.Second
+
+// errCircuitOpen is returned instead of calling OpenFGA while the breaker is
+// open.
+var errCircuitOpen = errors.New("openfga circuit breaker is open")
+
+// breakerState is the published state of the OpenFGA circuit breaker, and
+// breakerTrips counts how many times it has opened.
+var (
+	breakerState = expvar.NewString("fga_sync_circuit_breaker_state")
+	breakerTrips = expvar.NewInt("fga_sync_circuit_breaker_trips")
+)
+
+// circuitState is the state of a circuitBreaker.
+type circuitState string
+
+const (
+	// circuitClosed passes every call through.
+	circuitClosed circuitState = "closed"
+	// circuitOpen fast-fails every call until the cooldown elapses.
+	circuitOpen circuitState = "open"
+	// circuitHalfOpen lets a single probe call through; its outcome closes
+	// or reopens the breaker.
+	circuitHalfOpen circuitState = "half_open"
+)
+
+// circuitBreaker stops calls to a failing dependency: after threshold
+// consecutive failures it opens and fast-fails calls for cooldown, then lets
+// one probe call through and closes again if it succeeds.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker returns a closed breaker.
+func newCircuitBreaker(threshold int, cooldown time.Duration, now func() time.Time) *circuitBreaker {
+	b := &circuitBreaker{threshold: threshold, cooldown: cooldown, now: now}
+	b.setState(circuitClosed)
+	return b
+}
+
+// allow reports whether a call may proceed, returning errCircuitOpen if not.
+// A call allowed while half-open is the probe, and must be followed by
+// record.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return errCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return nil
+	case circuitHalfOpen:
+		if b.probing {
+			return errCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed call.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		// A call allowed before the breaker opened; the probe decides.
+		return
+	case circuitHalfOpen:
+		b.probing = false
+	}
+	if !breakerFailure(err) {
+		if b.state != circuitClosed {
+			logger.Info("openfga circuit breaker closed")
+		}
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		if b.state == circuitClosed {
+			logger.With(errKey, err, "failures", b.failures).Error("openfga circuit breaker opened")
+		}
+		b.openedAt = b.now()
+		b.setState(circuitOpen)
+		breakerTrips.Add(1)
+	}
+}
+
+// abandon ends an allowed call whose caller gave up before it completed. Its
+// outcome says nothing about OpenFGA, so the breaker is left as it was; an
+// abandoned probe lets the next call probe instead.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == circuitHalfOpen {
+		b.probing = false
+	}
+}
+
+// setState changes the state and publishes it. Callers hold b.mu.
+func (b *circuitBreaker) setState(state circuitState) {
+	b.state = state
+	breakerState.Set(string(state))
+}
+
+// breakerFailure reports whether err, from a call whose caller was still
+// waiting for it, indicates that OpenFGA is unhealthy. Requests OpenFGA
+// rejected as invalid say nothing about its health, so they do not count.
+func breakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var validationErr openfga.FgaApiValidationError
+	var notFoundErr openfga.FgaApiNotFoundError
+	return !errors.As(err, &validationErr) && !errors.As(err, &notFoundErr)
+}
+
+// breakerFgaClient is an IFgaClient that routes every call through a circuit
+// breaker.
+type breakerFgaClient struct {
+	client  IFgaClient
+	breaker *circuitBreaker
+}
+
+// callThroughBreaker runs call if the breaker allows it and records its
+// outcome. A call whose ctx ended first is not recorded: a deadline or
+// cancellation the caller imposed, such as a message budget, would otherwise
+// open the breaker for every caller because of one slow request.
+func callThroughBreaker[T any](ctx context.Context, b *circuitBreaker, call func() (T, error)) (T, error) {
+	if err := b.allow(); err != nil {
+		var zero T
+		return zero, err
+	}
+	resp, err := call()
+	if ctx.Err() != nil {
+		b.abandon()
+	} else {
+		b.record(err)
+	}
+	return resp, err
+}
+
+// Read implements [IFgaClient.Read].
+func (c breakerFgaClient) Read(
+	ctx context.Context,
+	req ClientReadRequest,
+	options ClientReadOptions,
+) (*ClientReadResponse, error) {
+	return callThroughBreaker(ctx, c.breaker, func() (*ClientReadResponse, error) {
+		return c.client.Read(ctx, req, options)
+	})
+}
+
+// Write implements [IFgaClient.Write].
+func (c breakerFgaClient) Write(ctx context.Context, req ClientWriteRequest) (*ClientWriteResponse, error) {
+	return callThroughBreaker(ctx, c.breaker, func() (*ClientWriteResponse, error) {
+		return c.client.Write(ctx, req)
+	})
+}
+
+// BatchCheck implements [IFgaClient.BatchCheck].
+func (c breakerFgaClient) BatchCheck(
+	ctx context.Context,
+	request ClientBatchCheckRequest,
+	options BatchCheckOptions,
+) (*openfga.BatchCheckResponse, error) {
+	return callThroughBreaker(ctx, c.breaker, func() (*openfga.BatchCheckResponse, error) {
+		return c.client.BatchCheck(ctx, request, options)
+	})
+}
+
+// ListObjects implements [IFgaClient.ListObjects].
+func (c breakerFgaClient) ListObjects(
+	ctx context.Context,
+	body ClientListObjectsRequest,
+	options ClientListObjectsOptions,
+) (*ClientListObjectsResponse, error) {
+	return callThroughBreaker(ctx, c.breaker, func() (*ClientListObjectsResponse, error) {
+		return c.client.ListObjects(ctx, body, options)
+	})
+}
+
+// ReadAuthorizationModel implements [IFgaClient.ReadAuthorizationModel].
+func (c breakerFgaClient) ReadAuthorizationModel(ctx context.Context) (*ClientReadAuthorizationModelResponse, error) {
+	return callThroughBreaker(ctx, c.breaker, func() (*ClientReadAuthorizationModelResponse, error) {
+		return c.client.ReadAuthorizationModel(ctx)
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCircuitBreaker_Lifecycle(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	mockClient := new(MockFgaClient)
+	fgaClient := breakerFgaClient{
+		client:  mockClient,
+		breaker: newCircuitBreaker(3, 30*time.Second, clock.now),
+	}
+	ctx := context.Background()
+	outage := errors.New("connection refused")
+	read := func() error {
+		_, err := fgaClient.Read(ctx, client.ClientReadRequest{}, client.ClientReadOptions{})
+		return err
+	}
+
+	// Closed: failures below the threshold still reach OpenFGA.
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), outage).Times(3)
+	for range 2 {
+		assert.ErrorIs(t, read(), outage)
+	}
+	assert.Equal(t, `"closed"`, breakerState.String())
+
+	// The third consecutive failure opens the breaker.
+	assert.ErrorIs(t, read(), outage)
+	assert.Equal(t, `"open"`, breakerState.String())
+
+	// Open: calls fail fast without reaching OpenFGA.
+	assert.ErrorIs(t, read(), errCircuitOpen)
+	clock.advance(29 * time.Second)
+	assert.ErrorIs(t, read(), errCircuitOpen)
+	mockClient.AssertNumberOfCalls(t, "Read", 3)
+
+	// Half-open: after the cooldown a failed probe reopens the breaker.
+	clock.advance(time.Second)
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), outage).Once()
+	assert.ErrorIs(t, read(), outage)
+	assert.Equal(t, `"open"`, breakerState.String())
+	assert.ErrorIs(t, read(), errCircuitOpen)
+
+	// A successful probe closes it again.
+	clock.advance(30 * time.Second)
+	mockClient.ExpectedCalls = nil
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.ClientReadResponse{}, nil)
+	assert.NoError(t, read())
+	assert.Equal(t, `"closed"`, breakerState.String())
+	assert.NoError(t, read())
+}
+
+func TestCircuitBreaker_HalfOpenAllowsSingleProbe(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	breaker := newCircuitBreaker(1, time.Minute, clock.now)
+	breaker.record(errors.New("timeout"))
+
+	clock.advance(time.Minute)
+	assert.NoError(t, breaker.allow(), "the first call after the cooldown is the probe")
+	assert.ErrorIs(t, breaker.allow(), errCircuitOpen, "other calls fail fast while the probe is in flight")
+	breaker.record(nil)
+	assert.NoError(t, breaker.allow())
+}
+
+func TestCircuitBreaker_IgnoresClientErrors(t *testing.T) {
+	breaker := newCircuitBreaker(1, time.Minute, time.Now)
+
+	breaker.record(makeValidationError("Invalid tuple 'a#b@c'"))
+
+	assert.NoError(t, breaker.allow(), "rejected requests should not open the breaker")
+}
+
+// TestCircuitBreaker_IgnoresCallersGivingUp tests that calls whose context
+// ended, e.g. on a message budget, neither open the breaker nor, as a
+// half-open probe, close it.
+func TestCircuitBreaker_IgnoresCallersGivingUp(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	mockClient := new(MockFgaClient)
+	fgaClient := breakerFgaClient{
+		client:  mockClient,
+		breaker: newCircuitBreaker(1, time.Minute, clock.now),
+	}
+	expired, cancel := context.WithDeadline(context.Background(), clock.t)
+	defer cancel()
+	read := func(ctx context.Context) error {
+		_, err := fgaClient.Read(ctx, client.ClientReadRequest{}, client.ClientReadOptions{})
+		return err
+	}
+
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), context.DeadlineExceeded).Once()
+	assert.ErrorIs(t, read(expired), context.DeadlineExceeded)
+	assert.Equal(t, `"closed"`, breakerState.String(), "a caller's own deadline should not open the breaker")
+
+	// An OpenFGA timeout the caller did not impose still counts.
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), context.DeadlineExceeded).Once()
+	assert.ErrorIs(t, read(context.Background()), context.DeadlineExceeded)
+	assert.Equal(t, `"open"`, breakerState.String())
+
+	// A cancelled probe leaves the breaker half-open for the next probe.
+	clock.advance(time.Minute)
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.ClientReadResponse{}, nil)
+	assert.NoError(t, read(expired))
+	assert.Equal(t, `"half_open"`, breakerState.String(), "a cancelled probe should not close the breaker")
+	assert.NoError(t, read(context.Background()))
+	assert.Equal(t, `"closed"`, breakerState.String())
+}
@@ -0,0 +1,798 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+)
+
+const (
+	defaultNatsURL     = "nats://nats:4222"
+	defaultCacheBucket = "fga-sync-cache"
+	defaultStateBucket = "fga-sync-state"
+
+	// defaultDeleteHeavyMinDeletes and defaultDeleteHeavyRatio flag a sync
+	// deleting at least 10 tuples and more than 5 per tuple written.
+	defaultDeleteHeavyMinDeletes = 10
+	defaultDeleteHeavyRatio      = 5
+)
+
+// fgaStoreConfig identifies an OpenFGA store and the authorization model
+// used with it.
+type fgaStoreConfig struct {
+	apiURL      string
+	storeID     string
+	authModelID string
+}
+
+// Config is the service configuration, loaded from the environment once at
+// startup by LoadConfig.
+type Config struct {
+	// NatsURL is the NATS server to connect to (NATS_URL).
+	NatsURL string
+	// SubjectPrefix replaces the lfx prefix of every subject the service
+	// subscribes to (SUBJECT_PREFIX).
+	SubjectPrefix string
+	// Fga is the primary OpenFGA store (OPENFGA_API_URL, OPENFGA_STORE_ID,
+	// OPENFGA_AUTH_MODEL_ID).
+	Fga fgaStoreConfig
+	// ShadowFga is the optional shadow store, unused when its store ID is
+	// empty (OPENFGA_SHADOW_API_URL, OPENFGA_SHADOW_STORE_ID,
+	// OPENFGA_SHADOW_AUTH_MODEL_ID).
+	ShadowFga fgaStoreConfig
+	// CacheBucket is the JetStream KV bucket for cached checks (CACHE_BUCKET).
+	CacheBucket string
+	// StateBucket is the JetStream KV bucket, without a TTL, for state that
+	// must not expire: object versions, member operation timestamps, grant
+	// expiries and pending deletes (STATE_BUCKET).
+	StateBucket string
+	// UseCache enables the check cache (USE_CACHE).
+	UseCache bool
+	// ReadPageSize is the page size requested by OpenFGA Read calls
+	// (READ_PAGE_SIZE).
+	ReadPageSize int32
+	// CacheIntegrity stores the relation alongside each cached check result
+	// and verifies it on read (CACHE_INTEGRITY_CHECK).
+	CacheIntegrity bool
+	// CacheLookupConcurrency bounds the concurrent cache reads issued for a
+	// check batch (CACHE_LOOKUP_CONCURRENCY).
+	CacheLookupConcurrency int
+	// PreserveConditionalTuples keeps conditional tuples that a sync does not
+	// list (PRESERVE_CONDITIONAL_TUPLES).
+	PreserveConditionalTuples bool
+	// CheckPolicies sets the consistency and cache TTL used to serve checks,
+	// by object type (CHECK_CONSISTENCY, CHECK_CACHE_TTL, CHECK_POLICIES).
+	CheckPolicies checkPolicies
+	// CheckGuard bounds how long a batch of checks may take to resolve in
+	// OpenFGA, optionally denying them once exceeded (CHECK_GUARD_TIMEOUT,
+	// CHECK_GUARD_DENY).
+	CheckGuard checkGuard
+	// DeleteHeavySync flags syncs deleting at least
+	// DELETE_HEAVY_SYNC_MIN_DELETES tuples and more than
+	// DELETE_HEAVY_SYNC_RATIO times as many as they write.
+	DeleteHeavySync deleteHeavyPolicy
+	// ObjectTupleCacheTTL, when non-zero, caches each object's tuple set for
+	// this long after it is read (OBJECT_TUPLE_CACHE_TTL).
+	ObjectTupleCacheTTL time.Duration
+	// ModelCacheTTL is how long the authorization model is reused before it is
+	// read again (MODEL_CACHE_TTL). Zero disables model caching.
+	ModelCacheTTL time.Duration
+
+	// BreakerThreshold, when non-zero, opens the OpenFGA circuit breaker after
+	// this many consecutive failed calls (CIRCUIT_BREAKER_THRESHOLD).
+	BreakerThreshold int
+	// BreakerCooldown is how long the open breaker fast-fails calls before
+	// probing OpenFGA again (CIRCUIT_BREAKER_COOLDOWN).
+	BreakerCooldown time.Duration
+
+	// LogSampleRate logs 1 in every N happy-path dispatches (LOG_SAMPLE_RATE).
+	LogSampleRate uint64
+	// SlowHandlerThreshold is the handler duration above which a message is
+	// always logged (SLOW_HANDLER_THRESHOLD).
+	SlowHandlerThreshold time.Duration
+	// CheckHotspotSampleRate records 1 in every N checked objects in the
+	// hotspot tracker (CHECK_HOTSPOT_SAMPLE_RATE).
+	CheckHotspotSampleRate uint64
+	// CaptureSampleRate logs the payload and applied mutations of 1 in every
+	// N messages, for replay (CAPTURE_SAMPLE_RATE). Zero disables capture.
+	CaptureSampleRate uint64
+
+	// StartupRetry controls connection retries at startup
+	// (STARTUP_RETRY_TIMEOUT, STARTUP_RETRY_BACKOFF).
+	StartupRetry retryConfig
+	// Reply is the success reply sent by sync handlers (REPLY_SUCCESS_PAYLOAD,
+	// REPLY_CONTENT_TYPE, REPLY_STRUCTURED).
+	Reply replyConfig
+
+	// ShadowChecks compares check results against the shadow store
+	// (SHADOW_CHECKS).
+	ShadowChecks bool
+	// HTTPCheck serves access checks over HTTP at POST /check, on the health
+	// check listener (HTTP_CHECK_ENABLED).
+	HTTPCheck bool
+	// StrictDecoding rejects message payloads with unknown fields
+	// (STRICT_PAYLOAD_DECODING).
+	StrictDecoding bool
+	// Maintenance starts the service in maintenance mode, parking writes
+	// (MAINTENANCE_MODE).
+	Maintenance bool
+	// MaintenanceMaxParked bounds the writes parked in maintenance mode
+	// (MAINTENANCE_MAX_PARKED).
+	MaintenanceMaxParked int
+	// StrictReferences rejects references to object types the model does not
+	// allow (STRICT_REFERENCE_VALIDATION).
+	StrictReferences bool
+	// ReferenceExistence controls references to objects missing from
+	// OpenFGA (REFERENCE_EXISTENCE, overridden per type by
+	// REFERENCE_EXISTENCE_OBJECT_TYPES).
+	ReferenceExistence referenceExistencePolicy
+	// ParentCycleDepth, when non-zero, rejects parents that would close a
+	// cycle, checking this many levels of ancestors (PARENT_CYCLE_DEPTH).
+	ParentCycleDepth int
+	// RelationValidation checks synced relations against the model
+	// (RELATION_VALIDATION).
+	RelationValidation relationValidationMode
+	// ObjectTypes maps object types to their object ID prefixes
+	// (OBJECT_TYPE_PREFIXES, STRICT_OBJECT_TYPES).
+	ObjectTypes objectTypeRegistry
+	// VersionedObjectTypes require an expected version on access updates
+	// (VERSIONED_OBJECT_TYPES).
+	VersionedObjectTypes map[string]bool
+	// AdditivePublicTypes treat public=false as "leave unchanged"
+	// (PUBLIC_ADDITIVE_OBJECT_TYPES).
+	AdditivePublicTypes map[string]bool
+	// ProjectRequiredTypes reject access updates without a project reference
+	// (PROJECT_REQUIRED_OBJECT_TYPES).
+	ProjectRequiredTypes map[string]bool
+	// VerifyWriteTypes are read back after every sync to confirm its changes
+	// (VERIFY_WRITE_OBJECT_TYPES).
+	VerifyWriteTypes map[string]bool
+	// EmailResolver resolves member emails to usernames (EMAIL_RESOLVER).
+	EmailResolver emailResolverConfig
+	// ReplyRequiredTypes reject sync messages sent without a reply inbox
+	// (REPLY_REQUIRED_OBJECT_TYPES).
+	ReplyRequiredTypes map[string]bool
+	// PrivateObjectTypes reject access updates that would make an object
+	// public (PRIVATE_OBJECT_TYPES).
+	PrivateObjectTypes map[string]bool
+	// PublicInheritance makes objects of a type public when an object they
+	// reference through the given relation is (PUBLIC_INHERITANCE).
+	PublicInheritance publicInheritance
+	// ExclusiveRepair repairs mutually exclusive relations left together by
+	// member_remove (MEMBER_EXCLUSIVE_REPAIR).
+	ExclusiveRepair exclusiveRepairPolicy
+	// DeleteGracePeriod defers delete_access by this long, cancelled by an
+	// update_access in the meantime (DELETE_GRACE_PERIOD). Zero deletes
+	// immediately.
+	DeleteGracePeriod time.Duration
+	// ResyncConcurrency bounds the objects of a resync_objects request
+	// reconciled at once (RESYNC_CONCURRENCY).
+	ResyncConcurrency int
+	// GrantExpiryCondition, when set, is the OpenFGA condition written on
+	// member_put tuples that carry expires_at (GRANT_EXPIRY_CONDITION).
+	// Otherwise the expiry is recorded and the grant swept once it passes.
+	GrantExpiryCondition string
+	// GrantExpirySweepInterval is how often expired grants are swept
+	// (GRANT_EXPIRY_SWEEP_INTERVAL).
+	GrantExpirySweepInterval time.Duration
+
+	// MaxMessageSize is the largest payload, in bytes, passed to a handler;
+	// larger messages are rejected unread (MAX_MESSAGE_SIZE). Zero disables
+	// the check.
+	MaxMessageSize int
+	// MessageBudget, when non-zero, bounds the total time spent handling one
+	// message, including every OpenFGA call and retry; a message exceeding
+	// it is dead-lettered (MESSAGE_BUDGET).
+	MessageBudget time.Duration
+	// DeadLetterSubject, when set, receives a copy of every rejected message
+	// (DEAD_LETTER_SUBJECT).
+	DeadLetterSubject string
+	// AuditSubject, when set, receives an audit record of every access change,
+	// published to the JetStream stream capturing it (AUDIT_SUBJECT).
+	AuditSubject string
+	// PartitionedWorkers, when non-zero, processes FGA sync messages on this
+	// many workers partitioned by object (PARTITIONED_WORKERS).
+	PartitionedWorkers int
+	// BackfillSubjects maps backfill subjects to the live subject whose
+	// handler processes them (BACKFILL_SUBJECTS).
+	BackfillSubjects backfillRoutes
+	// BackfillWorkers is the number of workers processing backfill messages
+	// (BACKFILL_WORKERS).
+	BackfillWorkers int
+
+	// StatsSummaryInterval, when non-zero, logs a summary of processing
+	// counts this often (STATS_SUMMARY_INTERVAL).
+	StatsSummaryInterval time.Duration
+
+	// WatchdogWindow enables the work watchdog when non-zero
+	// (WORK_WATCHDOG_WINDOW).
+	WatchdogWindow time.Duration
+	// WatchdogActiveFrom and WatchdogActiveTo are the UTC hours during which
+	// the watchdog alerts (WORK_WATCHDOG_ACTIVE_HOURS). Equal means all hours.
+	WatchdogActiveFrom, WatchdogActiveTo int
+	// WatchdogAlertSubject, when set, receives an alert event when the
+	// watchdog trips (WORK_WATCHDOG_ALERT_SUBJECT).
+	WatchdogAlertSubject string
+}
+
+// defaultConfig returns the configuration used when no environment variables
+// are set.
+func defaultConfig() Config {
+	return Config{
+		NatsURL:                defaultNatsURL,
+		SubjectPrefix:          defaultSubjectPrefix,
+		CacheBucket:            defaultCacheBucket,
+		StateBucket:            defaultStateBucket,
+		ModelCacheTTL:          defaultModelCacheTTL,
+		ReadPageSize:           defaultReadPageSize,
+		CacheLookupConcurrency: defaultCacheLookupConcurrency,
+		BreakerCooldown:        defaultBreakerCooldown,
+		DeleteHeavySync: deleteHeavyPolicy{
+			minDeletes: defaultDeleteHeavyMinDeletes,
+			ratio:      defaultDeleteHeavyRatio,
+		},
+		LogSampleRate:            1,
+		SlowHandlerThreshold:     defaultSlowHandlerThreshold,
+		CheckHotspotSampleRate:   defaultHotspotSampleRate,
+		MaintenanceMaxParked:     defaultMaintenanceMaxParked,
+		BackfillWorkers:          defaultBackfillWorkers,
+		ResyncConcurrency:        defaultResyncConcurrency,
+		GrantExpirySweepInterval: defaultGrantExpirySweepInterval,
+		StartupRetry: retryConfig{
+			timeout:        defaultStartupRetryTimeout,
+			initialBackoff: defaultStartupRetryBackoff,
+			maxBackoff:     maxStartupRetryBackoff,
+		},
+		Reply:                replyConfig{payload: []byte(defaultSuccessReply)},
+		ObjectTypes:          newObjectTypeRegistry(nil, false),
+		VersionedObjectTypes: map[string]bool{},
+		AdditivePublicTypes:  map[string]bool{},
+		ProjectRequiredTypes: map[string]bool{},
+		PrivateObjectTypes:   map[string]bool{},
+	}
+}
+
+// LoadConfig reads the configuration from the environment, applying defaults
+// for unset variables. Every malformed or out-of-range value is reported in
+// the returned error.
+func LoadConfig() (Config, error) {
+	cfg := defaultConfig()
+	var errs []error
+	parse := func(name string, fn func(string) error) {
+		if v := os.Getenv(name); v != "" {
+			if err := fn(v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, v, err))
+			}
+		}
+	}
+
+	parse("NATS_URL", func(v string) error { cfg.NatsURL = v; return nil })
+	parse("SUBJECT_PREFIX", func(v string) error { cfg.SubjectPrefix = v; return nil })
+	parse("CACHE_BUCKET", func(v string) error { cfg.CacheBucket = v; return nil })
+	parse("STATE_BUCKET", func(v string) error { cfg.StateBucket = v; return nil })
+	cfg.Fga = fgaStoreConfig{
+		apiURL:      os.Getenv("OPENFGA_API_URL"),
+		storeID:     os.Getenv("OPENFGA_STORE_ID"),
+		authModelID: os.Getenv("OPENFGA_AUTH_MODEL_ID"),
+	}
+	cfg.ShadowFga = fgaStoreConfig{
+		apiURL:      os.Getenv("OPENFGA_SHADOW_API_URL"),
+		storeID:     os.Getenv("OPENFGA_SHADOW_STORE_ID"),
+		authModelID: os.Getenv("OPENFGA_SHADOW_AUTH_MODEL_ID"),
+	}
+	if cfg.ShadowFga.apiURL == "" {
+		cfg.ShadowFga.apiURL = cfg.Fga.apiURL
+	}
+	cfg.UseCache = os.Getenv("USE_CACHE") == trueString
+	cfg.CacheIntegrity = os.Getenv("CACHE_INTEGRITY_CHECK") == trueString
+	cfg.PreserveConditionalTuples = os.Getenv("PRESERVE_CONDITIONAL_TUPLES") == trueString
+	parse("CHECK_CONSISTENCY", func(v string) error {
+		consistency, err := parseConsistency(v)
+		cfg.CheckPolicies.fallback.consistency = consistency
+		return err
+	})
+	parse("CHECK_CACHE_TTL", durationInto(&cfg.CheckPolicies.fallback.cacheTTL))
+	parse("CHECK_POLICIES", func(v string) error {
+		policies, err := parseCheckPolicies(v, cfg.CheckPolicies.fallback)
+		cfg.CheckPolicies.byType = policies
+		return err
+	})
+	parse("CHECK_GUARD_TIMEOUT", durationInto(&cfg.CheckGuard.timeout))
+	cfg.CheckGuard.deny = os.Getenv("CHECK_GUARD_DENY") == trueString
+	parse("DELETE_HEAVY_SYNC_MIN_DELETES", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.DeleteHeavySync.minDeletes = n
+		return err
+	})
+	parse("DELETE_HEAVY_SYNC_RATIO", func(v string) error {
+		ratio, err := strconv.ParseFloat(v, 64)
+		cfg.DeleteHeavySync.ratio = ratio
+		return err
+	})
+	parse("OBJECT_TUPLE_CACHE_TTL", durationInto(&cfg.ObjectTupleCacheTTL))
+	parse("MODEL_CACHE_TTL", durationInto(&cfg.ModelCacheTTL))
+	parse("READ_PAGE_SIZE", func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 32)
+		cfg.ReadPageSize = int32(n)
+		return err
+	})
+	parse("CACHE_LOOKUP_CONCURRENCY", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.CacheLookupConcurrency = n
+		return err
+	})
+	parse("CIRCUIT_BREAKER_THRESHOLD", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.BreakerThreshold = n
+		return err
+	})
+	parse("CIRCUIT_BREAKER_COOLDOWN", durationInto(&cfg.BreakerCooldown))
+	parse("MAINTENANCE_MAX_PARKED", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.MaintenanceMaxParked = n
+		return err
+	})
+	parse("LOG_SAMPLE_RATE", uintInto(&cfg.LogSampleRate))
+	parse("SLOW_HANDLER_THRESHOLD", durationInto(&cfg.SlowHandlerThreshold))
+	parse("CHECK_HOTSPOT_SAMPLE_RATE", uintInto(&cfg.CheckHotspotSampleRate))
+	parse("CAPTURE_SAMPLE_RATE", uintInto(&cfg.CaptureSampleRate))
+	parse("STARTUP_RETRY_TIMEOUT", durationInto(&cfg.StartupRetry.timeout))
+	parse("STARTUP_RETRY_BACKOFF", durationInto(&cfg.StartupRetry.initialBackoff))
+	cfg.Reply = replyConfigFromEnv()
+
+	cfg.ShadowChecks = os.Getenv("SHADOW_CHECKS") == trueString
+	cfg.StrictReferences = os.Getenv("STRICT_REFERENCE_VALIDATION") == trueString
+	parse("REFERENCE_EXISTENCE", func(v string) error {
+		var err error
+		cfg.ReferenceExistence.fallback, err = parseReferenceExistenceMode(v)
+		return err
+	})
+	parse("REFERENCE_EXISTENCE_OBJECT_TYPES", func(v string) error {
+		var err error
+		cfg.ReferenceExistence.byType, err = parseReferenceExistenceOverrides(v)
+		return err
+	})
+	parse("PARENT_CYCLE_DEPTH", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.ParentCycleDepth = n
+		return err
+	})
+	cfg.HTTPCheck = os.Getenv("HTTP_CHECK_ENABLED") == trueString
+	cfg.StrictDecoding = os.Getenv("STRICT_PAYLOAD_DECODING") == trueString
+	cfg.Maintenance = os.Getenv("MAINTENANCE_MODE") == trueString
+	cfg.RelationValidation = relationValidationMode(os.Getenv("RELATION_VALIDATION"))
+	var objectTypePrefixes map[string]string
+	parse("OBJECT_TYPE_PREFIXES", func(v string) error {
+		var err error
+		objectTypePrefixes, err = parseObjectTypePrefixes(v)
+		return err
+	})
+	cfg.ObjectTypes = newObjectTypeRegistry(objectTypePrefixes, os.Getenv("STRICT_OBJECT_TYPES") == trueString)
+	cfg.VersionedObjectTypes = objectTypeSetFromEnv("VERSIONED_OBJECT_TYPES")
+	cfg.AdditivePublicTypes = objectTypeSetFromEnv("PUBLIC_ADDITIVE_OBJECT_TYPES")
+	cfg.ProjectRequiredTypes = objectTypeSetFromEnv("PROJECT_REQUIRED_OBJECT_TYPES")
+	cfg.ReplyRequiredTypes = objectTypeSetFromEnv("REPLY_REQUIRED_OBJECT_TYPES")
+	cfg.VerifyWriteTypes = objectTypeSetFromEnv("VERIFY_WRITE_OBJECT_TYPES")
+	parse("EMAIL_RESOLVER", func(v string) error {
+		var err error
+		cfg.EmailResolver, err = parseEmailResolver(v)
+		return err
+	})
+	cfg.PrivateObjectTypes = objectTypeSetFromEnv("PRIVATE_OBJECT_TYPES")
+	parse("PUBLIC_INHERITANCE", func(v string) error {
+		var err error
+		cfg.PublicInheritance, err = parsePublicInheritance(v)
+		return err
+	})
+	cfg.ExclusiveRepair = exclusiveRepairPolicy(os.Getenv("MEMBER_EXCLUSIVE_REPAIR"))
+	parse("DELETE_GRACE_PERIOD", durationInto(&cfg.DeleteGracePeriod))
+	parse("RESYNC_CONCURRENCY", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.ResyncConcurrency = n
+		return err
+	})
+	cfg.GrantExpiryCondition = os.Getenv("GRANT_EXPIRY_CONDITION")
+	parse("GRANT_EXPIRY_SWEEP_INTERVAL", durationInto(&cfg.GrantExpirySweepInterval))
+
+	parse("MAX_MESSAGE_SIZE", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.MaxMessageSize = n
+		return err
+	})
+	parse("MESSAGE_BUDGET", durationInto(&cfg.MessageBudget))
+	cfg.DeadLetterSubject = os.Getenv("DEAD_LETTER_SUBJECT")
+	cfg.AuditSubject = os.Getenv("AUDIT_SUBJECT")
+	parse("PARTITIONED_WORKERS", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.PartitionedWorkers = n
+		return err
+	})
+	parse("BACKFILL_SUBJECTS", func(v string) error {
+		var err error
+		cfg.BackfillSubjects, err = parseBackfillRoutes(v)
+		return err
+	})
+	parse("BACKFILL_WORKERS", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.BackfillWorkers = n
+		return err
+	})
+
+	parse("STATS_SUMMARY_INTERVAL", durationInto(&cfg.StatsSummaryInterval))
+
+	parse("WORK_WATCHDOG_WINDOW", durationInto(&cfg.WatchdogWindow))
+	parse("WORK_WATCHDOG_ACTIVE_HOURS", func(v string) error {
+		fromStr, toStr, found := strings.Cut(v, "-")
+		if !found {
+			return errors.New("expected e.g. 8-20")
+		}
+		from, err := strconv.Atoi(fromStr)
+		if err != nil {
+			return err
+		}
+		to, err := strconv.Atoi(toStr)
+		if err != nil {
+			return err
+		}
+		cfg.WatchdogActiveFrom, cfg.WatchdogActiveTo = from, to
+		return nil
+	})
+	cfg.WatchdogAlertSubject = os.Getenv("WORK_WATCHDOG_ALERT_SUBJECT")
+
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that every value is within its allowed range.
+func (c Config) Validate() error {
+	var errs []error
+	if c.NatsURL == "" {
+		errs = append(errs, errors.New("NATS_URL must not be empty"))
+	}
+	if err := validateSubjectPrefix(c.SubjectPrefix); err != nil {
+		errs = append(errs, fmt.Errorf("SUBJECT_PREFIX %w", err))
+	}
+	if c.CacheBucket == "" {
+		errs = append(errs, errors.New("CACHE_BUCKET must not be empty"))
+	}
+	if c.StateBucket == "" {
+		errs = append(errs, errors.New("STATE_BUCKET must not be empty"))
+	}
+	if c.AuditSubject != "" {
+		if err := validateSubjectPrefix(c.AuditSubject); err != nil {
+			errs = append(errs, fmt.Errorf("AUDIT_SUBJECT %w", err))
+		} else if strings.HasPrefix(c.AuditSubject, newSubjectSet(c.SubjectPrefix).of(constants.FgaSyncSubjectPrefix)) {
+			// The service would consume its own audit records.
+			errs = append(errs, errors.New("AUDIT_SUBJECT must be outside the fga-sync subject namespace"))
+		}
+	}
+	// Rejected messages and watchdog alerts published into the consumed
+	// namespace would be rejected again as unhandled, and dead-lettered
+	// without end.
+	for _, published := range []struct{ env, subject string }{
+		{"DEAD_LETTER_SUBJECT", c.DeadLetterSubject},
+		{"WORK_WATCHDOG_ALERT_SUBJECT", c.WatchdogAlertSubject},
+	} {
+		if published.subject != "" &&
+			strings.HasPrefix(published.subject, newSubjectSet(c.SubjectPrefix).of(constants.FgaSyncSubjectPrefix)) {
+			errs = append(errs, fmt.Errorf("%s must be outside the fga-sync subject namespace", published.env))
+		}
+	}
+	if c.CheckPolicies.fallback.cacheTTL < 0 {
+		errs = append(errs, errors.New("CHECK_CACHE_TTL must not be negative"))
+	}
+	if c.CheckGuard.timeout < 0 {
+		errs = append(errs, errors.New("CHECK_GUARD_TIMEOUT must not be negative"))
+	}
+	if c.DeleteHeavySync.minDeletes < 0 {
+		errs = append(errs, errors.New("DELETE_HEAVY_SYNC_MIN_DELETES must not be negative"))
+	}
+	if c.DeleteHeavySync.ratio < 1 {
+		errs = append(errs, errors.New("DELETE_HEAVY_SYNC_RATIO must be at least 1"))
+	}
+	if c.ObjectTupleCacheTTL < 0 {
+		errs = append(errs, errors.New("OBJECT_TUPLE_CACHE_TTL must not be negative"))
+	}
+	if c.ModelCacheTTL < 0 {
+		errs = append(errs, errors.New("MODEL_CACHE_TTL must not be negative"))
+	}
+	if c.ReadPageSize < 1 || c.ReadPageSize > maxReadPageSize {
+		errs = append(errs, fmt.Errorf("READ_PAGE_SIZE must be between 1 and %d", maxReadPageSize))
+	}
+	if c.CacheLookupConcurrency < 1 {
+		errs = append(errs, errors.New("CACHE_LOOKUP_CONCURRENCY must be positive"))
+	}
+	if c.BreakerThreshold < 0 {
+		errs = append(errs, errors.New("CIRCUIT_BREAKER_THRESHOLD must not be negative"))
+	}
+	if c.BreakerCooldown <= 0 {
+		errs = append(errs, errors.New("CIRCUIT_BREAKER_COOLDOWN must be positive"))
+	}
+	if c.MaintenanceMaxParked < 1 {
+		errs = append(errs, errors.New("MAINTENANCE_MAX_PARKED must be positive"))
+	}
+	if c.LogSampleRate == 0 {
+		errs = append(errs, errors.New("LOG_SAMPLE_RATE must be positive"))
+	}
+	if c.SlowHandlerThreshold <= 0 {
+		errs = append(errs, errors.New("SLOW_HANDLER_THRESHOLD must be positive"))
+	}
+	if c.CheckHotspotSampleRate == 0 {
+		errs = append(errs, errors.New("CHECK_HOTSPOT_SAMPLE_RATE must be positive"))
+	}
+	if c.StartupRetry.timeout < 0 {
+		errs = append(errs, errors.New("STARTUP_RETRY_TIMEOUT must not be negative"))
+	}
+	if c.StartupRetry.initialBackoff <= 0 {
+		errs = append(errs, errors.New("STARTUP_RETRY_BACKOFF must be positive"))
+	}
+	switch c.RelationValidation {
+	case relationValidationOff, relationValidationWarn, relationValidationStrict:
+	default:
+		errs = append(errs, fmt.Errorf("RELATION_VALIDATION must be %q or %q, got %q",
+			relationValidationWarn, relationValidationStrict, c.RelationValidation))
+	}
+	switch c.ExclusiveRepair {
+	case exclusiveRepairOff, exclusiveRepairKeepFirst, exclusiveRepairRemoveAll:
+	default:
+		errs = append(errs, fmt.Errorf("MEMBER_EXCLUSIVE_REPAIR must be %q or %q, got %q",
+			exclusiveRepairKeepFirst, exclusiveRepairRemoveAll, c.ExclusiveRepair))
+	}
+	for _, objectType := range slices.Sorted(maps.Keys(c.PublicInheritance)) {
+		if c.PrivateObjectTypes[objectType] {
+			errs = append(errs, fmt.Errorf("PUBLIC_INHERITANCE: %s is listed in PRIVATE_OBJECT_TYPES", objectType))
+		}
+	}
+	if c.DeleteGracePeriod < 0 {
+		errs = append(errs, errors.New("DELETE_GRACE_PERIOD must not be negative"))
+	}
+	if c.ResyncConcurrency < 1 {
+		errs = append(errs, errors.New("RESYNC_CONCURRENCY must be at least 1"))
+	}
+	if c.GrantExpirySweepInterval <= 0 {
+		errs = append(errs, errors.New("GRANT_EXPIRY_SWEEP_INTERVAL must be positive"))
+	}
+	if c.MaxMessageSize < 0 {
+		errs = append(errs, errors.New("MAX_MESSAGE_SIZE must not be negative"))
+	}
+	if c.MessageBudget < 0 {
+		errs = append(errs, errors.New("MESSAGE_BUDGET must not be negative"))
+	}
+	if c.ParentCycleDepth < 0 {
+		errs = append(errs, errors.New("PARENT_CYCLE_DEPTH must not be negative"))
+	}
+	if c.PartitionedWorkers < 0 {
+		errs = append(errs, errors.New("PARTITIONED_WORKERS must not be negative"))
+	}
+	for backfill := range c.BackfillSubjects {
+		if strings.HasPrefix(backfill, newSubjectSet(c.SubjectPrefix).of(constants.FgaSyncSubjectPrefix)) {
+			// The wildcard subscription would route it as live traffic.
+			errs = append(errs, fmt.Errorf("BACKFILL_SUBJECTS: %s must be outside the fga-sync subject namespace", backfill))
+		}
+	}
+	if c.BackfillWorkers < 1 {
+		errs = append(errs, errors.New("BACKFILL_WORKERS must be at least 1"))
+	}
+	if c.StatsSummaryInterval < 0 {
+		errs = append(errs, errors.New("STATS_SUMMARY_INTERVAL must not be negative"))
+	}
+	if c.WatchdogWindow < 0 {
+		errs = append(errs, errors.New("WORK_WATCHDOG_WINDOW must not be negative"))
+	}
+	if c.WatchdogActiveFrom < 0 || c.WatchdogActiveFrom > 23 || c.WatchdogActiveTo < 0 || c.WatchdogActiveTo > 24 {
+		errs = append(errs, errors.New("WORK_WATCHDOG_ACTIVE_HOURS must be within 0-24"))
+	}
+	return errors.Join(errs...)
+}
+
+// durationInto returns a parser storing a time.Duration into dst.
+func durationInto(dst *time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*dst = d
+		return nil
+	}
+}
+
+// uintInto returns a parser storing an unsigned integer into dst.
+func uintInto(dst *uint64) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+// objectTypeSetFromEnv parses a comma-separated list of object types from the
+// named environment variable.
+func objectTypeSetFromEnv(name string) map[string]bool {
+	set := make(map[string]bool)
+	for _, objectType := range strings.Split(os.Getenv(name), ",") {
+		if objectType = strings.TrimSpace(objectType); objectType != "" {
+			set[objectType] = true
+		}
+	}
+	return set
+}
+
+// newHandlerService builds the handler service and its FGA service from cfg
+// and the connected clients.
+func newHandlerService(cfg Config, fgaClient, shadowClient IFgaClient, cacheBucket, stateBucket INatsKeyValue) HandlerService {
+	var models *modelCache
+	if cfg.ModelCacheTTL > 0 {
+		models = newModelCache(cfg.ModelCacheTTL)
+	}
+	var objectTuples *objectTupleCache
+	if cfg.ObjectTupleCacheTTL > 0 {
+		objectTuples = newObjectTupleCache(cfg.ObjectTupleCacheTTL, time.Now)
+	}
+	// Calls are counted inside the breaker, so that only calls which reach
+	// OpenFGA are counted.
+	fgaClient = countingFgaClient{client: fgaClient}
+	if cfg.BreakerThreshold > 0 {
+		fgaClient = breakerFgaClient{
+			client:  fgaClient,
+			breaker: newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, time.Now),
+		}
+	}
+	return HandlerService{
+		fgaService: FgaService{
+			client:                    fgaClient,
+			cacheBucket:               cacheBucket,
+			stateBucket:               stateBucket,
+			useCache:                  cfg.UseCache,
+			cacheIntegrity:            cfg.CacheIntegrity,
+			shadowClient:              shadowClient,
+			shadowChecks:              cfg.ShadowChecks,
+			modelCache:                models,
+			objectReads:               newTupleReadGroup(),
+			objectTuples:              objectTuples,
+			readPageSize:              cfg.ReadPageSize,
+			cacheLookupConcurrency:    cfg.CacheLookupConcurrency,
+			preserveConditionalTuples: cfg.PreserveConditionalTuples,
+			checkPolicies:             cfg.CheckPolicies,
+			checkGuard:                cfg.CheckGuard,
+			deleteHeavy:               cfg.DeleteHeavySync,
+			verifyWriteTypes:          cfg.VerifyWriteTypes,
+		},
+		strictReferences:     cfg.StrictReferences,
+		referenceExistence:   cfg.ReferenceExistence,
+		parentCycleDepth:     cfg.ParentCycleDepth,
+		relationValidation:   cfg.RelationValidation,
+		versionedObjectTypes: cfg.VersionedObjectTypes,
+		additivePublicTypes:  cfg.AdditivePublicTypes,
+		projectRequiredTypes: cfg.ProjectRequiredTypes,
+		replyRequiredTypes:   cfg.ReplyRequiredTypes,
+		emailResolver:        cfg.EmailResolver,
+		privateObjectTypes:   cfg.PrivateObjectTypes,
+		publicInheritance:    cfg.PublicInheritance,
+		exclusiveRepair:      cfg.ExclusiveRepair,
+		deleteGracePeriod:    cfg.DeleteGracePeriod,
+		resyncConcurrency:    cfg.ResyncConcurrency,
+		grantExpiryCondition: cfg.GrantExpiryCondition,
+		config:               cfg,
+		tuplePostProcessors:  tuplePostProcessors,
+	}
+}
+
+// redactedValue replaces secrets in the effective configuration.
+const redactedValue = "REDACTED"
+
+// effective returns the configuration keyed by environment variable, for
+// operators to compare against what they meant to deploy. Credentials
+// embedded in URLs are redacted.
+func (c Config) effective() map[string]any {
+	return map[string]any{
+		"NATS_URL":                         redactURL(c.NatsURL),
+		"SUBJECT_PREFIX":                   c.SubjectPrefix,
+		"OPENFGA_API_URL":                  redactURL(c.Fga.apiURL),
+		"OPENFGA_STORE_ID":                 c.Fga.storeID,
+		"OPENFGA_AUTH_MODEL_ID":            c.Fga.authModelID,
+		"OPENFGA_SHADOW_API_URL":           redactURL(c.ShadowFga.apiURL),
+		"OPENFGA_SHADOW_STORE_ID":          c.ShadowFga.storeID,
+		"OPENFGA_SHADOW_AUTH_MODEL_ID":     c.ShadowFga.authModelID,
+		"CACHE_BUCKET":                     c.CacheBucket,
+		"STATE_BUCKET":                     c.StateBucket,
+		"USE_CACHE":                        c.UseCache,
+		"READ_PAGE_SIZE":                   c.ReadPageSize,
+		"CACHE_INTEGRITY_CHECK":            c.CacheIntegrity,
+		"CACHE_LOOKUP_CONCURRENCY":         c.CacheLookupConcurrency,
+		"PRESERVE_CONDITIONAL_TUPLES":      c.PreserveConditionalTuples,
+		"CHECK_CONSISTENCY":                strings.ToLower(string(c.CheckPolicies.fallback.consistency)),
+		"CHECK_CACHE_TTL":                  c.CheckPolicies.fallback.cacheTTL.String(),
+		"CHECK_POLICIES":                   c.CheckPolicies.String(),
+		"CHECK_GUARD_TIMEOUT":              c.CheckGuard.timeout.String(),
+		"CHECK_GUARD_DENY":                 c.CheckGuard.deny,
+		"DELETE_HEAVY_SYNC_MIN_DELETES":    c.DeleteHeavySync.minDeletes,
+		"DELETE_HEAVY_SYNC_RATIO":          c.DeleteHeavySync.ratio,
+		"OBJECT_TUPLE_CACHE_TTL":           c.ObjectTupleCacheTTL.String(),
+		"MODEL_CACHE_TTL":                  c.ModelCacheTTL.String(),
+		"CIRCUIT_BREAKER_THRESHOLD":        c.BreakerThreshold,
+		"CIRCUIT_BREAKER_COOLDOWN":         c.BreakerCooldown.String(),
+		"LOG_SAMPLE_RATE":                  c.LogSampleRate,
+		"SLOW_HANDLER_THRESHOLD":           c.SlowHandlerThreshold.String(),
+		"CHECK_HOTSPOT_SAMPLE_RATE":        c.CheckHotspotSampleRate,
+		"CAPTURE_SAMPLE_RATE":              c.CaptureSampleRate,
+		"STARTUP_RETRY_TIMEOUT":            c.StartupRetry.timeout.String(),
+		"STARTUP_RETRY_BACKOFF":            c.StartupRetry.initialBackoff.String(),
+		"REPLY_SUCCESS_PAYLOAD":            string(c.Reply.payload),
+		"REPLY_CONTENT_TYPE":               c.Reply.contentType,
+		"REPLY_STRUCTURED":                 c.Reply.structured,
+		"SHADOW_CHECKS":                    c.ShadowChecks,
+		"STRICT_REFERENCE_VALIDATION":      c.StrictReferences,
+		"REFERENCE_EXISTENCE":              c.ReferenceExistence.fallback,
+		"REFERENCE_EXISTENCE_OBJECT_TYPES": c.ReferenceExistence.String(),
+		"PARENT_CYCLE_DEPTH":               c.ParentCycleDepth,
+		"HTTP_CHECK_ENABLED":               c.HTTPCheck,
+		"STRICT_PAYLOAD_DECODING":          c.StrictDecoding,
+		"MAINTENANCE_MODE":                 c.Maintenance,
+		"MAINTENANCE_MAX_PARKED":           c.MaintenanceMaxParked,
+		"RELATION_VALIDATION":              c.RelationValidation,
+		"OBJECT_TYPE_PREFIXES":             c.ObjectTypes.String(),
+		"STRICT_OBJECT_TYPES":              c.ObjectTypes.strict,
+		"VERSIONED_OBJECT_TYPES":           slices.Sorted(maps.Keys(c.VersionedObjectTypes)),
+		"PUBLIC_ADDITIVE_OBJECT_TYPES":     slices.Sorted(maps.Keys(c.AdditivePublicTypes)),
+		"PROJECT_REQUIRED_OBJECT_TYPES":    slices.Sorted(maps.Keys(c.ProjectRequiredTypes)),
+		"REPLY_REQUIRED_OBJECT_TYPES":      slices.Sorted(maps.Keys(c.ReplyRequiredTypes)),
+		"VERIFY_WRITE_OBJECT_TYPES":        slices.Sorted(maps.Keys(c.VerifyWriteTypes)),
+		"EMAIL_RESOLVER":                   c.EmailResolver.String(),
+		"PRIVATE_OBJECT_TYPES":             slices.Sorted(maps.Keys(c.PrivateObjectTypes)),
+		"PUBLIC_INHERITANCE":               c.PublicInheritance.String(),
+		"MEMBER_EXCLUSIVE_REPAIR":          c.ExclusiveRepair,
+		"DELETE_GRACE_PERIOD":              c.DeleteGracePeriod.String(),
+		"RESYNC_CONCURRENCY":               c.ResyncConcurrency,
+		"GRANT_EXPIRY_CONDITION":           c.GrantExpiryCondition,
+		"GRANT_EXPIRY_SWEEP_INTERVAL":      c.GrantExpirySweepInterval.String(),
+		"MAX_MESSAGE_SIZE":                 c.MaxMessageSize,
+		"MESSAGE_BUDGET":                   c.MessageBudget.String(),
+		"DEAD_LETTER_SUBJECT":              c.DeadLetterSubject,
+		"AUDIT_SUBJECT":                    c.AuditSubject,
+		"PARTITIONED_WORKERS":              c.PartitionedWorkers,
+		"BACKFILL_SUBJECTS":                c.BackfillSubjects.String(),
+		"BACKFILL_WORKERS":                 c.BackfillWorkers,
+		"STATS_SUMMARY_INTERVAL":           c.StatsSummaryInterval.String(),
+		"WORK_WATCHDOG_WINDOW":             c.WatchdogWindow.String(),
+		"WORK_WATCHDOG_ACTIVE_HOURS":       fmt.Sprintf("%d-%d", c.WatchdogActiveFrom, c.WatchdogActiveTo),
+		"WORK_WATCHDOG_ALERT_SUBJECT":      c.WatchdogAlertSubject,
+	}
+}
+
+// redactURL replaces the user information of a URL, which may carry a
+// password or token, with a placeholder. Values that do not parse as a URL
+// are redacted entirely, since they cannot be shown to be safe.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redactedValue
+	}
+	if u.User != nil {
+		u.User = url.User(redactedValue)
+	}
+	return u.String()
+}
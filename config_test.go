@@ -0,0 +1,144 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultNatsURL, cfg.NatsURL)
+	assert.Equal(t, defaultCacheBucket, cfg.CacheBucket)
+	assert.Equal(t, defaultStateBucket, cfg.StateBucket)
+	assert.Equal(t, defaultModelCacheTTL, cfg.ModelCacheTTL)
+	assert.Equal(t, int32(defaultReadPageSize), cfg.ReadPageSize)
+	assert.Equal(t, uint64(1), cfg.LogSampleRate)
+	assert.Equal(t, defaultSlowHandlerThreshold, cfg.SlowHandlerThreshold)
+	assert.Equal(t, defaultStartupRetryTimeout, cfg.StartupRetry.timeout)
+	assert.Equal(t, []byte(defaultSuccessReply), cfg.Reply.payload)
+	assert.Zero(t, cfg.WatchdogWindow, "watchdog should be disabled by default")
+}
+
+func TestLoadConfig_FromEnv(t *testing.T) {
+	t.Setenv("NATS_URL", "nats://example:4222")
+	t.Setenv("USE_CACHE", "true")
+	t.Setenv("MODEL_CACHE_TTL", "1m")
+	t.Setenv("LOG_SAMPLE_RATE", "10")
+	t.Setenv("RELATION_VALIDATION", "strict")
+	t.Setenv("VERSIONED_OBJECT_TYPES", "committee, project")
+	t.Setenv("CHECK_CACHE_TTL", "1h")
+	t.Setenv("CHECK_POLICIES", "meeting=higher_consistency/30s, project=minimize_latency")
+	t.Setenv("WORK_WATCHDOG_WINDOW", "15m")
+	t.Setenv("WORK_WATCHDOG_ACTIVE_HOURS", "8-20")
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "nats://example:4222", cfg.NatsURL)
+	assert.True(t, cfg.UseCache)
+	assert.Equal(t, time.Minute, cfg.ModelCacheTTL)
+	assert.Equal(t, uint64(10), cfg.LogSampleRate)
+	assert.Equal(t, relationValidationStrict, cfg.RelationValidation)
+	assert.Equal(t, map[string]bool{"committee": true, "project": true}, cfg.VersionedObjectTypes)
+	assert.Equal(t, map[string]checkPolicy{
+		"meeting": {consistency: openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY, cacheTTL: 30 * time.Second},
+		"project": {consistency: openfga.CONSISTENCYPREFERENCE_MINIMIZE_LATENCY, cacheTTL: time.Hour},
+	}, cfg.CheckPolicies.byType)
+	assert.Equal(t, 15*time.Minute, cfg.WatchdogWindow)
+	assert.Equal(t, 8, cfg.WatchdogActiveFrom)
+	assert.Equal(t, 20, cfg.WatchdogActiveTo)
+}
+
+func TestLoadConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		value   string
+		wantErr string
+	}{
+		{name: "negative delete heavy minimum", env: "DELETE_HEAVY_SYNC_MIN_DELETES", value: "-1", wantErr: "DELETE_HEAVY_SYNC_MIN_DELETES"},
+		{name: "delete heavy ratio below one", env: "DELETE_HEAVY_SYNC_RATIO", value: "0.5", wantErr: "DELETE_HEAVY_SYNC_RATIO"},
+		{name: "wildcard subject prefix", env: "SUBJECT_PREFIX", value: "lfx.*", wantErr: "SUBJECT_PREFIX"},
+		{name: "negative object tuple cache TTL", env: "OBJECT_TUPLE_CACHE_TTL", value: "-1s", wantErr: "OBJECT_TUPLE_CACHE_TTL"},
+		{name: "invalid object type prefix", env: "OBJECT_TYPE_PREFIXES", value: "survey=survey", wantErr: "OBJECT_TYPE_PREFIXES"},
+		{name: "wildcard audit subject", env: "AUDIT_SUBJECT", value: "audit.>", wantErr: "AUDIT_SUBJECT"},
+		{name: "audit subject in fga-sync namespace", env: "AUDIT_SUBJECT", value: "lfx.fga-sync.audit", wantErr: "AUDIT_SUBJECT"},
+		{name: "dead letter subject in fga-sync namespace", env: "DEAD_LETTER_SUBJECT", value: "lfx.fga-sync.dead_letter", wantErr: "DEAD_LETTER_SUBJECT"},
+		{name: "watchdog alert subject in fga-sync namespace", env: "WORK_WATCHDOG_ALERT_SUBJECT", value: "lfx.fga-sync.watchdog", wantErr: "WORK_WATCHDOG_ALERT_SUBJECT"},
+		{name: "malformed backfill subjects", env: "BACKFILL_SUBJECTS", value: "lfx.backfill", wantErr: "BACKFILL_SUBJECTS"},
+		{name: "backfill subject in fga-sync namespace", env: "BACKFILL_SUBJECTS", value: "lfx.fga-sync.backfill=lfx.fga-sync.update_access", wantErr: "BACKFILL_SUBJECTS"},
+		{name: "zero backfill workers", env: "BACKFILL_WORKERS", value: "0", wantErr: "BACKFILL_WORKERS"},
+		{name: "negative parent cycle depth", env: "PARENT_CYCLE_DEPTH", value: "-1", wantErr: "PARENT_CYCLE_DEPTH"},
+		{name: "unknown reference existence mode", env: "REFERENCE_EXISTENCE", value: "lenient", wantErr: "REFERENCE_EXISTENCE"},
+		{name: "malformed reference existence override", env: "REFERENCE_EXISTENCE_OBJECT_TYPES", value: "meeting", wantErr: "REFERENCE_EXISTENCE_OBJECT_TYPES"},
+		{name: "negative stats summary interval", env: "STATS_SUMMARY_INTERVAL", value: "-1m", wantErr: "STATS_SUMMARY_INTERVAL"},
+		{name: "zero grant expiry sweep interval", env: "GRANT_EXPIRY_SWEEP_INTERVAL", value: "0s", wantErr: "GRANT_EXPIRY_SWEEP_INTERVAL"},
+		{name: "negative check guard timeout", env: "CHECK_GUARD_TIMEOUT", value: "-1s", wantErr: "CHECK_GUARD_TIMEOUT"},
+		{name: "malformed public inheritance", env: "PUBLIC_INHERITANCE", value: "meeting_attachment", wantErr: "PUBLIC_INHERITANCE"},
+		{name: "unknown email resolver", env: "EMAIL_RESOLVER", value: "lfid", wantErr: "EMAIL_RESOLVER"},
+		{name: "zero resync concurrency", env: "RESYNC_CONCURRENCY", value: "0", wantErr: "RESYNC_CONCURRENCY"},
+		{name: "zero maintenance parked limit", env: "MAINTENANCE_MAX_PARKED", value: "0", wantErr: "MAINTENANCE_MAX_PARKED"},
+		{name: "negative model cache TTL", env: "MODEL_CACHE_TTL", value: "-1m", wantErr: "MODEL_CACHE_TTL"},
+		{name: "malformed duration", env: "SLOW_HANDLER_THRESHOLD", value: "soon", wantErr: "SLOW_HANDLER_THRESHOLD"},
+		{name: "zero slow handler threshold", env: "SLOW_HANDLER_THRESHOLD", value: "0s", wantErr: "SLOW_HANDLER_THRESHOLD"},
+		{name: "zero log sample rate", env: "LOG_SAMPLE_RATE", value: "0", wantErr: "LOG_SAMPLE_RATE"},
+		{name: "negative hotspot sample rate", env: "CHECK_HOTSPOT_SAMPLE_RATE", value: "-5", wantErr: "CHECK_HOTSPOT_SAMPLE_RATE"},
+		{name: "negative retry timeout", env: "STARTUP_RETRY_TIMEOUT", value: "-1s", wantErr: "STARTUP_RETRY_TIMEOUT"},
+		{name: "zero retry backoff", env: "STARTUP_RETRY_BACKOFF", value: "0s", wantErr: "STARTUP_RETRY_BACKOFF"},
+		{name: "unknown relation validation", env: "RELATION_VALIDATION", value: "loud", wantErr: "RELATION_VALIDATION"},
+		{name: "unknown exclusive repair policy", env: "MEMBER_EXCLUSIVE_REPAIR", value: "keep_last", wantErr: "MEMBER_EXCLUSIVE_REPAIR"},
+		{name: "zero read page size", env: "READ_PAGE_SIZE", value: "0", wantErr: "READ_PAGE_SIZE"},
+		{name: "read page size above OpenFGA max", env: "READ_PAGE_SIZE", value: "500", wantErr: "READ_PAGE_SIZE"},
+		{name: "zero cache lookup concurrency", env: "CACHE_LOOKUP_CONCURRENCY", value: "0", wantErr: "CACHE_LOOKUP_CONCURRENCY"},
+		{name: "unknown check consistency", env: "CHECK_CONSISTENCY", value: "eventual", wantErr: "CHECK_CONSISTENCY"},
+		{name: "negative check cache TTL", env: "CHECK_CACHE_TTL", value: "-1s", wantErr: "CHECK_CACHE_TTL"},
+		{name: "malformed check policy", env: "CHECK_POLICIES", value: "meeting", wantErr: "CHECK_POLICIES"},
+		{name: "check policy with bad TTL", env: "CHECK_POLICIES", value: "meeting=/soon", wantErr: "CHECK_POLICIES"},
+		{name: "negative breaker threshold", env: "CIRCUIT_BREAKER_THRESHOLD", value: "-1", wantErr: "CIRCUIT_BREAKER_THRESHOLD"},
+		{name: "zero breaker cooldown", env: "CIRCUIT_BREAKER_COOLDOWN", value: "0s", wantErr: "CIRCUIT_BREAKER_COOLDOWN"},
+		{name: "negative delete grace period", env: "DELETE_GRACE_PERIOD", value: "-1m", wantErr: "DELETE_GRACE_PERIOD"},
+		{name: "negative max message size", env: "MAX_MESSAGE_SIZE", value: "-1", wantErr: "MAX_MESSAGE_SIZE"},
+		{name: "negative message budget", env: "MESSAGE_BUDGET", value: "-1s", wantErr: "MESSAGE_BUDGET"},
+		{name: "negative partitioned workers", env: "PARTITIONED_WORKERS", value: "-2", wantErr: "PARTITIONED_WORKERS"},
+		{name: "negative watchdog window", env: "WORK_WATCHDOG_WINDOW", value: "-1m", wantErr: "WORK_WATCHDOG_WINDOW"},
+		{name: "malformed active hours", env: "WORK_WATCHDOG_ACTIVE_HOURS", value: "morning", wantErr: "WORK_WATCHDOG_ACTIVE_HOURS"},
+		{name: "out of range active hours", env: "WORK_WATCHDOG_ACTIVE_HOURS", value: "8-30", wantErr: "WORK_WATCHDOG_ACTIVE_HOURS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			_, err := LoadConfig()
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadConfig_ReportsEveryError(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "0")
+	t.Setenv("MODEL_CACHE_TTL", "-1m")
+
+	_, err := LoadConfig()
+	assert.ErrorContains(t, err, "LOG_SAMPLE_RATE")
+	assert.ErrorContains(t, err, "MODEL_CACHE_TTL")
+}
+
+func TestNewHandlerService(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.UseCache = true
+	cfg.StrictReferences = true
+	cfg.ModelCacheTTL = 0
+
+	service := newHandlerService(cfg, new(MockFgaClient), nil, NewMockKeyValue(), NewMockKeyValue())
+
+	assert.True(t, service.fgaService.useCache)
+	assert.True(t, service.strictReferences)
+	assert.Nil(t, service.fgaService.modelCache, "zero TTL should disable the model cache")
+	assert.NotNil(t, service.fgaService.objectReads)
+}
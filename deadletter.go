@@ -0,0 +1,38 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+const (
+	// deadLetterSubjectHeader carries the subject a dead-lettered message
+	// originally arrived on.
+	deadLetterSubjectHeader = "Fga-Sync-Original-Subject"
+	// deadLetterReasonHeader carries why the message was dead-lettered.
+	deadLetterReasonHeader = "Fga-Sync-Rejection-Reason"
+)
+
+// deadLetter, when set, receives messages rejected before reaching their
+// handler. It is nil when DEAD_LETTER_SUBJECT is unset.
+var deadLetter func(ctx context.Context, msg INatsMsg, reason string)
+
+// publishDeadLetter returns a dead-letter function republishing rejected
+// messages, with their original subject and rejection reason as headers, to
+// subject on the NATS connection.
+func publishDeadLetter(subject string) func(context.Context, INatsMsg, string) {
+	return func(ctx context.Context, msg INatsMsg, reason string) {
+		dlq := nats.NewMsg(subject)
+		dlq.Data = msg.Data()
+		dlq.Header.Set(deadLetterSubjectHeader, msg.Subject())
+		dlq.Header.Set(deadLetterReasonHeader, reason)
+		if err := natsConn.PublishMsg(dlq); err != nil {
+			logger.With(errKey, err, "subject", subject).ErrorContext(ctx, "failed to publish dead letter")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// strictDecoding rejects message payloads with fields the expected payload
+// does not define, so producers sending misspelled fields find out instead
+// of having the data silently dropped. It is off unless
+// STRICT_PAYLOAD_DECODING is set, since it also rejects benign additions.
+var strictDecoding bool
+
+// decodePayload unmarshals a message payload into v, per strictDecoding.
+func decodePayload(data []byte, v any) error {
+	if !strictDecoding {
+		return json.Unmarshal(data, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// decodeData unmarshals the Data payload of a generic message into v, per
+// strictDecoding.
+func decodeData(msg *types.GenericFGAMessage, v any) error {
+	if !strictDecoding {
+		return msg.UnmarshalData(v)
+	}
+	return msg.UnmarshalDataStrict(v)
+}
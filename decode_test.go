@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestGenericUpdateAccess_UnknownFields tests that payloads with unknown
+// fields, in the envelope or its data, are accepted in lenient mode and
+// rejected before anything is written in strict mode.
+func TestGenericUpdateAccess_UnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{
+			name:    "unknown data field",
+			payload: `{"object_type":"committee","operation":"update_access","data":{"uid":"c1","public":true,"comittees":["c2"]}}`,
+		},
+		{
+			name:    "unknown envelope field",
+			payload: `{"object_type":"committee","operation":"update_access","opertion":"x","data":{"uid":"c1","public":true}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, strict := range []bool{false, true} {
+			name := tt.name + "/lenient"
+			if strict {
+				name = tt.name + "/strict"
+			}
+			t.Run(name, func(t *testing.T) {
+				orig := strictDecoding
+				strictDecoding = strict
+				t.Cleanup(func() { strictDecoding = orig })
+
+				service := setupService()
+				fgaClient := service.fgaService.client.(*MockFgaClient)
+				fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+				fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+				err := service.genericUpdateAccessHandler(context.Background(), CreateMockNatsMsg([]byte(tt.payload)))
+
+				if strict {
+					assert.ErrorContains(t, err, "unknown field")
+					fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+				} else {
+					assert.NoError(t, err)
+					fgaClient.AssertNumberOfCalls(t, "Write", 1)
+				}
+			})
+		}
+	}
+}
+
+// TestDecodePayload tests that strict decoding still accepts payloads using
+// only known fields.
+func TestDecodePayload(t *testing.T) {
+	orig := strictDecoding
+	strictDecoding = true
+	t.Cleanup(func() { strictDecoding = orig })
+
+	var req struct {
+		User string `json:"user"`
+	}
+	assert.NoError(t, decodePayload([]byte(`{"user":"user:alice"}`), &req))
+	assert.Equal(t, "user:alice", req.User)
+	assert.Error(t, decodePayload([]byte(`{"user":"user:alice","usr":"x"}`), &req))
+}
@@ -0,0 +1,129 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// pendingDeleteSweepInterval is how often deferred deletes that have
+	// come due are applied.
+	pendingDeleteSweepInterval = 10 * time.Second
+	// pendingDeleteTimeout is the maximum time allowed for applying one
+	// deferred delete once its grace period has elapsed.
+	pendingDeleteTimeout = 30 * time.Second
+	// pendingDeleteKeyPrefix prefixes the KV keys recording deferred deletes.
+	pendingDeleteKeyPrefix = "pdel."
+)
+
+// pendingDelete is a delete_access deferred by the grace period.
+type pendingDelete struct {
+	Object  string       `json:"object"`
+	Cascade []string     `json:"cascade,omitempty"`
+	DueAt   time.Time    `json:"due_at"`
+	Audit   *auditOrigin `json:"audit,omitempty"`
+}
+
+// scheduleDelete records a deferred delete of object and its cascade
+// dependents, to be applied by the sweeper once the grace period elapses
+// unless an update_access cancels it first. The schedule lives in the KV
+// bucket, so that a cancelling update handled by another replica is seen and
+// a delete pending at shutdown is applied after a restart.
+func (h *HandlerService) scheduleDelete(ctx context.Context, object string, cascade []string) error {
+	pending := pendingDelete{
+		Object:  object,
+		Cascade: cascade,
+		DueAt:   time.Now().Add(h.deleteGracePeriod).UTC(),
+		Audit:   auditOriginOf(ctx),
+	}
+	if err := h.fgaService.SchedulePendingDelete(ctx, pending); err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to schedule delete")
+		return err
+	}
+	logger.With(
+		"object", object,
+		"cascade", cascade,
+		"grace_period", h.deleteGracePeriod,
+	).InfoContext(ctx, "scheduled deferred delete")
+	return nil
+}
+
+// runPendingDeleteSweeper applies deferred deletes as they come due, once at
+// startup and then every interval until ctx is done. Sweeps are skipped
+// during maintenance, and catch up once it ends.
+func (h *HandlerService) runPendingDeleteSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if active, _, _ := maintenance.status(); !active {
+			if _, err := h.sweepPendingDeletes(ctx, time.Now()); err != nil {
+				logger.With(errKey, err).ErrorContext(ctx, "failed to sweep deferred deletes")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepPendingDeletes applies the deferred deletes due by now and returns how
+// many were applied. When a delete fails, it is scheduled again so the next
+// sweep retries it.
+func (h *HandlerService) sweepPendingDeletes(ctx context.Context, now time.Time) (int, error) {
+	// Deletes claimed before a failure are still applied.
+	due, err := h.fgaService.ClaimDuePendingDeletes(ctx, now)
+	errs := []error{err}
+	applied := 0
+	for _, pending := range due {
+		if err := h.applyPendingDelete(ctx, pending); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pending.Object, err))
+			if err := h.fgaService.SchedulePendingDelete(ctx, pending); err != nil {
+				logger.With(errKey, err, "object", pending.Object).ErrorContext(ctx, "failed to restore deferred delete")
+			}
+			continue
+		}
+		applied++
+	}
+	return applied, errors.Join(errs...)
+}
+
+// applyPendingDelete applies a claimed deferred delete, auditing it under the
+// message that scheduled it.
+func (h *HandlerService) applyPendingDelete(ctx context.Context, pending pendingDelete) error {
+	ctx, cancel := context.WithTimeout(withAuditOrigin(ctx, pending.Audit), pendingDeleteTimeout)
+	defer cancel()
+
+	_, err := h.deleteObjectAccess(ctx, pending.Object, pending.Cascade)
+	publishAuditTrail(ctx)
+	if err != nil {
+		logger.With(errKey, err, "object", pending.Object).ErrorContext(ctx, "failed to apply deferred delete")
+		return err
+	}
+	logger.With("object", pending.Object, "cascade", pending.Cascade).InfoContext(ctx, "applied deferred delete")
+	return nil
+}
+
+// cancelPendingDelete cancels any deferred delete of object, because an
+// update for it shows the object still exists. Failures are logged: the
+// update itself still proceeds.
+func (h *HandlerService) cancelPendingDelete(ctx context.Context, object string) {
+	if h.deleteGracePeriod == 0 {
+		return
+	}
+	cancelled, err := h.fgaService.CancelPendingDelete(ctx, object)
+	if err != nil {
+		logger.With(errKey, err, "object", object).WarnContext(ctx, "failed to cancel deferred delete")
+		return
+	}
+	if cancelled {
+		logger.With("object", object).InfoContext(ctx, "cancelled deferred delete")
+	}
+}
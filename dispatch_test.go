@@ -0,0 +1,152 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatchTable_Dispatch(t *testing.T) {
+	const unknownSubject = constants.FgaSyncSubjectPrefix + "does_not_exist"
+
+	tests := []struct {
+		name            string
+		subject         string
+		reply           string
+		expectHandled   bool
+		expectUnhandled int64
+		expectResponse  string
+	}{
+		{
+			name:          "registered subject is routed to its handler",
+			subject:       constants.GenericUpdateAccessSubject,
+			expectHandled: true,
+		},
+		{
+			name:            "unregistered subject without reply is counted",
+			subject:         unknownSubject,
+			expectUnhandled: 1,
+		},
+		{
+			name:            "unregistered subject with reply gets an error reply",
+			subject:         unknownSubject,
+			reply:           "_INBOX.test",
+			expectUnhandled: 1,
+			expectResponse:  `{"status":"error","error":"unhandled subject: ` + unknownSubject + `"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unhandledMessages.Init()
+
+			handled := false
+			table := dispatchTable{
+				constants.GenericUpdateAccessSubject: {
+					subject:     constants.GenericUpdateAccessSubject,
+					description: "generic update access",
+					handler: func(_ context.Context, _ INatsMsg) error {
+						handled = true
+						return nil
+					},
+				},
+			}
+
+			msg := CreateMockNatsMsg([]byte(`{}`))
+			msg.subject = tt.subject
+			msg.reply = tt.reply
+			if tt.expectResponse != "" {
+				msg.On("Respond", []byte(tt.expectResponse)).Return(nil).Once()
+			}
+
+			table.dispatch(context.Background(), constants.FgaSyncQueue, msg)
+
+			assert.Equal(t, tt.expectHandled, handled)
+			var unhandled int64
+			if v, ok := unhandledMessages.Get(tt.subject).(*expvar.Int); ok {
+				unhandled = v.Value()
+			}
+			assert.Equal(t, tt.expectUnhandled, unhandled)
+			msg.AssertExpectations(t)
+		})
+	}
+}
+
+// TestHandleUnhandledSubject tests that unhandled messages are dead-lettered
+// and that subjects past maxUnhandledSubjectLabels are counted together.
+func TestHandleUnhandledSubject(t *testing.T) {
+	origDeadLetter := deadLetter
+	var deadLettered []string
+	deadLetter = func(_ context.Context, msg INatsMsg, reason string) {
+		deadLettered = append(deadLettered, msg.Subject()+": "+reason)
+	}
+	defer func() { deadLetter = origDeadLetter }()
+	unhandledMessages.Init()
+
+	for i := 0; i <= maxUnhandledSubjectLabels; i++ {
+		msg := CreateMockNatsMsg([]byte(`{}`))
+		msg.subject = fmt.Sprintf("%sunknown_%d", constants.FgaSyncSubjectPrefix, i)
+		handleUnhandledSubject(context.Background(), constants.FgaSyncQueue, msg)
+	}
+	first := constants.FgaSyncSubjectPrefix + "unknown_0"
+	msg := CreateMockNatsMsg([]byte(`{}`))
+	msg.subject = first
+	handleUnhandledSubject(context.Background(), constants.FgaSyncQueue, msg)
+
+	labels := 0
+	unhandledMessages.Do(func(expvar.KeyValue) { labels++ })
+	assert.Equal(t, maxUnhandledSubjectLabels+1, labels)
+	if v, ok := unhandledMessages.Get(first).(*expvar.Int); assert.True(t, ok) {
+		assert.Equal(t, int64(2), v.Value())
+	}
+	if v, ok := unhandledMessages.Get(unhandledSubjectOther).(*expvar.Int); assert.True(t, ok) {
+		assert.Equal(t, int64(1), v.Value())
+	}
+	assert.Len(t, deadLettered, maxUnhandledSubjectLabels+2)
+	assert.Equal(t, first+": unhandled subject: "+first, deadLettered[0])
+}
+
+func TestDispatchMessage_RejectsOversizedMessages(t *testing.T) {
+	origMax, origDeadLetter := maxMessageSize, deadLetter
+	maxMessageSize = 16
+	var deadLettered []string
+	deadLetter = func(_ context.Context, msg INatsMsg, reason string) {
+		deadLettered = append(deadLettered, msg.Subject()+": "+reason)
+	}
+	defer func() { maxMessageSize, deadLetter = origMax, origDeadLetter }()
+	oversizedMessages.Init()
+
+	called := false
+	handler := func(_ context.Context, _ INatsMsg) error {
+		called = true
+		return nil
+	}
+
+	msg := CreateMockNatsMsg([]byte(`{"relations":{"participant":["` + strings.Repeat("a", 64) + `"]}}`))
+	msg.subject = constants.GenericUpdateAccessSubject
+	msg.reply = "_INBOX.test"
+	reason := fmt.Sprintf("message size %d bytes exceeds limit of 16 bytes", len(msg.Data()))
+	msg.On("Respond", []byte(`{"status":"error","error":"`+reason+`"}`)).Return(nil).Once()
+
+	dispatchMessage(context.Background(), msg.subject, "generic update access", constants.FgaSyncQueue, handler, msg)
+
+	assert.False(t, called, "oversized message must be rejected before reaching the handler")
+	assert.Equal(t, []string{constants.GenericUpdateAccessSubject + ": " + reason}, deadLettered)
+	if v, ok := oversizedMessages.Get(constants.GenericUpdateAccessSubject).(*expvar.Int); assert.True(t, ok) {
+		assert.Equal(t, int64(1), v.Value())
+	}
+	msg.AssertExpectations(t)
+
+	small := CreateMockNatsMsg([]byte(`{}`))
+	dispatchMessage(context.Background(), "test.subject", "small", constants.FgaSyncQueue, handler, small)
+	assert.True(t, called, "messages within the limit are handled")
+}
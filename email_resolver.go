@@ -0,0 +1,110 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+const (
+	// emailResolverLocalPart resolves an email to its lowercased local part.
+	emailResolverLocalPart = "local_part"
+	// emailResolverRequestPrefix prefixes the subject of a mapping service
+	// that replies to an email with the username it belongs to.
+	emailResolverRequestPrefix = "request:"
+	// emailResolveTimeout bounds a mapping service request.
+	emailResolveTimeout = 5 * time.Second
+)
+
+// requestUsername asks the mapping service on subject for the username of
+// email, returning its reply. It is a variable so tests can answer without a
+// NATS connection.
+var requestUsername = func(ctx context.Context, subject, email string) (string, error) {
+	reply, err := natsConn.RequestWithContext(ctx, subject, []byte(email))
+	if err != nil {
+		return "", err
+	}
+	return string(reply.Data), nil
+}
+
+// emailResolverConfig is how member operations identifying their user by
+// email are resolved to a username: not at all, by transform, or by asking a
+// mapping service.
+type emailResolverConfig struct {
+	mode    string
+	subject string
+}
+
+// parseEmailResolver parses EMAIL_RESOLVER: local_part, or request:<subject>.
+func parseEmailResolver(v string) (emailResolverConfig, error) {
+	switch {
+	case v == emailResolverLocalPart:
+		return emailResolverConfig{mode: v}, nil
+	case strings.HasPrefix(v, emailResolverRequestPrefix):
+		subject := strings.TrimPrefix(v, emailResolverRequestPrefix)
+		if err := validateSubjectPrefix(subject); err != nil {
+			return emailResolverConfig{}, fmt.Errorf("subject %q %w", subject, err)
+		}
+		return emailResolverConfig{mode: emailResolverRequestPrefix, subject: subject}, nil
+	}
+	return emailResolverConfig{}, fmt.Errorf("must be %s or %s<subject>, got %q",
+		emailResolverLocalPart, emailResolverRequestPrefix, v)
+}
+
+// String formats the resolver as it is configured, for the effective
+// configuration.
+func (c emailResolverConfig) String() string {
+	if c.mode == emailResolverRequestPrefix {
+		return c.mode + c.subject
+	}
+	return c.mode
+}
+
+// resolve returns the username of email.
+func (c emailResolverConfig) resolve(ctx context.Context, email string) (string, error) {
+	if c.mode == emailResolverLocalPart {
+		localPart, _, _ := strings.Cut(email, "@")
+		return strings.ToLower(localPart), nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, emailResolveTimeout)
+	defer cancel()
+	username, err := requestUsername(ctx, c.subject, email)
+	return strings.TrimSpace(username), err
+}
+
+// resolveMemberEmail sets the username of a member operation that identifies
+// its user only by email, resolving it with the configured resolver. An
+// email-only operation is rejected if no resolver is configured, or if the
+// email does not resolve to a valid username.
+func (h *HandlerService) resolveMemberEmail(ctx context.Context, data *fgatypes.GenericMemberData) error {
+	if data.Username != "" || data.Email == "" {
+		return nil
+	}
+	if !strings.Contains(data.Email, "@") {
+		logger.ErrorContext(ctx, "invalid email", "email", data.Email)
+		return newFieldError("email", reasonInvalid, fmt.Sprintf("email %q is not an email address", data.Email))
+	}
+	if h.emailResolver.mode == "" {
+		logger.ErrorContext(ctx, "email identity without a resolver", "email", data.Email)
+		return newFieldError("email", reasonNotAllowed, "email identities require EMAIL_RESOLVER to be configured")
+	}
+	username, err := h.emailResolver.resolve(ctx, data.Email)
+	if err != nil {
+		logger.With(errKey, err, "email", data.Email).ErrorContext(ctx, "failed to resolve email")
+		return fmt.Errorf("failed to resolve email %q: %w", data.Email, err)
+	}
+	if username == "" || strings.ContainsAny(username, ":#@ *") {
+		logger.ErrorContext(ctx, "email did not resolve to a username", "email", data.Email, "username", username)
+		return newFieldError("email", reasonInvalid, fmt.Sprintf("email %q did not resolve to a user", data.Email))
+	}
+	logger.DebugContext(ctx, "resolved email to username", "email", data.Email, "username", username)
+	data.Username = username
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveMemberEmail(t *testing.T) {
+	original := requestUsername
+	t.Cleanup(func() { requestUsername = original })
+
+	tests := []struct {
+		name           string
+		resolver       string
+		data           fgatypes.GenericMemberData
+		reply          string
+		replyErr       error
+		expectUsername string
+		expectError    string
+	}{
+		{
+			name:        "no resolver configured",
+			data:        fgatypes.GenericMemberData{Email: "alice@example.com"},
+			expectError: "email identities require EMAIL_RESOLVER to be configured",
+		},
+		{
+			name:           "local part transform",
+			resolver:       "local_part",
+			data:           fgatypes.GenericMemberData{Email: "Alice@Example.com"},
+			expectUsername: "alice",
+		},
+		{
+			name:           "mapping service",
+			resolver:       "request:lfx.users.email_to_username",
+			data:           fgatypes.GenericMemberData{Email: "alice@example.com"},
+			reply:          "lfid-alice\n",
+			expectUsername: "lfid-alice",
+		},
+		{
+			name:        "mapping service finds no user",
+			resolver:    "request:lfx.users.email_to_username",
+			data:        fgatypes.GenericMemberData{Email: "nobody@example.com"},
+			expectError: `email "nobody@example.com" did not resolve to a user`,
+		},
+		{
+			name:        "mapping service unavailable",
+			resolver:    "request:lfx.users.email_to_username",
+			data:        fgatypes.GenericMemberData{Email: "alice@example.com"},
+			replyErr:    errors.New("no responders available for request"),
+			expectError: `failed to resolve email "alice@example.com": no responders available for request`,
+		},
+		{
+			name:        "not an email",
+			resolver:    "local_part",
+			data:        fgatypes.GenericMemberData{Email: "alice"},
+			expectError: `email "alice" is not an email address`,
+		},
+		{
+			name:           "username takes precedence",
+			data:           fgatypes.GenericMemberData{Username: "lfid-alice", Email: "alice@example.com"},
+			expectUsername: "lfid-alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestUsername = func(_ context.Context, subject, email string) (string, error) {
+				assert.Equal(t, "lfx.users.email_to_username", subject)
+				assert.Equal(t, tt.data.Email, email)
+				return tt.reply, tt.replyErr
+			}
+			service := setupService()
+			if tt.resolver != "" {
+				resolver, err := parseEmailResolver(tt.resolver)
+				assert.NoError(t, err)
+				service.emailResolver = resolver
+			}
+
+			data := tt.data
+			err := service.resolveMemberEmail(context.Background(), &data)
+			if tt.expectError != "" {
+				assert.EqualError(t, err, tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectUsername, data.Username)
+		})
+	}
+}
+
+// TestGenericMemberPut_EmailIdentity tests that a member_put identifying its
+// user by email is applied to the resolved user principal, and rejected
+// without a resolver.
+func TestGenericMemberPut_EmailIdentity(t *testing.T) {
+	service := setupService()
+	msg := buildGenericMessage(t, "committee", "member_put", fgatypes.GenericMemberData{
+		UID:       "c1",
+		Email:     "alice@example.com",
+		Relations: []string{"member"},
+	})
+
+	_, _, err := service.parseAndValidateMemberPutMessage(context.Background(), msg)
+	assert.EqualError(t, err, "email identities require EMAIL_RESOLVER to be configured")
+
+	service.emailResolver = emailResolverConfig{mode: emailResolverLocalPart}
+	_, data, err := service.parseAndValidateMemberPutMessage(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", data.Username)
+}
+
+func TestParseEmailResolver(t *testing.T) {
+	for _, v := range []string{"local_part", "request:lfx.users.email_to_username"} {
+		resolver, err := parseEmailResolver(v)
+		assert.NoError(t, err, v)
+		assert.Equal(t, v, resolver.String())
+	}
+	for _, v := range []string{"lfid", "request:", "request:lfx.users.*"} {
+		_, err := parseEmailResolver(v)
+		assert.Error(t, err, v)
+	}
+}
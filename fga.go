@@ -7,14 +7,20 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"expvar"
 	"fmt"
+	"maps"
 	"net/http"
-	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
@@ -31,12 +37,36 @@ import (
 const (
 	// trueString is used for cache values representing allowed access
 	trueString = "true"
+	// defaultReadPageSize is the page size requested by Read calls. It is the
+	// OpenFGA maximum, to minimize round trips when reading large objects.
+	defaultReadPageSize = 100
+	// maxReadPageSize is the largest page size OpenFGA accepts.
+	maxReadPageSize = 100
+	// defaultCacheLookupConcurrency is the number of cache Gets a check batch
+	// issues at once.
+	defaultCacheLookupConcurrency = 16
 )
 
 var (
-	cacheHits       *expvar.Int
-	cacheStaleHits  *expvar.Int
-	cacheMisses     *expvar.Int
+	cacheHits              *expvar.Int
+	cacheStaleHits         *expvar.Int
+	cacheMisses            *expvar.Int
+	shadowWriteErrors      *expvar.Int
+	shadowCheckErrors      *expvar.Int
+	shadowCheckDivergences *expvar.Int
+	cacheKeyCollisions     *expvar.Int
+	// unhandledMessages counts messages on subjects with no registered
+	// handler, keyed by subject, up to maxUnhandledSubjectLabels subjects.
+	unhandledMessages *expvar.Map
+	// oversizedMessages counts messages rejected for exceeding
+	// MAX_MESSAGE_SIZE, keyed by subject.
+	oversizedMessages *expvar.Map
+	// deleteHeavySyncs counts syncs that deleted far more tuples than they
+	// wrote, keyed by object type.
+	deleteHeavySyncs *expvar.Map
+	// excludedTuples counts existing tuples that syncs left in place because
+	// their relation was excluded, keyed by object type.
+	excludedTuples  *expvar.Map
 	cacheKeyEncoder = base32.StdEncoding.WithPadding(base32.NoPadding)
 )
 
@@ -44,6 +74,14 @@ func init() {
 	cacheHits = expvar.NewInt("cache_hits")
 	cacheStaleHits = expvar.NewInt("cache_stale_hits")
 	cacheMisses = expvar.NewInt("cache_misses")
+	shadowWriteErrors = expvar.NewInt("shadow_write_errors")
+	shadowCheckErrors = expvar.NewInt("shadow_check_errors")
+	shadowCheckDivergences = expvar.NewInt("shadow_check_divergences")
+	cacheKeyCollisions = expvar.NewInt("cache_key_collisions")
+	unhandledMessages = expvar.NewMap("fga_sync_unhandled_total")
+	oversizedMessages = expvar.NewMap("fga_sync_oversized_total")
+	deleteHeavySyncs = expvar.NewMap("fga_sync_delete_heavy_syncs")
+	excludedTuples = expvar.NewMap("fga_sync_excluded_tuples_preserved_total")
 }
 
 // INatsKeyValue is a NATS KV interface needed for the [ProjectsService].
@@ -51,21 +89,101 @@ type INatsKeyValue interface {
 	Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error)
 	Put(context.Context, string, []byte) (uint64, error)
 	PutString(context.Context, string, string) (uint64, error)
+	Create(ctx context.Context, key string, value []byte, opts ...jetstream.KVCreateOpt) (uint64, error)
+	Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error)
+	ListKeysFiltered(ctx context.Context, filters ...string) (jetstream.KeyLister, error)
 }
 
 // FgaService is a service for OpenFGA client operations used in this service.
 type FgaService struct {
 	client      IFgaClient
 	cacheBucket INatsKeyValue
+	// stateBucket holds state that must outlive the cache bucket's TTL:
+	// object versions, member operation timestamps, grant expiries and
+	// pending deletes. It has no TTL.
+	stateBucket INatsKeyValue
+	// useCache enables serving checks and fingerprints from cacheBucket.
+	useCache bool
+	// cacheIntegrity stores each cached check result with the relation it
+	// answers and refuses to serve entries whose relation does not match.
+	cacheIntegrity bool
+	// shadowClient, when set, receives a best-effort copy of every write so a
+	// secondary store can be validated before cutover. It is never
+	// authoritative.
+	shadowClient IFgaClient
+	// shadowChecks enables comparing primary check results against the
+	// shadow store. Requires shadowClient.
+	shadowChecks bool
+	// modelCache, when set, caches the authorization model between lookups.
+	modelCache *modelCache
+	// objectReads, when set, shares one in-flight ReadObjectTuples among
+	// concurrent callers for the same object.
+	objectReads *tupleReadGroup
+	// objectTuples, when set, briefly caches ReadObjectTuples results.
+	objectTuples *objectTupleCache
+	// readPageSize is the page size requested by Read calls. Zero uses the
+	// OpenFGA server default.
+	readPageSize int32
+	// cacheLookupConcurrency bounds the concurrent cache Gets issued for a
+	// check batch. Zero uses defaultCacheLookupConcurrency.
+	cacheLookupConcurrency int
+	// preserveConditionalTuples keeps tuples bearing a condition when a sync
+	// does not list them, instead of deleting them as stale.
+	preserveConditionalTuples bool
+	// checkPolicies sets the consistency and cache TTL used to serve checks,
+	// by the type of the checked object.
+	checkPolicies checkPolicies
+	// checkGuard bounds how long checks may take to resolve in OpenFGA.
+	checkGuard checkGuard
+	// deleteHeavy flags syncs deleting far more tuples than they write.
+	deleteHeavy deleteHeavyPolicy
+	// verifyWriteTypes are the object types whose syncs are read back after
+	// writing, to confirm the changes were applied.
+	verifyWriteTypes map[string]bool
+}
+
+// deleteHeavyPolicy decides when a sync deletes suspiciously many tuples: at
+// least minDeletes, and more than ratio times as many as it writes. A zero
+// minDeletes disables the check.
+type deleteHeavyPolicy struct {
+	minDeletes int
+	ratio      float64
+}
+
+// WithReadPageSize returns a copy of s whose Read calls request pageSize
+// tuples per page, overriding the configured page size for a single call
+// site.
+func (s FgaService) WithReadPageSize(pageSize int32) FgaService {
+	s.readPageSize = pageSize
+	return s
+}
+
+// readOptions returns the options for the first page of a Read call.
+func (s FgaService) readOptions() ClientReadOptions {
+	options := ClientReadOptions{}
+	if s.readPageSize > 0 {
+		options.PageSize = openfga.PtrInt32(s.readPageSize)
+	}
+	return options
+}
+
+// batchCheckOptions returns the options for a BatchCheck call made with
+// consistency.
+func batchCheckOptions(consistency openfga.ConsistencyPreference) BatchCheckOptions {
+	options := BatchCheckOptions{}
+	if consistency != "" {
+		options.Consistency = &consistency
+	}
+	return options
 }
 
 // connectFga initializes the global shared fgaClient connection. This demo
 // does not use or support authentication.
-func connectFga() (IFgaClient, error) {
+func connectFga(cfg fgaStoreConfig) (IFgaClient, error) {
 	var err error
-	fgaURL := os.Getenv("OPENFGA_API_URL")
-	fgaStoreID := os.Getenv("OPENFGA_STORE_ID")
-	fgaAuthModelID := os.Getenv("OPENFGA_AUTH_MODEL_ID")
+	fgaURL := cfg.apiURL
+	fgaStoreID := cfg.storeID
+	fgaAuthModelID := cfg.authModelID
 	if fgaURL == "" {
 		return nil, fmt.Errorf("OPENFGA_API_URL must be set")
 	}
@@ -75,6 +193,33 @@ func connectFga() (IFgaClient, error) {
 	if fgaAuthModelID == "" {
 		return nil, fmt.Errorf("OPENFGA_AUTH_MODEL_ID must be set")
 	}
+	fgaClient, err := NewSdkClient(&ClientConfiguration{
+		ApiUrl:               fgaURL,
+		StoreId:              fgaStoreID,
+		AuthorizationModelId: fgaAuthModelID,
+		HTTPClient: &http.Client{
+			Transport: requestIDTransport{next: otelhttp.NewTransport(http.DefaultTransport)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return FgaAdapter{OpenFgaClient: *fgaClient}, nil
+}
+
+// connectShadowFga initializes an optional OpenFGA client for a secondary
+// "shadow" store. It returns a nil client when OPENFGA_SHADOW_STORE_ID is not
+// set. OPENFGA_SHADOW_API_URL defaults to OPENFGA_API_URL.
+func connectShadowFga(cfg fgaStoreConfig) (IFgaClient, error) {
+	fgaStoreID := cfg.storeID
+	if fgaStoreID == "" {
+		return nil, nil
+	}
+	fgaURL := cfg.apiURL
+	fgaAuthModelID := cfg.authModelID
+	if fgaAuthModelID == "" {
+		return nil, fmt.Errorf("OPENFGA_SHADOW_AUTH_MODEL_ID must be set when OPENFGA_SHADOW_STORE_ID is set")
+	}
 	fgaClient, err := NewSdkClient(&ClientConfiguration{
 		ApiUrl:               fgaURL,
 		StoreId:              fgaStoreID,
@@ -117,11 +262,58 @@ func (s FgaService) TupleKeyWithoutCondition(user, relation, object string) Clie
 
 // ReadObjectTuples is a pagination helper to fetch all direct relationships (_no_
 // transitive evaluations) defined against a given object.
+//
+// Concurrent reads for the same object are deduplicated when objectReads is
+// set; the shared read runs detached from any caller's cancellation, as
+// tupleReadGroup describes. When
+// objectTuples is set, recently read objects are served from it.
 func (s FgaService) ReadObjectTuples(ctx context.Context, object string) ([]openfga.Tuple, error) {
+	var generation uint64
+	if s.objectTuples != nil {
+		tuples, gen, ok := s.objectTuples.get(object)
+		if ok {
+			return tuples, nil
+		}
+		generation = gen
+	}
+
+	var tuples []openfga.Tuple
+	var err error
+	if s.objectReads != nil {
+		tuples, err = s.objectReads.do(ctx, object, func(readCtx context.Context) ([]openfga.Tuple, error) {
+			return s.readObjectTuples(readCtx, object, "", s.readOptions())
+		})
+	} else {
+		tuples, err = s.readObjectTuples(ctx, object, "", s.readOptions())
+	}
+	if err == nil && s.objectTuples != nil {
+		s.objectTuples.put(object, tuples, generation)
+	}
+	return tuples, err
+}
+
+// ReadCurrentObjectTuples reads the tuples of object as ReadObjectTuples
+// does, but bypassing the object tuple cache, which does not see writes made
+// by other replicas. Callers deciding what to write or delete use it.
+func (s FgaService) ReadCurrentObjectTuples(ctx context.Context, object string) ([]openfga.Tuple, error) {
+	s.objectTuples = nil
+	return s.ReadObjectTuples(ctx, object)
+}
+
+// readObjectTuples reads every direct tuple on object, following pagination,
+// starting from options. A non-empty relation is passed to OpenFGA as a
+// filter, so that only tuples of that relation are read.
+func (s FgaService) readObjectTuples(
+	ctx context.Context,
+	object, relation string,
+	options ClientReadOptions,
+) ([]openfga.Tuple, error) {
 	req := ClientReadRequest{
 		Object: openfga.PtrString(object),
 	}
-	options := ClientReadOptions{}
+	if relation != "" {
+		req.Relation = openfga.PtrString(relation)
+	}
 	var tuples []openfga.Tuple
 	for {
 		resp, err := s.client.Read(ctx, req, options)
@@ -146,7 +338,7 @@ func (s FgaService) ReadUserTuples(ctx context.Context, user, objectType string)
 		User:   openfga.PtrString(user),
 		Object: openfga.PtrString(objectTypeColon),
 	}
-	options := ClientReadOptions{}
+	options := s.readOptions()
 	var tuples []openfga.Tuple
 	for {
 		resp, err := s.client.Read(ctx, req, options)
@@ -165,6 +357,9 @@ func (s FgaService) ReadUserTuples(ctx context.Context, user, objectType string)
 
 // ListObjectsByUserAndRelation uses the List Objects API to find all objects of a specific type
 // that have a given relation to a user. This is useful for finding all artifacts that relate to a past meeting.
+// The objects are returned as full IDs, e.g. "past_meeting_recording:r1". The API is not paginated: OpenFGA
+// returns at most its configured maximum number of results, so callers must not assume the list is complete
+// for users related to very many objects.
 func (s FgaService) ListObjectsByUserAndRelation(
 	ctx context.Context,
 	objectType, relation, user string,
@@ -208,6 +403,9 @@ func (s FgaService) getRelationsMap(object string, relations []ClientTupleKey) (
 }
 
 // SyncObjectTuples synchronizes the OpenFGA tuples for an object to match the desired relations.
+// Existing tuples are never deleted if their relation is in excludeRelations;
+// an entry of the form "relation@user" protects a single tuple instead.
+// excluded is the number of existing tuples left in place for that reason.
 func (s FgaService) SyncObjectTuples(
 	ctx context.Context,
 	object string,
@@ -216,11 +414,135 @@ func (s FgaService) SyncObjectTuples(
 ) (
 	writes []ClientTupleKey,
 	deletes []ClientTupleKeyWithoutCondition,
+	excluded int,
+	err error,
+) {
+	writes, deletes, excluded, err = s.diffObjectTuples(ctx, object, relations, excludeRelations...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	s.flagDeleteHeavySync(ctx, object, len(writes), len(deletes))
+
+	// Escape early if there is nothing to write or delete.
+	if len(writes) == 0 && len(deletes) == 0 {
+		return writes, deletes, excluded, nil
+	}
+
+	// Use the shared write and delete function
+	err = s.WriteAndDeleteTuples(ctx, writes, deletes)
+	if err != nil {
+		return writes, deletes, excluded, err
+	}
+
+	s.verifyObjectTuples(ctx, object, writes, deletes)
+
+	// Seed the new user relationships after the write (and its cache
+	// invalidation).
+	s.seedCachedWrites(ctx, writes)
+
+	return writes, deletes, excluded, nil
+}
+
+// flagDeleteHeavySync warns when a sync of object deletes far more tuples
+// than it writes, which usually means the producer sent a truncated relation
+// list. The sync is not blocked; the warning and metric are for follow-up.
+func (s FgaService) flagDeleteHeavySync(ctx context.Context, object string, writes, deletes int) {
+	policy := s.deleteHeavy
+	if policy.minDeletes <= 0 || deletes < policy.minDeletes || float64(deletes) <= policy.ratio*float64(writes) {
+		return
+	}
+	objectType, _, _ := strings.Cut(object, ":")
+	deleteHeavySyncs.Add(objectType, 1)
+	logger.With(
+		"object", object,
+		"writes_count", writes,
+		"deletes_count", deletes,
+	).WarnContext(ctx, "sync deletes far more tuples than it writes; the update may be truncated")
+}
+
+// preservedOnSync reports why an existing tuple outside the desired set must
+// not be deleted by a sync, or "" if it may be.
+func (s FgaService) preservedOnSync(tuple openfga.Tuple) string {
+	switch {
+	case strings.HasPrefix(tuple.Key.User, "team:"):
+		// Team member grant tuples (e.g. team:my-team#member) are managed by a
+		// separate workflow and must not be clobbered by resource service sync
+		// operations.
+		return "team member grant tuple"
+	case s.preserveConditionalTuples && tuple.Key.Condition != nil:
+		// Sync payloads cannot express conditions, so a conditional tuple is
+		// never part of the desired set and would otherwise be removed by
+		// every routine update.
+		return "conditional tuple"
+	}
+	return ""
+}
+
+// objectSync is the desired state of one object in a SyncObjectsTuples call.
+type objectSync struct {
+	object           string
+	relations        []ClientTupleKey
+	excludeRelations []string
+}
+
+// SyncObjectsTuples synchronizes several objects together, for operations
+// that span objects (e.g. a recording and the past meeting it references).
+// Every object's existing tuples are read and diffed first, and the changes
+// are only written once all diffs succeed, in a single WriteAndDeleteTuples
+// call. A failed read therefore leaves every object unchanged, and the
+// window in which only some objects are updated is kept to the write itself.
+// Each object is then verified as SyncObjectTuples verifies it.
+func (s FgaService) SyncObjectsTuples(
+	ctx context.Context,
+	objects []objectSync,
+) (
+	writes []ClientTupleKey,
+	deletes []ClientTupleKeyWithoutCondition,
+	err error,
+) {
+	objWrites := make([][]ClientTupleKey, len(objects))
+	objDeletes := make([][]ClientTupleKeyWithoutCondition, len(objects))
+	for i, obj := range objects {
+		var errDiff error
+		objWrites[i], objDeletes[i], _, errDiff = s.diffObjectTuples(ctx, obj.object, obj.relations, obj.excludeRelations...)
+		if errDiff != nil {
+			return nil, nil, fmt.Errorf("sync %s: %w", obj.object, errDiff)
+		}
+		s.flagDeleteHeavySync(ctx, obj.object, len(objWrites[i]), len(objDeletes[i]))
+		writes = append(writes, objWrites[i]...)
+		deletes = append(deletes, objDeletes[i]...)
+	}
+
+	if err = s.WriteAndDeleteTuples(ctx, writes, deletes); err != nil {
+		return writes, deletes, err
+	}
+	for i, obj := range objects {
+		if len(objWrites[i]) > 0 || len(objDeletes[i]) > 0 {
+			s.verifyObjectTuples(ctx, obj.object, objWrites[i], objDeletes[i])
+		}
+	}
+	s.seedCachedWrites(ctx, writes)
+	return writes, deletes, nil
+}
+
+// diffObjectTuples reads an object's tuples and returns the writes and
+// deletes that would make them match the desired relations, honoring
+// excludeRelations as SyncObjectTuples describes, and the number of tuples
+// excluded from deletion. It does not write.
+func (s FgaService) diffObjectTuples(
+	ctx context.Context,
+	object string,
+	relations []ClientTupleKey,
+	excludeRelations ...string,
+) (
+	writes []ClientTupleKey,
+	deletes []ClientTupleKeyWithoutCondition,
+	excluded int,
 	err error,
 ) {
 	relationsMap, err := s.getRelationsMap(object, relations)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 
 	// Create a map of relations to exclude from deletion
@@ -229,10 +551,12 @@ func (s FgaService) SyncObjectTuples(
 		excludeMap[rel] = true
 	}
 
-	tuples, err := s.ReadObjectTuples(ctx, object)
+	tuples, err := s.ReadCurrentObjectTuples(ctx, object)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
+	objectType, _, _ := strings.Cut(object, ":")
+	objectTupleCounts.observe(objectType, len(tuples))
 
 	// Iterate over the effective OpenFGA tuples and compare them against the
 	// desired state of relationships passed as a function argument. Any matches
@@ -257,23 +581,21 @@ func (s FgaService) SyncObjectTuples(
 			}
 		case false:
 			// Check if this relation should be excluded from deletion
-			if excludeMap[tuple.Key.Relation] {
+			if excludeMap[tuple.Key.Relation] || excludeMap[key] {
 				logger.With(
 					"user", tuple.Key.User,
 					"relation", tuple.Key.Relation,
 					"object", object,
 				).DebugContext(ctx, "skipping deletion of excluded relation")
+				excluded++
 				continue
 			}
-			// Preserve team member grant tuples (e.g. team:my-team#member) — these are
-			// managed by a separate workflow and must not be clobbered by resource
-			// service sync operations.
-			if strings.HasPrefix(tuple.Key.User, "team:") {
+			if reason := s.preservedOnSync(tuple); reason != "" {
 				logger.With(
 					"user", tuple.Key.User,
 					"relation", tuple.Key.Relation,
 					"object", object,
-				).DebugContext(ctx, "skipping deletion of team member grant tuple")
+				).DebugContext(ctx, "skipping deletion of "+reason)
 				continue
 			}
 			logger.With(
@@ -295,61 +617,90 @@ func (s FgaService) SyncObjectTuples(
 			"object", object,
 		).DebugContext(ctx, "will add relation in batch write")
 		writes = append(writes, relation)
-		if isUser := strings.HasPrefix(relation.User, "user:"); isUser {
-			// Seed any (direct) user relationships to the cache after this function
-			// returns (after the invalidation cache write, if there is one). Only
-			// user relationships are written, because we don't support explicit
-			// querying of resource-parent relationships (or similar) which don't
-			// resolve back to a user. TBD figure out a way to measure the impact
-			// this has on overall cache effectiveness, especially once we start
-			// updating large-scale relationships, like groups with over a thousand
-			// members.
-			relationKey := relation.Object + "#" + relation.Relation + "@" + relation.User
-			cacheKey := "rel." + cacheKeyEncoder.EncodeToString([]byte(relationKey))
-			// Execute cache update asynchronously without defer to avoid resource leak
-			go func(cacheKey string) {
-				// Define a timeout context for the cache update operation.
-				timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-				defer cancel() // Ensure the context is cleaned up after the operation.
-
-				// All direct relations handled in this function correspond to "true"
-				// access relations. This happens asynchronously so we are not checking
-				// for errors or logging anything.
-				//nolint:errcheck // This happens asynchronously so we are not checking for errors.
-				_, _ = s.cacheBucket.PutString(timeoutCtx, cacheKey, trueString)
-			}(cacheKey)
-		}
 	}
 
-	// Escape early if there is nothing to write or delete.
-	if len(writes) == 0 && len(deletes) == 0 {
-		return writes, deletes, nil
+	if excluded > 0 {
+		excludedTuples.Add(objectType, int64(excluded))
 	}
 
-	// Use the shared write and delete function
-	err = s.WriteAndDeleteTuples(ctx, writes, deletes)
-	if err != nil {
-		return writes, deletes, err
+	return writes, deletes, excluded, nil
+}
+
+// seedCachedWrites seeds the cache with the direct user relationships just
+// written. Only user relationships are seeded, because we don't support
+// explicit querying of resource-parent relationships (or similar) which don't
+// resolve back to a user. TBD figure out a way to measure the impact this has
+// on overall cache effectiveness, especially once we start updating
+// large-scale relationships, like groups with over a thousand members.
+func (s FgaService) seedCachedWrites(ctx context.Context, writes []ClientTupleKey) {
+	for _, relation := range writes {
+		if !strings.HasPrefix(relation.User, "user:") {
+			continue
+		}
+		relationKey := relation.Object + "#" + relation.Relation + "@" + relation.User
+		cacheKey := checkCacheKey("", relationKey)
+		// Execute cache update asynchronously without defer to avoid resource leak
+		go func(cacheKey, relationKey string) {
+			// Define a timeout context for the cache update operation,
+			// detached from the message context, which ends with the handler.
+			timeoutCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel() // Ensure the context is cleaned up after the operation.
+
+			// All direct relations written correspond to "true" access
+			// relations. This happens asynchronously so we are not checking for
+			// errors or logging anything.
+			//nolint:errcheck // This happens asynchronously so we are not checking for errors.
+			_, _ = s.cacheBucket.Put(timeoutCtx, cacheKey, s.cachedCheckValue(relationKey, trueString))
+		}(cacheKey, relationKey)
 	}
+}
 
-	return writes, deletes, nil
+// invalidateObjectTuples drops the cached tuples of every object written to
+// or deleted from.
+func (s FgaService) invalidateObjectTuples(writes []ClientTupleKey, deletes []ClientTupleKeyWithoutCondition) {
+	if s.objectTuples == nil {
+		return
+	}
+	objects := make([]string, 0, len(writes)+len(deletes))
+	for _, tuple := range writes {
+		objects = append(objects, tuple.Object)
+	}
+	for _, tuple := range deletes {
+		objects = append(objects, tuple.Object)
+	}
+	s.objectTuples.invalidate(objects...)
 }
 
 // invalidateCache invalidates the cache by writing a timestamp marker.
 // Any value will work, since it is the native timestamp of the record that is checked, not its value.
 func (s FgaService) invalidateCache(ctx context.Context) error {
-	_, err := s.cacheBucket.Put(ctx, "inv", []byte("1"))
-	if err != nil {
+	if err := s.putInvalidationMarker(ctx, cacheInvalidationKey("")); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to write cache invalidation marker")
 		return err
 	}
 	return nil
 }
 
+// RefreshCacheScope invalidates the cached checks of one cache scope, leaving
+// other scopes and the unscoped cache untouched.
+func (s FgaService) RefreshCacheScope(ctx context.Context, scope string) error {
+	if !s.useCache {
+		return nil
+	}
+	if err := s.putInvalidationMarker(ctx, cacheInvalidationKey(scope)); err != nil {
+		logger.With(errKey, err, "cache_scope", scope).ErrorContext(ctx, "failed to write cache scope invalidation marker")
+		return err
+	}
+	return nil
+}
+
 // WriteAndDeleteTuples writes and/or deletes the given tuples to/from OpenFGA.
 // This is a general-purpose method for modifying tuples without reading existing state.
 // OpenFGA has a limit of 100 total operations (writes + deletes combined) per request,
-// so this function will automatically batch operations if needed.
+// so this function will automatically batch operations if needed. Batches are
+// written in order, stopping at the first that fails. Batching is not atomic:
+// the batches before a failure remain applied, so callers must be able to
+// retry the whole set.
 func (s FgaService) WriteAndDeleteTuples(
 	ctx context.Context,
 	writes []ClientTupleKey,
@@ -439,13 +790,19 @@ func (s FgaService) WriteAndDeleteTuples(
 
 // writeAndDeleteTuplesBatch performs a single write/delete operation to OpenFGA.
 // If OpenFGA returns a validation_error for an invalid tuple, that tuple is
-// removed and the batch is retried with the remaining tuples.
+// removed and the batch is retried with the remaining tuples. Deletes of
+// tuples that no longer exist are dropped the same way, so removing an
+// already-removed relation succeeds instead of failing and being redelivered.
 // This is an internal helper function that should not be called directly.
 func (s FgaService) writeAndDeleteTuplesBatch(
 	ctx context.Context,
 	writes []ClientTupleKey,
 	deletes []ClientTupleKeyWithoutCondition,
 ) error {
+	// Whether or not the write succeeds, the cached tuples of the objects it
+	// touches may no longer be current.
+	defer s.invalidateObjectTuples(writes, deletes)
+
 	for {
 		req := ClientWriteRequest{
 			Writes:  writes,
@@ -454,6 +811,22 @@ func (s FgaService) writeAndDeleteTuplesBatch(
 
 		_, err := s.client.Write(ctx, req)
 		if err != nil {
+			if tupleStr, ok := extractMissingDeleteTuple(err); ok {
+				var removed bool
+				if deletes, removed = removeInvalidDeleteTuple(deletes, tupleStr); !removed {
+					return err
+				}
+				logger.With(
+					"skipped_tuple", tupleStr,
+					"remaining_writes", len(writes),
+					"remaining_deletes", len(deletes),
+				).InfoContext(ctx, "tuple to delete does not exist; retrying batch write without it")
+				if len(writes) == 0 && len(deletes) == 0 {
+					return nil
+				}
+				continue
+			}
+
 			tupleStr, ok := extractInvalidTuple(err)
 			if !ok {
 				return err
@@ -483,6 +856,11 @@ func (s FgaService) writeAndDeleteTuplesBatch(
 		break
 	}
 
+	recordAuditChanges(ctx, writes, deletes)
+	recordCapturedChanges(ctx, writes, deletes)
+	processingSummary.recordChanges(len(writes), len(deletes))
+	s.shadowWrite(ctx, writes, deletes)
+
 	// Invalidate cache after write
 	if err := s.invalidateCache(ctx); err != nil {
 		// Log but don't fail the operation since the write succeeded
@@ -499,6 +877,66 @@ func (s FgaService) writeAndDeleteTuplesBatch(
 	return nil
 }
 
+// shadowWrite mirrors a successful primary write to the shadow store, if
+// one is configured. Failures are logged and counted but never returned.
+func (s FgaService) shadowWrite(
+	ctx context.Context,
+	writes []ClientTupleKey,
+	deletes []ClientTupleKeyWithoutCondition,
+) {
+	if s.shadowClient == nil || (len(writes) == 0 && len(deletes) == 0) {
+		return
+	}
+	req := ClientWriteRequest{
+		Writes:  writes,
+		Deletes: deletes,
+	}
+	if _, err := s.shadowClient.Write(ctx, req); err != nil {
+		shadowWriteErrors.Add(1)
+		logger.With(
+			errKey, err,
+			"writes_count", len(writes),
+			"deletes_count", len(deletes),
+		).WarnContext(ctx, "shadow write failed")
+	}
+}
+
+// shadowCheck runs the same batch check against the shadow store and logs
+// any result that diverges from the primary store. It is a no-op unless
+// shadow checks are enabled.
+func (s FgaService) shadowCheck(
+	ctx context.Context,
+	request ClientBatchCheckRequest,
+	options BatchCheckOptions,
+	primary map[string]openfga.BatchCheckSingleResult,
+) {
+	if s.shadowClient == nil || !s.shadowChecks {
+		return
+	}
+	shadowResp, err := s.shadowClient.BatchCheck(ctx, request, options)
+	if err != nil || shadowResp == nil || shadowResp.Result == nil {
+		shadowCheckErrors.Add(1)
+		logger.With(errKey, err).WarnContext(ctx, "shadow check failed")
+		return
+	}
+	shadowResults := *shadowResp.Result
+	for _, item := range request.Checks {
+		primaryResult, ok := primary[item.CorrelationId]
+		if !ok {
+			continue
+		}
+		shadowResult, ok := shadowResults[item.CorrelationId]
+		if !ok || shadowResult.GetAllowed() != primaryResult.GetAllowed() {
+			shadowCheckDivergences.Add(1)
+			logger.With(
+				"relation_key", item.Object+"#"+item.Relation+"@"+item.User,
+				"primary_allowed", primaryResult.GetAllowed(),
+				"shadow_allowed", shadowResult.GetAllowed(),
+			).WarnContext(ctx, "shadow check diverged from primary")
+		}
+	}
+}
+
 // extractInvalidTuple extracts the tuple string from an OpenFGA validation error.
 // Returns the tuple string (e.g. "object:id#relation@user:id") and true if the
 // error is a validation_error containing an invalid tuple message.
@@ -519,6 +957,27 @@ func extractInvalidTuple(err error) (string, bool) {
 	return string(tuple), true
 }
 
+// missingDeletePattern matches the OpenFGA error for a delete of a tuple that
+// does not exist, capturing its user, relation and object.
+var missingDeletePattern = regexp.MustCompile(
+	`cannot delete a tuple which does not exist: user: '([^']*)', relation: '([^']*)', object: '([^']*)'`,
+)
+
+// extractMissingDeleteTuple extracts the tuple string (e.g.
+// "object:id#relation@user:id") of a delete OpenFGA rejected because the tuple
+// does not exist. Returns false for any other error.
+func extractMissingDeleteTuple(err error) (string, bool) {
+	var validationErr openfga.FgaApiValidationError
+	if !errors.As(err, &validationErr) {
+		return "", false
+	}
+	match := missingDeletePattern.FindStringSubmatch(validationErr.Error())
+	if match == nil {
+		return "", false
+	}
+	return match[3] + "#" + match[2] + "@" + match[1], true
+}
+
 // removeInvalidWriteTuple returns a new slice with the first write tuple matching
 // tupleStr removed. Returns the original slice and false if no match is found.
 func removeInvalidWriteTuple(writes []ClientTupleKey, tupleStr string) ([]ClientTupleKey, bool) {
@@ -569,6 +1028,46 @@ func (s FgaService) WriteTuple(ctx context.Context, user, relation, object strin
 	return s.WriteTuples(ctx, []ClientTupleKey{tuple})
 }
 
+// EnsureTuples writes whichever of tuples, all of object, are not already
+// present, and returns the tuples written. It is the additive counterpart to
+// SyncObjectTuples: existing tuples not listed are never deleted, so it can
+// guarantee a baseline (e.g. a system-admin viewer) without disturbing the
+// rest of the object's access. A tuple is present if its user and relation
+// match, whatever its condition.
+func (s FgaService) EnsureTuples(
+	ctx context.Context,
+	object string,
+	tuples []ClientTupleKey,
+) ([]ClientTupleKey, error) {
+	existing, err := s.ReadCurrentObjectTuples(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool, len(existing)+len(tuples))
+	for _, tuple := range existing {
+		present[tuple.Key.Relation+"@"+tuple.Key.User] = true
+	}
+
+	var writes []ClientTupleKey
+	for _, tuple := range tuples {
+		key := tuple.Relation + "@" + tuple.User
+		if present[key] {
+			continue
+		}
+		present[key] = true
+		writes = append(writes, tuple)
+	}
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	if err := s.WriteTuples(ctx, writes); err != nil {
+		return nil, err
+	}
+	s.seedCachedWrites(ctx, writes)
+	return writes, nil
+}
+
 // DeleteTuple deletes a single tuple from OpenFGA using simple string parameters.
 // This provides a cleaner API for handlers that don't need to know about OpenFGA types.
 func (s FgaService) DeleteTuple(ctx context.Context, user, relation, object string) error {
@@ -576,12 +1075,13 @@ func (s FgaService) DeleteTuple(ctx context.Context, user, relation, object stri
 	return s.DeleteTuples(ctx, []ClientTupleKeyWithoutCondition{tuple})
 }
 
-// DeleteTuplesByUserAndObject deletes all tuples for a specific user and object.
+// DeleteTuplesByUserAndObject deletes all tuples for a specific user and object,
+// and returns how many were deleted.
 // e.g. delete all tuples associated with user X on meeting Y.
-func (s FgaService) DeleteTuplesByUserAndObject(ctx context.Context, user, object string) error {
+func (s FgaService) DeleteTuplesByUserAndObject(ctx context.Context, user, object string) (int, error) {
 	tuples, err := s.GetTuplesByUserAndObject(ctx, user, object)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	tuplesWithoutConditions := make([]ClientTupleKeyWithoutCondition, 0, len(tuples))
 	for _, tuple := range tuples {
@@ -590,12 +1090,36 @@ func (s FgaService) DeleteTuplesByUserAndObject(ctx context.Context, user, objec
 			s.TupleKeyWithoutCondition(tuple.User, tuple.Relation, tuple.Object),
 		)
 	}
-	return s.DeleteTuples(ctx, tuplesWithoutConditions)
+	if err := s.DeleteTuples(ctx, tuplesWithoutConditions); err != nil {
+		return 0, err
+	}
+	return len(tuplesWithoutConditions), nil
 }
 
-// GetTuplesByUserAndObject returns all tuples for a specific user on a given object.
+// DeleteTuplesByObject deletes the tuples of object as delete_access does,
+// for cleanup that does not go through a delete_access message, and returns
+// how many were deleted.
+func (s FgaService) DeleteTuplesByObject(ctx context.Context, object string) (int, error) {
+	deletes, err := s.deleteObjectTuples(ctx, object)
+	return len(deletes), err
+}
+
+// deleteObjectTuples deletes the tuples of object and returns those deleted.
+// It is a sync to no tuples: team member grants, and conditional tuples when
+// they are preserved on sync, are kept, and deletes are batched and verified
+// as for SyncObjectTuples, so a failure may leave some tuples deleted.
+func (s FgaService) deleteObjectTuples(ctx context.Context, object string) ([]ClientTupleKeyWithoutCondition, error) {
+	_, deletes, _, err := s.SyncObjectTuples(ctx, object, nil)
+	if err != nil {
+		return nil, err
+	}
+	return deletes, nil
+}
+
+// GetTuplesByUserAndObject returns all tuples for a specific user on a given object,
+// read bypassing the object tuple cache.
 func (s FgaService) GetTuplesByUserAndObject(ctx context.Context, user, object string) ([]ClientTupleKey, error) {
-	tuples, err := s.ReadObjectTuples(ctx, object)
+	tuples, err := s.ReadCurrentObjectTuples(ctx, object)
 	if err != nil {
 		return nil, err
 	}
@@ -612,14 +1136,23 @@ func (s FgaService) GetTuplesByUserAndObject(ctx context.Context, user, object s
 
 // GetTuplesByRelation returns tuples for a specific object filtered by relation.
 // This provides a generic way to retrieve tuples with a specific relation from an object.
+// The filter is sent to OpenFGA, so objects with many tuples of other relations are not
+// read in full; an object held by the object tuple cache is filtered from it instead.
 func (s FgaService) GetTuplesByRelation(ctx context.Context, object, relation string) ([]openfga.Tuple, error) {
-	allTuples, err := s.ReadObjectTuples(ctx, object)
-	if err != nil {
-		return nil, err
+	var tuples []openfga.Tuple
+	cached := false
+	if s.objectTuples != nil {
+		tuples, _, cached = s.objectTuples.get(object)
+	}
+	if !cached {
+		var err error
+		if tuples, err = s.readObjectTuples(ctx, object, relation, s.readOptions()); err != nil {
+			return nil, err
+		}
 	}
 
 	var filteredTuples []openfga.Tuple
-	for _, tuple := range allTuples {
+	for _, tuple := range tuples {
 		if tuple.Key.Relation == relation {
 			filteredTuples = append(filteredTuples, tuple)
 		}
@@ -628,9 +1161,396 @@ func (s FgaService) GetTuplesByRelation(ctx context.Context, object, relation st
 	return filteredTuples, nil
 }
 
+// RenameRelation moves every oldRelation tuple on object to newRelation,
+// keeping each tuple's user and condition, and returns how many tuples were
+// moved. Users already holding newRelation only lose oldRelation. The writes
+// are ordered before the deletes, so no user loses access part way through a
+// rename split across several write batches.
+func (s FgaService) RenameRelation(ctx context.Context, object, oldRelation, newRelation string) (int, error) {
+	tuples, err := s.ReadCurrentObjectTuples(ctx, object)
+	if err != nil {
+		return 0, err
+	}
+
+	holdsNew := make(map[string]bool)
+	for _, tuple := range tuples {
+		if tuple.Key.Relation == newRelation {
+			holdsNew[tuple.Key.User] = true
+		}
+	}
+
+	var writes []ClientTupleKey
+	var deletes []ClientTupleKeyWithoutCondition
+	for _, tuple := range tuples {
+		if tuple.Key.Relation != oldRelation {
+			continue
+		}
+		if !holdsNew[tuple.Key.User] {
+			write := s.TupleKey(tuple.Key.User, newRelation, object)
+			write.Condition = tuple.Key.Condition
+			writes = append(writes, write)
+		}
+		deletes = append(deletes, s.TupleKeyWithoutCondition(tuple.Key.User, oldRelation, object))
+	}
+
+	if err := s.WriteAndDeleteTuples(ctx, writes, deletes); err != nil {
+		return 0, err
+	}
+	return len(deletes), nil
+}
+
+// Kinds of direct grant reported by ExplainAccess.
+const (
+	// accessGrantDirect is a tuple naming the user itself.
+	accessGrantDirect = "direct"
+	// accessGrantPublic is a wildcard tuple (e.g. user:*) matching the user's
+	// type.
+	accessGrantPublic = "public"
+	// accessGrantUserset is a tuple granting a userset (e.g.
+	// committee:123#member) that the user belongs to.
+	accessGrantUserset = "userset"
+)
+
+// accessGrant is a direct tuple on an object that contributes to a user's
+// access through one relation.
+type accessGrant struct {
+	Kind  string
+	Tuple string
+}
+
+// ExplainAccess reports whether user has relation on object, and which of the
+// direct tuples on object#relation grant it: an explicit tuple for the user,
+// a public wildcard, or a userset the user belongs to. Access that is only
+// inherited through the model (computed or parent relations) is allowed with
+// no grants. The check and object tuple caches are bypassed so the answer
+// reflects OpenFGA's current state.
+func (s FgaService) ExplainAccess(ctx context.Context, object, relation, user string) (bool, []accessGrant, error) {
+	s.objectTuples = nil
+	tuples, err := s.GetTuplesByRelation(ctx, object, relation)
+	if err != nil {
+		return false, nil, err
+	}
+
+	userType, _, _ := strings.Cut(user, ":")
+	checks := []ClientBatchCheckItem{{User: user, Relation: relation, Object: object, CorrelationId: "0"}}
+	usersets := make(map[string]string)
+	var grants []accessGrant
+	for i, tuple := range tuples {
+		key := tuple.Key.Object + "#" + tuple.Key.Relation + "@" + tuple.Key.User
+		switch {
+		case tuple.Key.User == user:
+			grants = append(grants, accessGrant{Kind: accessGrantDirect, Tuple: key})
+		case tuple.Key.User == userType+":*":
+			grants = append(grants, accessGrant{Kind: accessGrantPublic, Tuple: key})
+		case strings.Contains(tuple.Key.User, "#"):
+			usersetObject, usersetRelation, _ := strings.Cut(tuple.Key.User, "#")
+			correlationID := strconv.Itoa(i + 1)
+			checks = append(checks, ClientBatchCheckItem{
+				User:          user,
+				Relation:      usersetRelation,
+				Object:        usersetObject,
+				CorrelationId: correlationID,
+			})
+			usersets[correlationID] = key
+		}
+	}
+
+	resp, err := s.client.BatchCheck(ctx, ClientBatchCheckRequest{Checks: checks}, BatchCheckOptions{})
+	if err != nil {
+		return false, nil, err
+	}
+	if resp == nil || resp.Result == nil {
+		return false, nil, errors.New("batch check response was nil or empty")
+	}
+
+	var allowed bool
+	for correlationID, result := range *resp.Result {
+		if result.HasError() {
+			checkErr := result.GetError()
+			return false, nil, fmt.Errorf("check %s failed: %s", correlationID, checkErr.GetMessage())
+		}
+		if correlationID == "0" {
+			allowed = result.GetAllowed()
+			continue
+		}
+		if tuple, ok := usersets[correlationID]; ok && result.GetAllowed() {
+			grants = append(grants, accessGrant{Kind: accessGrantUserset, Tuple: tuple})
+		}
+	}
+
+	sort.Slice(grants, func(i, j int) bool { return grants[i].Tuple < grants[j].Tuple })
+	return allowed, grants, nil
+}
+
+// fingerprintKey returns the KV key holding the cached tuple set fingerprint
+// for an object.
+func fingerprintKey(object string) string {
+	return "fp." + cacheKeyEncoder.EncodeToString([]byte(object))
+}
+
+// fingerprintTuples returns a stable hex-encoded SHA-256 hash of a tuple set.
+// Tuples are sorted first, so the result does not depend on read order.
+func fingerprintTuples(tuples []openfga.Tuple) string {
+	keys := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		key := tuple.Key.Object + "#" + tuple.Key.Relation + "@" + tuple.Key.User
+		if tuple.Key.Condition != nil {
+			key += "?" + tuple.Key.Condition.Name
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// TupleSetFingerprint returns a stable hash of all direct tuples defined
+// against object, so a reconciler can detect drift without transferring the
+// tuples themselves. Fingerprints are cached and treated as stale after any
+// cache invalidation, like cached relation checks.
+func (s FgaService) TupleSetFingerprint(ctx context.Context, object string) (string, error) {
+	cacheKey := fingerprintKey(object)
+	if s.useCache {
+		lastInvalidation, err := s.getLastCacheInvalidation(ctx)
+		if err != nil {
+			return "", err
+		}
+		entry, errCache := s.cacheBucket.Get(ctx, cacheKey)
+		switch {
+		case errCache == nil && !lastInvalidation.After(entry.Created()):
+			cacheHits.Add(1)
+			return string(entry.Value()), nil
+		case errCache == nil:
+			cacheStaleHits.Add(1)
+		case errors.Is(errCache, jetstream.ErrKeyNotFound):
+			cacheMisses.Add(1)
+		default:
+			logger.With(errKey, errCache).ErrorContext(ctx, "cache error; continuing")
+		}
+	}
+
+	tuples, err := s.ReadObjectTuples(ctx, object)
+	if err != nil {
+		return "", err
+	}
+	fingerprint := fingerprintTuples(tuples)
+
+	if s.useCache {
+		if _, err = s.cacheBucket.PutString(ctx, cacheKey, fingerprint); err != nil {
+			logger.With(errKey, err, "object", object).WarnContext(ctx, "failed to cache tuple set fingerprint")
+		}
+	}
+
+	return fingerprint, nil
+}
+
+// ErrVersionConflict is returned when an update's expected object version
+// does not match the stored version.
+var ErrVersionConflict = errors.New("version conflict")
+
+// objectVersionKey returns the KV key holding the current access version of
+// an object.
+func objectVersionKey(object string) string {
+	return "ver." + cacheKeyEncoder.EncodeToString([]byte(object))
+}
+
+// isWrongLastSequence reports whether err is a KV compare-and-swap failure.
+func isWrongLastSequence(err error) bool {
+	var apiErr *jetstream.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == jetstream.JSErrCodeStreamWrongLastSequence
+}
+
+// ReserveObjectVersion bumps the access version of object from expected to
+// expected+1, failing with ErrVersionConflict if the stored version differs.
+// Version 0 means the object has no stored version. The bump is a KV
+// compare-and-swap, so of two racing updates with the same expected version
+// only one succeeds. The returned revision is passed to ReleaseObjectVersion
+// if the update is not applied.
+func (s FgaService) ReserveObjectVersion(ctx context.Context, object string, expected uint64) (uint64, error) {
+	key := objectVersionKey(object)
+	next := []byte(strconv.FormatUint(expected+1, 10))
+
+	entry, err := s.stateBucket.Get(ctx, key)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		if expected != 0 {
+			return 0, fmt.Errorf("%w: expected version %d, current version 0", ErrVersionConflict, expected)
+		}
+		revision, errCreate := s.stateBucket.Create(ctx, key, next)
+		if errors.Is(errCreate, jetstream.ErrKeyExists) || isWrongLastSequence(errCreate) {
+			return 0, fmt.Errorf("%w: object was versioned concurrently", ErrVersionConflict)
+		}
+		return revision, errCreate
+	case err != nil:
+		return 0, err
+	}
+
+	current, err := strconv.ParseUint(string(entry.Value()), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stored version for %s: %w", object, err)
+	}
+	if current != expected {
+		return 0, fmt.Errorf("%w: expected version %d, current version %d", ErrVersionConflict, expected, current)
+	}
+	revision, err := s.stateBucket.Update(ctx, key, next, entry.Revision())
+	if isWrongLastSequence(err) {
+		return 0, fmt.Errorf("%w: object was updated concurrently", ErrVersionConflict)
+	}
+	return revision, err
+}
+
+// ReleaseObjectVersion restores the version of object to previous after a
+// reserved update failed to apply, unless another update has reserved a
+// newer version since.
+func (s FgaService) ReleaseObjectVersion(ctx context.Context, object string, previous, revision uint64) error {
+	_, err := s.stateBucket.Update(ctx, objectVersionKey(object), []byte(strconv.FormatUint(previous, 10)), revision)
+	return err
+}
+
+// memberUpdateKey returns the KV key holding the last applied member
+// operation timestamp for a user on an object.
+func memberUpdateKey(object, user string) string {
+	return "mts." + cacheKeyEncoder.EncodeToString([]byte(object+"@"+user))
+}
+
+// GetLastMemberUpdate returns the timestamp of the last member operation
+// applied for user on object, or the zero time if none is recorded.
+func (s FgaService) GetLastMemberUpdate(ctx context.Context, object, user string) (time.Time, error) {
+	entry, err := s.stateBucket.Get(ctx, memberUpdateKey(object, user))
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return time.Time{}, nil
+	case err != nil:
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, string(entry.Value()))
+}
+
+// SetLastMemberUpdate records the timestamp of the last member operation
+// applied for user on object.
+func (s FgaService) SetLastMemberUpdate(ctx context.Context, object, user string, updatedAt time.Time) error {
+	_, err := s.stateBucket.PutString(ctx, memberUpdateKey(object, user), updatedAt.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// pendingDeleteKey returns the KV key holding the deferred delete_access
+// scheduled for an object, if any.
+func pendingDeleteKey(object string) string {
+	return pendingDeleteKeyPrefix + cacheKeyEncoder.EncodeToString([]byte(object))
+}
+
+// SchedulePendingDelete records a deferred delete, replacing any already
+// pending for its object.
+func (s FgaService) SchedulePendingDelete(ctx context.Context, pending pendingDelete) error {
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	key := pendingDeleteKey(pending.Object)
+	if err = checkKVValueSize(key, value); err != nil {
+		return fmt.Errorf("pending delete of %s: %w", pending.Object, err)
+	}
+	_, err = s.stateBucket.Put(ctx, key, value)
+	return err
+}
+
+// ClaimDuePendingDeletes takes ownership of the deferred deletes due by now,
+// and returns them to be applied, along with those claimed before any error.
+// Claiming is a KV compare-and-swap, so of several replicas sweeping at once
+// only one applies each delete, and a delete cancelled in the meantime is not
+// claimed.
+func (s FgaService) ClaimDuePendingDeletes(ctx context.Context, now time.Time) ([]pendingDelete, error) {
+	lister, err := s.stateBucket.ListKeysFiltered(ctx, pendingDeleteKeyPrefix+">")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lister.Stop() }()
+
+	var claimed []pendingDelete
+	for key := range lister.Keys() {
+		entry, err := s.stateBucket.Get(ctx, key)
+		switch {
+		case errors.Is(err, jetstream.ErrKeyNotFound):
+			continue
+		case err != nil:
+			return claimed, err
+		}
+		if len(entry.Value()) == 0 {
+			continue
+		}
+		var pending pendingDelete
+		if err = json.Unmarshal(entry.Value(), &pending); err != nil {
+			logger.With(errKey, err, "key", key).WarnContext(ctx, "invalid pending delete")
+			continue
+		}
+		if pending.DueAt.After(now) {
+			continue
+		}
+		if _, err = s.stateBucket.Update(ctx, key, nil, entry.Revision()); err != nil {
+			if isWrongLastSequence(err) {
+				continue
+			}
+			return claimed, err
+		}
+		claimed = append(claimed, pending)
+	}
+	return claimed, nil
+}
+
+// CancelPendingDelete cancels the deferred delete of object, reporting
+// whether one was pending. A delete already claimed can no longer be
+// cancelled.
+func (s FgaService) CancelPendingDelete(ctx context.Context, object string) (bool, error) {
+	key := pendingDeleteKey(object)
+	entry, err := s.stateBucket.Get(ctx, key)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if len(entry.Value()) == 0 {
+		return false, nil
+	}
+	if _, err = s.stateBucket.Update(ctx, key, nil, entry.Revision()); err != nil {
+		if isWrongLastSequence(err) {
+			// Claimed or rescheduled in the meantime.
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// getLastCacheInvalidation returns when the cache was last invalidated for
+// checks in the context's cache scope: the later of the unscoped marker and
+// the scope's own marker.
 func (s FgaService) getLastCacheInvalidation(ctx context.Context) (time.Time, error) {
+	lastInvalidation, err := s.cacheInvalidatedAt(ctx, cacheInvalidationKey(""))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if scope := cacheScopeFrom(ctx); scope != "" {
+		scopeInvalidation, err := s.cacheInvalidatedAt(ctx, cacheInvalidationKey(scope))
+		if err != nil {
+			return time.Time{}, err
+		}
+		if scopeInvalidation.After(lastInvalidation) {
+			lastInvalidation = scopeInvalidation
+		}
+	}
+	return lastInvalidation, nil
+}
+
+// cacheInvalidatedAt returns the timestamp of the invalidation marker at key.
+func (s FgaService) cacheInvalidatedAt(ctx context.Context, key string) (time.Time, error) {
 	var lastInvalidation time.Time
-	entry, err := s.cacheBucket.Get(ctx, "inv")
+	entry, err := s.cacheBucket.Get(ctx, key)
 	switch {
 	case err == jetstream.ErrKeyNotFound:
 		// No invalidation in the TTL of the cache; all found cache entries are
@@ -644,65 +1564,147 @@ func (s FgaService) getLastCacheInvalidation(ctx context.Context) (time.Time, er
 	return lastInvalidation, nil
 }
 
-func (s FgaService) appendToMessage(
-	ctx context.Context,
-	message []byte,
-	result map[string]openfga.BatchCheckSingleResult,
-	mapCorrelationIDToTuple map[string]ClientBatchCheckItem,
-) []byte {
-	for correlationID, resp := range result {
-		// This is the specific request tuple that the response corresponds to.
-		req, ok := mapCorrelationIDToTuple[correlationID]
-		if !ok {
-			continue
-		}
-		relationKey := req.Object + "#" + req.Relation + "@" + req.User
+// cachedCheckValue encodes a check result for the cache. With cache integrity
+// enabled, the relation key is stored with the result as "relation\tresult".
+func (s FgaService) cachedCheckValue(relationKey, result string) []byte {
+	if s.cacheIntegrity {
+		return []byte(relationKey + "\t" + result)
+	}
+	return []byte(result)
+}
 
-		// Need a bool to handle whether or not a response should be cached
-		// This is needed since it may be an error and not a valid response, but we
-		// still need to return not allowed and not cache it
-		shouldCache := true
-		// Check if the response contains an error (e.g., timeout, deadline exceeded)
-		// and skip caching/responding with error results.
-		if resp.HasError() {
-			checkErr := resp.GetError()
-			logger.With(
-				"correlation_id", correlationID,
-				"relation_key", relationKey,
-				"error_code", checkErr.GetInternalError(),
-				"error_message", checkErr.GetMessage(),
-			).WarnContext(ctx, "batch check returned error for tuple, skipping cache")
-			shouldCache = false
-		}
+// decodeCachedCheck returns the check result stored in a cache value and
+// whether it may be served for relationKey. A value stored with a different
+// relation key means two relations were mapped to the same cache key; it is
+// logged, counted, and never served. With cache integrity enabled, values
+// stored without a relation key can't be verified and are not served either.
+func (s FgaService) decodeCachedCheck(ctx context.Context, relationKey string, value []byte) (string, bool) {
+	storedKey, result, found := strings.Cut(string(value), "\t")
+	if !found {
+		return storedKey, !s.cacheIntegrity
+	}
+	if storedKey != relationKey {
+		cacheKeyCollisions.Add(1)
+		logger.With(
+			"relation_key", relationKey,
+			"stored_relation_key", storedKey,
+		).ErrorContext(ctx, "cache key collision: cached result belongs to a different relation")
+		return "", false
+	}
+	return result, true
+}
 
-		allowed := strconv.FormatBool(resp.GetAllowed())
+// cacheLookup is the cache Get result for one requested check.
+type cacheLookup struct {
+	relationKey string
+	entry       jetstream.KeyValueEntry
+	err         error
+}
 
-		// Append the result to our response message.
-		message = append(message, []byte(relationKey+"\t"+allowed+"\n")...)
+// lookupCachedChecks fetches the cached result of every item, issuing up to
+// cacheLookupConcurrency Gets at once so a large batch isn't resolved one
+// round trip at a time. Results are in the same order as items. It returns
+// nil when the cache is disabled.
+func (s FgaService) lookupCachedChecks(ctx context.Context, items []ClientBatchCheckItem) []cacheLookup {
+	if !s.useCache {
+		return nil
+	}
 
-		// Cache the result.
-		if shouldCache {
-			cacheKey := "rel." + cacheKeyEncoder.EncodeToString([]byte(relationKey))
-			_, err := s.cacheBucket.Put(ctx, cacheKey, []byte(allowed))
-			if err != nil {
-				logger.With(errKey, err).ErrorContext(ctx, "failed to cache relation")
-			}
+	scope := cacheScopeFrom(ctx)
+	lookups := make([]cacheLookup, len(items))
+	for i, item := range items {
+		lookups[i].relationKey = item.Object + "#" + item.Relation + "@" + item.User
+	}
+	get := func(l *cacheLookup) {
+		l.entry, l.err = s.cacheBucket.Get(ctx, checkCacheKey(scope, l.relationKey))
+	}
+
+	limit := s.cacheLookupConcurrency
+	if limit <= 0 {
+		limit = defaultCacheLookupConcurrency
+	}
+	if limit == 1 || len(lookups) == 1 {
+		for i := range lookups {
+			get(&lookups[i])
 		}
+		return lookups
 	}
 
-	return message
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := range lookups {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			get(&lookups[i])
+		})
+	}
+	wg.Wait()
+	return lookups
+}
+
+// BatchCheckResult is the result of one check of a [FgaService.BatchCheck]
+// call.
+type BatchCheckResult struct {
+	// RelationKey is the checked relation, as object#relation@user.
+	RelationKey string
+	Allowed     bool
+	// CacheHit is set when the result was served from the cache rather than
+	// checked in OpenFGA.
+	CacheHit bool
+	// Err is set when OpenFGA could not resolve the check; Allowed is then
+	// false, and the result is not cached.
+	Err error
+	// Guarded is set when the check guard gave up on resolving the check and
+	// denied it; Allowed is then false, and the result is not cached.
+	Guarded bool
 }
 
 // CheckRelationships uses OpenFGA to determine multiple relationships in
-// bulk for any relationships not found in the cache.
+// bulk for any relationships not found in the cache, returning one
+// tab-delimited result line per check, in request order.
 func (s FgaService) CheckRelationships(ctx context.Context, tuples []ClientCheckRequest) ([]byte, error) {
 	if len(tuples) == 0 {
 		return nil, nil
 	}
 
+	items := make([]ClientBatchCheckItem, 0, len(tuples))
+	for _, tuple := range tuples {
+		items = append(items, ClientBatchCheckItem{
+			User:     tuple.User,
+			Relation: tuple.Relation,
+			Object:   tuple.Object,
+		})
+	}
+	results, err := s.BatchCheck(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
 	// Preallocate our response slice based on an expected relation size of 80
 	// bytes each.
-	message := make([]byte, 0, 80*len(tuples))
+	message := make([]byte, 0, 80*len(results))
+	for i, result := range results {
+		if i > 0 {
+			message = append(message, '\n')
+		}
+		message = append(message, result.RelationKey+"\t"+strconv.FormatBool(result.Allowed)...)
+		if result.Guarded {
+			message = append(message, "\t"+checkGuardFlag...)
+		}
+	}
+	return message, nil
+}
+
+// BatchCheck checks items, serving each from the cache when it holds a
+// result newer than the last invalidation of the context's cache scope (and
+// within the object type's cache TTL), and checking the rest in OpenFGA.
+// Results checked in OpenFGA are written back to the cache. Results are in
+// the same order as items.
+func (s FgaService) BatchCheck(ctx context.Context, items []ClientBatchCheckItem) ([]BatchCheckResult, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
 
 	// Get the most recent cache invalidation.
 	lastInvalidation, err := s.getLastCacheInvalidation(ctx)
@@ -710,33 +1712,30 @@ func (s FgaService) CheckRelationships(ctx context.Context, tuples []ClientCheck
 		return nil, err
 	}
 
-	tuplesToCheck := make([]ClientBatchCheckItem, 0) // list of tuples to check in OpenFGA if not in cache
-	tupleItems := make([]ClientBatchCheckItem, 0, len(tuples))
-	for _, tuple := range tuples {
-		tupleItems = append(tupleItems, ClientBatchCheckItem{
-			User:     tuple.User,
-			Relation: tuple.Relation,
-			Object:   tuple.Object,
-		})
+	results := make([]BatchCheckResult, len(items))
+	for i, item := range items {
+		checkHotspots.record(item.Object)
+		results[i].RelationKey = item.Object + "#" + item.Relation + "@" + item.User
 	}
 
-	// Loop through the requested tuples to check for cache hits.
-	for i, tuple := range tupleItems {
+	// Look up every requested tuple in the cache, then walk the results in
+	// request order to collect cache hits; misses are the indexes of the
+	// items left to check in OpenFGA.
+	var misses []int
+	lookups := s.lookupCachedChecks(ctx, items)
+	for i, item := range items {
 		// If the cache is disabled, all tuples are added to the check list.
-		if !useCache {
-			tuplesToCheck = append(tuplesToCheck, tuple)
+		if !s.useCache {
+			misses = append(misses, i)
 			continue
 		}
 
-		relationKey := tuple.Object + "#" + tuple.Relation + "@" + tuple.User
-		// Encode relation using base32 without padding to conform to the allowed
-		// characters for NATS subjects.
-		cacheKey := "rel." + cacheKeyEncoder.EncodeToString([]byte(relationKey))
-		entry, errCache := s.cacheBucket.Get(ctx, cacheKey)
+		relationKey := lookups[i].relationKey
+		entry, errCache := lookups[i].entry, lookups[i].err
 		if errCache == jetstream.ErrKeyNotFound {
 			// No cache hit; continue.
 			cacheMisses.Add(1)
-			tuplesToCheck = append(tuplesToCheck, tuple)
+			misses = append(misses, i)
 			continue
 		}
 		if errCache != nil {
@@ -746,10 +1745,19 @@ func (s FgaService) CheckRelationships(ctx context.Context, tuples []ClientCheck
 			// request at this point.
 			logger.With(errKey, errCache).ErrorContext(ctx, "cache error; continuing")
 			// Add all remaining tuples to the check list.
-			tuplesToCheck = append(tuplesToCheck, tupleItems[i:]...)
+			for j := i; j < len(items); j++ {
+				misses = append(misses, j)
+			}
 			break
 		}
 
+		value, valid := s.decodeCachedCheck(ctx, relationKey, entry.Value())
+		if !valid {
+			cacheMisses.Add(1)
+			misses = append(misses, i)
+			continue
+		}
+
 		// Cache entry was found. If the cache entry is older than the invalidation
 		// timestamp, skip it.
 		if lastInvalidation.After(entry.Created()) {
@@ -760,7 +1768,18 @@ func (s FgaService) CheckRelationships(ctx context.Context, tuples []ClientCheck
 				"entry_value", string(entry.Value()),
 			).DebugContext(ctx, "cache stale hit")
 			cacheStaleHits.Add(1)
-			tuplesToCheck = append(tuplesToCheck, tupleItems[i])
+			misses = append(misses, i)
+			continue
+		}
+		// The entry is also stale once older than its object type's TTL.
+		if ttl := s.checkPolicies.forObject(item.Object).cacheTTL; ttl > 0 && time.Since(entry.Created()) > ttl {
+			logger.With(
+				"relation_key", relationKey,
+				"cache_ttl", ttl,
+				"entry_created", entry.Created(),
+			).DebugContext(ctx, "cache expired hit")
+			cacheStaleHits.Add(1)
+			misses = append(misses, i)
 			continue
 		}
 		logger.With(
@@ -770,56 +1789,118 @@ func (s FgaService) CheckRelationships(ctx context.Context, tuples []ClientCheck
 			"entry_value", string(entry.Value()),
 		).DebugContext(ctx, "cache hit")
 		cacheHits.Add(1)
-		// Append the cached value to our response message.
-		message = append(message, []byte(fmt.Sprintf("%s\t%s\n", relationKey, string(entry.Value())))...)
+		results[i].Allowed = value == "true"
+		results[i].CacheHit = true
 	}
 
-	// If we have no tuples to check, return the cached message.
-	if len(tuplesToCheck) == 0 {
-		if len(message) < 1 {
-			// This shouldn't happen (tuples was non-empty, so tuplesToCheck should
-			// only be empty if we appended cache-hits to message), but it's a sanity
-			// test before applying the len(message)-1 slice range.
-			return nil, errors.New("batch check cached-built message empty")
-		}
-		// Trim the last newline and return.
-		return message[:len(message)-1], nil
-	}
-
-	// Add correlation IDs to the tuples to check.
-	// Increment each correlation ID by 1, starting from 1.
-	mapCorrelationIDToTuple := make(map[string]ClientBatchCheckItem)
-	for idx := range tuplesToCheck {
-		correlationID := fmt.Sprintf("%d", idx+1)
-		tuplesToCheck[idx].CorrelationId = correlationID
-		mapCorrelationIDToTuple[correlationID] = tuplesToCheck[idx]
+	if len(misses) == 0 {
+		return results, nil
 	}
 
-	// Check all tuples that weren't found in the cache.
-	batchCheckRequest := ClientBatchCheckRequest{
-		Checks: tuplesToCheck,
+	// Check every miss in OpenFGA. Correlation IDs count the misses from 1;
+	// the caller's items are copied, so their own IDs are left as they were.
+	tuplesToCheck := make([]ClientBatchCheckItem, 0, len(misses))
+	for n, i := range misses {
+		item := items[i]
+		item.CorrelationId = strconv.Itoa(n + 1)
+		tuplesToCheck = append(tuplesToCheck, item)
 	}
-	batchResp, err := s.client.BatchCheck(ctx, batchCheckRequest)
+	checked, guarded, err := s.guardedBatchCheck(ctx, tuplesToCheck)
 	if err != nil {
 		return nil, err
 	}
-
-	if batchResp == nil || batchResp.Result == nil || len(*batchResp.Result) == 0 {
-		return nil, errors.New("batch check response was nil or empty")
+	if guarded {
+		for _, i := range misses {
+			results[i].Guarded = true
+		}
+		return results, nil
 	}
+	s.recordCheckResults(ctx, results, misses, checked)
+	return results, nil
+}
 
-	// Loop through the responses.
-	message = s.appendToMessage(ctx, message, *batchResp.Result, mapCorrelationIDToTuple)
+// recordCheckResults stores the OpenFGA results of a BatchCheck in results.
+// checked is keyed by correlation ID, the position of the check in misses
+// plus one, and misses holds the index in results of each check. It caches every
+// result that is not an error. Results are cached even with the cache
+// disabled for reads, so that it is warm once enabled.
+func (s FgaService) recordCheckResults(
+	ctx context.Context,
+	results []BatchCheckResult,
+	misses []int,
+	checked map[string]openfga.BatchCheckSingleResult,
+) {
+	for correlationID, resp := range checked {
+		n, err := strconv.Atoi(correlationID)
+		if err != nil || n < 1 || n > len(misses) {
+			continue
+		}
+		result := &results[misses[n-1]]
+		// Check if the response contains an error (e.g., timeout, deadline
+		// exceeded); it is reported as not allowed and not cached.
+		if resp.HasError() {
+			checkErr := resp.GetError()
+			logger.With(
+				"correlation_id", correlationID,
+				"relation_key", result.RelationKey,
+				"error_code", checkErr.GetInternalError(),
+				"error_message", checkErr.GetMessage(),
+			).WarnContext(ctx, "batch check returned error for tuple, skipping cache")
+			result.Err = errors.New(checkErr.GetMessage())
+			continue
+		}
 
-	if len(message) < 1 {
-		// This shouldn't happen (*batchResp was checked for ==0 above with an
-		// early return, so there must have been at least one loop iteration), but
-		// it's a sanity test before applying the `len(message)-1` slice range.
-		return nil, errors.New("batch check response message empty")
+		result.Allowed = resp.GetAllowed()
+		allowed := strconv.FormatBool(result.Allowed)
+		cacheKey := checkCacheKey(cacheScopeFrom(ctx), result.RelationKey)
+		if _, err = s.cacheBucket.Put(ctx, cacheKey, s.cachedCheckValue(result.RelationKey, allowed)); err != nil {
+			logger.With(errKey, err).ErrorContext(ctx, "failed to cache relation")
+		}
 	}
+}
 
-	// Trim the last newline and return.
-	return message[:len(message)-1], nil
+// batchCheckByConsistency checks items in OpenFGA, issuing one BatchCheck per
+// consistency preference their object types' policies call for, and returns
+// the results of every call keyed by correlation ID.
+func (s FgaService) batchCheckByConsistency(
+	ctx context.Context,
+	items []ClientBatchCheckItem,
+) (map[string]openfga.BatchCheckSingleResult, error) {
+	var order []openfga.ConsistencyPreference
+	groups := make(map[openfga.ConsistencyPreference][]ClientBatchCheckItem)
+	for _, item := range items {
+		consistency := s.checkPolicies.forObject(item.Object).consistency
+		if _, ok := groups[consistency]; !ok {
+			order = append(order, consistency)
+		}
+		groups[consistency] = append(groups[consistency], item)
+	}
+
+	results := make(map[string]openfga.BatchCheckSingleResult, len(items))
+	for _, consistency := range order {
+		request := ClientBatchCheckRequest{Checks: groups[consistency]}
+		options := batchCheckOptions(consistency)
+		batchResp, err := s.client.BatchCheck(ctx, request, options)
+		if err != nil {
+			return nil, err
+		}
+		if batchResp == nil || batchResp.Result == nil || len(*batchResp.Result) == 0 {
+			return nil, errors.New("batch check response was nil or empty")
+		}
+
+		if s.shadowClient != nil && s.shadowChecks {
+			// Compare against the shadow store off the request path so shadow
+			// latency or failures never affect the primary response.
+			go func(ctx context.Context) {
+				ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				defer cancel()
+				s.shadowCheck(ctx, request, options, *batchResp.Result)
+			}(context.WithoutCancel(ctx))
+		}
+
+		maps.Copy(results, *batchResp.Result)
+	}
+	return results, nil
 }
 
 // ExtractCheckRequests extracts the check requests from our binary message
@@ -16,12 +16,17 @@ import (
 type IFgaClient interface {
 	Read(ctx context.Context, req ClientReadRequest, options ClientReadOptions) (*ClientReadResponse, error)
 	Write(ctx context.Context, req ClientWriteRequest) (*ClientWriteResponse, error)
-	BatchCheck(ctx context.Context, request ClientBatchCheckRequest) (*openfga.BatchCheckResponse, error)
+	BatchCheck(
+		ctx context.Context,
+		request ClientBatchCheckRequest,
+		options BatchCheckOptions,
+	) (*openfga.BatchCheckResponse, error)
 	ListObjects(
 		ctx context.Context,
 		body ClientListObjectsRequest,
 		options ClientListObjectsOptions,
 	) (*ClientListObjectsResponse, error)
+	ReadAuthorizationModel(ctx context.Context) (*ClientReadAuthorizationModelResponse, error)
 }
 
 // FgaAdapter is a wrapper around the OpenFGA client that implements IFgaClient.
@@ -33,8 +38,9 @@ type FgaAdapter struct {
 func (c FgaAdapter) BatchCheck(
 	ctx context.Context,
 	request ClientBatchCheckRequest,
+	options BatchCheckOptions,
 ) (*openfga.BatchCheckResponse, error) {
-	return c.OpenFgaClient.BatchCheck(ctx).Body(request).Execute()
+	return c.OpenFgaClient.BatchCheck(ctx).Body(request).Options(options).Execute()
 }
 
 // Read executes a read request.
@@ -62,3 +68,8 @@ func (c FgaAdapter) ListObjects(
 ) (*ClientListObjectsResponse, error) {
 	return c.OpenFgaClient.ListObjects(ctx).Body(body).Options(options).Execute()
 }
+
+// ReadAuthorizationModel reads the configured authorization model.
+func (c FgaAdapter) ReadAuthorizationModel(ctx context.Context) (*ClientReadAuthorizationModelResponse, error) {
+	return c.OpenFgaClient.ReadAuthorizationModel(ctx).Execute()
+}
@@ -0,0 +1,125 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// defaultModelCacheTTL is how long a fetched authorization model is reused
+// before it is read again from OpenFGA.
+const defaultModelCacheTTL = 5 * time.Minute
+
+// authorizationModel is a read-only view of the type definitions in an
+// OpenFGA authorization model.
+type authorizationModel struct {
+	id string
+	// relations maps object type to relation to the user types that may be
+	// directly related (e.g. "project", "user:*", "team#member").
+	relations map[string]map[string][]string
+}
+
+// newAuthorizationModel indexes the type definitions of model.
+func newAuthorizationModel(model openfga.AuthorizationModel) *authorizationModel {
+	m := &authorizationModel{
+		id:        model.Id,
+		relations: make(map[string]map[string][]string, len(model.TypeDefinitions)),
+	}
+	for _, typeDef := range model.TypeDefinitions {
+		relations := make(map[string][]string)
+		for relation := range typeDef.GetRelations() {
+			relations[relation] = nil
+		}
+		typeMetadata := typeDef.GetMetadata()
+		for relation, metadata := range typeMetadata.GetRelations() {
+			for _, ref := range metadata.GetDirectlyRelatedUserTypes() {
+				userType := ref.Type
+				switch {
+				case ref.Wildcard != nil:
+					userType += ":*"
+				case ref.Relation != nil:
+					userType += "#" + *ref.Relation
+				}
+				relations[relation] = append(relations[relation], userType)
+			}
+		}
+		m.relations[typeDef.Type] = relations
+	}
+	return m
+}
+
+// relationsFor returns the sorted relations defined on objectType, and
+// whether the type exists in the model.
+func (m *authorizationModel) relationsFor(objectType string) ([]string, bool) {
+	relations, ok := m.relations[objectType]
+	if !ok {
+		return nil, false
+	}
+	names := make([]string, 0, len(relations))
+	for relation := range relations {
+		names = append(names, relation)
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// hasRelation reports whether relation is defined on objectType.
+func (m *authorizationModel) hasRelation(objectType, relation string) bool {
+	_, ok := m.relations[objectType][relation]
+	return ok
+}
+
+// directUserTypes returns the user types that may be directly related to
+// objectType through relation.
+func (m *authorizationModel) directUserTypes(objectType, relation string) []string {
+	return m.relations[objectType][relation]
+}
+
+// modelCache holds the most recently fetched authorization model so that
+// model lookups don't cost an OpenFGA round trip per message.
+type modelCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	model   *authorizationModel
+	fetched time.Time
+}
+
+// newModelCache returns an empty cache that refreshes after ttl.
+func newModelCache(ttl time.Duration) *modelCache {
+	return &modelCache{ttl: ttl}
+}
+
+// AuthorizationModel returns the configured authorization model, served from
+// the model cache when it is fresh.
+func (s FgaService) AuthorizationModel(ctx context.Context) (*authorizationModel, error) {
+	if s.modelCache != nil {
+		s.modelCache.mu.Lock()
+		defer s.modelCache.mu.Unlock()
+		if s.modelCache.model != nil && time.Since(s.modelCache.fetched) < s.modelCache.ttl {
+			return s.modelCache.model, nil
+		}
+	}
+
+	resp, err := s.client.ReadAuthorizationModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.AuthorizationModel == nil {
+		return nil, errors.New("authorization model response was empty")
+	}
+	model := newAuthorizationModel(*resp.AuthorizationModel)
+
+	if s.modelCache != nil {
+		s.modelCache.model = model
+		s.modelCache.fetched = time.Now()
+	}
+	return model, nil
+}
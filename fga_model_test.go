@@ -0,0 +1,97 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	. "github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// directRelation builds the type definition metadata for a relation that
+// users of the given types can be directly assigned.
+func directRelation(userTypes ...openfga.RelationReference) openfga.RelationMetadata {
+	return openfga.RelationMetadata{DirectlyRelatedUserTypes: &userTypes}
+}
+
+// testAuthorizationModelResponse returns a small authorization model covering
+// v1 and v2 meetings, for tests that exercise model introspection.
+func testAuthorizationModelResponse() *ClientReadAuthorizationModelResponse {
+	typeDefinition := func(objectType string, relations map[string]openfga.RelationMetadata) openfga.TypeDefinition {
+		usersets := make(map[string]openfga.Userset, len(relations))
+		for relation := range relations {
+			usersets[relation] = openfga.Userset{This: &map[string]interface{}{}}
+		}
+		return openfga.TypeDefinition{
+			Type:      objectType,
+			Relations: &usersets,
+			Metadata:  &openfga.Metadata{Relations: &relations},
+		}
+	}
+	user := openfga.RelationReference{Type: "user"}
+	userWildcard := openfga.RelationReference{Type: "user", Wildcard: &map[string]interface{}{}}
+
+	return &ClientReadAuthorizationModelResponse{
+		AuthorizationModel: &openfga.AuthorizationModel{
+			Id:            "model-1",
+			SchemaVersion: "1.1",
+			TypeDefinitions: []openfga.TypeDefinition{
+				{Type: "user"},
+				typeDefinition("project", map[string]openfga.RelationMetadata{
+					"writer": directRelation(user),
+					"viewer": directRelation(user, userWildcard),
+				}),
+				typeDefinition("meeting", map[string]openfga.RelationMetadata{
+					"project": directRelation(openfga.RelationReference{Type: "project"}),
+					"viewer":  directRelation(user, userWildcard),
+				}),
+				typeDefinition("past_meeting", map[string]openfga.RelationMetadata{
+					"meeting": directRelation(openfga.RelationReference{Type: "meeting"}),
+					"viewer":  directRelation(user, userWildcard),
+				}),
+				typeDefinition("v1_meeting", map[string]openfga.RelationMetadata{
+					"viewer": directRelation(user, userWildcard),
+				}),
+				typeDefinition("v1_past_meeting", map[string]openfga.RelationMetadata{
+					"meeting": directRelation(openfga.RelationReference{Type: "v1_meeting"}),
+					"viewer":  directRelation(user, userWildcard),
+				}),
+			},
+		},
+	}
+}
+
+func TestAuthorizationModel(t *testing.T) {
+	model := newAuthorizationModel(*testAuthorizationModelResponse().AuthorizationModel)
+
+	relations, ok := model.relationsFor("past_meeting")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"meeting", "viewer"}, relations)
+
+	_, ok = model.relationsFor("unknown")
+	assert.False(t, ok)
+
+	assert.True(t, model.hasRelation("project", "writer"))
+	assert.False(t, model.hasRelation("project", "owner"))
+	assert.Equal(t, []string{"user", "user:*"}, model.directUserTypes("project", "viewer"))
+	assert.Equal(t, []string{"v1_meeting"}, model.directUserTypes("v1_past_meeting", "meeting"))
+}
+
+func TestFgaService_AuthorizationModel_Cache(t *testing.T) {
+	client := new(MockFgaClient)
+	client.On("ReadAuthorizationModel", mock.Anything).Return(testAuthorizationModelResponse(), nil).Once()
+	service := FgaService{client: client, modelCache: newModelCache(defaultModelCacheTTL)}
+
+	for i := 0; i < 3; i++ {
+		model, err := service.AuthorizationModel(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "model-1", model.id)
+	}
+	client.AssertNumberOfCalls(t, "ReadAuthorizationModel", 1)
+}
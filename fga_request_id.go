@@ -0,0 +1,89 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// fgaRequestIDsKey is the context key for the OpenFGA request IDs recorded
+// while handling a message.
+type fgaRequestIDsKey struct{}
+
+// fgaRequestIDs collects the request IDs of the OpenFGA calls made while
+// handling a message, so they can be correlated with OpenFGA's own logs.
+type fgaRequestIDs struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+// withFgaRequestIDs returns a context that records the request IDs of
+// OpenFGA calls made with it.
+func withFgaRequestIDs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fgaRequestIDsKey{}, &fgaRequestIDs{})
+}
+
+// fgaRequestIDsFrom returns the OpenFGA request IDs recorded in ctx.
+func fgaRequestIDsFrom(ctx context.Context) []string {
+	recorder, ok := ctx.Value(fgaRequestIDsKey{}).(*fgaRequestIDs)
+	if !ok {
+		return nil
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]string(nil), recorder.ids...)
+}
+
+// requestIDTransport is an [http.RoundTripper] that records the OpenFGA
+// request ID of each response in the request context.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if resp == nil {
+		return resp, err
+	}
+	recorder, ok := req.Context().Value(fgaRequestIDsKey{}).(*fgaRequestIDs)
+	if !ok {
+		return resp, err
+	}
+	// OpenFGA sets Fga-Request-Id; X-Request-Id is the fallback the SDK also
+	// reads for errors.
+	id := resp.Header.Get("Fga-Request-Id")
+	if id == "" {
+		id = resp.Header.Get("X-Request-Id")
+	}
+	if id != "" {
+		recorder.mu.Lock()
+		recorder.ids = append(recorder.ids, id)
+		recorder.mu.Unlock()
+	}
+	return resp, err
+}
+
+// fgaRequestID returns the OpenFGA request ID carried by an SDK API error in
+// err's chain, if any.
+func fgaRequestID(err error) string {
+	var apiErr interface{ RequestId() string }
+	if errors.As(err, &apiErr) {
+		return apiErr.RequestId()
+	}
+	return ""
+}
+
+// withFgaRequestID appends the OpenFGA request ID of err to an error reply
+// text, so callers can hand it over when escalating.
+func withFgaRequestID(errText string, err error) string {
+	if id := fgaRequestID(err); id != "" {
+		return errText + " (openfga_request_id: " + id + ")"
+	}
+	return errText
+}
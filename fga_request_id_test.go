@@ -0,0 +1,71 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDispatchMessage_FgaRequestID asserts that the request IDs of OpenFGA
+// calls made by a handler are logged with the dispatch result.
+func TestDispatchMessage_FgaRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/with-id" {
+			w.Header().Set("Fga-Request-Id", "req-123")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	httpClient := &http.Client{Transport: requestIDTransport{next: http.DefaultTransport}}
+
+	var buf bytes.Buffer
+	origLogger := logger
+	logger = slog.New(slog.NewTextHandler(&buf, nil))
+	defer func() { logger = origLogger }()
+
+	callOpenFGA := func(path string) HandlerFunc {
+		return func(ctx context.Context, _ INatsMsg) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}
+	}
+
+	dispatchMessage(context.Background(), "test.subject", "with id", "queue", callOpenFGA("/with-id"), CreateMockNatsMsg(nil))
+	assert.Contains(t, buf.String(), "openfga_request_ids=[req-123]")
+
+	buf.Reset()
+	dispatchMessage(context.Background(), "test.subject", "without id", "queue", callOpenFGA("/without-id"), CreateMockNatsMsg(nil))
+	assert.NotContains(t, buf.String(), "openfga_request_ids")
+}
+
+func TestWithFgaRequestID(t *testing.T) {
+	httpResponse := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Fga-Request-Id": []string{"req-456"}},
+		Request:    httptest.NewRequest(http.MethodPost, "http://openfga/stores/store/write", nil),
+	}
+	apiErr := openfga.NewFgaApiError("Write", nil, httpResponse, nil, "store")
+
+	assert.Equal(t, "failed (openfga_request_id: req-456)", withFgaRequestID("failed", fmt.Errorf("wrapped: %w", apiErr)))
+	assert.Equal(t, "failed", withFgaRequestID("failed", errors.New("boom")))
+	assert.Equal(t, "failed", withFgaRequestID("failed", nil))
+}
@@ -8,8 +8,15 @@ import (
 	"context"
 	"encoding/base32"
 	"errors"
+	"fmt"
 	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -42,6 +49,24 @@ func (m *MockNatsKeyValue) PutString(ctx context.Context, key string, value stri
 	return args.Get(0).(uint64), args.Error(1)
 }
 
+func (m *MockNatsKeyValue) Create(ctx context.Context, key string, value []byte, _ ...jetstream.KVCreateOpt) (uint64, error) {
+	args := m.Called(ctx, key, value)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockNatsKeyValue) Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	args := m.Called(ctx, key, value, revision)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockNatsKeyValue) ListKeysFiltered(ctx context.Context, filters ...string) (jetstream.KeyLister, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(jetstream.KeyLister), args.Error(1)
+}
+
 // TestCacheKeyEncoding tests the cache key encoding functionality
 func TestCacheKeyEncoding(t *testing.T) {
 	tests := []struct {
@@ -377,6 +402,220 @@ func TestReadObjectTuples(t *testing.T) {
 	}
 }
 
+// TestReadObjectTuples_PageSize asserts that the configured page size, or a
+// per-call override, is passed on every page of a Read.
+func TestReadObjectTuples_PageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  func(FgaService) FgaService
+		expected *int32
+	}{
+		{
+			name:     "configured page size",
+			service:  func(s FgaService) FgaService { return s },
+			expected: openfga.PtrInt32(100),
+		},
+		{
+			name:     "per-call override",
+			service:  func(s FgaService) FgaService { return s.WithReadPageSize(10) },
+			expected: openfga.PtrInt32(10),
+		},
+		{
+			name:    "server default",
+			service: func(s FgaService) FgaService { return s.WithReadPageSize(0) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := new(MockFgaClient)
+			var pageSizes []*int32
+			client.On("Read", mock.Anything, mock.Anything, mock.MatchedBy(func(opts ClientReadOptions) bool {
+				return opts.ContinuationToken == nil
+			})).Run(func(args mock.Arguments) {
+				pageSizes = append(pageSizes, args.Get(2).(ClientReadOptions).PageSize)
+			}).Return(&ClientReadResponse{ContinuationToken: "page2"}, nil).Once()
+			client.On("Read", mock.Anything, mock.Anything, mock.MatchedBy(func(opts ClientReadOptions) bool {
+				return opts.ContinuationToken != nil
+			})).Run(func(args mock.Arguments) {
+				pageSizes = append(pageSizes, args.Get(2).(ClientReadOptions).PageSize)
+			}).Return(&ClientReadResponse{}, nil).Once()
+
+			service := tt.service(FgaService{client: client, readPageSize: defaultReadPageSize})
+			if _, err := service.ReadObjectTuples(context.Background(), "project:123"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(pageSizes) != 2 {
+				t.Fatalf("expected 2 reads, got %d", len(pageSizes))
+			}
+			for i, pageSize := range pageSizes {
+				switch {
+				case tt.expected == nil && pageSize != nil:
+					t.Errorf("read %d: expected no page size, got %d", i, *pageSize)
+				case tt.expected != nil && (pageSize == nil || *pageSize != *tt.expected):
+					t.Errorf("read %d: expected page size %d, got %v", i, *tt.expected, pageSize)
+				}
+			}
+		})
+	}
+}
+
+// pagingFgaClient serves a fixed number of tuples for one object, honoring
+// the requested page size, and counts round trips.
+type pagingFgaClient struct {
+	MockFgaClient
+	total int
+	reads int
+}
+
+func (c *pagingFgaClient) Read(_ context.Context, _ ClientReadRequest, options ClientReadOptions) (*ClientReadResponse, error) {
+	c.reads++
+	pageSize := 50 // OpenFGA server default
+	if options.PageSize != nil {
+		pageSize = int(*options.PageSize)
+	}
+	start := 0
+	if options.ContinuationToken != nil {
+		start, _ = strconv.Atoi(*options.ContinuationToken)
+	}
+	end := min(start+pageSize, c.total)
+	resp := &ClientReadResponse{Tuples: make([]openfga.Tuple, end-start)}
+	if end < c.total {
+		resp.ContinuationToken = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+// BenchmarkReadObjectTuples_PageSize reports the Read round trips needed to
+// read a large object at different page sizes.
+func BenchmarkReadObjectTuples_PageSize(b *testing.B) {
+	for _, pageSize := range []int32{0, 50, 100} {
+		b.Run(fmt.Sprintf("page_size=%d", pageSize), func(b *testing.B) {
+			client := &pagingFgaClient{total: 5000}
+			service := FgaService{client: client, readPageSize: pageSize}
+			for b.Loop() {
+				if _, err := service.ReadObjectTuples(context.Background(), "project:large"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(client.reads)/float64(b.N), "reads/op")
+		})
+	}
+}
+
+func TestReadObjectTuples_DeduplicatesConcurrentReads(t *testing.T) {
+	const callers = 10
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mockClient := new(MockFgaClient)
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) {
+			close(started)
+			<-release
+		}).
+		Return(&ClientReadResponse{
+			Tuples: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:456", Relation: "writer", Object: "project:123"}},
+			},
+		}, nil).Once()
+
+	service := FgaService{client: mockClient, objectReads: newTupleReadGroup()}
+
+	var wg sync.WaitGroup
+	results := make([][]openfga.Tuple, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = service.ReadObjectTuples(context.Background(), "project:123")
+		}()
+	}
+
+	// Hold the first read open until every other caller waits for it.
+	<-started
+	for waiters := 0; waiters < callers-1; {
+		runtime.Gosched()
+		service.objectReads.mu.Lock()
+		if call := service.objectReads.calls["project:123"]; call != nil {
+			waiters = call.waiters
+		}
+		service.objectReads.mu.Unlock()
+	}
+	close(release)
+	wg.Wait()
+
+	mockClient.AssertNumberOfCalls(t, "Read", 1)
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: unexpected error: %v", i, errs[i])
+		}
+		if len(results[i]) != 1 || results[i][0].Key.User != "user:456" {
+			t.Fatalf("caller %d: unexpected tuples %v", i, results[i])
+		}
+	}
+
+	// Callers must not be able to mutate each other's results.
+	results[0][0].Key.User = "user:mutated"
+	if results[1][0].Key.User != "user:456" {
+		t.Errorf("shared result was mutated through another caller: %v", results[1])
+	}
+}
+
+// TestReadObjectTuples_SharedReadOutlivesFirstCaller tests that a shared
+// read is not cancelled with the caller that started it, so that callers
+// waiting for it still receive its result.
+func TestReadObjectTuples_SharedReadOutlivesFirstCaller(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var readErr error
+	mockClient := new(MockFgaClient)
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-release
+			readErr = args.Get(0).(context.Context).Err()
+		}).
+		Return(&ClientReadResponse{
+			Tuples: []openfga.Tuple{{Key: openfga.TupleKey{User: "user:456", Relation: "writer", Object: "project:123"}}},
+		}, nil).Once()
+	service := FgaService{client: mockClient, objectReads: newTupleReadGroup()}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := service.ReadObjectTuples(firstCtx, "project:123")
+		firstDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan []openfga.Tuple, 1)
+	go func() {
+		tuples, _ := service.ReadObjectTuples(context.Background(), "project:123")
+		waiterDone <- tuples
+	}()
+	for waiters := 0; waiters < 1; {
+		runtime.Gosched()
+		service.objectReads.mu.Lock()
+		waiters = service.objectReads.calls["project:123"].waiters
+		service.objectReads.mu.Unlock()
+	}
+	cancelFirst()
+	close(release)
+
+	if tuples := <-waiterDone; len(tuples) != 1 {
+		t.Errorf("expected the waiter to receive the shared tuples, got %v", tuples)
+	}
+	if err := <-firstDone; err != nil {
+		t.Errorf("unexpected error for the first caller: %v", err)
+	}
+	if readErr != nil {
+		t.Errorf("expected the shared read context to outlive the first caller, got %v", readErr)
+	}
+}
+
 // TestSyncObjectTuples_RelationMapping tests the relation mapping logic
 func TestSyncObjectTuples_RelationMapping(t *testing.T) {
 	tests := []struct {
@@ -911,6 +1150,41 @@ func TestGetTuplesByRelation(t *testing.T) {
 	}
 }
 
+// TestGetTuplesByRelation_ServerSideFilter tests that GetTuplesByRelation
+// passes the relation to OpenFGA, while ReadObjectTuples still reads every
+// relation.
+func TestGetTuplesByRelation_ServerSideFilter(t *testing.T) {
+	mockClient := new(MockFgaClient)
+	var requests []ClientReadRequest
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		requests = append(requests, args.Get(1).(ClientReadRequest))
+	}).Return(&ClientReadResponse{Tuples: []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:organizer1", Relation: "meeting_coordinator", Object: "project:123"}},
+	}}, nil)
+	fgaService := FgaService{client: mockClient}
+
+	tuples, err := fgaService.GetTuplesByRelation(context.Background(), "project:123", "meeting_coordinator")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tuples) != 1 {
+		t.Errorf("expected 1 tuple, got %d", len(tuples))
+	}
+	if _, err = fgaService.ReadObjectTuples(context.Background(), "project:123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 reads, got %d", len(requests))
+	}
+	if requests[0].Relation == nil || *requests[0].Relation != "meeting_coordinator" {
+		t.Errorf("expected the relation filter in the read request, got %+v", requests[0])
+	}
+	if requests[1].Relation != nil {
+		t.Errorf("expected no relation filter reading all tuples, got %q", *requests[1].Relation)
+	}
+}
+
 // TestDeleteTuplesByUserAndObject tests the DeleteTuplesByUserAndObject functionality
 func TestDeleteTuplesByUserAndObject(t *testing.T) {
 	tests := []struct {
@@ -1104,7 +1378,7 @@ func TestDeleteTuplesByUserAndObject(t *testing.T) {
 			}
 
 			// Execute the function
-			err := service.DeleteTuplesByUserAndObject(context.Background(), tt.user, tt.object)
+			_, err := service.DeleteTuplesByUserAndObject(context.Background(), tt.user, tt.object)
 
 			// Verify error expectations
 			if tt.expectError && err == nil {
@@ -1120,6 +1394,101 @@ func TestDeleteTuplesByUserAndObject(t *testing.T) {
 	}
 }
 
+// TestDeleteTuplesByObject tests that the tuples of an object are deleted as
+// delete_access deletes them, keeping team member grants, in batches of at
+// most 100, and that the cache is invalidated.
+func TestDeleteTuplesByObject(t *testing.T) {
+	tuples := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "team:t1#member", Relation: "writer", Object: "project:p1"}},
+		{Key: openfga.TupleKey{
+			User: "user:bob", Relation: "viewer", Object: "project:p1",
+			Condition: &openfga.RelationshipCondition{Name: "non_expired"},
+		}},
+	}
+	for i := len(tuples); i < 150; i++ {
+		tuples = append(tuples, openfga.Tuple{Key: openfga.TupleKey{User: fmt.Sprintf("user:u%d", i), Relation: "viewer", Object: "project:p1"}})
+	}
+
+	mockClient := new(MockFgaClient)
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return(&ClientReadResponse{Tuples: tuples}, nil).Once()
+	var deleted []ClientTupleKeyWithoutCondition
+	mockClient.On("Write", mock.Anything, mock.MatchedBy(func(req ClientWriteRequest) bool {
+		return len(req.Writes) == 0 && len(req.Deletes) <= 100
+	})).Run(func(args mock.Arguments) {
+		deleted = append(deleted, args.Get(1).(ClientWriteRequest).Deletes...)
+	}).Return(&ClientWriteResponse{}, nil).Twice()
+	kv := NewMockKeyValue()
+	service := FgaService{client: mockClient, cacheBucket: kv}
+
+	count, err := service.DeleteTuplesByObject(context.Background(), "project:p1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 149 || len(deleted) != 149 {
+		t.Fatalf("expected 149 tuples deleted, got count %d and %d deletes", count, len(deleted))
+	}
+	for _, tuple := range deleted {
+		if tuple.User == "team:t1#member" {
+			t.Error("expected the team grant to be kept")
+		}
+	}
+	if !slices.ContainsFunc(deleted, func(tuple ClientTupleKeyWithoutCondition) bool { return tuple.User == "user:bob" }) {
+		t.Error("expected the conditional tuple to be deleted")
+	}
+	if _, ok := kv.data["inv"]; !ok {
+		t.Error("expected the cache to be invalidated")
+	}
+	mockClient.AssertExpectations(t)
+
+	t.Run("object without tuples", func(t *testing.T) {
+		mockClient := new(MockFgaClient)
+		mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+			Return(&ClientReadResponse{}, nil).Once()
+		service := FgaService{client: mockClient, cacheBucket: NewMockKeyValue()}
+
+		count, err := service.DeleteTuplesByObject(context.Background(), "project:p2")
+		if err != nil || count != 0 {
+			t.Errorf("expected nothing deleted, got %d, %v", count, err)
+		}
+		mockClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	})
+}
+
+// TestListObjectsByUserAndRelation tests that the objects OpenFGA lists are
+// returned as full object IDs, and that list errors are returned.
+func TestListObjectsByUserAndRelation(t *testing.T) {
+	objects := []string{"past_meeting_recording:r1", "past_meeting_recording:r2", "past_meeting_recording:r3"}
+	mockClient := new(MockFgaClient)
+	mockClient.On("ListObjects", mock.Anything, ClientListObjectsRequest{
+		User:     "user:alice",
+		Relation: "viewer",
+		Type:     "past_meeting_recording",
+	}, mock.Anything).Return(&ClientListObjectsResponse{Objects: objects}, nil).Once()
+	service := FgaService{client: mockClient, cacheBucket: NewMockKeyValue()}
+
+	listed, err := service.ListObjectsByUserAndRelation(context.Background(), "past_meeting_recording", "viewer", "user:alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(listed, objects) {
+		t.Errorf("expected %v, got %v", objects, listed)
+	}
+	mockClient.AssertExpectations(t)
+
+	t.Run("list error", func(t *testing.T) {
+		mockClient := new(MockFgaClient)
+		mockClient.On("ListObjects", mock.Anything, mock.Anything, mock.Anything).
+			Return((*ClientListObjectsResponse)(nil), errors.New("store unavailable")).Once()
+		service := FgaService{client: mockClient, cacheBucket: NewMockKeyValue()}
+
+		listed, err := service.ListObjectsByUserAndRelation(context.Background(), "past_meeting_recording", "viewer", "user:alice")
+		if err == nil || listed != nil {
+			t.Errorf("expected the list error, got %v, %v", listed, err)
+		}
+	})
+}
+
 // TestGetTuplesByUserAndObject tests the GetTuplesByUserAndObject functionality
 func TestGetTuplesByUserAndObject(t *testing.T) {
 	tests := []struct {
@@ -1387,7 +1756,7 @@ func TestSyncObjectTuples_ExcludeRelations(t *testing.T) {
 				client:      mockClient,
 				cacheBucket: mockCache,
 			}
-			writes, deletes, err := service.SyncObjectTuples(context.Background(), tt.object, tt.desiredRelations, tt.excludeRelations...)
+			writes, deletes, _, err := service.SyncObjectTuples(context.Background(), tt.object, tt.desiredRelations, tt.excludeRelations...)
 
 			// Verify no error
 			if err != nil {
@@ -1419,6 +1788,77 @@ func TestSyncObjectTuples_ExcludeRelations(t *testing.T) {
 	}
 }
 
+// TestSyncObjectsTuples tests that a two-object sync diffs both objects and
+// applies their changes together, and writes nothing if any read fails.
+func TestSyncObjectsTuples(t *testing.T) {
+	readsFor := func(object string) func(ClientReadRequest) bool {
+		return func(req ClientReadRequest) bool { return req.Object != nil && *req.Object == object }
+	}
+	recording := objectSync{
+		object: "past_meeting_recording:r1",
+		relations: []ClientTupleKey{
+			{User: "past_meeting:pm1", Relation: "past_meeting"},
+			{User: "user:alice", Relation: "viewer"},
+		},
+	}
+	pastMeeting := objectSync{
+		object:    "past_meeting:pm1",
+		relations: []ClientTupleKey{{User: "user:alice", Relation: "participant"}},
+	}
+
+	t.Run("changes for both objects are written together", func(t *testing.T) {
+		client := new(MockFgaClient)
+		client.On("Read", mock.Anything, mock.MatchedBy(readsFor(recording.object)), mock.Anything).
+			Return(&ClientReadResponse{Tuples: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "past_meeting:pm1", Relation: "past_meeting", Object: recording.object}},
+			}}, nil)
+		client.On("Read", mock.Anything, mock.MatchedBy(readsFor(pastMeeting.object)), mock.Anything).
+			Return(&ClientReadResponse{Tuples: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:bob", Relation: "participant", Object: pastMeeting.object}},
+			}}, nil)
+		client.On("Write", mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil).Once()
+		service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+		writes, deletes, err := service.SyncObjectsTuples(context.Background(), []objectSync{recording, pastMeeting})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		client.AssertNumberOfCalls(t, "Write", 1)
+		req := client.Calls[len(client.Calls)-1].Arguments.Get(1).(ClientWriteRequest)
+		if len(req.Writes) != 2 || len(req.Deletes) != 1 {
+			t.Fatalf("expected 2 writes and 1 delete in one request, got %d and %d", len(req.Writes), len(req.Deletes))
+		}
+		wantWrites := map[string]bool{
+			"past_meeting_recording:r1#viewer@user:alice": true,
+			"past_meeting:pm1#participant@user:alice":     true,
+		}
+		for _, w := range writes {
+			if !wantWrites[w.Object+"#"+w.Relation+"@"+w.User] {
+				t.Errorf("unexpected write %s#%s@%s", w.Object, w.Relation, w.User)
+			}
+		}
+		if len(deletes) != 1 || deletes[0].Object != pastMeeting.object || deletes[0].User != "user:bob" {
+			t.Errorf("unexpected deletes: %v", deletes)
+		}
+	})
+
+	t.Run("a failed read writes nothing", func(t *testing.T) {
+		client := new(MockFgaClient)
+		client.On("Read", mock.Anything, mock.MatchedBy(readsFor(recording.object)), mock.Anything).
+			Return(&ClientReadResponse{}, nil)
+		client.On("Read", mock.Anything, mock.MatchedBy(readsFor(pastMeeting.object)), mock.Anything).
+			Return((*ClientReadResponse)(nil), errors.New("store unavailable"))
+		service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+		_, _, err := service.SyncObjectsTuples(context.Background(), []objectSync{recording, pastMeeting})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		client.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	})
+}
+
 // TestSyncObjectTuples_PreserveTeamGrants tests that team member grant tuples are never deleted
 // during a sync operation, regardless of the desired relations list.
 func TestSyncObjectTuples_PreserveTeamGrants(t *testing.T) {
@@ -1529,7 +1969,7 @@ func TestSyncObjectTuples_PreserveTeamGrants(t *testing.T) {
 				client:      mockClient,
 				cacheBucket: mockCache,
 			}
-			writes, deletes, err := service.SyncObjectTuples(context.Background(), tt.object, tt.desiredRelations)
+			writes, deletes, _, err := service.SyncObjectTuples(context.Background(), tt.object, tt.desiredRelations)
 
 			if err != nil {
 				t.Errorf("%s: unexpected error: %v", tt.description, err)
@@ -1551,6 +1991,77 @@ func TestSyncObjectTuples_PreserveTeamGrants(t *testing.T) {
 	}
 }
 
+func TestSyncObjectTuples_PreserveConditionalTuples(t *testing.T) {
+	object := "project:proj-1"
+	condition := &openfga.RelationshipCondition{Name: "in_business_hours"}
+	existingTuples := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:writer1", Relation: "writer", Object: object}},
+		{Key: openfga.TupleKey{User: "user:stale", Relation: "writer", Object: object}},
+		{Key: openfga.TupleKey{User: "user:contractor", Relation: "writer", Object: object, Condition: condition}},
+		{Key: openfga.TupleKey{User: "user:auditor1", Relation: "auditor", Object: object, Condition: condition}},
+	}
+	desired := []ClientTupleKey{
+		{User: "user:writer1", Relation: "writer", Object: object},
+		{User: "user:auditor1", Relation: "auditor", Object: object},
+		{User: "user:new", Relation: "writer", Object: object},
+	}
+
+	tests := []struct {
+		name            string
+		preserve        bool
+		expectedDeletes []string
+	}{
+		{
+			name:            "conditional tuples preserved when enabled",
+			preserve:        true,
+			expectedDeletes: []string{"writer@user:stale"},
+		},
+		{
+			name:            "conditional tuples deleted when disabled",
+			preserve:        false,
+			expectedDeletes: []string{"writer@user:stale", "writer@user:contractor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockFgaClient)
+			mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&ClientReadResponse{Tuples: existingTuples}, nil).Once()
+			mockClient.On("Write", mock.Anything, mock.Anything, mock.Anything).
+				Return((*ClientWriteResponse)(nil), nil).Once()
+
+			mockCache := new(MockNatsKeyValue)
+			mockCache.On("Put", mock.Anything, mock.Anything, mock.Anything).Return(uint64(1), nil).Maybe()
+			mockCache.On("PutString", mock.Anything, mock.Anything, mock.Anything).Return(uint64(1), nil).Maybe()
+
+			service := FgaService{
+				client:                    mockClient,
+				cacheBucket:               mockCache,
+				preserveConditionalTuples: tt.preserve,
+			}
+			writes, deletes, _, err := service.SyncObjectTuples(context.Background(), object, desired)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The auditor tuple is in the desired set, so it matches whether
+			// or not it carries a condition, and only user:new is written.
+			if len(writes) != 1 || writes[0].User != "user:new" {
+				t.Errorf("expected only user:new to be written, got %v", writes)
+			}
+			var got []string
+			for _, del := range deletes {
+				got = append(got, del.Relation+"@"+del.User)
+			}
+			if !reflect.DeepEqual(got, tt.expectedDeletes) {
+				t.Errorf("expected deletes %v, got %v", tt.expectedDeletes, got)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
 // makeValidationError creates an openfga.FgaApiValidationError containing the given message.
 func makeValidationError(message string) openfga.FgaApiValidationError {
 	req, _ := http.NewRequest("POST", "http://localhost:8080", nil) //nolint:noctx
@@ -1616,6 +2127,42 @@ func TestWriteAndDeleteTuplesBatch(t *testing.T) {
 			expectError: false,
 			description: "invalid delete tuple should be removed and batch retried",
 		},
+		{
+			name:   "missing delete tuple skipped and retry succeeds",
+			writes: nil,
+			deletes: []ClientTupleKeyWithoutCondition{
+				{Object: "committee:c1", Relation: "member", User: "user:alice"},
+				{Object: "committee:c1", Relation: "writer", User: "user:alice"},
+			},
+			mockSetup: func(m *MockFgaClient) {
+				// First call fails because the member tuple is already gone
+				m.On("Write", mock.Anything, mock.MatchedBy(func(req ClientWriteRequest) bool {
+					return len(req.Deletes) == 2
+				})).Return((*ClientWriteResponse)(nil),
+					makeValidationError("cannot delete a tuple which does not exist: user: 'user:alice', relation: 'member', object: 'committee:c1': invalid write input"),
+				).Once()
+				// Retry with only the remaining delete succeeds
+				m.On("Write", mock.Anything, mock.MatchedBy(func(req ClientWriteRequest) bool {
+					return len(req.Deletes) == 1 && req.Deletes[0].Relation == "writer"
+				})).Return(&ClientWriteResponse{}, nil).Once()
+			},
+			expectError: false,
+			description: "deletes of missing tuples should be dropped and the batch retried",
+		},
+		{
+			name:   "only delete tuple missing returns nil",
+			writes: nil,
+			deletes: []ClientTupleKeyWithoutCondition{
+				{Object: "committee:c1", Relation: "member", User: "user:alice"},
+			},
+			mockSetup: func(m *MockFgaClient) {
+				m.On("Write", mock.Anything, mock.Anything).Return((*ClientWriteResponse)(nil),
+					makeValidationError("cannot delete a tuple which does not exist: user: 'user:alice', relation: 'member', object: 'committee:c1': invalid write input"),
+				).Once()
+			},
+			expectError: false,
+			description: "removing a tuple that is already gone should succeed",
+		},
 		{
 			name: "all write tuples invalid returns nil",
 			writes: []ClientTupleKey{
@@ -1698,3 +2245,776 @@ func TestWriteAndDeleteTuplesBatch(t *testing.T) {
 		})
 	}
 }
+
+// TestShadowWrite asserts that writes are mirrored to the shadow store and
+// that shadow failures never affect the primary write.
+func TestShadowWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		shadowErr   error
+		expectError bool
+	}{
+		{
+			name:        "shadow write succeeds",
+			shadowErr:   nil,
+			expectError: false,
+		},
+		{
+			name:        "shadow write failure is not fatal",
+			shadowErr:   errors.New("shadow store unavailable"),
+			expectError: false,
+		},
+	}
+
+	writes := []ClientTupleKey{{Object: "project:123", Relation: "writer", User: "user:alice"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := new(MockFgaClient)
+			shadow := new(MockFgaClient)
+			primary.On("Write", mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil).Once()
+			shadow.On("Write", mock.Anything, mock.MatchedBy(func(req ClientWriteRequest) bool {
+				return len(req.Writes) == 1 && req.Writes[0].User == "user:alice"
+			})).Return(&ClientWriteResponse{}, tt.shadowErr).Once()
+
+			service := FgaService{
+				client:       primary,
+				cacheBucket:  NewMockKeyValue(),
+				shadowClient: shadow,
+			}
+
+			before := shadowWriteErrors.Value()
+			err := service.WriteAndDeleteTuples(context.Background(), writes, nil)
+			if tt.expectError && err == nil {
+				t.Errorf("expected error but got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.shadowErr != nil && shadowWriteErrors.Value() != before+1 {
+				t.Errorf("expected shadow_write_errors to increment")
+			}
+
+			primary.AssertExpectations(t)
+			shadow.AssertExpectations(t)
+		})
+	}
+}
+
+// TestWriteAndDeleteTuples_Chunking asserts that operations beyond the
+// OpenFGA Write limit are written in batches of at most 100, in order, and
+// that a failed batch stops the rest.
+func TestWriteAndDeleteTuples_Chunking(t *testing.T) {
+	writes := make([]ClientTupleKey, 250)
+	for i := range writes {
+		writes[i] = ClientTupleKey{Object: "project:123", Relation: "viewer", User: fmt.Sprintf("user:u%d", i)}
+	}
+	batchOf := func(first, size int) any {
+		return mock.MatchedBy(func(req ClientWriteRequest) bool {
+			return len(req.Writes) == size && len(req.Deletes) == 0 && req.Writes[0].User == writes[first].User
+		})
+	}
+
+	t.Run("writes every batch", func(t *testing.T) {
+		client := new(MockFgaClient)
+		client.On("Write", mock.Anything, batchOf(0, 100)).Return(&ClientWriteResponse{}, nil).Once()
+		client.On("Write", mock.Anything, batchOf(100, 100)).Return(&ClientWriteResponse{}, nil).Once()
+		client.On("Write", mock.Anything, batchOf(200, 50)).Return(&ClientWriteResponse{}, nil).Once()
+		service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+		if err := service.WriteTuples(context.Background(), writes); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.AssertExpectations(t)
+		client.AssertNumberOfCalls(t, "Write", 3)
+	})
+
+	t.Run("stops at the first failed batch", func(t *testing.T) {
+		client := new(MockFgaClient)
+		client.On("Write", mock.Anything, batchOf(0, 100)).Return(&ClientWriteResponse{}, nil).Once()
+		client.On("Write", mock.Anything, batchOf(100, 100)).
+			Return((*ClientWriteResponse)(nil), errors.New("store unavailable")).Once()
+		service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+		if err := service.WriteTuples(context.Background(), writes); err == nil {
+			t.Fatal("expected error")
+		}
+		client.AssertNumberOfCalls(t, "Write", 2)
+	})
+}
+
+// TestShadowWrite_PrimaryFailure asserts that nothing is mirrored when the
+// primary write fails.
+func TestShadowWrite_PrimaryFailure(t *testing.T) {
+	primary := new(MockFgaClient)
+	shadow := new(MockFgaClient)
+	primary.On("Write", mock.Anything, mock.Anything).Return((*ClientWriteResponse)(nil), errors.New("primary down")).Once()
+
+	service := FgaService{
+		client:       primary,
+		cacheBucket:  NewMockKeyValue(),
+		shadowClient: shadow,
+	}
+
+	err := service.WriteTuple(context.Background(), "user:alice", "writer", "project:123")
+	if err == nil {
+		t.Errorf("expected primary error")
+	}
+	shadow.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+}
+
+// TestShadowCheck asserts that shadow check divergences and failures are
+// counted without affecting the primary result.
+func TestShadowCheck(t *testing.T) {
+	request := ClientBatchCheckRequest{
+		Checks: []ClientBatchCheckItem{
+			{Object: "project:123", Relation: "viewer", User: "user:alice", CorrelationId: "1"},
+			{Object: "project:123", Relation: "writer", User: "user:alice", CorrelationId: "2"},
+		},
+	}
+	primary := map[string]openfga.BatchCheckSingleResult{
+		"1": {Allowed: openfga.PtrBool(true)},
+		"2": {Allowed: openfga.PtrBool(false)},
+	}
+
+	tests := []struct {
+		name                string
+		shadowResult        map[string]openfga.BatchCheckSingleResult
+		shadowErr           error
+		expectedDivergences int64
+		expectedErrors      int64
+	}{
+		{
+			name: "matching results",
+			shadowResult: map[string]openfga.BatchCheckSingleResult{
+				"1": {Allowed: openfga.PtrBool(true)},
+				"2": {Allowed: openfga.PtrBool(false)},
+			},
+			expectedDivergences: 0,
+		},
+		{
+			name: "diverging result",
+			shadowResult: map[string]openfga.BatchCheckSingleResult{
+				"1": {Allowed: openfga.PtrBool(false)},
+				"2": {Allowed: openfga.PtrBool(false)},
+			},
+			expectedDivergences: 1,
+		},
+		{
+			name:           "shadow failure",
+			shadowErr:      errors.New("shadow store unavailable"),
+			expectedErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadow := new(MockFgaClient)
+			if tt.shadowErr != nil {
+				shadow.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return((*openfga.BatchCheckResponse)(nil), tt.shadowErr)
+			} else {
+				shadow.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{Result: &tt.shadowResult}, nil)
+			}
+			service := FgaService{
+				client:       new(MockFgaClient),
+				cacheBucket:  NewMockKeyValue(),
+				shadowClient: shadow,
+				shadowChecks: true,
+			}
+
+			divergencesBefore, errorsBefore := shadowCheckDivergences.Value(), shadowCheckErrors.Value()
+			service.shadowCheck(context.Background(), request, BatchCheckOptions{}, primary)
+
+			if got := shadowCheckDivergences.Value() - divergencesBefore; got != tt.expectedDivergences {
+				t.Errorf("expected %d divergences, got %d", tt.expectedDivergences, got)
+			}
+			if got := shadowCheckErrors.Value() - errorsBefore; got != tt.expectedErrors {
+				t.Errorf("expected %d shadow errors, got %d", tt.expectedErrors, got)
+			}
+		})
+	}
+}
+
+// TestCheckRelationships_ShadowFailure asserts that the primary check result
+// is returned unchanged when the shadow store fails.
+func TestCheckRelationships_ShadowFailure(t *testing.T) {
+	primary := new(MockFgaClient)
+	shadow := new(MockFgaClient)
+	resultMap := map[string]openfga.BatchCheckSingleResult{"1": {Allowed: openfga.PtrBool(true)}}
+	primary.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{Result: &resultMap}, nil)
+	shadow.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return((*openfga.BatchCheckResponse)(nil), errors.New("shadow store unavailable"))
+
+	service := FgaService{
+		client:       primary,
+		cacheBucket:  NewMockKeyValue(),
+		shadowClient: shadow,
+		shadowChecks: true,
+	}
+
+	resp, err := service.CheckRelationships(context.Background(), []ClientCheckRequest{
+		{Object: "project:123", Relation: "viewer", User: "user:alice"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp) != "project:123#viewer@user:alice\ttrue" {
+		t.Errorf("unexpected response: %q", resp)
+	}
+}
+
+// TestCheckRelationships_CacheIntegrity asserts that cached results stored
+// for a different relation than the one requested are caught and rechecked.
+func TestCheckRelationships_CacheIntegrity(t *testing.T) {
+	const relationKey = "project:123#viewer@user:alice"
+	cacheKey := "rel." + cacheKeyEncoder.EncodeToString([]byte(relationKey))
+
+	tests := []struct {
+		name            string
+		integrity       bool
+		cachedValue     string
+		expectBatch     bool
+		expectCollision int64
+		expectResponse  string
+	}{
+		{
+			name:           "matching relation is served from cache",
+			integrity:      true,
+			cachedValue:    relationKey + "\tfalse",
+			expectResponse: relationKey + "\tfalse",
+		},
+		{
+			name:            "mismatched relation is a miss and is counted",
+			integrity:       true,
+			cachedValue:     "project:456#writer@user:bob\tfalse",
+			expectBatch:     true,
+			expectCollision: 1,
+			expectResponse:  relationKey + "\ttrue",
+		},
+		{
+			name:           "unverifiable legacy value is a miss",
+			integrity:      true,
+			cachedValue:    "false",
+			expectBatch:    true,
+			expectResponse: relationKey + "\ttrue",
+		},
+		{
+			name:           "legacy value is served without integrity mode",
+			cachedValue:    "false",
+			expectResponse: relationKey + "\tfalse",
+		},
+		{
+			name:            "mismatched relation is caught without integrity mode",
+			cachedValue:     "project:456#writer@user:bob\tfalse",
+			expectBatch:     true,
+			expectCollision: 1,
+			expectResponse:  relationKey + "\ttrue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheKeyCollisions.Set(0)
+			client := new(MockFgaClient)
+			resultMap := map[string]openfga.BatchCheckSingleResult{"1": {Allowed: openfga.PtrBool(true)}}
+			client.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{Result: &resultMap}, nil)
+			kv := NewMockKeyValue()
+			if _, err := kv.PutString(context.Background(), cacheKey, tt.cachedValue); err != nil {
+				t.Fatalf("failed to seed cache: %v", err)
+			}
+			service := FgaService{client: client, cacheBucket: kv, useCache: true, cacheIntegrity: tt.integrity}
+
+			resp, err := service.CheckRelationships(context.Background(), []ClientCheckRequest{
+				{Object: "project:123", Relation: "viewer", User: "user:alice"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resp) != tt.expectResponse {
+				t.Errorf("unexpected response: %q", resp)
+			}
+			if tt.expectBatch {
+				client.AssertNumberOfCalls(t, "BatchCheck", 1)
+			} else {
+				client.AssertNotCalled(t, "BatchCheck", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if got := cacheKeyCollisions.Value(); got != tt.expectCollision {
+				t.Errorf("cache_key_collisions = %d, want %d", got, tt.expectCollision)
+			}
+		})
+	}
+}
+
+// TestCheckRelationships_CheckPolicies asserts that each check in a mixed
+// batch is served with its object type's cache TTL and consistency, and that
+// checks with different consistency preferences go in separate BatchCheck
+// calls.
+func TestCheckRelationships_CheckPolicies(t *testing.T) {
+	service := FgaService{
+		client:      new(MockFgaClient),
+		cacheBucket: NewMockKeyValue(),
+		useCache:    true,
+		checkPolicies: checkPolicies{
+			byType: map[string]checkPolicy{
+				"meeting": {consistency: openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY, cacheTTL: time.Minute},
+				"project": {consistency: openfga.CONSISTENCYPREFERENCE_MINIMIZE_LATENCY, cacheTTL: time.Hour},
+			},
+		},
+	}
+	client := service.client.(*MockFgaClient)
+	kv := service.cacheBucket.(*MockKeyValue)
+
+	// Cache results written ten minutes ago: past the meeting TTL, within the
+	// project TTL, and served until invalidation for committees, which have
+	// no policy.
+	for _, relationKey := range []string{
+		"meeting:1#viewer@user:alice",
+		"project:1#viewer@user:alice",
+		"committee:1#viewer@user:alice",
+	} {
+		cacheKey := "rel." + cacheKeyEncoder.EncodeToString([]byte(relationKey))
+		if _, err := kv.PutString(context.Background(), cacheKey, relationKey+"\tfalse"); err != nil {
+			t.Fatalf("failed to seed cache: %v", err)
+		}
+		kv.createdTimes[cacheKey] = time.Now().Add(-10 * time.Minute)
+	}
+
+	withConsistency := func(want openfga.ConsistencyPreference) any {
+		return mock.MatchedBy(func(options BatchCheckOptions) bool {
+			if options.Consistency == nil {
+				return want == ""
+			}
+			return *options.Consistency == want
+		})
+	}
+	allowed := func(correlationID string) *openfga.BatchCheckResponse {
+		result := map[string]openfga.BatchCheckSingleResult{correlationID: {Allowed: openfga.PtrBool(true)}}
+		return &openfga.BatchCheckResponse{Result: &result}
+	}
+	client.On("BatchCheck", mock.Anything, mock.Anything, withConsistency(openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY)).
+		Return(allowed("1"), nil).Once()
+	client.On("BatchCheck", mock.Anything, mock.Anything, withConsistency(openfga.CONSISTENCYPREFERENCE_MINIMIZE_LATENCY)).
+		Return(allowed("2"), nil).Once()
+	client.On("BatchCheck", mock.Anything, mock.Anything, withConsistency("")).
+		Return(allowed("3"), nil).Once()
+
+	resp, err := service.CheckRelationships(context.Background(), []ClientCheckRequest{
+		{Object: "meeting:1", Relation: "viewer", User: "user:alice"},
+		{Object: "project:1", Relation: "viewer", User: "user:alice"},
+		{Object: "committee:1", Relation: "viewer", User: "user:alice"},
+		{Object: "project:2", Relation: "viewer", User: "user:alice"},
+		{Object: "committee:2", Relation: "viewer", User: "user:alice"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := strings.Split(string(resp), "\n")
+	sort.Strings(got)
+	want := []string{
+		"committee:1#viewer@user:alice\tfalse",
+		"committee:2#viewer@user:alice\ttrue",
+		"meeting:1#viewer@user:alice\ttrue",
+		"project:1#viewer@user:alice\tfalse",
+		"project:2#viewer@user:alice\ttrue",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected response: %q", got)
+	}
+	client.AssertExpectations(t)
+}
+
+// slowKeyValue adds a fixed round-trip delay to every cache Get.
+type slowKeyValue struct {
+	*MockKeyValue
+	delay time.Duration
+}
+
+func (kv slowKeyValue) Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error) {
+	time.Sleep(kv.delay)
+	return kv.MockKeyValue.Get(ctx, key)
+}
+
+// seedCachedChecks caches an allowed result for n checks and returns the
+// requests for them.
+func seedCachedChecks(t testing.TB, kv *MockKeyValue, n int) []ClientCheckRequest {
+	t.Helper()
+	checks := make([]ClientCheckRequest, n)
+	for i := range checks {
+		checks[i] = ClientCheckRequest{Object: fmt.Sprintf("project:%d", i), Relation: "viewer", User: "user:alice"}
+		relationKey := checks[i].Object + "#viewer@user:alice"
+		cacheKey := "rel." + cacheKeyEncoder.EncodeToString([]byte(relationKey))
+		if _, err := kv.PutString(context.Background(), cacheKey, trueString); err != nil {
+			t.Fatalf("failed to seed cache: %v", err)
+		}
+	}
+	return checks
+}
+
+// TestCheckRelationships_ConcurrentCacheLookups asserts that cache hits
+// resolved concurrently are still returned in request order.
+func TestCheckRelationships_ConcurrentCacheLookups(t *testing.T) {
+	kv := NewMockKeyValue()
+	checks := seedCachedChecks(t, kv, 50)
+	client := new(MockFgaClient)
+	service := FgaService{client: client, cacheBucket: kv, useCache: true, cacheLookupConcurrency: 8}
+
+	resp, err := service.CheckRelationships(context.Background(), checks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(string(resp), "\n")
+	if len(lines) != len(checks) {
+		t.Fatalf("expected %d results, got %d", len(checks), len(lines))
+	}
+	for i, line := range lines {
+		want := checks[i].Object + "#viewer@user:alice\ttrue"
+		if line != want {
+			t.Errorf("result %d = %q, want %q", i, line, want)
+		}
+	}
+	client.AssertNotCalled(t, "BatchCheck", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestBatchCheck asserts that BatchCheck serves fresh cached results, checks
+// misses and entries older than the invalidation marker in OpenFGA, and
+// caches what OpenFGA resolved, returning results in request order.
+func TestBatchCheck(t *testing.T) {
+	kv := NewMockKeyValue()
+	client := new(MockFgaClient)
+	service := FgaService{client: client, cacheBucket: kv, useCache: true}
+
+	// project:1 was cached after the last invalidation, project:2 before it.
+	kv.data["inv"] = []byte("1")
+	kv.createdTimes["inv"] = time.Now().Add(-time.Minute)
+	for relationKey, created := range map[string]time.Time{
+		"project:1#viewer@user:alice": time.Now(),
+		"project:2#viewer@user:alice": time.Now().Add(-time.Hour),
+	} {
+		cacheKey := checkCacheKey("", relationKey)
+		kv.data[cacheKey] = service.cachedCheckValue(relationKey, "true")
+		kv.createdTimes[cacheKey] = created
+	}
+
+	result := map[string]openfga.BatchCheckSingleResult{
+		"1": {Allowed: openfga.PtrBool(false)},
+		"2": {Allowed: openfga.PtrBool(true)},
+		"3": {Error: &openfga.CheckError{Message: openfga.PtrString("deadline exceeded")}},
+	}
+	client.On("BatchCheck", mock.Anything, mock.MatchedBy(func(body ClientBatchCheckRequest) bool {
+		return len(body.Checks) == 3
+	}), mock.Anything).Return(&openfga.BatchCheckResponse{Result: &result}, nil).Once()
+
+	items := []ClientBatchCheckItem{
+		{Object: "project:1", Relation: "viewer", User: "user:alice", CorrelationId: "a"},
+		{Object: "project:2", Relation: "viewer", User: "user:alice", CorrelationId: "b"},
+		{Object: "project:3", Relation: "viewer", User: "user:alice", CorrelationId: "c"},
+		{Object: "project:4", Relation: "viewer", User: "user:alice", CorrelationId: "d"},
+	}
+	results, err := service.BatchCheck(context.Background(), items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.AssertExpectations(t)
+
+	want := []struct {
+		allowed, cacheHit, err bool
+	}{
+		{allowed: true, cacheHit: true},
+		{allowed: false},
+		{allowed: true},
+		{err: true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for i, result := range results {
+		relationKey := items[i].Object + "#viewer@user:alice"
+		if result.RelationKey != relationKey {
+			t.Errorf("result %d relation key = %q, want %q", i, result.RelationKey, relationKey)
+		}
+		if result.Allowed != want[i].allowed || result.CacheHit != want[i].cacheHit || (result.Err != nil) != want[i].err {
+			t.Errorf("result %d = %+v, want %+v", i, result, want[i])
+		}
+	}
+	if items[0].CorrelationId != "a" {
+		t.Errorf("caller's correlation ID was changed to %q", items[0].CorrelationId)
+	}
+
+	// Resolved checks are cached; the errored one is not.
+	for relationKey, want := range map[string]string{
+		"project:2#viewer@user:alice": "false",
+		"project:3#viewer@user:alice": "true",
+	} {
+		if got := string(kv.data[checkCacheKey("", relationKey)]); got != want {
+			t.Errorf("cached %s = %q, want %q", relationKey, got, want)
+		}
+	}
+	if _, ok := kv.data[checkCacheKey("", "project:4#viewer@user:alice")]; ok {
+		t.Error("errored check was cached")
+	}
+}
+
+// BenchmarkCheckRelationships_CacheLookups compares resolving a 50-item check
+// batch from the cache with serial and concurrent Gets.
+func BenchmarkCheckRelationships_CacheLookups(b *testing.B) {
+	for _, concurrency := range []int{1, defaultCacheLookupConcurrency} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			kv := NewMockKeyValue()
+			checks := seedCachedChecks(b, kv, 50)
+			service := FgaService{
+				client:                 new(MockFgaClient),
+				cacheBucket:            slowKeyValue{MockKeyValue: kv, delay: 100 * time.Microsecond},
+				useCache:               true,
+				cacheLookupConcurrency: concurrency,
+			}
+			for b.Loop() {
+				if _, err := service.CheckRelationships(context.Background(), checks); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestTupleSetFingerprint asserts that fingerprints depend only on the tuple
+// set, not on the order in which tuples are read.
+func TestTupleSetFingerprint(t *testing.T) {
+	object := "project:123"
+	ordered := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:456", Relation: "writer", Object: object}},
+		{Key: openfga.TupleKey{User: "user:789", Relation: "viewer", Object: object}},
+		{Key: openfga.TupleKey{User: "project:parent", Relation: "parent", Object: object}},
+	}
+	reversed := []openfga.Tuple{ordered[2], ordered[1], ordered[0]}
+	different := []openfga.Tuple{ordered[0], ordered[1]}
+
+	fingerprint := func(tuples []openfga.Tuple) string {
+		client := new(MockFgaClient)
+		client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{Tuples: tuples}, nil).Once()
+		service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+		fp, err := service.TupleSetFingerprint(context.Background(), object)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return fp
+	}
+
+	if fingerprint(ordered) != fingerprint(reversed) {
+		t.Error("expected identical tuple sets to have identical fingerprints regardless of read order")
+	}
+	if fingerprint(ordered) == fingerprint(different) {
+		t.Error("expected different tuple sets to have different fingerprints")
+	}
+}
+
+// TestTupleSetFingerprint_Cache asserts that cached fingerprints are served
+// until the cache is invalidated.
+func TestTupleSetFingerprint_Cache(t *testing.T) {
+	object := "project:123"
+	client := new(MockFgaClient)
+	client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{
+		Tuples: []openfga.Tuple{{Key: openfga.TupleKey{User: "user:456", Relation: "writer", Object: object}}},
+	}, nil).Twice()
+	kv := NewMockKeyValue()
+	service := FgaService{client: client, cacheBucket: kv, useCache: true}
+
+	first, err := service.TupleSetFingerprint(context.Background(), object)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := service.TupleSetFingerprint(context.Background(), object)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Error("expected cached fingerprint to match")
+	}
+	client.AssertNumberOfCalls(t, "Read", 1)
+
+	// Invalidation after the cached entry was written makes it stale.
+	time.Sleep(time.Millisecond)
+	if err = service.invalidateCache(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = service.TupleSetFingerprint(context.Background(), object); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.AssertNumberOfCalls(t, "Read", 2)
+}
+
+// TestSyncObjectTuples_TupleCountMetric asserts that the object tuple count
+// histogram records the tuple count read before the sync is applied.
+func TestSyncObjectTuples_TupleCountMetric(t *testing.T) {
+	client := new(MockFgaClient)
+	client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{
+		Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:1", Relation: "viewer", Object: "tuple_count_test:123"}},
+			{Key: openfga.TupleKey{User: "user:2", Relation: "viewer", Object: "tuple_count_test:123"}},
+			{Key: openfga.TupleKey{User: "user:3", Relation: "viewer", Object: "tuple_count_test:123"}},
+		},
+	}, nil)
+	client.On("Write", mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil)
+	service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+	// The desired state removes every existing tuple; the metric should still
+	// reflect the three tuples present before the sync.
+	_, _, _, err := service.SyncObjectTuples(context.Background(), "tuple_count_test:123", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	snapshot := objectTupleCounts.get("tuple_count_test")
+	if snapshot == nil {
+		t.Fatal("expected tuple count to be recorded for tuple_count_test")
+	}
+	if snapshot["count"] != uint64(1) || snapshot["max"] != 3 || snapshot["sum"] != uint64(3) {
+		t.Errorf("unexpected snapshot: %v", snapshot)
+	}
+	if objectTupleCounts.get("tuple_count_unused") != nil {
+		t.Error("expected no tuple count for an unsynced type")
+	}
+}
+
+// TestSyncObjectTuples_ExcludedCountMetric asserts that the existing tuples a
+// sync leaves in place because of excludeRelations are returned and counted
+// by object type, while other preserved tuples are not.
+func TestSyncObjectTuples_ExcludedCountMetric(t *testing.T) {
+	client := new(MockFgaClient)
+	client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{
+		Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:1", Relation: "participant", Object: "excluded_count_test:123"}},
+			{Key: openfga.TupleKey{User: "user:2", Relation: "participant", Object: "excluded_count_test:123"}},
+			{Key: openfga.TupleKey{User: "user:3", Relation: "host", Object: "excluded_count_test:123"}},
+			{Key: openfga.TupleKey{User: "team:t1#member", Relation: "viewer", Object: "excluded_count_test:123"}},
+			{Key: openfga.TupleKey{User: "user:4", Relation: "organizer", Object: "excluded_count_test:123"}},
+		},
+	}, nil)
+	client.On("Write", mock.Anything, mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil)
+	service := FgaService{client: client, cacheBucket: NewMockKeyValue()}
+
+	for range 2 {
+		_, deletes, excluded, err := service.SyncObjectTuples(context.Background(), "excluded_count_test:123", nil,
+			"participant", "host@user:3")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if excluded != 3 {
+			t.Errorf("expected 3 excluded tuples, got %d", excluded)
+		}
+		if len(deletes) != 1 {
+			t.Errorf("expected only the organizer tuple to be deleted, got %v", deletes)
+		}
+	}
+
+	if got := excludedTuples.Get("excluded_count_test"); got == nil || got.String() != "6" {
+		t.Errorf("expected 6 excluded tuples counted across both syncs, got %v", got)
+	}
+	if excludedTuples.Get("excluded_count_unused") != nil {
+		t.Error("expected no excluded tuple count for an unsynced type")
+	}
+}
+
+// TestSyncObjectTuples_DeleteHeavyWarning asserts that a sync deleting far
+// more tuples than it writes is counted by object type, and still applied.
+func TestSyncObjectTuples_DeleteHeavyWarning(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectType string
+		policy     deleteHeavyPolicy
+		keep       int
+		add        int
+		expectFlag bool
+	}{
+		{
+			name:       "truncated list is flagged",
+			objectType: "delete_heavy_truncated",
+			policy:     deleteHeavyPolicy{minDeletes: 10, ratio: 5},
+			keep:       1,
+			expectFlag: true,
+		},
+		{
+			name:       "replacement with enough writes is not flagged",
+			objectType: "delete_heavy_replaced",
+			policy:     deleteHeavyPolicy{minDeletes: 10, ratio: 5},
+			add:        3,
+		},
+		{
+			name:       "too few deletes are not flagged",
+			objectType: "delete_heavy_small",
+			policy:     deleteHeavyPolicy{minDeletes: 20, ratio: 5},
+		},
+		{
+			name:       "disabled check never flags",
+			objectType: "delete_heavy_disabled",
+			policy:     deleteHeavyPolicy{ratio: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := tt.objectType + ":123"
+			existing := make([]openfga.Tuple, 12)
+			for i := range existing {
+				existing[i] = openfga.Tuple{Key: openfga.TupleKey{User: fmt.Sprintf("user:%d", i), Relation: "viewer", Object: object}}
+			}
+			var desired []ClientTupleKey
+			for i := range tt.keep {
+				desired = append(desired, ClientTupleKey{User: existing[i].Key.User, Relation: "viewer", Object: object})
+			}
+			for i := range tt.add {
+				desired = append(desired, ClientTupleKey{User: fmt.Sprintf("user:new%d", i), Relation: "viewer", Object: object})
+			}
+
+			client := new(MockFgaClient)
+			client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{Tuples: existing}, nil)
+			client.On("Write", mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil)
+			service := FgaService{client: client, cacheBucket: NewMockKeyValue(), deleteHeavy: tt.policy}
+
+			_, deletes, _, err := service.SyncObjectTuples(context.Background(), object, desired)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(deletes) != len(existing)-tt.keep {
+				t.Errorf("expected %d deletes to be applied, got %d", len(existing)-tt.keep, len(deletes))
+			}
+			if flagged := deleteHeavySyncs.Get(tt.objectType) != nil; flagged != tt.expectFlag {
+				t.Errorf("flagged = %v, want %v", flagged, tt.expectFlag)
+			}
+		})
+	}
+}
+
+// TestReserveObjectVersion tests the compare-and-swap semantics of object
+// versions.
+func TestReserveObjectVersion(t *testing.T) {
+	ctx := context.Background()
+	service := FgaService{client: new(MockFgaClient), stateBucket: NewMockKeyValue()}
+	object := "committee:123"
+
+	// Unversioned objects accept only version 0.
+	_, err := service.ReserveObjectVersion(ctx, object, 3)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected conflict for unversioned object, got %v", err)
+	}
+	if _, err = service.ReserveObjectVersion(ctx, object, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The version is now 1; a stale token fails and the current one succeeds.
+	if _, err = service.ReserveObjectVersion(ctx, object, 0); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected conflict for stale version, got %v", err)
+	}
+	revision, err := service.ReserveObjectVersion(ctx, object, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Releasing restores the previous version.
+	if err = service.ReleaseObjectVersion(ctx, object, 1, revision); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = service.ReserveObjectVersion(ctx, object, 1); err != nil {
+		t.Fatalf("expected version 1 after release, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// Reasons a message field fails validation, reported in error replies.
+const (
+	reasonRequired   = "required"
+	reasonInvalid    = "invalid"
+	reasonNotAllowed = "not_allowed"
+)
+
+// fieldError is a message rejected because of one of its fields. Its text is
+// what is logged and replied; field and reason let clients identify the
+// failure without parsing the text.
+type fieldError struct {
+	field  string
+	reason string
+	msg    string
+}
+
+// newFieldError returns a fieldError for field with reason and text msg.
+func newFieldError(field, reason, msg string) error {
+	return &fieldError{field: field, reason: reason, msg: msg}
+}
+
+// Error implements [error].
+func (e *fieldError) Error() string { return e.msg }
+
+// replyTracker is an [INatsMsg] that records whether a reply was sent.
+type replyTracker struct {
+	INatsMsg
+	replied bool
+}
+
+// Respond implements [INatsMsg.Respond].
+func (m *replyTracker) Respond(data []byte) error {
+	m.replied = true
+	return m.INatsMsg.Respond(data)
+}
+
+// RespondMsg implements [INatsMsg.RespondMsg].
+func (m *replyTracker) RespondMsg(msg *nats.Msg) error {
+	m.replied = true
+	return m.INatsMsg.RespondMsg(msg)
+}
+
+// replyHandlerError sends a JSON error reply for an error returned by a
+// handler that did not reply itself, so request/reply publishers of sync
+// messages learn why a message failed instead of timing out.
+func replyHandlerError(ctx context.Context, msg *replyTracker, err error) {
+	if err == nil || msg.replied {
+		return
+	}
+	respondError(ctx, msg, err)
+}
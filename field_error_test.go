@@ -0,0 +1,201 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestGenericHandlers_FieldErrors tests that validation failures identify the
+// field at fault and why.
+func TestGenericHandlers_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		objectType   string
+		operation    string
+		data         any
+		expectField  string
+		expectReason string
+	}{
+		{
+			name:         "update without uid",
+			objectType:   "committee",
+			operation:    "update_access",
+			data:         fgatypes.GenericAccessData{Public: true},
+			expectField:  "uid",
+			expectReason: reasonRequired,
+		},
+		{
+			name:         "update without object type",
+			operation:    "update_access",
+			data:         fgatypes.GenericAccessData{UID: "c1"},
+			expectField:  "object_type",
+			expectReason: reasonRequired,
+		},
+		{
+			name:         "update with a malformed reference",
+			objectType:   "committee",
+			operation:    "update_access",
+			data:         fgatypes.GenericAccessData{UID: "c1", References: map[string][]string{"project": {"project:"}}},
+			expectField:  "references",
+			expectReason: reasonInvalid,
+		},
+		{
+			name:         "delete with a URN uid",
+			objectType:   "committee",
+			operation:    "delete_access",
+			data:         fgatypes.GenericDeleteData{UID: "urn:lfx:committee:1"},
+			expectField:  "uid",
+			expectReason: reasonInvalid,
+		},
+		{
+			name:         "member put without username",
+			objectType:   "committee",
+			operation:    "member_put",
+			data:         fgatypes.GenericMemberData{UID: "c1", Relations: []string{"member"}},
+			expectField:  "username",
+			expectReason: reasonRequired,
+		},
+		{
+			name:         "member put without relations",
+			objectType:   "committee",
+			operation:    "member_put",
+			data:         fgatypes.GenericMemberData{UID: "c1", Username: "alice"},
+			expectField:  "relations",
+			expectReason: reasonRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			handlers := map[string]HandlerFunc{
+				"update_access": service.genericUpdateAccessHandler,
+				"delete_access": service.genericDeleteAccessHandler,
+				"member_put":    service.genericMemberPutHandler,
+			}
+			msg := buildGenericMessage(t, tt.objectType, tt.operation, tt.data)
+
+			err := handlers[tt.operation](context.Background(), msg)
+
+			var fieldErr *fieldError
+			if assert.ErrorAs(t, err, &fieldErr) {
+				assert.Equal(t, tt.expectField, fieldErr.field)
+				assert.Equal(t, tt.expectReason, fieldErr.reason)
+			}
+		})
+	}
+}
+
+// TestDispatchMessage_FieldErrorReply tests that a sync message rejected for a
+// field gets an error reply naming it, that other failures, including
+// version conflicts, get the same error reply without a field, and that
+// handlers that reply themselves are left alone.
+func TestDispatchMessage_FieldErrorReply(t *testing.T) {
+	t.Run("rejected field is replied", func(t *testing.T) {
+		service := setupService()
+		msg := buildGenericMessage(t, "committee", "update_access", fgatypes.GenericAccessData{Public: true})
+		msg.reply = "reply.inbox"
+		var resp fgatypes.ErrorReply
+		msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+		}).Return(nil).Once()
+
+		dispatchMessage(context.Background(), constants.GenericUpdateAccessSubject, "generic update access",
+			constants.FgaSyncQueue, service.genericUpdateAccessHandler, msg)
+
+		msg.AssertExpectations(t)
+		assert.Equal(t, fgatypes.ErrorReply{
+			Status: fgatypes.SyncReplyStatusError, Error: "committee ID not found", Field: "uid", Reason: "required",
+		}, resp)
+	})
+
+	t.Run("missing project UID is replied", func(t *testing.T) {
+		service := setupService()
+		msg := buildGenericMessage(t, "project", "update_access", fgatypes.GenericAccessData{Public: true})
+		msg.reply = "reply.inbox"
+		var resp fgatypes.ErrorReply
+		msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+		}).Return(nil).Once()
+
+		dispatchMessage(context.Background(), constants.GenericUpdateAccessSubject, "generic update access",
+			constants.FgaSyncQueue, service.genericUpdateAccessHandler, msg)
+
+		msg.AssertExpectations(t)
+		assert.Equal(t, fgatypes.ErrorReply{
+			Status: fgatypes.SyncReplyStatusError, Error: "project ID not found", Field: "uid", Reason: "required",
+		}, resp)
+	})
+
+	t.Run("write error is replied", func(t *testing.T) {
+		service := setupService()
+		fgaClient := service.fgaService.client.(*MockFgaClient)
+		fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+		fgaClient.On("Write", mock.Anything, mock.Anything).
+			Return((*client.ClientWriteResponse)(nil), errors.New("store unavailable"))
+		msg := buildGenericMessage(t, "committee", "member_put", fgatypes.GenericMemberData{
+			UID: "c1", Username: "alice", Relations: []string{"member"},
+		})
+		msg.reply = "reply.inbox"
+		msg.On("Respond", mock.MatchedBy(func(data []byte) bool {
+			var resp fgatypes.ErrorReply
+			return json.Unmarshal(data, &resp) == nil &&
+				resp.Status == fgatypes.SyncReplyStatusError && strings.Contains(resp.Error, "store unavailable")
+		})).Return(nil).Once()
+
+		dispatchMessage(context.Background(), constants.GenericMemberPutSubject, "generic member put",
+			constants.FgaSyncQueue, service.genericMemberPutHandler, msg)
+
+		msg.AssertExpectations(t)
+	})
+
+	t.Run("version conflict is replied", func(t *testing.T) {
+		service := setupService()
+		service.versionedObjectTypes = map[string]bool{"committee": true}
+		expected := uint64(3)
+		msg := buildGenericMessage(t, "committee", "update_access", fgatypes.GenericAccessData{
+			UID: "c1", Public: true, ExpectedVersion: &expected,
+		})
+		msg.reply = "reply.inbox"
+		var resp fgatypes.ErrorReply
+		msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+		}).Return(nil).Once()
+
+		dispatchMessage(context.Background(), constants.GenericUpdateAccessSubject, "generic update access",
+			constants.FgaSyncQueue, service.genericUpdateAccessHandler, msg)
+
+		msg.AssertExpectations(t)
+		assert.Equal(t, fgatypes.ErrorReply{
+			Status: fgatypes.SyncReplyStatusError, Error: "version conflict: expected version 3, current version 0",
+		}, resp)
+	})
+
+	t.Run("handlers that replied are not replied again", func(t *testing.T) {
+		msg := CreateMockNatsMsg([]byte("{}"))
+		msg.reply = "reply.inbox"
+		msg.On("Respond", []byte("own reply")).Return(nil).Once()
+		handler := func(_ context.Context, message INatsMsg) error {
+			_ = message.Respond([]byte("own reply"))
+			return newFieldError("uid", reasonRequired, "uid is required")
+		}
+
+		dispatchMessage(context.Background(), constants.ResyncObjectSubject, "resync object",
+			constants.FgaSyncQueue, handler, msg)
+
+		msg.AssertExpectations(t)
+		msg.AssertNumberOfCalls(t, "Respond", 1)
+	})
+}
@@ -0,0 +1,354 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	openfga "github.com/openfga/go-sdk"
+
+	. "github.com/openfga/go-sdk/client"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+const (
+	// defaultGrantExpirySweepInterval is how often expired grants are swept
+	// unless GRANT_EXPIRY_SWEEP_INTERVAL is set.
+	defaultGrantExpirySweepInterval = time.Minute
+	// grantExpirySweepTimeout bounds one sweep of expired grants.
+	grantExpirySweepTimeout = time.Minute
+	// grantExpiryKeyPrefix prefixes the KV keys recording grant expiries.
+	grantExpiryKeyPrefix = "gexp."
+	// grantExpiryConditionParam is the condition parameter holding the
+	// expiry of a conditional grant.
+	grantExpiryConditionParam = "expires_at"
+	// renewRestoreTimeout bounds the restore of grants left deleted by a
+	// failed renewal.
+	renewRestoreTimeout = 10 * time.Second
+)
+
+// grantExpiry is the recorded expiry of a relation granted by member_put.
+type grantExpiry struct {
+	Object    string    `json:"object"`
+	Relation  string    `json:"relation"`
+	User      string    `json:"user"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// grantExpiryKey returns the KV key recording the expiry of a grant of
+// relation on object to user, if any.
+func grantExpiryKey(object, relation, user string) string {
+	return grantExpiryKeyPrefix + cacheKeyEncoder.EncodeToString([]byte(object+"#"+relation+"@"+user))
+}
+
+// grantExpiryTupleCondition returns the condition written on grants expiring
+// at expiresAt, or nil if they are written without one.
+func (h *HandlerService) grantExpiryTupleCondition(expiresAt *time.Time) *openfga.RelationshipCondition {
+	if h.grantExpiryCondition == "" || expiresAt == nil {
+		return nil
+	}
+	return &openfga.RelationshipCondition{
+		Name: h.grantExpiryCondition,
+		Context: &map[string]interface{}{
+			grantExpiryConditionParam: expiresAt.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// applyGrantExpiryCondition makes the grants member_put writes conditional on
+// the expiry of data, when a condition is configured for expiring grants.
+func (h *HandlerService) applyGrantExpiryCondition(tuples []ClientTupleKey, data *fgatypes.GenericMemberData) {
+	condition := h.grantExpiryTupleCondition(data.ExpiresAt)
+	if condition == nil {
+		return
+	}
+	for i := range tuples {
+		tuples[i].Condition = condition
+	}
+}
+
+// renewsGrant reports whether a put of a relation the user already holds,
+// with condition held, must rewrite its tuple, because expiring grants are
+// written as conditional tuples and the put's expiresAt calls for a different
+// condition. This is how a later expiry extends a grant, and a put without
+// one makes it permanent.
+func (h *HandlerService) renewsGrant(held *openfga.RelationshipCondition, expiresAt *time.Time) bool {
+	if h.grantExpiryCondition == "" {
+		return false
+	}
+	want := h.grantExpiryTupleCondition(expiresAt)
+	if held == nil || want == nil {
+		return held != want
+	}
+	return held.GetName() != want.GetName() || !reflect.DeepEqual(held.GetContext(), want.GetContext())
+}
+
+// deleteRenewedGrants deletes the held tuples of renewed grants on object
+// before they are written again with their new condition, as OpenFGA rejects
+// a request that writes and deletes the same tuple. If the delete fails, the
+// grants it removed are restored.
+func (h *HandlerService) deleteRenewedGrants(ctx context.Context, object string, renewed []ClientTupleKey) error {
+	if len(renewed) == 0 {
+		return nil
+	}
+	deletes := make([]ClientTupleKeyWithoutCondition, 0, len(renewed))
+	for _, grant := range renewed {
+		deletes = append(deletes, h.fgaService.TupleKeyWithoutCondition(grant.User, grant.Relation, grant.Object))
+	}
+	if err := h.fgaService.DeleteTuples(ctx, deletes); err != nil {
+		logger.With(errKey, err, "object", object, "renewed", len(renewed)).
+			ErrorContext(ctx, "failed to delete renewed grants")
+		h.restoreRenewedGrants(ctx, object, renewed)
+		return err
+	}
+	return nil
+}
+
+// restoreRenewedGrants writes back, with the condition they were held under,
+// the renewed grants on object that a failed renewal left deleted. Messages
+// are not redelivered, so without it the user would lose grants they held.
+// It runs detached from ctx, which may be what ended, bounded by
+// renewRestoreTimeout; failures are logged.
+func (h *HandlerService) restoreRenewedGrants(ctx context.Context, object string, renewed []ClientTupleKey) {
+	if len(renewed) == 0 {
+		return
+	}
+	restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), renewRestoreTimeout)
+	defer cancel()
+	tuples, err := h.fgaService.ReadCurrentObjectTuples(restoreCtx, object)
+	if err != nil {
+		logger.With(errKey, err, "object", object, "renewed", len(renewed)).
+			ErrorContext(ctx, "failed to read renewed grants to restore")
+		return
+	}
+	present := make(map[ClientTupleKeyWithoutCondition]bool, len(tuples))
+	for _, tuple := range tuples {
+		present[h.fgaService.TupleKeyWithoutCondition(tuple.Key.User, tuple.Key.Relation, object)] = true
+	}
+	var missing []ClientTupleKey
+	for _, grant := range renewed {
+		if !present[h.fgaService.TupleKeyWithoutCondition(grant.User, grant.Relation, grant.Object)] {
+			missing = append(missing, grant)
+		}
+	}
+	if err = h.fgaService.WriteAndDeleteTuples(restoreCtx, missing, nil); err != nil {
+		logger.With(errKey, err, "object", object, "grants", missing).
+			ErrorContext(ctx, "failed to restore renewed grants")
+		return
+	}
+	if len(missing) > 0 {
+		logger.With("object", object, "restored", len(missing)).WarnContext(ctx, "restored renewed grants")
+	}
+}
+
+// recordGrantExpiries records when the relations member_put granted to
+// userPrincipal on object expire, for the sweeper. A put without expires_at
+// makes the relations permanent, cancelling any expiry recorded by an earlier
+// put. Nothing is recorded when expiring grants are written as conditional
+// tuples.
+func (h *HandlerService) recordGrantExpiries(
+	ctx context.Context,
+	object, userPrincipal string,
+	data *fgatypes.GenericMemberData,
+) error {
+	if h.grantExpiryCondition != "" {
+		return nil
+	}
+	for _, relation := range data.Relations {
+		action := "record"
+		var err error
+		if data.ExpiresAt != nil {
+			err = h.fgaService.SetGrantExpiry(ctx, grantExpiry{
+				Object:    object,
+				Relation:  relation,
+				User:      userPrincipal,
+				ExpiresAt: data.ExpiresAt.UTC(),
+			})
+		} else {
+			action = "cancel"
+			_, err = h.fgaService.CancelGrantExpiry(ctx, object, relation, userPrincipal)
+		}
+		if err != nil {
+			logger.With(errKey, err, "user", userPrincipal, "relation", relation, "object", object).
+				ErrorContext(ctx, "failed to "+action+" grant expiry")
+			return err
+		}
+	}
+	return nil
+}
+
+// runGrantExpirySweeper removes expired grants every interval until ctx is
+// done. Sweeps are skipped during maintenance, and catch up once it ends.
+func (h *HandlerService) runGrantExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if active, _, _ := maintenance.status(); active {
+				continue
+			}
+			sweepCtx, cancel := context.WithTimeout(ctx, grantExpirySweepTimeout)
+			if _, err := h.sweepExpiredGrants(sweepCtx, time.Now()); err != nil {
+				logger.With(errKey, err).ErrorContext(ctx, "failed to sweep expired grants")
+			}
+			cancel()
+		}
+	}
+}
+
+// sweepExpiredGrants removes the grants that expired by now and returns how
+// many tuples were deleted. Grants whose tuple is already gone are dropped.
+// When a removal fails, the expiries of its object are recorded again so the
+// next sweep retries them.
+func (h *HandlerService) sweepExpiredGrants(ctx context.Context, now time.Time) (int, error) {
+	// Grants claimed before a failure are still removed.
+	expired, err := h.fgaService.ClaimExpiredGrants(ctx, now)
+	errs := []error{err}
+	byObject := make(map[string][]grantExpiry)
+	for _, grant := range expired {
+		byObject[grant.Object] = append(byObject[grant.Object], grant)
+	}
+
+	deleted := 0
+	for _, object := range slices.Sorted(maps.Keys(byObject)) {
+		grants := byObject[object]
+		n, err := h.removeExpiredGrants(ctx, object, grants)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", object, err))
+			for _, grant := range grants {
+				if err := h.fgaService.SetGrantExpiry(ctx, grant); err != nil {
+					logger.With(errKey, err, "user", grant.User, "relation", grant.Relation, "object", object).
+						ErrorContext(ctx, "failed to restore grant expiry")
+				}
+			}
+			continue
+		}
+		deleted += n
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// removeExpiredGrants deletes the tuples of the expired grants on object that
+// still exist, and returns how many were deleted.
+func (h *HandlerService) removeExpiredGrants(ctx context.Context, object string, grants []grantExpiry) (int, error) {
+	tuples, err := h.fgaService.ReadCurrentObjectTuples(ctx, object)
+	if err != nil {
+		return 0, err
+	}
+	existing := make(map[ClientTupleKeyWithoutCondition]bool, len(tuples))
+	for _, tuple := range tuples {
+		existing[h.fgaService.TupleKeyWithoutCondition(tuple.Key.User, tuple.Key.Relation, object)] = true
+	}
+	var deletes []ClientTupleKeyWithoutCondition
+	for _, grant := range grants {
+		if tuple := h.fgaService.TupleKeyWithoutCondition(grant.User, grant.Relation, object); existing[tuple] {
+			deletes = append(deletes, tuple)
+		}
+	}
+	if len(deletes) == 0 {
+		return 0, nil
+	}
+	if err = h.fgaService.DeleteTuples(ctx, deletes); err != nil {
+		return 0, err
+	}
+	logger.With("object", object, "deletes", len(deletes)).InfoContext(ctx, "removed expired grants")
+	return len(deletes), nil
+}
+
+// SetGrantExpiry records when a grant expires, replacing any expiry already
+// recorded for it.
+func (s FgaService) SetGrantExpiry(ctx context.Context, grant grantExpiry) error {
+	value, err := json.Marshal(grant)
+	if err != nil {
+		return err
+	}
+	key := grantExpiryKey(grant.Object, grant.Relation, grant.User)
+	if err = checkKVValueSize(key, value); err != nil {
+		return fmt.Errorf("grant expiry of %s#%s@%s: %w", grant.Object, grant.Relation, grant.User, err)
+	}
+	_, err = s.stateBucket.Put(ctx, key, value)
+	return err
+}
+
+// CancelGrantExpiry cancels the recorded expiry of a grant of relation on
+// object to user, reporting whether one was recorded. An expiry already
+// claimed by a sweep can no longer be cancelled.
+func (s FgaService) CancelGrantExpiry(ctx context.Context, object, relation, user string) (bool, error) {
+	key := grantExpiryKey(object, relation, user)
+	entry, err := s.stateBucket.Get(ctx, key)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if len(entry.Value()) == 0 {
+		return false, nil
+	}
+	if _, err = s.stateBucket.Update(ctx, key, nil, entry.Revision()); err != nil {
+		if isWrongLastSequence(err) {
+			// Claimed or re-recorded in the meantime.
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ClaimExpiredGrants takes ownership of the grants recorded as expiring by
+// now, and returns them for removal, along with those claimed before any
+// error. Claiming is a KV compare-and-swap, so of several replicas sweeping
+// at once only one removes each grant, and a grant renewed in the meantime is
+// not claimed. Expiries are kept in the state bucket, which has no TTL, so
+// they are not lost however long sweeps are paused.
+func (s FgaService) ClaimExpiredGrants(ctx context.Context, now time.Time) ([]grantExpiry, error) {
+	lister, err := s.stateBucket.ListKeysFiltered(ctx, grantExpiryKeyPrefix+">")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lister.Stop() }()
+
+	var claimed []grantExpiry
+	for key := range lister.Keys() {
+		entry, err := s.stateBucket.Get(ctx, key)
+		switch {
+		case errors.Is(err, jetstream.ErrKeyNotFound):
+			continue
+		case err != nil:
+			return claimed, err
+		}
+		if len(entry.Value()) == 0 {
+			continue
+		}
+		var grant grantExpiry
+		if err = json.Unmarshal(entry.Value(), &grant); err != nil {
+			logger.With(errKey, err, "key", key).WarnContext(ctx, "invalid grant expiry")
+			continue
+		}
+		if grant.ExpiresAt.After(now) {
+			continue
+		}
+		if _, err = s.stateBucket.Update(ctx, key, nil, entry.Revision()); err != nil {
+			if isWrongLastSequence(err) {
+				continue
+			}
+			return claimed, err
+		}
+		claimed = append(claimed, grant)
+	}
+	return claimed, nil
+}
@@ -0,0 +1,288 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestGrantExpiry_Sweep tests that grants put with expires_at on objects of
+// different types are removed by the sweep once they expire, and that grants
+// not yet expired, or made permanent by a later put, are kept.
+func TestGrantExpiry_Sweep(t *testing.T) {
+	store := &memoryFgaClient{
+		MockFgaClient: new(MockFgaClient),
+		tuples: map[client.ClientTupleKeyWithoutCondition]bool{
+			{User: "user:bob", Relation: "member", Object: "committee:c1"}: true,
+		},
+	}
+	service := setupService()
+	service.fgaService.client = store
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	put := func(objectType, uid, username, relation string, expiresAt *time.Time) {
+		t.Helper()
+		msg := buildGenericMessage(t, objectType, "member_put", fgatypes.GenericMemberData{
+			UID:       uid,
+			Username:  username,
+			Relations: []string{relation},
+			ExpiresAt: expiresAt,
+		})
+		assert.NoError(t, service.genericMemberPutHandler(ctx, msg))
+	}
+	in := func(d time.Duration) *time.Time {
+		expiresAt := now.Add(d)
+		return &expiresAt
+	}
+
+	put("committee", "c1", "alice", "member", in(time.Hour))
+	put("meeting", "m1", "alice", "participant", in(time.Hour))
+	put("meeting", "m1", "carol", "participant", in(2*time.Hour))
+	// Bob already held the relation; the expiry still applies to it.
+	put("committee", "c1", "bob", "member", in(time.Hour))
+	// Dave's grant is renewed without an expiry, which makes it permanent.
+	put("meeting", "m1", "dave", "host", in(time.Hour))
+	put("meeting", "m1", "dave", "host", nil)
+
+	deleted, err := service.sweepExpiredGrants(ctx, now.Add(30*time.Minute))
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+	assert.Len(t, store.tuples, 5)
+
+	deleted, err = service.sweepExpiredGrants(ctx, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	assert.Equal(t, map[client.ClientTupleKeyWithoutCondition]bool{
+		{User: "user:carol", Relation: "participant", Object: "meeting:m1"}: true,
+		{User: "user:dave", Relation: "host", Object: "meeting:m1"}:         true,
+	}, store.tuples)
+
+	// Claimed grants are not removed again, and a grant whose tuple is
+	// already gone is dropped.
+	delete(store.tuples, client.ClientTupleKeyWithoutCondition{
+		User: "user:carol", Relation: "participant", Object: "meeting:m1",
+	})
+	deleted, err = service.sweepExpiredGrants(ctx, now.Add(3*time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+	assert.Equal(t, map[client.ClientTupleKeyWithoutCondition]bool{
+		{User: "user:dave", Relation: "host", Object: "meeting:m1"}: true,
+	}, store.tuples)
+}
+
+// TestGrantExpiry_SweepRetriesFailedRemoval tests that an expired grant whose
+// removal fails is recorded again, and removed by the next sweep.
+func TestGrantExpiry_SweepRetriesFailedRemoval(t *testing.T) {
+	service := setupService()
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	grant := grantExpiry{Object: "committee:c1", Relation: "member", User: "user:alice", ExpiresAt: now}
+	assert.NoError(t, service.fgaService.SetGrantExpiry(ctx, grant))
+
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), errors.New("store unavailable")).Once()
+	deleted, err := service.sweepExpiredGrants(ctx, now)
+	assert.ErrorContains(t, err, "committee:c1: store unavailable")
+	assert.Zero(t, deleted)
+
+	claimed, err := service.fgaService.ClaimExpiredGrants(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []grantExpiry{grant}, claimed)
+}
+
+// TestGrantExpiry_Condition tests that with a grant expiry condition
+// configured, member_put writes expiring grants as conditional tuples and
+// records no expiry for the sweeper.
+func TestGrantExpiry_Condition(t *testing.T) {
+	service := setupService()
+	service.grantExpiryCondition = "not_expired"
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+	var written []client.ClientTupleKey
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		written = args.Get(1).(client.ClientWriteRequest).Writes
+	}).Return(&client.ClientWriteResponse{}, nil).Once()
+
+	expiresAt := time.Date(2099, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := buildGenericMessage(t, "committee", "member_put", fgatypes.GenericMemberData{
+		UID:       "c1",
+		Username:  "alice",
+		Relations: []string{"member"},
+		ExpiresAt: &expiresAt,
+	})
+	assert.NoError(t, service.genericMemberPutHandler(context.Background(), msg))
+
+	if assert.Len(t, written, 1) && assert.NotNil(t, written[0].Condition) {
+		assert.Equal(t, "not_expired", written[0].Condition.Name)
+		assert.Equal(t, map[string]interface{}{"expires_at": "2099-01-02T03:04:05Z"}, *written[0].Condition.Context)
+	}
+	assert.NotContains(t, service.fgaService.stateBucket.(*MockKeyValue).data,
+		grantExpiryKey("committee:c1", "member", "user:alice"))
+}
+
+// TestGrantExpiry_ConditionRenewal tests that with a grant expiry condition
+// configured, putting a relation the user holds under a different expiry, or
+// without one, deletes its tuple and writes it again with the new condition,
+// and that an unchanged expiry rewrites nothing.
+func TestGrantExpiry_ConditionRenewal(t *testing.T) {
+	heldAt := time.Date(2099, 1, 2, 3, 4, 5, 0, time.UTC)
+	laterAt := heldAt.Add(24 * time.Hour)
+	held := &openfga.RelationshipCondition{
+		Name:    "not_expired",
+		Context: &map[string]interface{}{"expires_at": "2099-01-02T03:04:05Z"},
+	}
+
+	tests := []struct {
+		name      string
+		operation string
+		data      interface{}
+		condition *openfga.RelationshipCondition
+		renewed   bool
+	}{
+		{
+			name:      "put with a later expiry",
+			operation: "member_put",
+			data: fgatypes.GenericMemberData{
+				UID: "c1", Username: "alice", Relations: []string{"member"}, ExpiresAt: &laterAt,
+			},
+			condition: &openfga.RelationshipCondition{
+				Name:    "not_expired",
+				Context: &map[string]interface{}{"expires_at": "2099-01-03T03:04:05Z"},
+			},
+			renewed: true,
+		},
+		{
+			name:      "put without expiry",
+			operation: "member_put",
+			data:      fgatypes.GenericMemberData{UID: "c1", Username: "alice", Relations: []string{"member"}},
+			renewed:   true,
+		},
+		{
+			name:      "batch put",
+			operation: "member_batch",
+			data: fgatypes.GenericMemberBatchData{
+				UID:  "c1",
+				Puts: []fgatypes.GenericMemberChange{{Username: "alice", Relations: []string{"member"}}},
+			},
+			renewed: true,
+		},
+		{
+			name:      "put with the same expiry",
+			operation: "member_put",
+			data: fgatypes.GenericMemberData{
+				UID: "c1", Username: "alice", Relations: []string{"member"}, ExpiresAt: &heldAt,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.grantExpiryCondition = "not_expired"
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{
+				Tuples: []openfga.Tuple{{Key: openfga.TupleKey{
+					User: "user:alice", Relation: "member", Object: "committee:c1", Condition: held,
+				}}},
+			}, nil)
+			var requests []client.ClientWriteRequest
+			fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				requests = append(requests, args.Get(1).(client.ClientWriteRequest))
+			}).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "committee", tt.operation, tt.data)
+			var err error
+			if tt.operation == "member_batch" {
+				msg.reply = "reply.inbox"
+				msg.On("Respond", []byte("OK")).Return(nil).Once()
+				err = service.genericMemberBatchHandler(context.Background(), msg)
+			} else {
+				err = service.genericMemberPutHandler(context.Background(), msg)
+			}
+			assert.NoError(t, err)
+
+			if !tt.renewed {
+				assert.Empty(t, requests)
+				return
+			}
+			tuple := client.ClientTupleKeyWithoutCondition{User: "user:alice", Relation: "member", Object: "committee:c1"}
+			if assert.Len(t, requests, 2) {
+				assert.Equal(t, []client.ClientTupleKeyWithoutCondition{tuple}, requests[0].Deletes)
+				assert.Empty(t, requests[0].Writes)
+				if assert.Len(t, requests[1].Writes, 1) {
+					assert.Equal(t, tt.condition, requests[1].Writes[0].Condition)
+				}
+				assert.Empty(t, requests[1].Deletes)
+			}
+		})
+	}
+}
+
+// TestGrantExpiry_ConditionRenewalRestoredOnFailure tests that when the write
+// of a renewed grant fails after its held tuple was deleted, the tuple is
+// written back with the condition it was held under.
+func TestGrantExpiry_ConditionRenewalRestoredOnFailure(t *testing.T) {
+	service := setupService()
+	service.grantExpiryCondition = "not_expired"
+	held := &openfga.RelationshipCondition{
+		Name:    "not_expired",
+		Context: &map[string]interface{}{"expires_at": "2099-01-02T03:04:05Z"},
+	}
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{
+		Tuples: []openfga.Tuple{{Key: openfga.TupleKey{
+			User: "user:alice", Relation: "member", Object: "committee:c1", Condition: held,
+		}}},
+	}, nil).Once()
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil).Once()
+	var requests []client.ClientWriteRequest
+	record := func(args mock.Arguments) { requests = append(requests, args.Get(1).(client.ClientWriteRequest)) }
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(record).Return(&client.ClientWriteResponse{}, nil).Once()
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(record).
+		Return((*client.ClientWriteResponse)(nil), errors.New("store unavailable")).Once()
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(record).Return(&client.ClientWriteResponse{}, nil).Once()
+
+	msg := buildGenericMessage(t, "committee", "member_put", fgatypes.GenericMemberData{
+		UID: "c1", Username: "alice", Relations: []string{"member"},
+	})
+	assert.ErrorContains(t, service.genericMemberPutHandler(context.Background(), msg), "store unavailable")
+
+	fgaClient.AssertExpectations(t)
+	if assert.Len(t, requests, 3) && assert.Len(t, requests[2].Writes, 1) {
+		restored := requests[2].Writes[0]
+		assert.Equal(t, "user:alice", restored.User)
+		assert.Equal(t, "member", restored.Relation)
+		assert.Equal(t, held, restored.Condition)
+	}
+}
+
+// TestGrantExpiry_PastExpiryRejected tests that a member_put whose expiry has
+// already passed is rejected.
+func TestGrantExpiry_PastExpiryRejected(t *testing.T) {
+	service := setupService()
+	expiresAt := time.Now().Add(-time.Minute)
+	msg := buildGenericMessage(t, "committee", "member_put", fgatypes.GenericMemberData{
+		UID:       "c1",
+		Username:  "alice",
+		Relations: []string{"member"},
+		ExpiresAt: &expiresAt,
+	})
+
+	err := service.genericMemberPutHandler(context.Background(), msg)
+	assert.EqualError(t, err, "expires_at must be in the future")
+	assert.Contains(t, string(errorReply(err)), `"field":"expires_at"`)
+	service.fgaService.client.(*MockFgaClient).AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+}
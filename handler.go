@@ -7,22 +7,164 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
 	nats "github.com/nats-io/nats.go"
+
+	. "github.com/openfga/go-sdk/client"
 )
 
 // HandlerService is the service that handles the messages from NATS about FGA syncing.
 type HandlerService struct {
 	fgaService FgaService
+	// strictReferences rejects access updates whose references point at an
+	// object type the authorization model does not allow for that relation.
+	strictReferences bool
+	// referenceExistence controls whether access updates may reference
+	// objects that do not exist in OpenFGA, by object type.
+	referenceExistence referenceExistencePolicy
+	// relationValidation controls whether synced tuples are checked for
+	// relations that are not defined in the authorization model.
+	relationValidation relationValidationMode
+	// versionedObjectTypes are the object types whose access updates must
+	// carry the expected object version (optimistic concurrency).
+	versionedObjectTypes map[string]bool
+	// additivePublicTypes are the object types whose public flag only ever
+	// adds the user:* viewer tuple; public=false leaves an existing one in
+	// place. Other types treat the flag as authoritative.
+	additivePublicTypes map[string]bool
+	// projectRequiredTypes are the object types whose access updates must
+	// reference a project.
+	projectRequiredTypes map[string]bool
+	// replyRequiredTypes are the object types whose sync messages must be
+	// sent as requests, with a reply inbox.
+	replyRequiredTypes map[string]bool
+	// privateObjectTypes are the object types that must never be public: no
+	// user:* tuple may be written on their objects.
+	privateObjectTypes map[string]bool
+	// parentCycleDepth, when non-zero, rejects access updates whose parent
+	// would close a cycle, reading up to this many levels of ancestors.
+	parentCycleDepth int
+	// emailResolver resolves the username of member operations identifying
+	// their user by email. Its zero value rejects them.
+	emailResolver emailResolverConfig
+	// exclusiveRepair controls how member_remove cleans up mutually exclusive
+	// relations that a user still holds together after the removal.
+	exclusiveRepair exclusiveRepairPolicy
+	// resyncConcurrency bounds the objects of a resync_objects request
+	// reconciled at once.
+	resyncConcurrency int
+	// publicInheritance maps object types to the reference relation of the
+	// object whose public status they inherit.
+	publicInheritance publicInheritance
+	// grantExpiryCondition, when set, is the condition written on member_put
+	// tuples carrying an expiry. Otherwise expiries are recorded for the
+	// sweeper.
+	grantExpiryCondition string
+	// deleteGracePeriod, when non-zero, defers delete_access by this long; an
+	// update_access for the object in the meantime cancels the delete.
+	deleteGracePeriod time.Duration
+	// tuplePostProcessors adjust the desired tuples of their object type
+	// after they are built and before they are synced.
+	tuplePostProcessors map[string]tuplePostProcessor
+	// config is the effective configuration the service was built from,
+	// reported by the config admin subject.
+	config Config
+}
+
+// relationValidationMode controls how tuples whose relation is not defined
+// on their object's type in the authorization model are handled.
+type relationValidationMode string
+
+const (
+	// relationValidationOff skips relation validation.
+	relationValidationOff relationValidationMode = ""
+	// relationValidationWarn logs tuples with undefined relations and writes
+	// them anyway.
+	relationValidationWarn relationValidationMode = "warn"
+	// relationValidationStrict rejects the sync if any tuple has an undefined
+	// relation.
+	relationValidationStrict relationValidationMode = "strict"
+)
+
+// exclusiveRepairPolicy controls which of a user's remaining mutually
+// exclusive relations member_remove deletes when more than one is left.
+type exclusiveRepairPolicy string
+
+const (
+	// exclusiveRepairOff leaves remaining relations untouched.
+	exclusiveRepairOff exclusiveRepairPolicy = ""
+	// exclusiveRepairKeepFirst keeps the remaining relation listed first in
+	// mutually_exclusive_with and deletes the others.
+	exclusiveRepairKeepFirst exclusiveRepairPolicy = "keep_first"
+	// exclusiveRepairRemoveAll deletes every remaining exclusive relation.
+	exclusiveRepairRemoveAll exclusiveRepairPolicy = "remove_all"
+)
+
+// validateUID rejects UIDs containing characters that delimit the tuple
+// format (`type:uid#relation@user`). Object IDs are built by joining the type
+// and UID with a colon, so a URN-style UID would be ambiguous to anything
+// splitting the object ID, and `#` or `@` would corrupt the access check
+// payload format.
+func validateUID(uid string) error {
+	if i := strings.IndexAny(uid, ":#@"); i >= 0 {
+		return newFieldError("uid", reasonInvalid, fmt.Sprintf("uid %q must not contain %q", uid, uid[i]))
+	}
+	return nil
+}
+
+// canonicalObjectType returns objectType in its canonical lowercase form,
+// resolved through the object type registry. Object types are case-sensitive
+// in OpenFGA, so "Committee" would otherwise build objects in a namespace no
+// check ever reaches; a producer sending it is logged so the producer can be
+// fixed. Types containing tuple delimiters are rejected, as for UIDs, and so
+// are unregistered types when the registry is strict.
+func canonicalObjectType(ctx context.Context, objectType string) (string, error) {
+	if objectType == "" {
+		logger.ErrorContext(ctx, "object_type is required")
+		return "", newFieldError("object_type", reasonRequired, "object_type is required")
+	}
+	if i := strings.IndexAny(objectType, ":#@ "); i >= 0 {
+		logger.ErrorContext(ctx, "invalid object_type", "object_type", objectType)
+		return "", newFieldError("object_type", reasonInvalid,
+			fmt.Sprintf("object_type %q must not contain %q", objectType, objectType[i]))
+	}
+	canonical := strings.ToLower(objectType)
+	if canonical != objectType {
+		logger.WarnContext(ctx, "canonicalized object_type casing",
+			"object_type", objectType,
+			"canonical", canonical,
+		)
+	}
+	resolved, err := objectTypes.resolve(canonical)
+	if err != nil {
+		logger.ErrorContext(ctx, "unregistered object_type", "object_type", canonical)
+		return "", newFieldError("object_type", reasonNotAllowed, err.Error())
+	}
+	return resolved, nil
+}
+
+// requireReply rejects a sync message for objectType that was sent without a
+// reply inbox, if the type's producers are configured to use request-reply.
+// Omitting the inbox is an integration mistake: the producer fires and
+// forgets, never learning whether its change was applied.
+func (h *HandlerService) requireReply(ctx context.Context, message INatsMsg, objectType string) error {
+	if message.Reply() != "" || !h.replyRequiredTypes[objectType] {
+		return nil
+	}
+	logger.ErrorContext(ctx, "reply inbox required", "object_type", objectType, "subject", message.Subject())
+	return newFieldError("reply", reasonRequired, fmt.Sprintf("%s messages must be sent with a reply inbox", objectType))
 }
 
 // buildObjectID constructs a standardized object identifier from type and UID.
 // This ensures consistent object identifier construction across all handlers.
-// Format: "objectType:uid" (e.g., "committee:123", "project:abc-def")
+// Format: "prefix:uid" (e.g., "committee:123", "project:abc-def"), where the
+// prefix is the object type's registered prefix.
 func buildObjectID(objectType, uid string) string {
-	return fmt.Sprintf("%s:%s", objectType, uid)
+	return objectTypes.objectID(objectType, uid)
 }
 
 // standardAccessStub represents the default structure for access control objects
@@ -32,20 +174,28 @@ type standardAccessStub struct {
 	Public     bool                `json:"public"`
 	Relations  map[string][]string `json:"relations"`
 	References map[string][]string `json:"references"`
+	// ExpectedVersion is the object version the update was based on, for
+	// object types with optimistic concurrency enabled.
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
 }
 
 // INatsMsg is an interface for [nats.Msg] that allows for mocking.
 type INatsMsg interface {
 	Reply() string
 	Respond(data []byte) error
+	RespondMsg(msg *nats.Msg) error
 	Data() []byte
 	Subject() string
 	Header() nats.Header
+	Timestamp() time.Time
 }
 
 // NatsMsg is a wrapper around [nats.Msg] that implements [INatsMsg].
 type NatsMsg struct {
 	*nats.Msg
+	// received is when the message was received, the timestamp of messages
+	// without JetStream metadata.
+	received time.Time
 }
 
 // Reply implements [INatsMsg.Reply].
@@ -58,6 +208,11 @@ func (m *NatsMsg) Respond(data []byte) error {
 	return m.Msg.Respond(data)
 }
 
+// RespondMsg implements [INatsMsg.RespondMsg].
+func (m *NatsMsg) RespondMsg(msg *nats.Msg) error {
+	return m.Msg.RespondMsg(msg)
+}
+
 // Data implements [INatsMsg.Data].
 func (m *NatsMsg) Data() []byte {
 	return m.Msg.Data
@@ -73,6 +228,16 @@ func (m *NatsMsg) Header() nats.Header {
 	return m.Msg.Header
 }
 
+// Timestamp implements [INatsMsg.Timestamp]. It is when the message was
+// stored in its stream for a JetStream message, and when it was received
+// otherwise.
+func (m *NatsMsg) Timestamp() time.Time {
+	if meta, err := m.Msg.Metadata(); err == nil {
+		return meta.Timestamp
+	}
+	return m.received
+}
+
 // processStandardAccessUpdate handles the default access control update logic
 func (h *HandlerService) processStandardAccessUpdate(
 	ctx context.Context,
@@ -86,9 +251,62 @@ func (h *HandlerService) processStandardAccessUpdate(
 		fmt.Sprintf("handling %s access control update", obj.ObjectType),
 	)
 
+	object, tuples, err := h.standardAccessTuples(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	if !obj.Public && h.additivePublicTypes[obj.ObjectType] {
+		// Producers of additive types may send public=false without knowing the
+		// public status, so never remove the wildcard viewer on their say-so.
+		excludeRelations = append(slices.Clip(excludeRelations), constants.RelationViewer+"@"+constants.UserWildcard)
+	}
+
+	release, err := h.reserveObjectVersion(ctx, message, obj, object)
+	if err != nil {
+		return err
+	}
+
+	tuplesWrites, tuplesDeletes, excluded, err := h.fgaService.SyncObjectTuples(ctx, object, tuples, excludeRelations...)
+	if err != nil {
+		logger.With(errKey, err, "tuples", tuples, "object", object).ErrorContext(ctx, "failed to sync tuples")
+		release(ctx)
+		return err
+	}
+	// Only an update actually applied shows the object still exists.
+	h.cancelPendingDelete(ctx, object)
+
+	logger.With(
+		"tuples", tuples,
+		"object", object,
+		"writes", tuplesWrites,
+		"deletes", tuplesDeletes,
+		"excluded_count", excluded,
+	).InfoContext(ctx, "synced tuples")
+
+	if err = h.sendReplyIfNeeded(ctx, message, object, len(tuplesWrites), len(tuplesDeletes)); err != nil {
+		return err
+	}
+	if message.Reply() != "" {
+		logger.With("object", object).InfoContext(ctx, fmt.Sprintf("sent %s access control update response", obj.ObjectType))
+	}
+
+	return nil
+}
+
+// standardAccessTuples validates obj and builds its object ID and the full set
+// of desired tuples: the public wildcard, references, and relations.
+func (h *HandlerService) standardAccessTuples(
+	ctx context.Context,
+	obj *standardAccessStub,
+) (string, []ClientTupleKey, error) {
 	if obj.UID == "" {
 		logger.ErrorContext(ctx, fmt.Sprintf("%s ID not found", obj.ObjectType))
-		return fmt.Errorf("%s ID not found", obj.ObjectType)
+		return "", nil, newFieldError("uid", reasonRequired, fmt.Sprintf("%s ID not found", obj.ObjectType))
+	}
+	if err := validateUID(obj.UID); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "invalid uid")
+		return "", nil, err
 	}
 
 	object := buildObjectID(obj.ObjectType, obj.UID)
@@ -102,6 +320,8 @@ func (h *HandlerService) processStandardAccessUpdate(
 	}
 
 	// for parent relation, project relation, etc
+	referencesStart := len(tuples)
+	var parents []string
 	for reference, valueList := range obj.References {
 		refType := reference
 		// When the reference is parent, use the object type itself as the reference type.
@@ -110,6 +330,15 @@ func (h *HandlerService) processStandardAccessUpdate(
 			refType = obj.ObjectType
 		}
 		for _, value := range valueList {
+			// An empty value would build a reference to no object, e.g.
+			// "project:"; skip it rather than write a garbage tuple.
+			if strings.TrimSpace(value) == "" {
+				logger.WarnContext(ctx, "skipping empty reference value",
+					"object", object,
+					"reference", reference,
+				)
+				continue
+			}
 			// Check if value already contains a type prefix (e.g., "committee:123")
 			var key string
 			if strings.Contains(value, ":") {
@@ -120,7 +349,8 @@ func (h *HandlerService) processStandardAccessUpdate(
 						"reference", reference,
 						"value", value,
 					)
-					return fmt.Errorf("invalid reference format '%s': must be 'type:id' with both parts non-empty", value)
+					return "", nil, newFieldError("references", reasonInvalid,
+						fmt.Sprintf("invalid reference format '%s': must be 'type:id' with both parts non-empty", value))
 				}
 				// Value already has valid type:id format, use as-is
 				key = value
@@ -128,41 +358,220 @@ func (h *HandlerService) processStandardAccessUpdate(
 				// Value is just an ID, prepend the type
 				key = fmt.Sprintf("%s:%s", refType, value)
 			}
+			// An object that is its own parent creates a self-loop that
+			// OpenFGA would resolve through indefinitely.
+			if reference == constants.RelationParent && key == object {
+				logger.ErrorContext(ctx, "object references itself as parent", "object", object)
+				return "", nil, newFieldError("references", reasonInvalid,
+					fmt.Sprintf("%s cannot reference itself as parent", object))
+			}
+			if reference == constants.RelationParent {
+				parents = append(parents, key)
+			}
 			tuples = append(tuples, h.fgaService.TupleKey(key, reference, object))
 		}
 	}
 
+	if h.projectRequiredTypes[obj.ObjectType] && !slices.ContainsFunc(tuples[referencesStart:], isProjectReference) {
+		logger.ErrorContext(ctx, "project reference not found", "object", object)
+		return "", nil, newFieldError("references", reasonRequired,
+			fmt.Sprintf("%s requires a project reference", obj.ObjectType))
+	}
+
+	if h.strictReferences {
+		if err := h.validateReferenceTypes(ctx, obj.ObjectType, tuples[referencesStart:]); err != nil {
+			logger.With(errKey, err, "object", object).ErrorContext(ctx, "invalid reference")
+			return "", nil, err
+		}
+	}
+
+	if err := h.checkReferenceExistence(ctx, obj.ObjectType, object, tuples[referencesStart:]); err != nil {
+		return "", nil, err
+	}
+
+	if h.parentCycleDepth > 0 && len(parents) > 0 {
+		if err := h.checkParentCycle(ctx, object, parents); err != nil {
+			return "", nil, err
+		}
+	}
+
 	// Add each principal from the object as the corresponding relationship tuple
 	// (as defined in the OpenFGA schema).
 	// for writer, auditor etc
-	for relation, principals := range obj.Relations {
+	tuples = append(tuples, h.principalTuples(ctx, object, obj.Relations)...)
+
+	// Post-processed tuples are validated like the built ones below, so a
+	// post-processor cannot introduce a relation the model does not define.
+	if postProcess, ok := h.tuplePostProcessors[obj.ObjectType]; ok {
+		tuples = postProcess(object, tuples)
+	}
+
+	tuples, err := h.inheritPublic(ctx, obj.ObjectType, object, tuples)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if h.privateObjectTypes[obj.ObjectType] && slices.ContainsFunc(tuples, isWildcardTuple) {
+		logger.ErrorContext(ctx, "public access requested on private object type", "object", object)
+		return "", nil, newFieldError("public", reasonNotAllowed, fmt.Sprintf("%s objects must not be public", obj.ObjectType))
+	}
+
+	if err := h.validateTupleRelations(ctx, tuples); err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "invalid relation")
+		return "", nil, err
+	}
+
+	return object, tuples, nil
+}
+
+// isProjectReference reports whether tuple references the project of its
+// object.
+func isProjectReference(tuple ClientTupleKey) bool {
+	return tuple.Relation == constants.RelationProject
+}
+
+// isWildcardTuple reports whether tuple grants its relation to every user.
+func isWildcardTuple(tuple ClientTupleKey) bool {
+	return tuple.User == constants.UserWildcard
+}
+
+// principalTuples builds a user tuple on object for each principal of each
+// relation. A principal listed twice under one relation is a producer bug: it
+// is logged and its tuple is built once, so tuple counts and write batches
+// stay accurate. Empty principals are logged and skipped, as they would build
+// a tuple for the bare "user:" prefix.
+func (h *HandlerService) principalTuples(
+	ctx context.Context,
+	object string,
+	relations map[string][]string,
+) []ClientTupleKey {
+	var tuples []ClientTupleKey
+	for relation, principals := range relations {
+		seen := make(map[string]bool, len(principals))
 		for _, principal := range principals {
+			if strings.TrimSpace(principal) == "" {
+				logger.WarnContext(ctx, "skipping empty principal in relation",
+					"object", object,
+					"relation", relation,
+				)
+				continue
+			}
+			if seen[principal] {
+				logger.WarnContext(ctx, "duplicate principal in relation",
+					"object", object,
+					"relation", relation,
+					"principal", principal,
+				)
+				continue
+			}
+			seen[principal] = true
 			tuples = append(tuples, h.fgaService.TupleKey(constants.ObjectTypeUser+principal, relation, object))
 		}
 	}
+	return tuples
+}
 
-	tuplesWrites, tuplesDeletes, err := h.fgaService.SyncObjectTuples(ctx, object, tuples, excludeRelations...)
+// validateReferenceTypes checks that each reference tuple's user has a type
+// the authorization model allows for that relation on objectType, so that a
+// reference to the wrong object type (e.g. a v1_meeting UID on a v2
+// past_meeting) is rejected instead of written as a tuple that resolves to
+// nothing. Only types are checked: the referenced object may legitimately not
+// have been synced yet.
+func (h *HandlerService) validateReferenceTypes(
+	ctx context.Context,
+	objectType string,
+	references []ClientTupleKey,
+) error {
+	if len(references) == 0 {
+		return nil
+	}
+	model, err := h.fgaService.AuthorizationModel(ctx)
 	if err != nil {
-		logger.With(errKey, err, "tuples", tuples, "object", object).ErrorContext(ctx, "failed to sync tuples")
-		return err
+		return fmt.Errorf("failed to read authorization model: %w", err)
+	}
+	for _, reference := range references {
+		refType, _, _ := strings.Cut(reference.User, ":")
+		allowed := model.directUserTypes(objectType, reference.Relation)
+		if !slices.Contains(allowed, refType) {
+			return newFieldError("references", reasonNotAllowed, fmt.Sprintf(
+				"reference %q for relation %q on %s must be one of types %v, got %q",
+				reference.User, reference.Relation, objectType, allowed, refType,
+			))
+		}
 	}
+	return nil
+}
 
-	logger.With(
-		"tuples", tuples,
-		"object", object,
-		"writes", tuplesWrites,
-		"deletes", tuplesDeletes,
-	).InfoContext(ctx, "synced tuples")
+// validateTupleRelations checks that every tuple's relation is defined on its
+// object's type in the authorization model, catching producer bugs and drift
+// after a relation is renamed or removed from the model. Depending on the
+// configured mode, offending tuples are logged or the sync is rejected.
+func (h *HandlerService) validateTupleRelations(ctx context.Context, tuples []ClientTupleKey) error {
+	if h.relationValidation == relationValidationOff || len(tuples) == 0 {
+		return nil
+	}
 
-	if message.Reply() != "" {
-		// Send a reply if an inbox was provided.
-		if err = message.Respond([]byte("OK")); err != nil {
-			logger.With(errKey, err).WarnContext(ctx, "failed to send reply")
-			return err
+	model, err := h.fgaService.AuthorizationModel(ctx)
+	if err != nil {
+		if h.relationValidation == relationValidationStrict {
+			return fmt.Errorf("failed to read authorization model: %w", err)
 		}
+		logger.With(errKey, err).WarnContext(ctx, "skipping relation validation")
+		return nil
+	}
 
-		logger.With("object", object).InfoContext(ctx, fmt.Sprintf("sent %s access control update response", obj.ObjectType))
+	var invalid []string
+	for _, tuple := range tuples {
+		objectType, _, _ := strings.Cut(tuple.Object, ":")
+		if !model.hasRelation(objectType, tuple.Relation) {
+			invalid = append(invalid, tuple.Object+"#"+tuple.Relation+"@"+tuple.User)
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
 	}
 
+	if h.relationValidation == relationValidationStrict {
+		return newFieldError("relations", reasonNotAllowed,
+			fmt.Sprintf("relations not defined in the authorization model: %v", invalid))
+	}
+	logger.With("tuples", invalid, "model_id", model.id).
+		WarnContext(ctx, "tuples reference relations not defined in the model")
 	return nil
 }
+
+// reserveObjectVersion enforces optimistic concurrency for versioned object
+// types: the update must carry the object's current version, which is then
+// bumped. On conflict an error reply is sent, so the producer can re-read and
+// retry. The returned release function restores the version if the update
+// is not applied; it is a no-op for unversioned object types.
+func (h *HandlerService) reserveObjectVersion(
+	ctx context.Context,
+	message INatsMsg,
+	obj *standardAccessStub,
+	object string,
+) (func(context.Context), error) {
+	noop := func(context.Context) {}
+	if !h.versionedObjectTypes[obj.ObjectType] {
+		return noop, nil
+	}
+	if obj.ExpectedVersion == nil {
+		err := newFieldError("expected_version", reasonRequired,
+			fmt.Sprintf("expected_version is required for %s", obj.ObjectType))
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "missing version token")
+		return noop, h.sendErrorReplyIfNeeded(ctx, message, err)
+	}
+
+	expected := *obj.ExpectedVersion
+	revision, err := h.fgaService.ReserveObjectVersion(ctx, object, expected)
+	if err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to reserve object version")
+		return noop, h.sendErrorReplyIfNeeded(ctx, message, err)
+	}
+
+	return func(ctx context.Context) {
+		if errRelease := h.fgaService.ReleaseObjectVersion(ctx, object, expected, revision); errRelease != nil {
+			logger.With(errKey, errRelease, "object", object).WarnContext(ctx, "failed to release object version")
+		}
+	}, nil
+}
@@ -6,57 +6,49 @@ package main
 
 import (
 	"context"
+	"errors"
+
+	nats "github.com/nats-io/nats.go"
 )
 
-// accessCheckHandler handles access check requests from the NATS server.
-func (h *HandlerService) accessCheckHandler(ctx context.Context, message INatsMsg) error {
+// errNoCheckRequests is returned by checkAccess for a payload containing no
+// checks.
+var errNoCheckRequests = errors.New("no check requests found")
 
-	var response []byte
-	var err error
+// accessCheckError is a failed access check request, with the text to reply
+// to its caller.
+type accessCheckError struct {
+	reply string
+	// invalid is set when the request itself is at fault, rather than the
+	// service or OpenFGA.
+	invalid bool
+	err     error
+}
 
-	logger.With("message", string(message.Data())).InfoContext(ctx, "handling access check request")
+// Error implements [error].
+func (e *accessCheckError) Error() string { return e.err.Error() }
 
-	// Extract the check requests from the message payload.
-	checkRequests, err := h.fgaService.ExtractCheckRequests(message.Data())
-	if err != nil {
-		errText := "failed to extract check requests"
-		logger.With(errKey, err).WarnContext(ctx, errText)
-		if message.Reply() != "" {
-			// Send a reply if an inbox was provided.
-			if errRespond := message.Respond([]byte(errText)); errRespond != nil {
-				logger.With(errKey, errRespond).WarnContext(ctx, "failed to send reply")
-				return errRespond
-			}
-		}
-		return err
-	}
+// Unwrap returns the underlying error.
+func (e *accessCheckError) Unwrap() error { return e.err }
 
-	if len(checkRequests) == 0 {
-		errText := "no check requests found"
-		logger.WarnContext(ctx, errText)
-		if message.Reply() != "" {
-			// Send a reply if an inbox was provided.
-			if errRespond := message.Respond([]byte(errText)); errRespond != nil {
-				logger.With(errKey, errRespond).WarnContext(ctx, "failed to send reply")
-				return errRespond
-			}
-		}
-		// The message containing no check requests is not an error.
-		return nil
-	}
+// accessCheckHandler handles access check requests from the NATS server.
+func (h *HandlerService) accessCheckHandler(ctx context.Context, message INatsMsg) error {
+	logger.With("message", string(message.Data())).InfoContext(ctx, "handling access check request")
 
-	logger.With("count", len(checkRequests)).DebugContext(ctx, "checking fga relationships")
-	response, err = h.fgaService.CheckRelationships(ctx, checkRequests)
+	response, err := h.checkAccess(ctx, message.Data(), message.Header())
 	if err != nil {
-		errText := "failed to check relationship"
-		logger.With(errKey, err).ErrorContext(ctx, errText)
-		if message.Reply() != "" {
+		var checkErr *accessCheckError
+		if errors.As(err, &checkErr) && message.Reply() != "" {
 			// Send a reply if an inbox was provided.
-			if errRespond := message.Respond([]byte(errText)); errRespond != nil {
+			if errRespond := message.Respond([]byte(checkErr.reply)); errRespond != nil {
 				logger.With(errKey, errRespond).WarnContext(ctx, "failed to send reply")
 				return errRespond
 			}
 		}
+		if errors.Is(err, errNoCheckRequests) {
+			// The message containing no check requests is not an error.
+			return nil
+		}
 		return err
 	}
 
@@ -75,3 +67,62 @@ func (h *HandlerService) accessCheckHandler(ctx context.Context, message INatsMs
 
 	return nil
 }
+
+// checkAccess answers the checks in payload, one object#relation@user per
+// line, with one tab-delimited result line per check. header carries the
+// optional cache scope headers. It backs every access check transport, so
+// they behave identically; failures are returned as *accessCheckError.
+func (h *HandlerService) checkAccess(ctx context.Context, payload []byte, header nats.Header) ([]byte, error) {
+	// Extract the check requests from the message payload.
+	checkRequests, err := h.fgaService.ExtractCheckRequests(payload)
+	if err != nil {
+		errText := "failed to extract check requests"
+		logger.With(errKey, err).WarnContext(ctx, errText)
+		return nil, &accessCheckError{reply: errText, invalid: true, err: err}
+	}
+
+	if len(checkRequests) == 0 {
+		logger.WarnContext(ctx, errNoCheckRequests.Error())
+		return nil, &accessCheckError{reply: errNoCheckRequests.Error(), invalid: true, err: errNoCheckRequests}
+	}
+
+	ctx, err = h.applyCacheScope(ctx, header)
+	if err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to apply cache scope")
+		return nil, err
+	}
+
+	logger.With("count", len(checkRequests)).DebugContext(ctx, "checking fga relationships")
+	response, err := h.fgaService.CheckRelationships(ctx, checkRequests)
+	if err != nil {
+		errText := "failed to check relationship"
+		logger.With(errKey, err, "openfga_request_id", fgaRequestID(err)).ErrorContext(ctx, errText)
+		return nil, &accessCheckError{reply: withFgaRequestID(errText, err), err: err}
+	}
+	return response, nil
+}
+
+// applyCacheScope returns ctx scoped to the cache scope named by the
+// request's Fga-Cache-Scope header, if any, first invalidating that scope
+// when the request sets Fga-Cache-Refresh.
+func (h *HandlerService) applyCacheScope(ctx context.Context, header nats.Header) (context.Context, error) {
+	scope := header.Get(cacheScopeHeader)
+	refresh := header.Get(cacheRefreshHeader) == trueString
+	if scope == "" {
+		if refresh {
+			err := errors.New("cache refresh requires a cache scope")
+			return ctx, &accessCheckError{reply: err.Error(), invalid: true, err: err}
+		}
+		return ctx, nil
+	}
+	if err := validateCacheScope(scope); err != nil {
+		return ctx, &accessCheckError{reply: err.Error(), invalid: true, err: err}
+	}
+	if refresh {
+		if err := h.fgaService.RefreshCacheScope(ctx, scope); err != nil {
+			return ctx, &accessCheckError{reply: "failed to refresh cache scope", err: err}
+		}
+		logger.With("cache_scope", scope).DebugContext(ctx, "refreshed cache scope")
+	}
+	return withCacheScope(ctx, scope), nil
+}
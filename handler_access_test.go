@@ -43,6 +43,7 @@ func setupService() *HandlerService {
 		fgaService: FgaService{
 			client:      &MockFgaClient{},
 			cacheBucket: NewMockKeyValue(),
+			stateBucket: NewMockKeyValue(),
 		},
 	}
 
@@ -75,7 +76,7 @@ func TestAccessCheckHandler(t *testing.T) {
 				resultMap["1"] = openfga.BatchCheckSingleResult{
 					Allowed: openfga.PtrBool(true),
 				}
-				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
+				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
 					Result: &resultMap,
 				}, nil)
 				service.fgaService.cacheBucket.(*MockKeyValue).On("PutString", mock.Anything, mock.Anything, mock.Anything).Return(uint64(0), nil)
@@ -102,7 +103,7 @@ func TestAccessCheckHandler(t *testing.T) {
 				resultMap["2"] = openfga.BatchCheckSingleResult{
 					Allowed: openfga.PtrBool(true),
 				}
-				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
+				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
 					Result: &resultMap,
 				}, nil)
 				// Mock cache operations
@@ -145,7 +146,7 @@ func TestAccessCheckHandler(t *testing.T) {
 				resultMap["1"] = openfga.BatchCheckSingleResult{
 					Allowed: openfga.PtrBool(true),
 				}
-				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
+				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
 					Result: &resultMap,
 				}, nil)
 			},
@@ -205,7 +206,7 @@ func TestAccessCheckHandler(t *testing.T) {
 					},
 				}
 
-				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
+				service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(&openfga.BatchCheckResponse{
 					Result: &resultMap,
 				}, nil)
 
@@ -364,6 +365,35 @@ func TestProcessStandardAccessUpdate(t *testing.T) {
 			expectedError:  true,
 			expectedCalled: false,
 		},
+		{
+			name: "self-referential parent should fail",
+			obj: &standardAccessStub{
+				UID:        "loop-123",
+				ObjectType: "committee",
+				Relations:  map[string][]string{"writer": {"user1"}},
+				References: map[string][]string{"parent": {"loop-123"}},
+			},
+			replySubject: "reply.subject",
+			setupMocks: func(service *HandlerService, msg *MockNatsMsg) {
+				// No mocks needed as function should fail before writing
+			},
+			expectedError:  true,
+			expectedCalled: false,
+		},
+		{
+			name: "self-referential prefixed parent should fail",
+			obj: &standardAccessStub{
+				UID:        "loop-456",
+				ObjectType: "groupsio_service",
+				References: map[string][]string{"parent": {"groupsio_service:loop-456"}},
+			},
+			replySubject: "reply.subject",
+			setupMocks: func(service *HandlerService, msg *MockNatsMsg) {
+				// No mocks needed as function should fail before writing
+			},
+			expectedError:  true,
+			expectedCalled: false,
+		},
 		{
 			name: "FGA sync failure should propagate error",
 			obj: &standardAccessStub{
@@ -683,3 +713,164 @@ func TestProcessStandardAccessUpdate(t *testing.T) {
 		})
 	}
 }
+
+// TestStandardAccessTuples_DuplicatePrincipals tests that a principal listed
+// more than once under a relation yields a single tuple.
+func TestStandardAccessTuples_DuplicatePrincipals(t *testing.T) {
+	service := setupService()
+
+	_, tuples, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+		UID:        "m1",
+		ObjectType: "past_meeting",
+		Relations: map[string][]string{
+			"viewer": {"alice", "bob", "alice"},
+			"host":   {"alice"},
+		},
+	})
+
+	assert.NoError(t, err)
+	keys := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		keys = append(keys, tuple.Relation+"@"+tuple.User)
+	}
+	assert.ElementsMatch(t, []string{"viewer@user:alice", "viewer@user:bob", "host@user:alice"}, keys)
+}
+
+// TestStandardAccessTuples_EmptyValues tests that empty reference values and
+// principals are skipped instead of building tuples with no UID.
+func TestStandardAccessTuples_EmptyValues(t *testing.T) {
+	service := setupService()
+
+	_, tuples, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+		UID:        "c1",
+		ObjectType: "committee",
+		References: map[string][]string{
+			"project": {"", "p1"},
+			"parent":  {" "},
+		},
+		Relations: map[string][]string{
+			"writer": {"", "alice"},
+			"member": {""},
+		},
+	})
+
+	assert.NoError(t, err)
+	keys := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		keys = append(keys, tuple.Relation+"@"+tuple.User)
+	}
+	assert.ElementsMatch(t, []string{"project@project:p1", "writer@user:alice"}, keys)
+}
+
+// TestStandardAccessTuples_ProjectRequired tests that object types configured
+// to require a project reference are rejected without one, and that other
+// types are not.
+func TestStandardAccessTuples_ProjectRequired(t *testing.T) {
+	tests := []struct {
+		name        string
+		objectType  string
+		references  map[string][]string
+		expectError string
+	}{
+		{
+			name:       "required type with a project reference",
+			objectType: "committee",
+			references: map[string][]string{"project": {"p1"}},
+		},
+		{
+			name:        "required type without references",
+			objectType:  "committee",
+			expectError: "committee requires a project reference",
+		},
+		{
+			name:        "required type with only other references",
+			objectType:  "committee",
+			references:  map[string][]string{"parent": {"c0"}},
+			expectError: "committee requires a project reference",
+		},
+		{
+			name:        "required type with an empty project reference",
+			objectType:  "committee",
+			references:  map[string][]string{"project": {""}},
+			expectError: "committee requires a project reference",
+		},
+		{
+			name:       "not-required type without references",
+			objectType: "project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.projectRequiredTypes = map[string]bool{"committee": true, "meeting": true}
+
+			_, _, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+				UID:        "x1",
+				ObjectType: tt.objectType,
+				References: tt.references,
+			})
+
+			if tt.expectError != "" {
+				assert.EqualError(t, err, tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestStandardAccessTuples_PrivateObjectTypes tests that access updates that
+// would make an object of a private type public are rejected.
+func TestStandardAccessTuples_PrivateObjectTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		objectType  string
+		public      bool
+		relations   map[string][]string
+		expectError string
+	}{
+		{
+			name:        "public private-type object",
+			objectType:  "committee",
+			public:      true,
+			expectError: "committee objects must not be public",
+		},
+		{
+			name:        "private-type object granting the wildcard as a principal",
+			objectType:  "committee",
+			relations:   map[string][]string{"viewer": {"*"}},
+			expectError: "committee objects must not be public",
+		},
+		{
+			name:       "non-public private-type object",
+			objectType: "committee",
+			relations:  map[string][]string{"viewer": {"alice"}},
+		},
+		{
+			name:       "public object of another type",
+			objectType: "project",
+			public:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.privateObjectTypes = map[string]bool{"committee": true}
+
+			_, _, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+				UID:        "x1",
+				ObjectType: tt.objectType,
+				Public:     tt.public,
+				Relations:  tt.relations,
+			})
+
+			if tt.expectError != "" {
+				assert.EqualError(t, err, tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
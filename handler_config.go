@@ -0,0 +1,39 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// configHandler handles requests for the service's effective configuration,
+// so operators can verify a running replica matches what they deployed. The
+// request payload is ignored. It responds with a JSON-encoded ConfigResponse.
+func (h *HandlerService) configHandler(ctx context.Context, message INatsMsg) error {
+	data, err := json.Marshal(types.ConfigResponse{Config: h.config.effective()})
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal config response")
+		return h.respondConfigError(ctx, message, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send config reply")
+			return errRespond
+		}
+		logger.InfoContext(ctx, "sent config response")
+	}
+
+	return nil
+}
+
+// respondConfigError replies to a config request with a ConfigResponse
+// carrying errMsg, as respondJSONError describes.
+func (h *HandlerService) respondConfigError(_ context.Context, message INatsMsg, errMsg string) error {
+	return respondJSONError(message, "config", types.ConfigResponse{Error: errMsg}, errMsg)
+}
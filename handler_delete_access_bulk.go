@@ -0,0 +1,119 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+const (
+	// deleteAccessBulkTimeout is the maximum time allowed for deleting the
+	// access of every object of a bulk request.
+	deleteAccessBulkTimeout = 2 * time.Minute
+	// maxDeleteAccessBulkObjects bounds the objects deleted by one request, so
+	// a request finishes well within deleteAccessBulkTimeout.
+	maxDeleteAccessBulkObjects = 500
+)
+
+// deleteAccessBulkHandler handles requests to delete all access to a list of
+// objects, as a delete_access for each, e.g. when tearing down a project and
+// its meetings. Objects are deleted in order and independently: an object
+// that fails, or is invalid, is reported in its result and the remaining
+// objects are still deleted. It responds with a JSON-encoded
+// DeleteAccessBulkResponse.
+func (h *HandlerService) deleteAccessBulkHandler(ctx context.Context, message INatsMsg) error {
+	ctx, cancel := context.WithTimeout(ctx, deleteAccessBulkTimeout)
+	defer cancel()
+
+	var req types.DeleteAccessBulkRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal delete access bulk request")
+		return h.respondDeleteAccessBulkError(ctx, message, "invalid request payload")
+	}
+	if len(req.Objects) == 0 {
+		logger.WarnContext(ctx, "delete access bulk request lists no objects")
+		return h.respondDeleteAccessBulkError(ctx, message, "objects is required")
+	}
+	if len(req.Objects) > maxDeleteAccessBulkObjects {
+		logger.With("count", len(req.Objects)).WarnContext(ctx, "delete access bulk request lists too many objects")
+		return h.respondDeleteAccessBulkError(ctx, message,
+			fmt.Sprintf("at most %d objects may be deleted per request", maxDeleteAccessBulkObjects))
+	}
+
+	resp := types.DeleteAccessBulkResponse{Results: make([]types.DeleteAccessBulkResult, 0, len(req.Objects))}
+	failed := 0
+	for _, entry := range req.Objects {
+		result := h.deleteAccessBulkObject(ctx, entry)
+		if result.Error != "" {
+			failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	logger.With("objects", len(req.Objects), "failed", failed).InfoContext(ctx, "handled delete access bulk request")
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal delete access bulk response")
+		return h.respondDeleteAccessBulkError(ctx, message, "failed to marshal response")
+	}
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send delete access bulk reply")
+			return errRespond
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("delete access bulk: %d of %d objects failed", failed, len(req.Objects))
+	}
+	return nil
+}
+
+// deleteAccessBulkObject validates one object of a bulk request and deletes,
+// or schedules the delete of, its access.
+func (h *HandlerService) deleteAccessBulkObject(
+	ctx context.Context,
+	entry types.DeleteAccessBulkObject,
+) types.DeleteAccessBulkResult {
+	objectType, err := canonicalObjectType(ctx, entry.ObjectType)
+	if err == nil && entry.UID == "" {
+		err = errors.New("uid is required")
+	}
+	if err == nil {
+		err = validateUID(entry.UID)
+	}
+	if err != nil {
+		logger.With(errKey, err, "object_type", entry.ObjectType, "uid", entry.UID).
+			WarnContext(ctx, "invalid object in delete access bulk request")
+		return types.DeleteAccessBulkResult{Object: entry.ObjectType + ":" + entry.UID, Error: err.Error()}
+	}
+
+	object := buildObjectID(objectType, entry.UID)
+	if h.deleteGracePeriod > 0 {
+		if err := h.scheduleDelete(ctx, object, nil); err != nil {
+			return types.DeleteAccessBulkResult{Object: object, Error: withFgaRequestID("failed to schedule delete", err)}
+		}
+		return types.DeleteAccessBulkResult{Object: object, Scheduled: true}
+	}
+
+	deletes, err := h.deleteObjectAccess(ctx, object, nil)
+	if err != nil {
+		return types.DeleteAccessBulkResult{Object: object, Error: withFgaRequestID("failed to delete access", err)}
+	}
+	return types.DeleteAccessBulkResult{Object: object, Deleted: len(deletes)}
+}
+
+// respondDeleteAccessBulkError replies to a bulk delete that failed as a
+// whole, with no per-object results.
+func (h *HandlerService) respondDeleteAccessBulkError(_ context.Context, message INatsMsg, errMsg string) error {
+	resp := types.DeleteAccessBulkResponse{Results: []types.DeleteAccessBulkResult{}, Error: errMsg}
+	return respondJSONError(message, "delete access bulk", resp, errMsg)
+}
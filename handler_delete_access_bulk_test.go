@@ -0,0 +1,127 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestDeleteAccessBulkHandler tests the deleteAccessBulkHandler method of
+// HandlerService.
+func TestDeleteAccessBulkHandler(t *testing.T) {
+	existing := map[string][]openfga.Tuple{
+		"meeting:m1": {
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: "meeting:m1"}},
+			{Key: openfga.TupleKey{User: "project:p1", Relation: "project", Object: "meeting:m1"}},
+		},
+		"meeting:m2": {
+			{Key: openfga.TupleKey{User: "user:bob", Relation: "viewer", Object: "meeting:m2"}},
+		},
+		"committee:c1": {
+			{Key: openfga.TupleKey{User: "user:carol", Relation: "member", Object: "committee:c1"}},
+		},
+	}
+	tooMany := `{"objects":[` + strings.Repeat(`{"object_type":"meeting","uid":"m1"},`, maxDeleteAccessBulkObjects) +
+		`{"object_type":"meeting","uid":"m1"}]}`
+
+	tests := []struct {
+		name          string
+		messageData   string
+		failObject    string
+		expectedResp  types.DeleteAccessBulkResponse
+		expectDeleted []string
+		expectError   bool
+	}{
+		{
+			name: "deletes the access of every listed object",
+			messageData: `{"objects":[{"object_type":"meeting","uid":"m1"},{"object_type":"meeting","uid":"m2"},` +
+				`{"object_type":"committee","uid":"c1"}]}`,
+			expectedResp: types.DeleteAccessBulkResponse{Results: []types.DeleteAccessBulkResult{
+				{Object: "meeting:m1", Deleted: 2},
+				{Object: "meeting:m2", Deleted: 1},
+				{Object: "committee:c1", Deleted: 1},
+			}},
+			expectDeleted: []string{"meeting:m1", "meeting:m2", "committee:c1"},
+		},
+		{
+			name: "failed and invalid objects do not stop the rest",
+			messageData: `{"objects":[{"object_type":"meeting","uid":"m1"},{"object_type":"meeting","uid":""},` +
+				`{"object_type":"meeting","uid":"m2"},{"object_type":"committee","uid":"c1"}]}`,
+			failObject: "meeting:m2",
+			expectedResp: types.DeleteAccessBulkResponse{Results: []types.DeleteAccessBulkResult{
+				{Object: "meeting:m1", Deleted: 2},
+				{Object: "meeting:", Error: "uid is required"},
+				{Object: "meeting:m2", Error: "failed to delete access"},
+				{Object: "committee:c1", Deleted: 1},
+			}},
+			expectDeleted: []string{"meeting:m1", "committee:c1"},
+			expectError:   true,
+		},
+		{
+			name:         "empty object list is rejected",
+			messageData:  `{"objects":[]}`,
+			expectedResp: types.DeleteAccessBulkResponse{Results: []types.DeleteAccessBulkResult{}, Error: "objects is required"},
+			expectError:  true,
+		},
+		{
+			name:        "too many objects are rejected before any delete",
+			messageData: tooMany,
+			expectedResp: types.DeleteAccessBulkResponse{
+				Results: []types.DeleteAccessBulkResult{},
+				Error:   "at most 500 objects may be deleted per request",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			for object, tuples := range existing {
+				fgaClient.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+					return req.Object != nil && *req.Object == object
+				}), mock.Anything).Return(&client.ClientReadResponse{Tuples: tuples}, nil).Maybe()
+			}
+
+			var deleted []string
+			isFailObject := func(req client.ClientWriteRequest) bool { return req.Deletes[0].Object == tt.failObject }
+			fgaClient.On("Write", mock.Anything, mock.MatchedBy(isFailObject)).
+				Return((*client.ClientWriteResponse)(nil), errors.New("store unavailable")).Maybe()
+			fgaClient.On("Write", mock.Anything, mock.MatchedBy(func(req client.ClientWriteRequest) bool {
+				return !isFailObject(req)
+			})).Run(func(args mock.Arguments) {
+				deleted = append(deleted, args.Get(1).(client.ClientWriteRequest).Deletes[0].Object)
+			}).Return(&client.ClientWriteResponse{}, nil).Maybe()
+
+			msg := CreateMockNatsMsg([]byte(tt.messageData))
+			msg.reply = "reply.delete_access_bulk"
+			var resp types.DeleteAccessBulkResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.deleteAccessBulkHandler(context.Background(), msg)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedResp, resp)
+			assert.Equal(t, tt.expectDeleted, deleted)
+		})
+	}
+}
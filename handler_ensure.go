@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// ensureHandler writes the tuples of the baseline access in the request that
+// an object does not have yet, e.g. the system-admin viewer of a newly
+// created object. The request is built into tuples like an update_access,
+// but nothing is deleted, so tuples written by other updates are left alone
+// and the request can be repeated safely. It responds with a JSON-encoded
+// EnsureResponse listing the tuples written.
+func (h *HandlerService) ensureHandler(ctx context.Context, message INatsMsg) error {
+	var req types.EnsureRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal ensure request")
+		return h.respondEnsureError(ctx, message, "", "invalid request payload")
+	}
+
+	if req.ObjectType == "" {
+		logger.WarnContext(ctx, "ensure request missing object_type")
+		return h.respondEnsureError(ctx, message, "", "object_type is required")
+	}
+
+	object, tuples, err := h.standardAccessTuples(ctx, &standardAccessStub{
+		UID:        req.UID,
+		ObjectType: req.ObjectType,
+		Public:     req.Public,
+		Relations:  req.Relations,
+		References: req.References,
+	})
+	if err != nil {
+		return h.respondEnsureError(ctx, message, "", err.Error())
+	}
+
+	writes, err := h.fgaService.EnsureTuples(ctx, object, tuples)
+	if err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to ensure tuples")
+		return h.respondEnsureError(ctx, message, object, withFgaRequestID("failed to ensure tuples", err))
+	}
+
+	resp := types.EnsureResponse{Object: object, Writes: make([]string, 0, len(writes))}
+	for _, t := range writes {
+		resp.Writes = append(resp.Writes, fmt.Sprintf("%s#%s@%s", t.Object, t.Relation, t.User))
+	}
+	logger.With("object", object, "writes", resp.Writes).InfoContext(ctx, "ensured object tuples")
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal ensure response")
+		return h.respondEnsureError(ctx, message, object, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send ensure reply")
+			return errRespond
+		}
+	}
+
+	return nil
+}
+
+// respondEnsureError replies to an ensure request for object, which may be
+// empty if the request could not be parsed, with errMsg.
+func (h *HandlerService) respondEnsureError(_ context.Context, message INatsMsg, object, errMsg string) error {
+	return respondJSONError(message, "ensure", types.EnsureResponse{Object: object, Error: errMsg}, errMsg)
+}
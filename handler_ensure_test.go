@@ -0,0 +1,110 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestEnsureHandler tests the ensureHandler method of HandlerService.
+func TestEnsureHandler(t *testing.T) {
+	existing := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "project:p1"}},
+		{Key: openfga.TupleKey{User: "user:mallory", Relation: "writer", Object: "project:p1"}},
+	}
+
+	tests := []struct {
+		name           string
+		messageData    []byte
+		writeErr       error
+		expectedWrites []string
+		expectedError  string
+	}{
+		{
+			name:        "writes only the missing tuples",
+			messageData: []byte(`{"object_type":"project","uid":"p1","relations":{"writer":["alice","bob"],"auditor":["alice"]}}`),
+			expectedWrites: []string{
+				"project:p1#auditor@user:alice",
+				"project:p1#writer@user:bob",
+			},
+		},
+		{
+			name:           "nothing is written when every tuple is present",
+			messageData:    []byte(`{"object_type":"project","uid":"p1","relations":{"writer":["alice"]}}`),
+			expectedWrites: []string{},
+		},
+		{
+			name:          "missing object type returns error",
+			messageData:   []byte(`{"uid":"p1"}`),
+			expectedError: "object_type is required",
+		},
+		{
+			name:          "missing uid returns error",
+			messageData:   []byte(`{"object_type":"project"}`),
+			expectedError: "project ID not found",
+		},
+		{
+			name:          "invalid JSON payload returns error",
+			messageData:   []byte(`not-json`),
+			expectedError: "invalid request payload",
+		},
+		{
+			name:          "write failure returns error",
+			messageData:   []byte(`{"object_type":"project","uid":"p1","relations":{"writer":["bob"]}}`),
+			writeErr:      errors.New("store unavailable"),
+			expectedError: "failed to ensure tuples",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: existing}, nil)
+			var written []string
+			fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				req := args.Get(1).(client.ClientWriteRequest)
+				assert.Empty(t, req.Deletes)
+				for _, w := range req.Writes {
+					written = append(written, w.Object+"#"+w.Relation+"@"+w.User)
+				}
+			}).Return(&client.ClientWriteResponse{}, tt.writeErr)
+
+			msg := CreateMockNatsMsg(tt.messageData)
+			msg.reply = "reply.ensure"
+			var resp types.EnsureResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.ensureHandler(context.Background(), msg)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedError, resp.Error)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, resp.Error)
+			assert.Equal(t, "project:p1", resp.Object)
+			assert.ElementsMatch(t, tt.expectedWrites, resp.Writes)
+			assert.ElementsMatch(t, tt.expectedWrites, written)
+			if len(tt.expectedWrites) == 0 {
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
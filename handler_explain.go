@@ -0,0 +1,78 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// explainAccessTimeout is the maximum time allowed for reading and checking
+// the grants on an object.
+const explainAccessTimeout = 10 * time.Second
+
+// explainAccessHandler handles diagnostic requests asking why a user has a
+// relation on an object, e.g. whether a user is a viewer because the object
+// is public or because of a committee membership. It responds with a
+// JSON-encoded ExplainAccessResponse.
+func (h *HandlerService) explainAccessHandler(ctx context.Context, message INatsMsg) error {
+	ctx, cancel := context.WithTimeout(ctx, explainAccessTimeout)
+	defer cancel()
+
+	var req types.ExplainAccessRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal explain access request")
+		return h.respondExplainError(ctx, message, "invalid request payload")
+	}
+
+	if req.Object == "" || req.Relation == "" || req.User == "" {
+		logger.WarnContext(ctx, "explain access request missing fields")
+		return h.respondExplainError(ctx, message, "object, relation, and user are required")
+	}
+
+	allowed, grants, err := h.fgaService.ExplainAccess(ctx, req.Object, req.Relation, req.User)
+	if err != nil {
+		logger.With(errKey, err, "object", req.Object).ErrorContext(ctx, "failed to explain access")
+		return h.respondExplainError(ctx, message, withFgaRequestID("failed to explain access", err))
+	}
+
+	resp := types.ExplainAccessResponse{
+		Allowed: allowed,
+		Grants:  make([]types.AccessGrant, 0, len(grants)),
+	}
+	for _, grant := range grants {
+		resp.Grants = append(resp.Grants, types.AccessGrant{Kind: grant.Kind, Tuple: grant.Tuple})
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal explain access response")
+		return h.respondExplainError(ctx, message, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send explain access reply")
+			return errRespond
+		}
+		logger.With(
+			"object", req.Object,
+			"relation", req.Relation,
+			"allowed", allowed,
+			"grants", len(grants),
+		).InfoContext(ctx, "sent explain access response")
+	}
+
+	return nil
+}
+
+// respondExplainError replies to an explain request with an
+// ExplainAccessResponse carrying errMsg.
+func (h *HandlerService) respondExplainError(_ context.Context, message INatsMsg, errMsg string) error {
+	return respondJSONError(message, "explain access", types.ExplainAccessResponse{Error: errMsg}, errMsg)
+}
@@ -0,0 +1,133 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestExplainAccessHandler tests the explainAccessHandler method of HandlerService.
+func TestExplainAccessHandler(t *testing.T) {
+	tuple := func(user string) openfga.Tuple {
+		return openfga.Tuple{Key: openfga.TupleKey{User: user, Relation: "viewer", Object: "meeting:m1"}}
+	}
+	multiplePaths := []openfga.Tuple{
+		tuple("user:alice"),
+		tuple("user:*"),
+		tuple("committee:c1#member"),
+		tuple("committee:c2#member"),
+		tuple("user:bob"),
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "organizer", Object: "meeting:m1"}},
+	}
+
+	tests := []struct {
+		name           string
+		messageData    []byte
+		tuples         []openfga.Tuple
+		checkResults   map[string]openfga.BatchCheckSingleResult
+		checkErr       error
+		expectedResp   types.ExplainAccessResponse
+		expectedError  string
+		expectNoChecks bool
+	}{
+		{
+			name:        "user granted through multiple paths",
+			messageData: []byte(`{"object":"meeting:m1","relation":"viewer","user":"user:alice"}`),
+			tuples:      multiplePaths,
+			// Correlation IDs follow the tuple order: c1 is the third tuple, c2
+			// the fourth.
+			checkResults: map[string]openfga.BatchCheckSingleResult{
+				"0": {Allowed: openfga.PtrBool(true)},
+				"3": {Allowed: openfga.PtrBool(true)},
+				"4": {Allowed: openfga.PtrBool(false)},
+			},
+			expectedResp: types.ExplainAccessResponse{
+				Allowed: true,
+				Grants: []types.AccessGrant{
+					{Kind: "userset", Tuple: "meeting:m1#viewer@committee:c1#member"},
+					{Kind: "public", Tuple: "meeting:m1#viewer@user:*"},
+					{Kind: "direct", Tuple: "meeting:m1#viewer@user:alice"},
+				},
+			},
+		},
+		{
+			name:        "access only inherited through the model",
+			messageData: []byte(`{"object":"meeting:m1","relation":"viewer","user":"user:carol"}`),
+			checkResults: map[string]openfga.BatchCheckSingleResult{
+				"0": {Allowed: openfga.PtrBool(true)},
+			},
+			expectedResp: types.ExplainAccessResponse{Allowed: true, Grants: []types.AccessGrant{}},
+		},
+		{
+			name:           "missing fields returns error",
+			messageData:    []byte(`{"object":"meeting:m1"}`),
+			expectedError:  "object, relation, and user are required",
+			expectNoChecks: true,
+		},
+		{
+			name:           "invalid JSON payload returns error",
+			messageData:    []byte(`not-json`),
+			expectedError:  "invalid request payload",
+			expectNoChecks: true,
+		},
+		{
+			name:          "check failure returns error",
+			messageData:   []byte(`{"object":"meeting:m1","relation":"viewer","user":"user:alice"}`),
+			checkErr:      errors.New("store unavailable"),
+			expectedError: "failed to explain access",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: tt.tuples}, nil)
+			if tt.checkErr != nil {
+				fgaClient.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).
+					Return((*openfga.BatchCheckResponse)(nil), tt.checkErr)
+			} else {
+				fgaClient.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).
+					Return(&openfga.BatchCheckResponse{Result: &tt.checkResults}, nil)
+			}
+
+			msg := CreateMockNatsMsg(tt.messageData)
+			msg.reply = "reply.explain"
+			var resp types.ExplainAccessResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.explainAccessHandler(context.Background(), msg)
+
+			if tt.expectNoChecks {
+				fgaClient.AssertNotCalled(t, "BatchCheck", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedError, resp.Error)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedResp, resp)
+
+			// Only usersets are checked; direct and public grants are read
+			// straight from the tuples.
+			req := fgaClient.Calls[len(fgaClient.Calls)-1].Arguments.Get(1).(client.ClientBatchCheckRequest)
+			assert.Len(t, req.Checks, len(tt.checkResults))
+		})
+	}
+}
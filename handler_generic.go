@@ -7,7 +7,10 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
 
 	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
 	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
@@ -37,24 +40,28 @@ func (h *HandlerService) genericUpdateAccessHandler(ctx context.Context, message
 
 	// Parse generic message
 	genericMsg := new(fgatypes.GenericFGAMessage)
-	if err := json.Unmarshal(message.Data(), genericMsg); err != nil {
+	if err := decodePayload(message.Data(), genericMsg); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse generic message")
 		return err
 	}
 
 	// Validate
-	if genericMsg.ObjectType == "" {
-		logger.ErrorContext(ctx, "object_type is required")
-		return errors.New("object_type is required")
+	objectType, err := canonicalObjectType(ctx, genericMsg.ObjectType)
+	if err != nil {
+		return err
 	}
+	genericMsg.ObjectType = objectType
 	if genericMsg.Operation != "update_access" {
 		logger.ErrorContext(ctx, "invalid operation for this handler", "operation", genericMsg.Operation)
-		return errors.New("invalid operation for update_access handler")
+		return newFieldError("operation", reasonInvalid, "invalid operation for update_access handler")
+	}
+	if err := h.requireReply(ctx, message, genericMsg.ObjectType); err != nil {
+		return err
 	}
 
 	// Parse data field
 	data := new(fgatypes.GenericAccessData)
-	if err := genericMsg.UnmarshalData(data); err != nil {
+	if err := decodeData(genericMsg, data); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse access data")
 		return err
 	}
@@ -66,11 +73,12 @@ func (h *HandlerService) genericUpdateAccessHandler(ctx context.Context, message
 
 	// Convert to standardAccessStub (reuse existing generic logic)
 	stub := &standardAccessStub{
-		UID:        data.UID,
-		ObjectType: genericMsg.ObjectType,
-		Public:     data.Public,
-		Relations:  data.Relations,
-		References: data.References,
+		UID:             data.UID,
+		ObjectType:      genericMsg.ObjectType,
+		Public:          data.Public,
+		Relations:       data.Relations,
+		References:      data.References,
+		ExpectedVersion: data.ExpectedVersion,
 	}
 
 	// Use existing generic handler
@@ -95,24 +103,28 @@ func (h *HandlerService) genericDeleteAccessHandler(ctx context.Context, message
 
 	// Parse generic message
 	genericMsg := new(fgatypes.GenericFGAMessage)
-	if err := json.Unmarshal(message.Data(), genericMsg); err != nil {
+	if err := decodePayload(message.Data(), genericMsg); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse generic message")
 		return err
 	}
 
 	// Validate
-	if genericMsg.ObjectType == "" {
-		logger.ErrorContext(ctx, "object_type is required")
-		return errors.New("object_type is required")
+	objectType, err := canonicalObjectType(ctx, genericMsg.ObjectType)
+	if err != nil {
+		return err
 	}
+	genericMsg.ObjectType = objectType
 	if genericMsg.Operation != "delete_access" {
 		logger.ErrorContext(ctx, "invalid operation for this handler", "operation", genericMsg.Operation)
-		return errors.New("invalid operation for delete_access handler")
+		return newFieldError("operation", reasonInvalid, "invalid operation for delete_access handler")
+	}
+	if err := h.requireReply(ctx, message, genericMsg.ObjectType); err != nil {
+		return err
 	}
 
 	// Parse data field
 	data := new(fgatypes.GenericDeleteData)
-	if err := genericMsg.UnmarshalData(data); err != nil {
+	if err := decodeData(genericMsg, data); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse delete data")
 		return err
 	}
@@ -120,7 +132,11 @@ func (h *HandlerService) genericDeleteAccessHandler(ctx context.Context, message
 	// Validate UID is non-empty
 	if data.UID == "" {
 		logger.ErrorContext(ctx, "uid is required")
-		return errors.New("uid is required")
+		return newFieldError("uid", reasonRequired, "uid is required")
+	}
+	if err := validateUID(data.UID); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "invalid uid")
+		return err
 	}
 
 	logger.With(
@@ -131,30 +147,118 @@ func (h *HandlerService) genericDeleteAccessHandler(ctx context.Context, message
 	// Build object identifier using standard helper
 	object := buildObjectID(genericMsg.ObjectType, data.UID)
 
-	// Use existing generic sync with empty tuples (deletes all)
-	tuplesWrites, tuplesDeletes, err := h.fgaService.SyncObjectTuples(ctx, object, nil)
+	// Validate every cascade object before deleting (or scheduling) anything.
+	for _, dependent := range data.Cascade {
+		dependentType, dependentUID, found := strings.Cut(dependent, ":")
+		if !found || dependentType == "" || dependentUID == "" {
+			logger.ErrorContext(ctx, "invalid cascade object", "object", object, "cascade", dependent)
+			return newFieldError("cascade", reasonInvalid,
+				fmt.Sprintf("invalid cascade object '%s': must be 'type:id' with both parts non-empty", dependent))
+		}
+		if err := validateUID(dependentUID); err != nil {
+			logger.With(errKey, err).ErrorContext(ctx, "invalid cascade uid", "cascade", dependent)
+			return newFieldError("cascade", reasonInvalid, err.Error())
+		}
+	}
+
+	if h.deleteGracePeriod > 0 {
+		// Nothing is removed yet, so verbose requests get the usual reply.
+		if err := h.scheduleDelete(ctx, object, data.Cascade); err != nil {
+			return err
+		}
+		return h.sendReplyIfNeeded(ctx, message, object, 0, 0)
+	}
+
+	deletes, err := h.deleteObjectAccess(ctx, object, data.Cascade)
 	if err != nil {
-		logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to delete access")
 		return err
 	}
 
-	logger.With(
-		"object", object,
-		"writes", tuplesWrites,
-		"deletes", tuplesDeletes,
-	).InfoContext(ctx, "deleted all access for "+genericMsg.ObjectType)
-
 	// Send reply
-	if message.Reply() != "" {
-		if err = message.Respond([]byte("OK")); err != nil {
-			logger.With(errKey, err).WarnContext(ctx, "failed to send reply")
-			return err
+	if data.Verbose {
+		return h.sendDeleteSummaryIfNeeded(ctx, message, deletes)
+	}
+	return h.sendReplyIfNeeded(ctx, message, object, 0, len(deletes))
+}
+
+// maxDeleteSummaryTuples bounds the tuples listed in a verbose delete_access
+// reply; the count always covers every removed tuple.
+const maxDeleteSummaryTuples = 100
+
+// sendDeleteSummaryIfNeeded replies to a verbose delete_access request with
+// the tuples removed, if the message has a reply inbox.
+func (h *HandlerService) sendDeleteSummaryIfNeeded(
+	ctx context.Context,
+	message INatsMsg,
+	deletes []client.ClientTupleKeyWithoutCondition,
+) error {
+	if message.Reply() == "" {
+		return nil
+	}
+
+	resp := fgatypes.DeleteAccessResponse{
+		Deleted: len(deletes),
+		Tuples:  make([]string, 0, min(len(deletes), maxDeleteSummaryTuples)),
+	}
+	for _, tuple := range deletes {
+		if len(resp.Tuples) == maxDeleteSummaryTuples {
+			resp.Truncated = true
+			break
 		}
+		resp.Tuples = append(resp.Tuples, tuple.Object+"#"+tuple.Relation+"@"+tuple.User)
 	}
 
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal delete summary")
+		return err
+	}
+	if err := message.Respond(data); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to send reply")
+		return err
+	}
 	return nil
 }
 
+// deleteObjectAccess deletes every tuple on object and on its cascade
+// dependents. Dependents are deleted first, so a failure leaves the parent in
+// place and a redelivered message retries the whole cascade. It returns every
+// tuple deleted.
+func (h *HandlerService) deleteObjectAccess(
+	ctx context.Context,
+	object string,
+	cascade []string,
+) ([]client.ClientTupleKeyWithoutCondition, error) {
+	var allDeletes []client.ClientTupleKeyWithoutCondition
+	for _, dependent := range cascade {
+		deletes, err := h.fgaService.deleteObjectTuples(ctx, dependent)
+		if err != nil {
+			logger.With(errKey, err, "object", object, "cascade", dependent).
+				ErrorContext(ctx, "failed to delete cascaded access")
+			return nil, err
+		}
+		allDeletes = append(allDeletes, deletes...)
+		logger.With(
+			"object", object,
+			"cascade", dependent,
+			"deletes", deletes,
+		).InfoContext(ctx, "deleted cascaded access")
+	}
+
+	tuplesDeletes, err := h.fgaService.deleteObjectTuples(ctx, object)
+	if err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to delete access")
+		return nil, err
+	}
+
+	objectType, _, _ := strings.Cut(object, ":")
+	logger.With(
+		"object", object,
+		"deletes", tuplesDeletes,
+	).InfoContext(ctx, "deleted all access for "+objectType)
+	return append(allDeletes, tuplesDeletes...), nil
+}
+
 // genericMemberPutHandler handles universal member_put operations with support for multiple relations.
 // This allows adding a user to a resource with one or more relations atomically.
 //
@@ -215,22 +319,93 @@ func (h *HandlerService) genericMemberPutHandler(ctx context.Context, message IN
 	object := buildObjectID(genericMsg.ObjectType, data.UID)
 	userPrincipal := constants.ObjectTypeUser + data.Username
 
+	stale, err := h.isStaleMemberOperation(ctx, object, userPrincipal, data.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if stale {
+		return h.sendReplyIfNeeded(ctx, message, object, 0, 0)
+	}
+
 	// Compute tuple changes
-	tuplesToWrite, tuplesToDelete, err := h.computeMemberPutChanges(ctx, object, userPrincipal, data)
+	tuplesToWrite, tuplesToDelete, renewed, err := h.computeMemberPutChanges(ctx, object, userPrincipal, data)
 	if err != nil {
 		return err
 	}
+	h.applyGrantExpiryCondition(tuplesToWrite, data)
+	if err = h.validateTupleRelations(ctx, tuplesToWrite); err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "invalid relation")
+		return err
+	}
+	if err = h.deleteRenewedGrants(ctx, object, renewed); err != nil {
+		return err
+	}
 
 	// Apply changes
 	err = h.applyMemberPutChanges(
 		ctx, genericMsg.ObjectType, object, userPrincipal, data.Relations, tuplesToWrite, tuplesToDelete,
 	)
 	if err != nil {
+		h.restoreRenewedGrants(ctx, object, renewed)
+		return err
+	}
+	if err = h.recordGrantExpiries(ctx, object, userPrincipal, data); err != nil {
 		return err
 	}
 
+	h.recordMemberOperation(ctx, object, userPrincipal, data.UpdatedAt)
+
 	// Send reply
-	return h.sendReplyIfNeeded(ctx, message)
+	return h.sendReplyIfNeeded(ctx, message, object, len(tuplesToWrite), len(tuplesToDelete))
+}
+
+// isStaleMemberOperation reports whether a member operation carrying
+// updatedAt is older than the last operation applied for the same object and
+// user. Operations without updatedAt are never considered stale.
+func (h *HandlerService) isStaleMemberOperation(
+	ctx context.Context,
+	object, userPrincipal string,
+	updatedAt *time.Time,
+) (bool, error) {
+	if updatedAt == nil {
+		return false, nil
+	}
+	lastApplied, err := h.fgaService.GetLastMemberUpdate(ctx, object, userPrincipal)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read last member update",
+			errKey, err,
+			"user", userPrincipal,
+			"object", object,
+		)
+		return false, err
+	}
+	if updatedAt.Before(lastApplied) {
+		logger.With(
+			"user", userPrincipal,
+			"object", object,
+			"updated_at", updatedAt,
+			"last_applied", lastApplied,
+		).InfoContext(ctx, "skipping out-of-order member operation")
+		return true, nil
+	}
+	return false, nil
+}
+
+// recordMemberOperation stores updatedAt as the last applied member operation
+// for the object and user. Failures are logged but not returned since the
+// OpenFGA change has already been applied.
+func (h *HandlerService) recordMemberOperation(
+	ctx context.Context,
+	object, userPrincipal string,
+	updatedAt *time.Time,
+) {
+	if updatedAt == nil {
+		return
+	}
+	if err := h.fgaService.SetLastMemberUpdate(ctx, object, userPrincipal, *updatedAt); err != nil {
+		logger.With(errKey, err, "user", userPrincipal, "object", object).
+			WarnContext(ctx, "failed to record last member update")
+	}
 }
 
 // parseAndValidateMemberPutMessage parses and validates the member_put message
@@ -239,58 +414,80 @@ func (h *HandlerService) parseAndValidateMemberPutMessage(
 ) (*fgatypes.GenericFGAMessage, *fgatypes.GenericMemberData, error) {
 	// Parse generic message
 	genericMsg := new(fgatypes.GenericFGAMessage)
-	if err := json.Unmarshal(message.Data(), genericMsg); err != nil {
+	if err := decodePayload(message.Data(), genericMsg); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse generic message")
 		return nil, nil, err
 	}
 
 	// Validate object_type
-	if genericMsg.ObjectType == "" {
-		logger.ErrorContext(ctx, "object_type is required")
-		return nil, nil, errors.New("object_type is required")
+	objectType, err := canonicalObjectType(ctx, genericMsg.ObjectType)
+	if err != nil {
+		return nil, nil, err
 	}
+	genericMsg.ObjectType = objectType
 	if genericMsg.Operation != "member_put" {
 		logger.ErrorContext(ctx, "invalid operation for this handler", "operation", genericMsg.Operation)
-		return nil, nil, errors.New("invalid operation for member_put handler")
+		return nil, nil, newFieldError("operation", reasonInvalid, "invalid operation for member_put handler")
+	}
+	if err := h.requireReply(ctx, message, genericMsg.ObjectType); err != nil {
+		return nil, nil, err
 	}
 
 	// Parse data field
 	data := new(fgatypes.GenericMemberData)
-	if err := genericMsg.UnmarshalData(data); err != nil {
+	if err := decodeData(genericMsg, data); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse member data")
 		return nil, nil, err
 	}
 
 	// Validate required fields
+	if err := h.resolveMemberEmail(ctx, data); err != nil {
+		return nil, nil, err
+	}
 	if data.Username == "" {
 		logger.ErrorContext(ctx, "username is required")
-		return nil, nil, errors.New("username is required")
+		return nil, nil, newFieldError("username", reasonRequired, "username is required")
 	}
 	if data.UID == "" {
 		logger.ErrorContext(ctx, "uid is required")
-		return nil, nil, errors.New("uid is required")
+		return nil, nil, newFieldError("uid", reasonRequired, "uid is required")
+	}
+	if err := validateUID(data.UID); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "invalid uid")
+		return nil, nil, err
+	}
+	if constants.ObjectTypeUser+data.Username == constants.UserWildcard && h.privateObjectTypes[genericMsg.ObjectType] {
+		logger.ErrorContext(ctx, "public access requested on private object type", "object_type", genericMsg.ObjectType)
+		return nil, nil, newFieldError("username", reasonNotAllowed,
+			fmt.Sprintf("%s objects must not be public", genericMsg.ObjectType))
 	}
 	if len(data.Relations) == 0 {
 		logger.ErrorContext(ctx, "relations array cannot be empty")
-		return nil, nil, errors.New("relations array cannot be empty")
+		return nil, nil, newFieldError("relations", reasonRequired, "relations array cannot be empty")
 	}
 	// Validate each relation is non-empty
 	for _, relation := range data.Relations {
 		if relation == "" {
 			logger.ErrorContext(ctx, "relation value cannot be empty")
-			return nil, nil, errors.New("relation value cannot be empty")
+			return nil, nil, newFieldError("relations", reasonInvalid, "relation value cannot be empty")
 		}
 	}
+	if data.ExpiresAt != nil && !data.ExpiresAt.After(time.Now()) {
+		logger.ErrorContext(ctx, "expires_at is not in the future", "expires_at", data.ExpiresAt)
+		return nil, nil, newFieldError("expires_at", reasonInvalid, "expires_at must be in the future")
+	}
 
 	return genericMsg, data, nil
 }
 
-// computeMemberPutChanges determines which tuples to write and delete
+// computeMemberPutChanges determines which tuples to write and delete, and
+// which held tuples are renewed: deleted and written again with the
+// condition of the put's expiry.
 func (h *HandlerService) computeMemberPutChanges(
 	ctx context.Context,
 	object, userPrincipal string,
 	data *fgatypes.GenericMemberData,
-) ([]client.ClientTupleKey, []client.ClientTupleKeyWithoutCondition, error) {
+) ([]client.ClientTupleKey, []client.ClientTupleKeyWithoutCondition, []client.ClientTupleKey, error) {
 	// Build mutually exclusive map for quick lookup
 	mutuallyExclusiveMap := make(map[string]bool)
 	for _, rel := range data.MutuallyExclusiveWith {
@@ -298,14 +495,14 @@ func (h *HandlerService) computeMemberPutChanges(
 	}
 
 	// Read existing tuples
-	existingTuples, err := h.fgaService.ReadObjectTuples(ctx, object)
+	existingTuples, err := h.fgaService.ReadCurrentObjectTuples(ctx, object)
 	if err != nil {
 		logger.ErrorContext(ctx, "failed to read existing tuples",
 			errKey, err,
 			"user", userPrincipal,
 			"object", object,
 		)
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Build desired relations set
@@ -317,12 +514,21 @@ func (h *HandlerService) computeMemberPutChanges(
 	// Determine what to write and delete
 	var tuplesToWrite []client.ClientTupleKey
 	var tuplesToDelete []client.ClientTupleKeyWithoutCondition
+	var renewed []client.ClientTupleKey
 	existingRelationsMap := make(map[string]bool)
 
 	for _, tuple := range existingTuples {
 		if tuple.Key.User == userPrincipal {
 			existingRelationsMap[tuple.Key.Relation] = true
 
+			// If this relation is desired under a different expiry, renew it
+			if desiredRelations[tuple.Key.Relation] && h.renewsGrant(tuple.Key.Condition, data.ExpiresAt) {
+				held := h.fgaService.TupleKey(userPrincipal, tuple.Key.Relation, object)
+				held.Condition = tuple.Key.Condition
+				renewed = append(renewed, held)
+				tuplesToWrite = append(tuplesToWrite, h.fgaService.TupleKey(userPrincipal, tuple.Key.Relation, object))
+			}
+
 			// If this relation is mutually exclusive and NOT desired, delete it
 			if mutuallyExclusiveMap[tuple.Key.Relation] && !desiredRelations[tuple.Key.Relation] {
 				tuplesToDelete = append(tuplesToDelete, client.ClientTupleKeyWithoutCondition{
@@ -341,7 +547,7 @@ func (h *HandlerService) computeMemberPutChanges(
 		}
 	}
 
-	return tuplesToWrite, tuplesToDelete, nil
+	return tuplesToWrite, tuplesToDelete, renewed, nil
 }
 
 // applyMemberPutChanges applies the computed tuple changes
@@ -382,17 +588,6 @@ func (h *HandlerService) applyMemberPutChanges(
 	return nil
 }
 
-// sendReplyIfNeeded sends a reply message if requested
-func (h *HandlerService) sendReplyIfNeeded(ctx context.Context, message INatsMsg) error {
-	if message.Reply() != "" {
-		if err := message.Respond([]byte("OK")); err != nil {
-			logger.With(errKey, err).WarnContext(ctx, "failed to send reply")
-			return err
-		}
-	}
-	return nil
-}
-
 // genericMemberRemoveHandler handles universal member_remove operations with support for multiple relations.
 // If relations array is empty, removes ALL relations for the user.
 // If relations array is provided, removes only those specific relations.
@@ -426,36 +621,47 @@ func (h *HandlerService) genericMemberRemoveHandler(ctx context.Context, message
 
 	// Parse generic message
 	genericMsg := new(fgatypes.GenericFGAMessage)
-	if err := json.Unmarshal(message.Data(), genericMsg); err != nil {
+	if err := decodePayload(message.Data(), genericMsg); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse generic message")
 		return err
 	}
 
 	// Validate
-	if genericMsg.ObjectType == "" {
-		logger.ErrorContext(ctx, "object_type is required")
-		return errors.New("object_type is required")
+	objectType, err := canonicalObjectType(ctx, genericMsg.ObjectType)
+	if err != nil {
+		return err
 	}
+	genericMsg.ObjectType = objectType
 	if genericMsg.Operation != "member_remove" {
 		logger.ErrorContext(ctx, "invalid operation for this handler", "operation", genericMsg.Operation)
-		return errors.New("invalid operation for member_remove handler")
+		return newFieldError("operation", reasonInvalid, "invalid operation for member_remove handler")
+	}
+	if err := h.requireReply(ctx, message, genericMsg.ObjectType); err != nil {
+		return err
 	}
 
 	// Parse data field
 	data := new(fgatypes.GenericMemberData)
-	if err := genericMsg.UnmarshalData(data); err != nil {
+	if err := decodeData(genericMsg, data); err != nil {
 		logger.With(errKey, err).ErrorContext(ctx, "failed to parse member data")
 		return err
 	}
 
 	// Validate required fields
+	if err := h.resolveMemberEmail(ctx, data); err != nil {
+		return err
+	}
 	if data.Username == "" {
 		logger.ErrorContext(ctx, "username is required")
-		return errors.New("username is required")
+		return newFieldError("username", reasonRequired, "username is required")
 	}
 	if data.UID == "" {
 		logger.ErrorContext(ctx, "uid is required")
-		return errors.New("uid is required")
+		return newFieldError("uid", reasonRequired, "uid is required")
+	}
+	if err := validateUID(data.UID); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "invalid uid")
+		return err
 	}
 
 	logger.With(
@@ -469,6 +675,14 @@ func (h *HandlerService) genericMemberRemoveHandler(ctx context.Context, message
 	object := buildObjectID(genericMsg.ObjectType, data.UID)
 	userPrincipal := constants.ObjectTypeUser + data.Username
 
+	stale, err := h.isStaleMemberOperation(ctx, object, userPrincipal, data.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if stale {
+		return h.sendReplyIfNeeded(ctx, message, object, 0, 0)
+	}
+
 	// Filter out empty relations and build list of valid relations to delete
 	var validRelations []string
 	for _, relation := range data.Relations {
@@ -478,8 +692,9 @@ func (h *HandlerService) genericMemberRemoveHandler(ctx context.Context, message
 	}
 
 	// If no specific relations provided (or all were empty), delete ALL relations for this user
+	var deleted int
 	if len(validRelations) == 0 {
-		err := h.fgaService.DeleteTuplesByUserAndObject(ctx, userPrincipal, object)
+		deleted, err = h.fgaService.DeleteTuplesByUserAndObject(ctx, userPrincipal, object)
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to remove all member relations",
 				errKey, err,
@@ -494,9 +709,30 @@ func (h *HandlerService) genericMemberRemoveHandler(ctx context.Context, message
 			"object", object,
 		).InfoContext(ctx, "removed all relations from "+genericMsg.ObjectType)
 	} else {
-		// Delete specific relations (only non-empty ones)
-		var tuplesToDelete []client.ClientTupleKeyWithoutCondition
+		// Delete the specific relations the user holds, along with any
+		// exclusive relations the removal would leave in conflict.
+		heldTuples, errRead := h.fgaService.GetTuplesByUserAndObject(ctx, userPrincipal, object)
+		if errRead != nil {
+			logger.ErrorContext(ctx, "failed to read existing tuples",
+				errKey, errRead,
+				"user", userPrincipal,
+				"object", object,
+			)
+			return errRead
+		}
+		held := make(map[string]bool, len(heldTuples))
+		for _, tuple := range heldTuples {
+			held[tuple.Relation] = true
+		}
+
+		tuplesToDelete := h.exclusiveRepairDeletes(ctx, object, userPrincipal, held, validRelations,
+			data.MutuallyExclusiveWith)
+		removed := make(map[string]bool, len(validRelations))
 		for _, relation := range validRelations {
+			if !held[relation] || removed[relation] {
+				continue
+			}
+			removed[relation] = true
 			tuplesToDelete = append(tuplesToDelete, client.ClientTupleKeyWithoutCondition{
 				User:     userPrincipal,
 				Relation: relation,
@@ -505,7 +741,7 @@ func (h *HandlerService) genericMemberRemoveHandler(ctx context.Context, message
 		}
 
 		// Use WriteAndDeleteTuples with empty writes
-		err := h.fgaService.WriteAndDeleteTuples(ctx, nil, tuplesToDelete)
+		err = h.fgaService.WriteAndDeleteTuples(ctx, nil, tuplesToDelete)
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to remove member relations",
 				errKey, err,
@@ -522,15 +758,65 @@ func (h *HandlerService) genericMemberRemoveHandler(ctx context.Context, message
 			"object", object,
 			"deletes", len(tuplesToDelete),
 		).InfoContext(ctx, "removed member from "+genericMsg.ObjectType)
+		deleted = len(tuplesToDelete)
 	}
 
+	h.recordMemberOperation(ctx, object, userPrincipal, data.UpdatedAt)
+
 	// Send reply
-	if message.Reply() != "" {
-		if err := message.Respond([]byte("OK")); err != nil {
-			logger.With(errKey, err).WarnContext(ctx, "failed to send reply")
-			return err
+	return h.sendReplyIfNeeded(ctx, message, object, 0, deleted)
+}
+
+// exclusiveRepairDeletes returns the extra tuples member_remove must delete
+// so that, once the removed relations are gone, the user holds at most one
+// of the mutually exclusive relations (or none, under remove_all). held is
+// the set of relations the user holds. It returns nil when repair is
+// disabled or the remaining state is consistent.
+func (h *HandlerService) exclusiveRepairDeletes(
+	ctx context.Context,
+	object, userPrincipal string,
+	held map[string]bool,
+	removed, exclusive []string,
+) []client.ClientTupleKeyWithoutCondition {
+	if h.exclusiveRepair == exclusiveRepairOff || len(exclusive) < 2 {
+		return nil
+	}
+
+	held = maps.Clone(held)
+	for _, relation := range removed {
+		delete(held, relation)
+	}
+
+	// Collect the remaining exclusive relations in mutually_exclusive_with
+	// order, so keep_first keeps the caller's preferred one.
+	var remaining []string
+	for _, relation := range exclusive {
+		if held[relation] {
+			remaining = append(remaining, relation)
+			delete(held, relation)
 		}
 	}
+	if len(remaining) < 2 {
+		return nil
+	}
+	if h.exclusiveRepair == exclusiveRepairKeepFirst {
+		remaining = remaining[1:]
+	}
 
-	return nil
+	logger.With(
+		"user", userPrincipal,
+		"object", object,
+		"relations", remaining,
+		"policy", h.exclusiveRepair,
+	).WarnContext(ctx, "repairing mutually exclusive relations left after member_remove")
+
+	tuplesToDelete := make([]client.ClientTupleKeyWithoutCondition, 0, len(remaining))
+	for _, relation := range remaining {
+		tuplesToDelete = append(tuplesToDelete, client.ClientTupleKeyWithoutCondition{
+			User:     userPrincipal,
+			Relation: relation,
+			Object:   object,
+		})
+	}
+	return tuplesToDelete
 }
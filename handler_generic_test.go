@@ -0,0 +1,1080 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/nats-io/nats.go/jetstream"
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// buildGenericMessage marshals a GenericFGAMessage into a mock NATS message.
+func buildGenericMessage(t *testing.T, objectType, operation string, data any) *MockNatsMsg {
+	t.Helper()
+	payload, err := json.Marshal(fgatypes.GenericFGAMessage{
+		ObjectType: objectType,
+		Operation:  operation,
+		Data:       data,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal generic message: %v", err)
+	}
+	return CreateMockNatsMsg(payload)
+}
+
+// TestGenericMemberOperations_UpdatedAt tests the out-of-order protection of
+// the member_put and member_remove handlers.
+func TestGenericMemberOperations_UpdatedAt(t *testing.T) {
+	t1 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+
+	memberAt := func(ts time.Time) fgatypes.GenericMemberData {
+		return fgatypes.GenericMemberData{UID: "committee-1", Username: "alice", Relations: []string{"member"}, UpdatedAt: &ts}
+	}
+
+	type step struct {
+		operation string
+		data      fgatypes.GenericMemberData
+	}
+
+	tests := []struct {
+		name         string
+		steps        []step
+		expectMember bool
+	}{
+		{
+			name:  "in-order put then remove applies both",
+			steps: []step{{"member_put", memberAt(t1)}, {"member_remove", memberAt(t2)}},
+		},
+		{
+			name:  "stale put after newer remove is skipped",
+			steps: []step{{"member_remove", memberAt(t2)}, {"member_put", memberAt(t1)}},
+		},
+		{
+			name:         "stale remove after newer put is skipped",
+			steps:        []step{{"member_put", memberAt(t2)}, {"member_remove", memberAt(t1)}},
+			expectMember: true,
+		},
+		{
+			name: "operations without updated_at are always applied",
+			steps: []step{
+				{"member_remove", fgatypes.GenericMemberData{UID: "committee-1", Username: "alice", Relations: []string{"member"}}},
+				{"member_put", fgatypes.GenericMemberData{UID: "committee-1", Username: "alice", Relations: []string{"member"}}},
+			},
+			expectMember: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryFgaClient{
+				MockFgaClient: new(MockFgaClient),
+				tuples:        map[client.ClientTupleKeyWithoutCondition]bool{},
+			}
+			service := setupService()
+			service.fgaService.client = store
+
+			for _, s := range tt.steps {
+				msg := buildGenericMessage(t, "committee", s.operation, s.data)
+				var err error
+				if s.operation == "member_put" {
+					err = service.genericMemberPutHandler(context.Background(), msg)
+				} else {
+					err = service.genericMemberRemoveHandler(context.Background(), msg)
+				}
+				assert.NoError(t, err)
+			}
+
+			member := client.ClientTupleKeyWithoutCondition{
+				User: "user:alice", Relation: "member", Object: "committee:committee-1",
+			}
+			assert.Equal(t, tt.expectMember, store.tuples[member])
+		})
+	}
+}
+
+// TestGenericUpdateAccess_StrictReferences tests that strict reference
+// validation rejects references to an object type the model does not allow.
+func TestGenericUpdateAccess_StrictReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		objectType  string
+		references  map[string][]string
+		expectError bool
+	}{
+		{
+			name:       "v2 past meeting referencing a v2 meeting",
+			objectType: "past_meeting",
+			references: map[string][]string{"meeting": {"meeting-1"}},
+		},
+		{
+			name:        "v2 past meeting referencing a v1 meeting",
+			objectType:  "past_meeting",
+			references:  map[string][]string{"meeting": {"v1_meeting:meeting-1"}},
+			expectError: true,
+		},
+		{
+			name:       "v1 past meeting referencing a v1 meeting",
+			objectType: "v1_past_meeting",
+			references: map[string][]string{"meeting": {"v1_meeting:meeting-1"}},
+		},
+		{
+			name:        "v1 past meeting referencing a v2 meeting",
+			objectType:  "v1_past_meeting",
+			references:  map[string][]string{"meeting": {"meeting-1"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.strictReferences = true
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("ReadAuthorizationModel", mock.Anything).Return(testAuthorizationModelResponse(), nil)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, tt.objectType, "update_access", fgatypes.GenericAccessData{
+				UID:        "past-meeting-1",
+				References: tt.references,
+			})
+			err := service.genericUpdateAccessHandler(context.Background(), msg)
+
+			if tt.expectError {
+				var fieldErr *fieldError
+				if assert.ErrorAs(t, err, &fieldErr) {
+					assert.Equal(t, "references", fieldErr.field)
+					assert.Equal(t, reasonNotAllowed, fieldErr.reason)
+				}
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				fgaClient.AssertNumberOfCalls(t, "Write", 1)
+			}
+		})
+	}
+}
+
+// TestGenericUpdateAccess_PublicMode tests that public=false removes the
+// wildcard viewer for authoritative object types and keeps it for additive
+// ones, while public=true adds it in both modes.
+func TestGenericUpdateAccess_PublicMode(t *testing.T) {
+	wildcardViewer := openfga.Tuple{Key: openfga.TupleKey{User: "user:*", Relation: "viewer", Object: "project:project-1"}}
+
+	tests := []struct {
+		name          string
+		additive      bool
+		public        bool
+		existing      []openfga.Tuple
+		expectWrite   bool
+		expectDeletes int
+		expectWrites  int
+	}{
+		{
+			name:          "authoritative public=false removes wildcard",
+			existing:      []openfga.Tuple{wildcardViewer},
+			expectWrite:   true,
+			expectDeletes: 1,
+		},
+		{
+			name:     "additive public=false keeps wildcard",
+			additive: true,
+			existing: []openfga.Tuple{wildcardViewer},
+		},
+		{
+			name:         "authoritative public=true adds wildcard",
+			public:       true,
+			expectWrite:  true,
+			expectWrites: 1,
+		},
+		{
+			name:         "additive public=true adds wildcard",
+			additive:     true,
+			public:       true,
+			expectWrite:  true,
+			expectWrites: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			if tt.additive {
+				service.additivePublicTypes = map[string]bool{"project": true}
+			}
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: tt.existing}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "project", "update_access", fgatypes.GenericAccessData{
+				UID:    "project-1",
+				Public: tt.public,
+			})
+			err := service.genericUpdateAccessHandler(context.Background(), msg)
+			assert.NoError(t, err)
+
+			if !tt.expectWrite {
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+				return
+			}
+			fgaClient.AssertNumberOfCalls(t, "Write", 1)
+			req := fgaClient.Calls[len(fgaClient.Calls)-1].Arguments.Get(1).(client.ClientWriteRequest)
+			assert.Len(t, req.Writes, tt.expectWrites)
+			assert.Len(t, req.Deletes, tt.expectDeletes)
+		})
+	}
+}
+
+// TestGenericDeleteAccess_Cascade tests that dependent objects listed in
+// cascade are deleted before the object itself, and that a failed or invalid
+// cascade leaves the object in place.
+func TestGenericDeleteAccess_Cascade(t *testing.T) {
+	tuplesFor := func(object string) []openfga.Tuple {
+		return []openfga.Tuple{{Key: openfga.TupleKey{User: "meeting:m1", Relation: "meeting", Object: object}}}
+	}
+
+	tests := []struct {
+		name          string
+		cascade       []string
+		failObject    string
+		expectDeleted []string
+		expectError   bool
+	}{
+		{
+			name:          "attachments are deleted before the meeting",
+			cascade:       []string{"meeting_attachment:a1", "meeting_attachment:a2"},
+			expectDeleted: []string{"meeting_attachment:a1", "meeting_attachment:a2", "meeting:m1"},
+		},
+		{
+			name:          "no cascade deletes only the meeting",
+			expectDeleted: []string{"meeting:m1"},
+		},
+		{
+			name:        "invalid cascade object is rejected before any delete",
+			cascade:     []string{"meeting_attachment:a1", "a2"},
+			expectError: true,
+		},
+		{
+			name:          "failed cascade leaves the meeting in place",
+			cascade:       []string{"meeting_attachment:a1", "meeting_attachment:a2"},
+			failObject:    "meeting_attachment:a2",
+			expectDeleted: []string{"meeting_attachment:a1"},
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			for _, object := range append([]string{"meeting:m1"}, tt.cascade...) {
+				fgaClient.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+					return *req.Object == object
+				}), mock.Anything).Return(&client.ClientReadResponse{Tuples: tuplesFor(object)}, nil)
+			}
+
+			var deleted []string
+			isFailObject := func(req client.ClientWriteRequest) bool { return req.Deletes[0].Object == tt.failObject }
+			fgaClient.On("Write", mock.Anything, mock.MatchedBy(isFailObject)).
+				Return((*client.ClientWriteResponse)(nil), errors.New("store unavailable"))
+			fgaClient.On("Write", mock.Anything, mock.MatchedBy(func(req client.ClientWriteRequest) bool {
+				return !isFailObject(req)
+			})).Run(func(args mock.Arguments) {
+				deleted = append(deleted, args.Get(1).(client.ClientWriteRequest).Deletes[0].Object)
+			}).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "meeting", "delete_access", fgatypes.GenericDeleteData{
+				UID:     "m1",
+				Cascade: tt.cascade,
+			})
+			err := service.genericDeleteAccessHandler(context.Background(), msg)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectDeleted, deleted)
+		})
+	}
+}
+
+// TestGenericDeleteAccess_Verbose tests that a verbose delete_access replies
+// with a summary of the tuples removed from the object and its cascade, and
+// that other deletes keep the usual reply.
+func TestGenericDeleteAccess_Verbose(t *testing.T) {
+	manyViewers := make([]openfga.Tuple, 150)
+	for i := range manyViewers {
+		manyViewers[i] = openfga.Tuple{Key: openfga.TupleKey{User: fmt.Sprintf("user:u%d", i), Relation: "viewer", Object: "meeting:m1"}}
+	}
+
+	tests := []struct {
+		name          string
+		verbose       bool
+		meetingTuples []openfga.Tuple
+		expectReply   string
+		expectSummary *fgatypes.DeleteAccessResponse
+	}{
+		{
+			name:    "verbose delete lists the removed tuples",
+			verbose: true,
+			meetingTuples: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: "meeting:m1"}},
+				{Key: openfga.TupleKey{User: "project:p1", Relation: "project", Object: "meeting:m1"}},
+			},
+			expectSummary: &fgatypes.DeleteAccessResponse{
+				Deleted: 3,
+				Tuples: []string{
+					"meeting_attachment:a1#meeting@meeting:m1",
+					"meeting:m1#viewer@user:alice",
+					"meeting:m1#project@project:p1",
+				},
+			},
+		},
+		{
+			name:          "verbose delete of many tuples lists a sample",
+			verbose:       true,
+			meetingTuples: manyViewers,
+			expectSummary: &fgatypes.DeleteAccessResponse{Deleted: 151, Truncated: true},
+		},
+		{
+			name: "non-verbose delete sends the usual reply",
+			meetingTuples: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: "meeting:m1"}},
+			},
+			expectReply: "OK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+				return *req.Object == "meeting:m1"
+			}), mock.Anything).Return(&client.ClientReadResponse{Tuples: tt.meetingTuples}, nil)
+			fgaClient.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+				return *req.Object == "meeting_attachment:a1"
+			}), mock.Anything).Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "meeting:m1", Relation: "meeting", Object: "meeting_attachment:a1"}},
+			}}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "meeting", "delete_access", fgatypes.GenericDeleteData{
+				UID:     "m1",
+				Cascade: []string{"meeting_attachment:a1"},
+				Verbose: tt.verbose,
+			})
+			msg.reply = "_INBOX.test"
+			var reply []byte
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				reply = args.Get(0).([]byte)
+			}).Return(nil).Once()
+
+			assert.NoError(t, service.genericDeleteAccessHandler(context.Background(), msg))
+
+			if tt.expectSummary == nil {
+				assert.Equal(t, tt.expectReply, string(reply))
+				return
+			}
+			var summary fgatypes.DeleteAccessResponse
+			assert.NoError(t, json.Unmarshal(reply, &summary))
+			assert.Equal(t, tt.expectSummary.Deleted, summary.Deleted)
+			assert.Equal(t, tt.expectSummary.Truncated, summary.Truncated)
+			if tt.expectSummary.Truncated {
+				assert.Len(t, summary.Tuples, maxDeleteSummaryTuples)
+			} else {
+				assert.Equal(t, tt.expectSummary.Tuples, summary.Tuples)
+			}
+		})
+	}
+}
+
+// TestGenericDeleteAccess_GracePeriod tests that delete_access is deferred
+// by the grace period, is applied by the sweep once due, and that an
+// update_access in the meantime cancels it.
+func TestGenericDeleteAccess_GracePeriod(t *testing.T) {
+	tests := []struct {
+		name          string
+		updateBefore  bool
+		updateFails   bool
+		expectDeletes int
+	}{
+		{name: "delete is applied after the grace period", expectDeletes: 1},
+		{name: "update within the grace period cancels the delete", updateBefore: true, expectDeletes: 0},
+		{name: "update failing to sync leaves the delete pending", updateBefore: true, updateFails: true, expectDeletes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.deleteGracePeriod = 5 * time.Minute
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			if tt.updateFails {
+				fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+					Return((*client.ClientReadResponse)(nil), errors.New("read failed")).Once()
+			}
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+					{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: "meeting:m1"}},
+				}}, nil)
+			deletes := 0
+			fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				deletes += len(args.Get(1).(client.ClientWriteRequest).Deletes)
+			}).Return(&client.ClientWriteResponse{}, nil)
+
+			ctx := context.Background()
+			msg := buildGenericMessage(t, "meeting", "delete_access", fgatypes.GenericDeleteData{UID: "m1"})
+			assert.NoError(t, service.genericDeleteAccessHandler(ctx, msg))
+			fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+
+			// Not yet due.
+			applied, err := service.sweepPendingDeletes(ctx, time.Now())
+			assert.NoError(t, err)
+			assert.Zero(t, applied)
+
+			if tt.updateBefore {
+				msg = buildGenericMessage(t, "meeting", "update_access", fgatypes.GenericAccessData{
+					UID:       "m1",
+					Relations: map[string][]string{"viewer": {"alice"}},
+				})
+				err := service.genericUpdateAccessHandler(ctx, msg)
+				assert.Equal(t, tt.updateFails, err != nil)
+			}
+
+			applied, err = service.sweepPendingDeletes(ctx, time.Now().Add(6*time.Minute))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectDeletes, applied)
+			assert.Equal(t, tt.expectDeletes, deletes)
+
+			// A delete is applied once.
+			applied, err = service.sweepPendingDeletes(ctx, time.Now().Add(time.Hour))
+			assert.NoError(t, err)
+			assert.Zero(t, applied)
+		})
+	}
+}
+
+// TestSweepPendingDeletes_Retry tests that a deferred delete that fails is
+// scheduled again and applied by a later sweep, and that one not yet due is
+// left pending.
+func TestSweepPendingDeletes_Retry(t *testing.T) {
+	service := setupService()
+	bucket := service.fgaService.stateBucket.(*MockKeyValue)
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), errors.New("read failed")).Once()
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: "meeting:m1"}},
+		}}, nil)
+	fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil).Once()
+
+	ctx := context.Background()
+	now := time.Now()
+	assert.NoError(t, service.fgaService.SchedulePendingDelete(ctx, pendingDelete{Object: "meeting:m1", DueAt: now}))
+	assert.NoError(t, service.fgaService.SchedulePendingDelete(ctx,
+		pendingDelete{Object: "meeting:m2", DueAt: now.Add(time.Hour)}))
+	revision := bucket.revisions[pendingDeleteKey("meeting:m2")]
+	later := now.Add(time.Minute)
+
+	applied, err := service.sweepPendingDeletes(ctx, later)
+	assert.ErrorContains(t, err, "read failed")
+	assert.Zero(t, applied)
+	assert.Equal(t, revision, bucket.revisions[pendingDeleteKey("meeting:m2")], "pending delete rewritten")
+
+	applied, err = service.sweepPendingDeletes(ctx, later)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	fgaClient.AssertExpectations(t)
+}
+
+// TestGenericMemberRemove_ExclusiveRepair tests that member_remove repairs
+// mutually exclusive relations left held together, per the repair policy.
+func TestGenericMemberRemove_ExclusiveRepair(t *testing.T) {
+	held := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "host", Object: "meeting:m1"}},
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "participant", Object: "meeting:m1"}},
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "organizer", Object: "meeting:m1"}},
+		{Key: openfga.TupleKey{User: "user:bob", Relation: "host", Object: "meeting:m1"}},
+	}
+
+	tests := []struct {
+		name          string
+		policy        exclusiveRepairPolicy
+		relations     []string
+		exclusive     []string
+		expectDeleted []string
+	}{
+		{
+			name:          "repair disabled deletes only the requested relation",
+			relations:     []string{"organizer"},
+			exclusive:     []string{"host", "participant"},
+			expectDeleted: []string{"organizer"},
+		},
+		{
+			name:          "relations not held are not deleted",
+			policy:        exclusiveRepairRemoveAll,
+			relations:     []string{"organizer", "viewer"},
+			exclusive:     []string{"host", "participant"},
+			expectDeleted: []string{"host", "participant", "organizer"},
+		},
+		{
+			name:          "keep_first keeps the first listed exclusive relation",
+			policy:        exclusiveRepairKeepFirst,
+			relations:     []string{"organizer"},
+			exclusive:     []string{"participant", "host"},
+			expectDeleted: []string{"host", "organizer"},
+		},
+		{
+			name:          "remove_all deletes every remaining exclusive relation",
+			policy:        exclusiveRepairRemoveAll,
+			relations:     []string{"organizer"},
+			exclusive:     []string{"host", "participant"},
+			expectDeleted: []string{"host", "participant", "organizer"},
+		},
+		{
+			name:          "consistent state after removal needs no repair",
+			policy:        exclusiveRepairRemoveAll,
+			relations:     []string{"host"},
+			exclusive:     []string{"host", "participant"},
+			expectDeleted: []string{"host"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.exclusiveRepair = tt.policy
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: held}, nil)
+
+			var deleted []string
+			fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				for _, tuple := range args.Get(1).(client.ClientWriteRequest).Deletes {
+					assert.Equal(t, "user:alice", tuple.User)
+					deleted = append(deleted, tuple.Relation)
+				}
+			}).Return(&client.ClientWriteResponse{}, nil).Once()
+
+			msg := buildGenericMessage(t, "meeting", "member_remove", fgatypes.GenericMemberData{
+				UID:                   "m1",
+				Username:              "alice",
+				Relations:             tt.relations,
+				MutuallyExclusiveWith: tt.exclusive,
+			})
+			err := service.genericMemberRemoveHandler(context.Background(), msg)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectDeleted, deleted)
+		})
+	}
+}
+
+// TestGenericMemberRemove_MissingTuple tests that removing a member relation
+// deleted after it was read succeeds, so a repeated remove does not fail.
+func TestGenericMemberRemove_MissingTuple(t *testing.T) {
+	service := setupService()
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{
+		Tuples: []openfga.Tuple{{Key: openfga.TupleKey{User: "user:alice", Relation: "member", Object: "committee:c1"}}},
+	}, nil)
+	fgaClient.On("Write", mock.Anything, mock.Anything).Return((*client.ClientWriteResponse)(nil),
+		makeValidationError("cannot delete a tuple which does not exist: user: 'user:alice', relation: 'member', object: 'committee:c1': invalid write input"),
+	).Once()
+
+	msg := buildGenericMessage(t, "committee", "member_remove", fgatypes.GenericMemberData{
+		UID:       "c1",
+		Username:  "alice",
+		Relations: []string{"member"},
+	})
+	err := service.genericMemberRemoveHandler(context.Background(), msg)
+
+	assert.NoError(t, err)
+	fgaClient.AssertNumberOfCalls(t, "Write", 1)
+}
+
+// TestGenericMemberPut_PrivateObjectType tests that member_put cannot grant
+// the wildcard user on an object of a private type.
+func TestGenericMemberPut_PrivateObjectType(t *testing.T) {
+	service := setupService()
+	service.privateObjectTypes = map[string]bool{"committee": true}
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+
+	msg := buildGenericMessage(t, "committee", "member_put", fgatypes.GenericMemberData{
+		UID:       "c1",
+		Username:  "*",
+		Relations: []string{"viewer"},
+	})
+	err := service.genericMemberPutHandler(context.Background(), msg)
+
+	assert.EqualError(t, err, "committee objects must not be public")
+	fgaClient.AssertNotCalled(t, "Read", mock.Anything, mock.Anything, mock.Anything)
+	fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+}
+
+// TestGenericHandlers_RejectSeparatorUIDs tests that UIDs containing tuple
+// format separators, such as URNs, are rejected before any OpenFGA call.
+func TestGenericHandlers_RejectSeparatorUIDs(t *testing.T) {
+	const urnUID = "urn:lfx:committee:123"
+
+	tests := []struct {
+		name      string
+		operation string
+		data      any
+		handle    func(*HandlerService, context.Context, INatsMsg) error
+	}{
+		{
+			name:      "update_access",
+			operation: "update_access",
+			data:      fgatypes.GenericAccessData{UID: urnUID, Public: true},
+			handle:    (*HandlerService).genericUpdateAccessHandler,
+		},
+		{
+			name:      "delete_access",
+			operation: "delete_access",
+			data:      fgatypes.GenericDeleteData{UID: urnUID},
+			handle:    (*HandlerService).genericDeleteAccessHandler,
+		},
+		{
+			name:      "member_put",
+			operation: "member_put",
+			data:      fgatypes.GenericMemberData{UID: urnUID, Username: "alice", Relations: []string{"member"}},
+			handle:    (*HandlerService).genericMemberPutHandler,
+		},
+		{
+			name:      "member_remove",
+			operation: "member_remove",
+			data:      fgatypes.GenericMemberData{UID: urnUID, Username: "alice"},
+			handle:    (*HandlerService).genericMemberRemoveHandler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+
+			msg := buildGenericMessage(t, "committee", tt.operation, tt.data)
+			err := tt.handle(service, context.Background(), msg)
+
+			assert.ErrorContains(t, err, "must not contain")
+			fgaClient.AssertNotCalled(t, "Read", mock.Anything, mock.Anything, mock.Anything)
+			fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// TestGenericHandlers_RequireReply tests that sync messages for types listed
+// in REPLY_REQUIRED_OBJECT_TYPES are rejected before any OpenFGA call when
+// sent without a reply inbox.
+func TestGenericHandlers_RequireReply(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		data      any
+		handle    func(*HandlerService, context.Context, INatsMsg) error
+	}{
+		{
+			name:      "update_access",
+			operation: "update_access",
+			data:      fgatypes.GenericAccessData{UID: "c1", Public: true},
+			handle:    (*HandlerService).genericUpdateAccessHandler,
+		},
+		{
+			name:      "delete_access",
+			operation: "delete_access",
+			data:      fgatypes.GenericDeleteData{UID: "c1"},
+			handle:    (*HandlerService).genericDeleteAccessHandler,
+		},
+		{
+			name:      "member_put",
+			operation: "member_put",
+			data:      fgatypes.GenericMemberData{UID: "c1", Username: "alice", Relations: []string{"member"}},
+			handle:    (*HandlerService).genericMemberPutHandler,
+		},
+		{
+			name:      "member_remove",
+			operation: "member_remove",
+			data:      fgatypes.GenericMemberData{UID: "c1", Username: "alice"},
+			handle:    (*HandlerService).genericMemberRemoveHandler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.replyRequiredTypes = map[string]bool{"committee": true}
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+
+			msg := buildGenericMessage(t, "committee", tt.operation, tt.data)
+			err := tt.handle(service, context.Background(), msg)
+
+			assert.EqualError(t, err, "committee messages must be sent with a reply inbox")
+			var fieldErr *fieldError
+			if assert.ErrorAs(t, err, &fieldErr) {
+				assert.Equal(t, "reply", fieldErr.field)
+				assert.Equal(t, reasonRequired, fieldErr.reason)
+			}
+			fgaClient.AssertNotCalled(t, "Read", mock.Anything, mock.Anything, mock.Anything)
+			fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+		})
+	}
+
+	service := setupService()
+	service.replyRequiredTypes = map[string]bool{"committee": true}
+	msg := CreateMockNatsMsg(nil)
+	assert.NoError(t, service.requireReply(context.Background(), msg, "project"), "type not listed")
+	msg.reply = "_INBOX.1"
+	assert.NoError(t, service.requireReply(context.Background(), msg, "committee"), "reply inbox set")
+}
+
+func TestValidateUID(t *testing.T) {
+	assert.NoError(t, validateUID("committee-123"))
+	assert.NoError(t, validateUID("a1b2c3d4-e5f6-7890-abcd-ef1234567890"))
+	assert.Error(t, validateUID("urn:lfx:committee:123"))
+	assert.Error(t, validateUID("committee#member"))
+	assert.Error(t, validateUID("alice@example.com"))
+}
+
+// TestGenericHandlers_CanonicalizeObjectType tests that mixed-case object
+// types are synced under the lowercase type.
+func TestGenericHandlers_CanonicalizeObjectType(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectType string
+		operation  string
+		data       any
+		handle     func(*HandlerService, context.Context, INatsMsg) error
+	}{
+		{
+			name:       "update_access",
+			objectType: "Committee",
+			operation:  "update_access",
+			data:       fgatypes.GenericAccessData{UID: "c1", Relations: map[string][]string{"writer": {"alice"}}},
+			handle:     (*HandlerService).genericUpdateAccessHandler,
+		},
+		{
+			name:       "member_put",
+			objectType: "COMMITTEE",
+			operation:  "member_put",
+			data:       fgatypes.GenericMemberData{UID: "c1", Username: "alice", Relations: []string{"member"}},
+			handle:     (*HandlerService).genericMemberPutHandler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+			var written []string
+			fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				for _, tuple := range args.Get(1).(client.ClientWriteRequest).Writes {
+					written = append(written, tuple.Object)
+				}
+			}).Return(&client.ClientWriteResponse{}, nil).Once()
+
+			msg := buildGenericMessage(t, tt.objectType, tt.operation, tt.data)
+			assert.NoError(t, tt.handle(service, context.Background(), msg))
+			assert.Equal(t, []string{"committee:c1"}, written)
+		})
+	}
+}
+
+func TestCanonicalObjectType(t *testing.T) {
+	ctx := context.Background()
+	for _, objectType := range []string{"committee", "Committee", "PAST_MEETING"} {
+		canonical, err := canonicalObjectType(ctx, objectType)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.ToLower(objectType), canonical)
+	}
+	for _, objectType := range []string{"", "committee:c1", "team#member", "user@x", "past meeting"} {
+		_, err := canonicalObjectType(ctx, objectType)
+		assert.Error(t, err, objectType)
+	}
+}
+
+// TestGenericHandlers_RelationValidation tests that tuples using relations
+// absent from the authorization model are written in warn mode and rejected
+// in strict mode.
+func TestGenericHandlers_RelationValidation(t *testing.T) {
+	updateAccess := func(relation string) fgatypes.GenericAccessData {
+		return fgatypes.GenericAccessData{UID: "project-1", Relations: map[string][]string{relation: {"alice"}}}
+	}
+	memberPut := func(relation string) fgatypes.GenericMemberData {
+		return fgatypes.GenericMemberData{UID: "project-1", Username: "alice", Relations: []string{relation}}
+	}
+
+	tests := []struct {
+		name        string
+		mode        relationValidationMode
+		operation   string
+		data        any
+		handle      func(*HandlerService, context.Context, INatsMsg) error
+		expectError bool
+	}{
+		{
+			name:      "update_access with defined relation in strict mode",
+			mode:      relationValidationStrict,
+			operation: "update_access",
+			data:      updateAccess("writer"),
+			handle:    (*HandlerService).genericUpdateAccessHandler,
+		},
+		{
+			name:        "update_access with undefined relation in strict mode",
+			mode:        relationValidationStrict,
+			operation:   "update_access",
+			data:        updateAccess("owner"),
+			handle:      (*HandlerService).genericUpdateAccessHandler,
+			expectError: true,
+		},
+		{
+			name:      "update_access with undefined relation in warn mode",
+			mode:      relationValidationWarn,
+			operation: "update_access",
+			data:      updateAccess("owner"),
+			handle:    (*HandlerService).genericUpdateAccessHandler,
+		},
+		{
+			name:        "member_put with undefined relation in strict mode",
+			mode:        relationValidationStrict,
+			operation:   "member_put",
+			data:        memberPut("owner"),
+			handle:      (*HandlerService).genericMemberPutHandler,
+			expectError: true,
+		},
+		{
+			name:      "member_put with undefined relation in warn mode",
+			mode:      relationValidationWarn,
+			operation: "member_put",
+			data:      memberPut("owner"),
+			handle:    (*HandlerService).genericMemberPutHandler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.relationValidation = tt.mode
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("ReadAuthorizationModel", mock.Anything).Return(testAuthorizationModelResponse(), nil)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "project", tt.operation, tt.data)
+			err := tt.handle(service, context.Background(), msg)
+
+			if tt.expectError {
+				assert.ErrorContains(t, err, "project:project-1#owner@user:alice")
+				var fieldErr *fieldError
+				if assert.ErrorAs(t, err, &fieldErr) {
+					assert.Equal(t, "relations", fieldErr.field)
+					assert.Equal(t, reasonNotAllowed, fieldErr.reason)
+				}
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				fgaClient.AssertNumberOfCalls(t, "Write", 1)
+			}
+		})
+	}
+}
+
+// TestGenericUpdateAccess_ExpectedVersion tests optimistic concurrency for
+// versioned object types.
+func TestGenericUpdateAccess_ExpectedVersion(t *testing.T) {
+	version := func(v uint64) *uint64 { return &v }
+
+	tests := []struct {
+		name          string
+		objectType    string
+		storedVersion *uint64
+		expected      *uint64
+		expectError   bool
+		expectReply   string
+		expectVersion string
+	}{
+		{
+			name:          "matching token applies and bumps the version",
+			objectType:    "committee",
+			storedVersion: version(2),
+			expected:      version(2),
+			expectReply:   "OK",
+			expectVersion: "3",
+		},
+		{
+			name:          "first versioned update with token 0",
+			objectType:    "committee",
+			expected:      version(0),
+			expectReply:   "OK",
+			expectVersion: "1",
+		},
+		{
+			name:          "mismatching token is rejected",
+			objectType:    "committee",
+			storedVersion: version(2),
+			expected:      version(1),
+			expectError:   true,
+			expectReply:   `{"status":"error","error":"version conflict: expected version 1, current version 2"}`,
+			expectVersion: "2",
+		},
+		{
+			name:          "missing token is rejected",
+			objectType:    "committee",
+			storedVersion: version(2),
+			expectError:   true,
+			expectReply: `{"status":"error","error":"expected_version is required for committee",` +
+				`"field":"expected_version","reason":"required"}`,
+			expectVersion: "2",
+		},
+		{
+			name:        "unversioned object type ignores the token",
+			objectType:  "project",
+			expectReply: "OK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.versionedObjectTypes = map[string]bool{"committee": true}
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			object := buildObjectID(tt.objectType, "obj-1")
+			kv := service.fgaService.stateBucket
+			if tt.storedVersion != nil {
+				_, err := kv.PutString(context.Background(), objectVersionKey(object), fmt.Sprint(*tt.storedVersion))
+				assert.NoError(t, err)
+			}
+
+			msg := buildGenericMessage(t, tt.objectType, "update_access", fgatypes.GenericAccessData{
+				UID:             "obj-1",
+				Public:          true,
+				ExpectedVersion: tt.expected,
+			})
+			msg.reply = "reply.inbox"
+			msg.On("Respond", []byte(tt.expectReply)).Return(nil).Once()
+
+			err := service.genericUpdateAccessHandler(context.Background(), msg)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+			msg.AssertExpectations(t)
+
+			entry, err := kv.Get(context.Background(), objectVersionKey(object))
+			if tt.expectVersion == "" {
+				assert.ErrorIs(t, err, jetstream.ErrKeyNotFound)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectVersion, string(entry.Value()))
+			}
+		})
+	}
+}
+
+// memoryFgaClient is an in-memory tuple store for tests exercising
+// read-modify-write interleavings. Only Read and Write are implemented.
+type memoryFgaClient struct {
+	*MockFgaClient
+	mu     sync.Mutex
+	tuples map[client.ClientTupleKeyWithoutCondition]bool
+	// readBarrier, when set, holds each read until it is released, so tests
+	// can make several handlers read before any of them writes.
+	readBarrier *sync.WaitGroup
+}
+
+// Read implements [IFgaClient.Read], returning the stored tuples of the
+// requested object.
+func (c *memoryFgaClient) Read(_ context.Context, req client.ClientReadRequest, _ client.ClientReadOptions) (
+	*client.ClientReadResponse, error,
+) {
+	c.mu.Lock()
+	var tuples []openfga.Tuple
+	for key := range c.tuples {
+		if key.Object == *req.Object {
+			tuples = append(tuples, openfga.Tuple{Key: openfga.TupleKey{User: key.User, Relation: key.Relation, Object: key.Object}})
+		}
+	}
+	c.mu.Unlock()
+	if c.readBarrier != nil {
+		c.readBarrier.Done()
+		c.readBarrier.Wait()
+	}
+	return &client.ClientReadResponse{Tuples: tuples}, nil
+}
+
+// Write implements [IFgaClient.Write], applying the request's deletes and
+// writes.
+func (c *memoryFgaClient) Write(_ context.Context, req client.ClientWriteRequest) (*client.ClientWriteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tuple := range req.Deletes {
+		delete(c.tuples, tuple)
+	}
+	for _, tuple := range req.Writes {
+		c.tuples[client.ClientTupleKeyWithoutCondition{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object}] = true
+	}
+	return &client.ClientWriteResponse{}, nil
+}
+
+// TestGenericMemberPut_ConcurrentParticipants tests that member_put messages
+// for different participants of one past meeting, handled concurrently from
+// the same snapshot of its tuples, do not clobber each other: each put only
+// deletes its own user's tuples.
+func TestGenericMemberPut_ConcurrentParticipants(t *testing.T) {
+	barrier := new(sync.WaitGroup)
+	barrier.Add(2)
+	store := &memoryFgaClient{
+		MockFgaClient: new(MockFgaClient),
+		tuples: map[client.ClientTupleKeyWithoutCondition]bool{
+			{User: "user:alice", Relation: "participant", Object: "past_meeting:pm1"}: true,
+			{User: "user:bob", Relation: "host", Object: "past_meeting:pm1"}:          true,
+		},
+		readBarrier: barrier,
+	}
+	service := setupService()
+	service.fgaService.client = store
+
+	puts := map[string]string{"alice": "host", "bob": "participant"}
+	var wg sync.WaitGroup
+	for username, relation := range puts {
+		msg := buildGenericMessage(t, "past_meeting", "member_put", fgatypes.GenericMemberData{
+			UID:                   "pm1",
+			Username:              username,
+			Relations:             []string{relation},
+			MutuallyExclusiveWith: []string{"host", "participant"},
+		})
+		wg.Go(func() {
+			assert.NoError(t, service.genericMemberPutHandler(context.Background(), msg))
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[client.ClientTupleKeyWithoutCondition]bool{
+		{User: "user:alice", Relation: "host", Object: "past_meeting:pm1"}:      true,
+		{User: "user:bob", Relation: "participant", Object: "past_meeting:pm1"}: true,
+	}, store.tuples)
+}
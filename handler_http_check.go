@@ -0,0 +1,121 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	nats "github.com/nats-io/nats.go"
+)
+
+// defaultHTTPCheckMaxBody bounds HTTP check request bodies when
+// MAX_MESSAGE_SIZE is unset.
+const defaultHTTPCheckMaxBody = 1 << 20
+
+// httpCheckHandler returns the HTTP POST /check handler, answering the same
+// access checks as lfx.access_check.request for callers outside the NATS
+// mesh. A text/plain body uses the NATS payload and reply formats; an
+// application/json body is a types.CheckRequest answered with a
+// types.CheckResponse. The cache scope headers work as they do over NATS.
+func (h *HandlerService) httpCheckHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		asJSON := mediaType == "application/json"
+
+		limit := int64(defaultHTTPCheckMaxBody)
+		if maxMessageSize > 0 {
+			limit = int64(maxMessageSize)
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+		if err != nil {
+			writeCheckError(w, asJSON, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+
+		payload := body
+		if asJSON {
+			var req types.CheckRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				writeCheckError(w, asJSON, http.StatusBadRequest, "invalid request payload")
+				return
+			}
+			payload = []byte(strings.Join(req.Checks, "\n"))
+		}
+
+		ctx := r.Context()
+		response, err := h.checkAccess(ctx, payload, nats.Header(r.Header))
+		if err != nil {
+			status, reply := http.StatusInternalServerError, err.Error()
+			var checkErr *accessCheckError
+			if errors.As(err, &checkErr) {
+				reply = checkErr.reply
+				if checkErr.invalid {
+					status = http.StatusBadRequest
+				}
+			}
+			writeCheckError(w, asJSON, status, reply)
+			return
+		}
+
+		if !asJSON {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if _, err := w.Write(response); err != nil {
+				logger.With(errKey, err).WarnContext(ctx, "failed to write check response")
+			}
+			return
+		}
+		writeCheckJSON(w, http.StatusOK, types.CheckResponse{Results: parseCheckResults(response)})
+	})
+}
+
+// parseCheckResults parses checkAccess response lines, object#relation@user
+// and the result separated by a tab, followed by the guarded flag for checks
+// denied by the check guard, into check results.
+func parseCheckResults(response []byte) []types.CheckResult {
+	results := make([]types.CheckResult, 0, bytes.Count(response, []byte("\n"))+1)
+	for _, line := range strings.Split(string(response), "\n") {
+		check, result, found := strings.Cut(line, "\t")
+		if !found {
+			continue
+		}
+		allowed, flag, _ := strings.Cut(result, "\t")
+		results = append(results, types.CheckResult{
+			Check:   check,
+			Allowed: allowed == trueString,
+			Guarded: flag == checkGuardFlag,
+		})
+	}
+	return results
+}
+
+// writeCheckError writes an HTTP check failure in the request's format.
+func writeCheckError(w http.ResponseWriter, asJSON bool, status int, reply string) {
+	if !asJSON {
+		http.Error(w, reply, status)
+		return
+	}
+	writeCheckJSON(w, status, types.CheckResponse{Results: []types.CheckResult{}, Error: reply})
+}
+
+// writeCheckJSON writes resp as the JSON body of an HTTP check response.
+func writeCheckJSON(w http.ResponseWriter, status int, resp types.CheckResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.With(errKey, err).Warn("failed to write check response")
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	openfga "github.com/openfga/go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestHTTPCheckHandler tests the HTTP POST /check endpoint in both text and
+// JSON formats, including its error statuses.
+func TestHTTPCheckHandler(t *testing.T) {
+	allowed := &openfga.BatchCheckResponse{Result: &map[string]openfga.BatchCheckSingleResult{
+		"1": {Allowed: openfga.PtrBool(true)},
+	}}
+
+	tests := []struct {
+		name         string
+		method       string
+		contentType  string
+		body         string
+		header       map[string]string
+		checkResp    *openfga.BatchCheckResponse
+		checkErr     error
+		expectStatus int
+		expectBody   string
+		expectJSON   *types.CheckResponse
+	}{
+		{
+			name:         "text check",
+			method:       http.MethodPost,
+			contentType:  "text/plain",
+			body:         "project:123#writer@user:456",
+			checkResp:    allowed,
+			expectStatus: http.StatusOK,
+			expectBody:   "project:123#writer@user:456\ttrue",
+		},
+		{
+			name:         "json check",
+			method:       http.MethodPost,
+			contentType:  "application/json; charset=utf-8",
+			body:         `{"checks": ["project:123#writer@user:456"]}`,
+			checkResp:    allowed,
+			expectStatus: http.StatusOK,
+			expectJSON: &types.CheckResponse{Results: []types.CheckResult{
+				{Check: "project:123#writer@user:456", Allowed: true},
+			}},
+		},
+		{
+			name:         "method not allowed",
+			method:       http.MethodGet,
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "malformed check",
+			method:       http.MethodPost,
+			contentType:  "text/plain",
+			body:         "project:123",
+			expectStatus: http.StatusBadRequest,
+			expectBody:   "failed to extract check requests\n",
+		},
+		{
+			name:         "malformed json",
+			method:       http.MethodPost,
+			contentType:  "application/json",
+			body:         `{"checks": `,
+			expectStatus: http.StatusBadRequest,
+			expectJSON:   &types.CheckResponse{Results: []types.CheckResult{}, Error: "invalid request payload"},
+		},
+		{
+			name:         "no checks",
+			method:       http.MethodPost,
+			contentType:  "application/json",
+			body:         `{"checks": []}`,
+			expectStatus: http.StatusBadRequest,
+			expectJSON:   &types.CheckResponse{Results: []types.CheckResult{}, Error: "no check requests found"},
+		},
+		{
+			name:         "invalid cache scope",
+			method:       http.MethodPost,
+			contentType:  "text/plain",
+			body:         "project:123#writer@user:456",
+			header:       map[string]string{cacheScopeHeader: "admin.view"},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "openfga failure",
+			method:       http.MethodPost,
+			contentType:  "text/plain",
+			body:         "project:123#writer@user:456",
+			checkErr:     errors.New("openfga unavailable"),
+			expectStatus: http.StatusInternalServerError,
+			expectBody:   "failed to check relationship\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			if tt.checkResp != nil || tt.checkErr != nil {
+				fgaClient.On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).Return(tt.checkResp, tt.checkErr)
+			}
+
+			req := httptest.NewRequest(tt.method, "/check", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			service.httpCheckHandler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			if tt.expectBody != "" {
+				assert.Equal(t, tt.expectBody, rec.Body.String())
+			}
+			if tt.expectJSON != nil {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				var resp types.CheckResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, *tt.expectJSON, resp)
+			}
+		})
+	}
+}
+
+// TestHTTPCheckHandler_MatchesNats asserts that the HTTP endpoint and the NATS
+// handler return the same result for the same checks.
+func TestHTTPCheckHandler_MatchesNats(t *testing.T) {
+	service := setupService()
+	service.fgaService.client.(*MockFgaClient).On("BatchCheck", mock.Anything, mock.Anything, mock.Anything).
+		Return(&openfga.BatchCheckResponse{Result: &map[string]openfga.BatchCheckSingleResult{
+			"1": {Allowed: openfga.PtrBool(false)},
+		}}, nil)
+	const payload = "project:123#viewer@user:456"
+
+	var natsReply []byte
+	msg := CreateMockNatsMsg([]byte(payload))
+	msg.reply = "reply.subject"
+	msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+		natsReply, _ = args.Get(0).([]byte)
+	}).Return(nil)
+	assert.NoError(t, service.accessCheckHandler(t.Context(), msg))
+
+	req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(payload))
+	rec := httptest.NewRecorder()
+	service.httpCheckHandler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, string(natsReply), rec.Body.String())
+}
+
+// TestHTTPCheckHandler_Guarded asserts that JSON callers can tell checks
+// denied by the check guard from ordinary denies.
+func TestHTTPCheckHandler_Guarded(t *testing.T) {
+	service := setupService()
+	service.fgaService.checkGuard = checkGuard{timeout: 10 * time.Millisecond, deny: true}
+	mockSlowBatchCheck(service.fgaService.client.(*MockFgaClient), "project:huge")
+
+	req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(`{"checks": ["project:huge#viewer@user:456"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	service.httpCheckHandler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp types.CheckResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []types.CheckResult{{Check: "project:huge#viewer@user:456", Guarded: true}}, resp.Results)
+}
@@ -0,0 +1,134 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+const (
+	// listObjectsTimeout is the maximum time allowed for listing and
+	// streaming the objects.
+	listObjectsTimeout = 30 * time.Second
+	// defaultListObjectsChunkSize is the number of objects per streamed chunk
+	// when the request does not set one.
+	defaultListObjectsChunkSize = 100
+	// maxListObjectsChunkSize bounds the objects per streamed chunk so a chunk
+	// stays well under the NATS payload limit.
+	maxListObjectsChunkSize = 1000
+)
+
+// publishChunk publishes one streamed result chunk. It is a variable so tests
+// can capture chunks without a NATS connection.
+var publishChunk = func(subject string, data []byte) error {
+	return natsConn.Publish(subject, data)
+}
+
+// listObjectsHandler handles requests to list the objects of a type that a
+// user has a relation on. Small results are returned in the reply; when the
+// request names a chunk subject, the objects are instead published there in
+// ordered chunks followed by a completion marker, so large result sets can be
+// consumed progressively. It responds with a JSON-encoded ListObjectsResponse.
+func (h *HandlerService) listObjectsHandler(ctx context.Context, message INatsMsg) error {
+	ctx, cancel := context.WithTimeout(ctx, listObjectsTimeout)
+	defer cancel()
+
+	var req types.ListObjectsRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal list objects request")
+		return h.respondListObjectsError(ctx, message, "invalid request payload")
+	}
+
+	if req.User == "" || req.Relation == "" || req.ObjectType == "" {
+		logger.WarnContext(ctx, "list objects request missing fields")
+		return h.respondListObjectsError(ctx, message, "user, relation, and object_type are required")
+	}
+	if strings.Contains(req.ObjectType, ":") {
+		logger.With("object_type", req.ObjectType).WarnContext(ctx, "list objects request contains invalid object_type")
+		return h.respondListObjectsError(ctx, message, "object_type must not contain ':'")
+	}
+	if req.ChunkSize < 0 || req.ChunkSize > maxListObjectsChunkSize {
+		logger.With("chunk_size", req.ChunkSize).WarnContext(ctx, "list objects request has invalid chunk_size")
+		errMsg := fmt.Sprintf("chunk_size must be between 1 and %d", maxListObjectsChunkSize)
+		return h.respondListObjectsError(ctx, message, errMsg)
+	}
+
+	objects, err := h.fgaService.ListObjectsByUserAndRelation(ctx, req.ObjectType, req.Relation, req.User)
+	if err != nil {
+		logger.With(errKey, err, "user", req.User, "object_type", req.ObjectType).
+			ErrorContext(ctx, "failed to list objects")
+		return h.respondListObjectsError(ctx, message, withFgaRequestID("failed to list objects", err))
+	}
+
+	resp := types.ListObjectsResponse{Total: len(objects)}
+	if req.ChunkSubject == "" {
+		resp.Objects = objects
+	} else if err := streamListObjects(req.ChunkSubject, objects, req.ChunkSize); err != nil {
+		logger.With(errKey, err, "chunk_subject", req.ChunkSubject).ErrorContext(ctx, "failed to stream list objects")
+		return h.respondListObjectsError(ctx, message, "failed to publish chunk")
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal list objects response")
+		return h.respondListObjectsError(ctx, message, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send list objects reply")
+			return errRespond
+		}
+		logger.With(
+			"user", req.User,
+			"relation", req.Relation,
+			"object_type", req.ObjectType,
+			"count", len(objects),
+			"streamed", req.ChunkSubject != "",
+		).InfoContext(ctx, "sent list objects response")
+	}
+
+	return nil
+}
+
+// streamListObjects publishes objects to subject in order, chunkSize at a
+// time (defaultListObjectsChunkSize when zero), followed by a done marker
+// carrying the total.
+func streamListObjects(subject string, objects []string, chunkSize int) error {
+	if chunkSize == 0 {
+		chunkSize = defaultListObjectsChunkSize
+	}
+
+	sequence := 0
+	publish := func(chunk types.ListObjectsChunk) error {
+		chunk.Sequence = sequence
+		sequence++
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		return publishChunk(subject, data)
+	}
+
+	for start := 0; start < len(objects); start += chunkSize {
+		end := min(start+chunkSize, len(objects))
+		if err := publish(types.ListObjectsChunk{Objects: objects[start:end]}); err != nil {
+			return err
+		}
+	}
+	return publish(types.ListObjectsChunk{Done: true, Total: len(objects)})
+}
+
+// respondListObjectsError replies to a list objects request with errMsg and
+// no objects.
+func (h *HandlerService) respondListObjectsError(_ context.Context, message INatsMsg, errMsg string) error {
+	return respondJSONError(message, "list objects", types.ListObjectsResponse{Error: errMsg}, errMsg)
+}
@@ -0,0 +1,121 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// captureChunks replaces publishChunk for the duration of the test and
+// returns the chunks published to each subject.
+func captureChunks(t *testing.T) map[string][]types.ListObjectsChunk {
+	t.Helper()
+	published := make(map[string][]types.ListObjectsChunk)
+	original := publishChunk
+	publishChunk = func(subject string, data []byte) error {
+		var chunk types.ListObjectsChunk
+		assert.NoError(t, json.Unmarshal(data, &chunk))
+		published[subject] = append(published[subject], chunk)
+		return nil
+	}
+	t.Cleanup(func() { publishChunk = original })
+	return published
+}
+
+// TestListObjectsHandler tests the listObjectsHandler method of HandlerService.
+func TestListObjectsHandler(t *testing.T) {
+	objects := []string{"project:p1", "project:p2", "project:p3", "project:p4", "project:p5"}
+
+	tests := []struct {
+		name           string
+		messageData    []byte
+		listErr        error
+		expectedResp   types.ListObjectsResponse
+		expectedChunks []types.ListObjectsChunk
+		expectNoList   bool
+	}{
+		{
+			name:         "objects returned in the reply",
+			messageData:  []byte(`{"user":"user:alice","relation":"viewer","object_type":"project"}`),
+			expectedResp: types.ListObjectsResponse{Objects: objects, Total: 5},
+		},
+		{
+			name: "objects streamed in ordered chunks with a terminator",
+			messageData: []byte(`{"user":"user:alice","relation":"viewer","object_type":"project",` +
+				`"chunk_subject":"_INBOX.chunks","chunk_size":2}`),
+			expectedResp: types.ListObjectsResponse{Total: 5},
+			expectedChunks: []types.ListObjectsChunk{
+				{Sequence: 0, Objects: []string{"project:p1", "project:p2"}},
+				{Sequence: 1, Objects: []string{"project:p3", "project:p4"}},
+				{Sequence: 2, Objects: []string{"project:p5"}},
+				{Sequence: 3, Done: true, Total: 5},
+			},
+		},
+		{
+			name:         "missing fields returns error",
+			messageData:  []byte(`{"user":"user:alice"}`),
+			expectedResp: types.ListObjectsResponse{Error: "user, relation, and object_type are required"},
+			expectNoList: true,
+		},
+		{
+			name: "chunk size above the maximum returns error",
+			messageData: []byte(`{"user":"user:alice","relation":"viewer","object_type":"project",` +
+				`"chunk_subject":"_INBOX.chunks","chunk_size":5000}`),
+			expectedResp: types.ListObjectsResponse{Error: "chunk_size must be between 1 and 1000"},
+			expectNoList: true,
+		},
+		{
+			name:         "list failure returns error",
+			messageData:  []byte(`{"user":"user:alice","relation":"viewer","object_type":"project"}`),
+			listErr:      errors.New("store unavailable"),
+			expectedResp: types.ListObjectsResponse{Error: "failed to list objects"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published := captureChunks(t)
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			if tt.listErr != nil {
+				fgaClient.On("ListObjects", mock.Anything, mock.Anything, mock.Anything).
+					Return((*client.ClientListObjectsResponse)(nil), tt.listErr)
+			} else {
+				fgaClient.On("ListObjects", mock.Anything, client.ClientListObjectsRequest{
+					User: "user:alice", Relation: "viewer", Type: "project",
+				}, mock.Anything).Return(&client.ClientListObjectsResponse{Objects: objects}, nil)
+			}
+
+			msg := CreateMockNatsMsg(tt.messageData)
+			msg.reply = "reply.list_objects"
+			var resp types.ListObjectsResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.listObjectsHandler(context.Background(), msg)
+
+			if tt.expectNoList {
+				fgaClient.AssertNotCalled(t, "ListObjects", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectedResp.Error != "" {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedResp, resp)
+			assert.Equal(t, tt.expectedChunks, published["_INBOX.chunks"])
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// maintenanceHandler handles requests to read or set maintenance mode, in
+// which writes are parked while checks keep being served. Every replica
+// handles each request and replies with its own mode. It responds with a
+// JSON-encoded MaintenanceResponse.
+func (h *HandlerService) maintenanceHandler(ctx context.Context, message INatsMsg) error {
+	var req types.MaintenanceRequest
+	if len(message.Data()) > 0 {
+		if err := decodePayload(message.Data(), &req); err != nil {
+			logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal maintenance request")
+			return h.respondMaintenanceError(ctx, message, "invalid request payload")
+		}
+	}
+	if req.Enabled != nil {
+		maintenance.set(*req.Enabled)
+	}
+
+	active, since, parked := maintenance.status()
+	resp := types.MaintenanceResponse{Enabled: active, Parked: parked}
+	if active {
+		resp.Since = &since
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal maintenance response")
+		return h.respondMaintenanceError(ctx, message, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send maintenance reply")
+			return errRespond
+		}
+		logger.With("enabled", active, "parked", parked).InfoContext(ctx, "sent maintenance response")
+	}
+
+	return nil
+}
+
+// respondMaintenanceError replies to a maintenance request with a
+// MaintenanceResponse carrying errMsg.
+func (h *HandlerService) respondMaintenanceError(_ context.Context, message INatsMsg, errMsg string) error {
+	return respondJSONError(message, "maintenance", types.MaintenanceResponse{Error: errMsg}, errMsg)
+}
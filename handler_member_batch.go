@@ -0,0 +1,320 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// genericMemberBatchHandler handles member_batch operations, which apply the
+// puts and removes of many users to one object with a single read of its
+// tuples and one WriteAndDeleteTuples call, e.g. for a bulk roster import.
+// Puts and removes behave like member_put and member_remove: relations
+// already held are not rewritten, relations not held are not deleted, and
+// changes older than their user's last applied operation are skipped.
+// Writes are batched as WriteAndDeleteTuples describes, so a failure may
+// leave some changes applied; the message can be retried as a whole.
+//
+// NATS Subject: lfx.fga-sync.member_batch
+//
+// Message Format:
+//
+//	{
+//	  "object_type": "committee",
+//	  "operation": "member_batch",
+//	  "data": {
+//	    "uid": "committee-123",
+//	    "puts": [{"username": "alice", "relations": ["member"]}],
+//	    "removes": [{"username": "bob", "relations": []}]
+//	  }
+//	}
+func (h *HandlerService) genericMemberBatchHandler(ctx context.Context, message INatsMsg) error {
+	genericMsg, data, err := h.parseAndValidateMemberBatchMessage(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	logger.With(
+		"object_type", genericMsg.ObjectType,
+		"uid", data.UID,
+		"puts", len(data.Puts),
+		"removes", len(data.Removes),
+	).InfoContext(ctx, "handling generic member_batch")
+
+	object := buildObjectID(genericMsg.ObjectType, data.UID)
+	if err = h.skipStaleMemberChanges(ctx, object, data); err != nil {
+		return err
+	}
+	tuplesToWrite, tuplesToDelete, renewed, err := h.computeMemberBatchChanges(ctx, object, data)
+	if err != nil {
+		return err
+	}
+	if err = h.validateTupleRelations(ctx, tuplesToWrite); err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "invalid relation")
+		return err
+	}
+	if err = h.deleteRenewedGrants(ctx, object, renewed); err != nil {
+		return err
+	}
+
+	if err = h.fgaService.WriteAndDeleteTuples(ctx, tuplesToWrite, tuplesToDelete); err != nil {
+		logger.ErrorContext(ctx, "failed to apply member batch",
+			errKey, err,
+			"object", object,
+			"writes", len(tuplesToWrite),
+			"deletes", len(tuplesToDelete),
+		)
+		h.restoreRenewedGrants(ctx, object, renewed)
+		return err
+	}
+	logger.With(
+		"object", object,
+		"writes", len(tuplesToWrite),
+		"deletes", len(tuplesToDelete),
+	).InfoContext(ctx, "applied member batch to "+genericMsg.ObjectType)
+
+	// Puts carry no expiry, so they make any expiring grant they put
+	// permanent, as a member_put without expires_at does: recorded expiries
+	// are cancelled here, and conditional grants were renewed above.
+	for _, put := range data.Puts {
+		err = h.recordGrantExpiries(ctx, object, constants.ObjectTypeUser+put.Username, &fgatypes.GenericMemberData{
+			Relations: put.Relations,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	h.recordMemberChanges(ctx, object, data)
+
+	return h.sendReplyIfNeeded(ctx, message, object, len(tuplesToWrite), len(tuplesToDelete))
+}
+
+// skipStaleMemberChanges drops the puts and removes of data that are older
+// than the last member operation applied for their user on object.
+func (h *HandlerService) skipStaleMemberChanges(
+	ctx context.Context,
+	object string,
+	data *fgatypes.GenericMemberBatchData,
+) error {
+	var err error
+	if data.Puts, err = h.freshMemberChanges(ctx, object, data.Puts); err != nil {
+		return err
+	}
+	data.Removes, err = h.freshMemberChanges(ctx, object, data.Removes)
+	return err
+}
+
+// freshMemberChanges returns the changes on object that are not stale.
+func (h *HandlerService) freshMemberChanges(
+	ctx context.Context,
+	object string,
+	changes []fgatypes.GenericMemberChange,
+) ([]fgatypes.GenericMemberChange, error) {
+	fresh := make([]fgatypes.GenericMemberChange, 0, len(changes))
+	for _, change := range changes {
+		stale, err := h.isStaleMemberOperation(ctx, object, constants.ObjectTypeUser+change.Username, change.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if !stale {
+			fresh = append(fresh, change)
+		}
+	}
+	return fresh, nil
+}
+
+// recordMemberChanges stores the latest updated_at of each user's applied
+// changes as the last member operation applied for them on object.
+func (h *HandlerService) recordMemberChanges(
+	ctx context.Context,
+	object string,
+	data *fgatypes.GenericMemberBatchData,
+) {
+	latest := make(map[string]time.Time)
+	for _, change := range slices.Concat(data.Puts, data.Removes) {
+		if change.UpdatedAt != nil && change.UpdatedAt.After(latest[change.Username]) {
+			latest[change.Username] = *change.UpdatedAt
+		}
+	}
+	for _, username := range slices.Sorted(maps.Keys(latest)) {
+		updatedAt := latest[username]
+		h.recordMemberOperation(ctx, object, constants.ObjectTypeUser+username, &updatedAt)
+	}
+}
+
+// parseAndValidateMemberBatchMessage parses and validates the member_batch
+// message.
+func (h *HandlerService) parseAndValidateMemberBatchMessage(
+	ctx context.Context, message INatsMsg,
+) (*fgatypes.GenericFGAMessage, *fgatypes.GenericMemberBatchData, error) {
+	genericMsg := new(fgatypes.GenericFGAMessage)
+	if err := decodePayload(message.Data(), genericMsg); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to parse generic message")
+		return nil, nil, err
+	}
+
+	objectType, err := canonicalObjectType(ctx, genericMsg.ObjectType)
+	if err != nil {
+		return nil, nil, err
+	}
+	genericMsg.ObjectType = objectType
+	if genericMsg.Operation != "member_batch" {
+		logger.ErrorContext(ctx, "invalid operation for this handler", "operation", genericMsg.Operation)
+		return nil, nil, newFieldError("operation", reasonInvalid, "invalid operation for member_batch handler")
+	}
+	if err := h.requireReply(ctx, message, genericMsg.ObjectType); err != nil {
+		return nil, nil, err
+	}
+
+	data := new(fgatypes.GenericMemberBatchData)
+	if err := decodeData(genericMsg, data); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to parse member batch data")
+		return nil, nil, err
+	}
+
+	if data.UID == "" {
+		logger.ErrorContext(ctx, "uid is required")
+		return nil, nil, newFieldError("uid", reasonRequired, "uid is required")
+	}
+	if err := validateUID(data.UID); err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "invalid uid")
+		return nil, nil, err
+	}
+	if len(data.Puts) == 0 && len(data.Removes) == 0 {
+		logger.ErrorContext(ctx, "member batch has no puts or removes")
+		return nil, nil, newFieldError("puts", reasonRequired, "puts or removes is required")
+	}
+
+	put := make(map[string]map[string]bool, len(data.Puts))
+	for _, change := range data.Puts {
+		if err := validateMemberChange(ctx, "puts", change); err != nil {
+			return nil, nil, err
+		}
+		if len(change.Relations) == 0 {
+			logger.ErrorContext(ctx, "relations array cannot be empty", "username", change.Username)
+			return nil, nil, newFieldError("puts", reasonRequired,
+				fmt.Sprintf("relations of %s cannot be empty", change.Username))
+		}
+		if constants.ObjectTypeUser+change.Username == constants.UserWildcard && h.privateObjectTypes[objectType] {
+			logger.ErrorContext(ctx, "public access requested on private object type", "object_type", objectType)
+			return nil, nil, newFieldError("puts", reasonNotAllowed,
+				fmt.Sprintf("%s objects must not be public", objectType))
+		}
+		if put[change.Username] == nil {
+			put[change.Username] = make(map[string]bool, len(change.Relations))
+		}
+		for _, relation := range change.Relations {
+			put[change.Username][relation] = true
+		}
+	}
+	// A relation both put and removed has no well-defined outcome.
+	for _, change := range data.Removes {
+		if err := validateMemberChange(ctx, "removes", change); err != nil {
+			return nil, nil, err
+		}
+		conflict := len(change.Relations) == 0 && len(put[change.Username]) > 0
+		for _, relation := range change.Relations {
+			conflict = conflict || put[change.Username][relation]
+		}
+		if conflict {
+			logger.ErrorContext(ctx, "member batch puts and removes the same relation", "username", change.Username)
+			return nil, nil, newFieldError("removes", reasonInvalid,
+				fmt.Sprintf("%s is both put and removed", change.Username))
+		}
+	}
+
+	return genericMsg, data, nil
+}
+
+// validateMemberChange checks the username and relations of one change of a
+// member_batch message, listed under field.
+func validateMemberChange(ctx context.Context, field string, change fgatypes.GenericMemberChange) error {
+	if change.Username == "" {
+		logger.ErrorContext(ctx, "username is required", "field", field)
+		return newFieldError(field, reasonRequired, "username is required")
+	}
+	for _, relation := range change.Relations {
+		if relation == "" {
+			logger.ErrorContext(ctx, "relation value cannot be empty", "username", change.Username)
+			return newFieldError(field, reasonInvalid, "relation value cannot be empty")
+		}
+	}
+	return nil
+}
+
+// computeMemberBatchChanges reads the object's tuples once and determines
+// the tuples to write for the batch's puts and to delete for its removes, and
+// the held conditional tuples the puts renew as permanent ones.
+func (h *HandlerService) computeMemberBatchChanges(
+	ctx context.Context,
+	object string,
+	data *fgatypes.GenericMemberBatchData,
+) ([]client.ClientTupleKey, []client.ClientTupleKeyWithoutCondition, []client.ClientTupleKey, error) {
+	existingTuples, err := h.fgaService.ReadCurrentObjectTuples(ctx, object)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read existing tuples", errKey, err, "object", object)
+		return nil, nil, nil, err
+	}
+	existing := make(map[string][]string)
+	conditions := make(map[string]*openfga.RelationshipCondition)
+	for _, tuple := range existingTuples {
+		existing[tuple.Key.User] = append(existing[tuple.Key.User], tuple.Key.Relation)
+		if tuple.Key.Condition != nil {
+			conditions[tuple.Key.Relation+"@"+tuple.Key.User] = tuple.Key.Condition
+		}
+	}
+	held := func(user, relation string) bool { return slices.Contains(existing[user], relation) }
+
+	var tuplesToWrite []client.ClientTupleKey
+	var renewed []client.ClientTupleKey
+	written := make(map[string]bool)
+	for _, change := range data.Puts {
+		user := constants.ObjectTypeUser + change.Username
+		for _, relation := range change.Relations {
+			if written[relation+"@"+user] {
+				continue
+			}
+			if held(user, relation) {
+				if !h.renewsGrant(conditions[relation+"@"+user], nil) {
+					continue
+				}
+				held := h.fgaService.TupleKey(user, relation, object)
+				held.Condition = conditions[relation+"@"+user]
+				renewed = append(renewed, held)
+			}
+			written[relation+"@"+user] = true
+			tuplesToWrite = append(tuplesToWrite, h.fgaService.TupleKey(user, relation, object))
+		}
+	}
+
+	var tuplesToDelete []client.ClientTupleKeyWithoutCondition
+	deleted := make(map[string]bool)
+	for _, change := range data.Removes {
+		user := constants.ObjectTypeUser + change.Username
+		relations := change.Relations
+		if len(relations) == 0 {
+			relations = existing[user]
+		}
+		for _, relation := range relations {
+			if !held(user, relation) || deleted[relation+"@"+user] {
+				continue
+			}
+			deleted[relation+"@"+user] = true
+			tuplesToDelete = append(tuplesToDelete, h.fgaService.TupleKeyWithoutCondition(user, relation, object))
+		}
+	}
+
+	return tuplesToWrite, tuplesToDelete, renewed, nil
+}
@@ -0,0 +1,184 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestGenericMemberBatch tests that a member_batch applies every put and
+// remove to the object, leaving relations already held or not held alone.
+func TestGenericMemberBatch(t *testing.T) {
+	store := &memoryFgaClient{
+		MockFgaClient: new(MockFgaClient),
+		tuples: map[client.ClientTupleKeyWithoutCondition]bool{
+			{User: "user:alice", Relation: "member", Object: "committee:c1"}: true,
+			{User: "user:bob", Relation: "member", Object: "committee:c1"}:   true,
+			{User: "user:bob", Relation: "viewer", Object: "committee:c1"}:   true,
+			{User: "user:carol", Relation: "viewer", Object: "committee:c1"}: true,
+			{User: "user:erin", Relation: "member", Object: "committee:c2"}:  true,
+		},
+	}
+	service := setupService()
+	service.fgaService.client = store
+
+	msg := buildGenericMessage(t, "committee", "member_batch", fgatypes.GenericMemberBatchData{
+		UID: "c1",
+		Puts: []fgatypes.GenericMemberChange{
+			{Username: "alice", Relations: []string{"member"}},
+			{Username: "dave", Relations: []string{"member", "viewer"}},
+		},
+		Removes: []fgatypes.GenericMemberChange{
+			{Username: "bob"},
+			{Username: "carol", Relations: []string{"viewer", "member"}},
+		},
+	})
+	msg.reply = "reply.inbox"
+	msg.On("Respond", []byte("OK")).Return(nil).Once()
+
+	assert.NoError(t, service.genericMemberBatchHandler(context.Background(), msg))
+	msg.AssertExpectations(t)
+	assert.Equal(t, map[client.ClientTupleKeyWithoutCondition]bool{
+		{User: "user:alice", Relation: "member", Object: "committee:c1"}: true,
+		{User: "user:dave", Relation: "member", Object: "committee:c1"}:  true,
+		{User: "user:dave", Relation: "viewer", Object: "committee:c1"}:  true,
+		{User: "user:erin", Relation: "member", Object: "committee:c2"}:  true,
+	}, store.tuples)
+}
+
+// TestGenericMemberBatch_SingleReadChunkedWrite tests that a large batch
+// reads the object's tuples once and writes them in OpenFGA-sized chunks.
+func TestGenericMemberBatch_SingleReadChunkedWrite(t *testing.T) {
+	service := setupService()
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+	var batches []int
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		req := args.Get(1).(client.ClientWriteRequest)
+		batches = append(batches, len(req.Writes)+len(req.Deletes))
+	}).Return(&client.ClientWriteResponse{}, nil)
+
+	puts := make([]fgatypes.GenericMemberChange, 0, 150)
+	for i := range 150 {
+		puts = append(puts, fgatypes.GenericMemberChange{Username: fmt.Sprintf("user-%d", i), Relations: []string{"member"}})
+	}
+	msg := buildGenericMessage(t, "committee", "member_batch", fgatypes.GenericMemberBatchData{UID: "c1", Puts: puts})
+
+	assert.NoError(t, service.genericMemberBatchHandler(context.Background(), msg))
+	fgaClient.AssertNumberOfCalls(t, "Read", 1)
+	assert.Equal(t, []int{100, 50}, batches)
+}
+
+func TestGenericMemberBatch_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   string
+		data        fgatypes.GenericMemberBatchData
+		expectError string
+	}{
+		{
+			name:        "wrong operation",
+			operation:   "member_put",
+			data:        fgatypes.GenericMemberBatchData{UID: "c1"},
+			expectError: "invalid operation for member_batch handler",
+		},
+		{
+			name:        "missing uid",
+			operation:   "member_batch",
+			data:        fgatypes.GenericMemberBatchData{Removes: []fgatypes.GenericMemberChange{{Username: "bob"}}},
+			expectError: "uid is required",
+		},
+		{
+			name:        "no changes",
+			operation:   "member_batch",
+			data:        fgatypes.GenericMemberBatchData{UID: "c1"},
+			expectError: "puts or removes is required",
+		},
+		{
+			name:      "missing username",
+			operation: "member_batch",
+			data: fgatypes.GenericMemberBatchData{UID: "c1", Puts: []fgatypes.GenericMemberChange{
+				{Relations: []string{"member"}},
+			}},
+			expectError: "username is required",
+		},
+		{
+			name:      "put without relations",
+			operation: "member_batch",
+			data: fgatypes.GenericMemberBatchData{UID: "c1", Puts: []fgatypes.GenericMemberChange{
+				{Username: "alice"},
+			}},
+			expectError: "relations of alice cannot be empty",
+		},
+		{
+			name:      "relation both put and removed",
+			operation: "member_batch",
+			data: fgatypes.GenericMemberBatchData{
+				UID:     "c1",
+				Puts:    []fgatypes.GenericMemberChange{{Username: "alice", Relations: []string{"member"}}},
+				Removes: []fgatypes.GenericMemberChange{{Username: "alice"}},
+			},
+			expectError: "alice is both put and removed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			msg := buildGenericMessage(t, "committee", tt.operation, tt.data)
+
+			err := service.genericMemberBatchHandler(context.Background(), msg)
+			assert.EqualError(t, err, tt.expectError)
+			assert.Contains(t, string(errorReply(err)), `"field"`)
+			service.fgaService.client.(*MockFgaClient).AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// TestGenericMemberBatch_OutOfOrder tests that batch changes older than the
+// last operation applied for their user are skipped, and that the updated_at
+// of applied changes is recorded.
+func TestGenericMemberBatch_OutOfOrder(t *testing.T) {
+	store := &memoryFgaClient{
+		MockFgaClient: new(MockFgaClient),
+		tuples: map[client.ClientTupleKeyWithoutCondition]bool{
+			{User: "user:bob", Relation: "member", Object: "committee:c1"}: true,
+		},
+	}
+	service := setupService()
+	service.fgaService.client = store
+	ctx := context.Background()
+	lastApplied := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, service.fgaService.SetLastMemberUpdate(ctx, "committee:c1", "user:alice", lastApplied))
+	earlier, later := lastApplied.Add(-time.Minute), lastApplied.Add(time.Minute)
+
+	msg := buildGenericMessage(t, "committee", "member_batch", fgatypes.GenericMemberBatchData{
+		UID: "c1",
+		Puts: []fgatypes.GenericMemberChange{
+			{Username: "alice", Relations: []string{"member"}, UpdatedAt: &earlier},
+			{Username: "dave", Relations: []string{"member"}},
+		},
+		Removes: []fgatypes.GenericMemberChange{{Username: "bob", UpdatedAt: &later}},
+	})
+	assert.NoError(t, service.genericMemberBatchHandler(ctx, msg))
+
+	assert.Equal(t, map[client.ClientTupleKeyWithoutCondition]bool{
+		{User: "user:dave", Relation: "member", Object: "committee:c1"}: true,
+	}, store.tuples)
+	recorded, err := service.fgaService.GetLastMemberUpdate(ctx, "committee:c1", "user:bob")
+	assert.NoError(t, err)
+	assert.True(t, later.Equal(recorded), "expected %v, got %v", later, recorded)
+	recorded, err = service.fgaService.GetLastMemberUpdate(ctx, "committee:c1", "user:alice")
+	assert.NoError(t, err)
+	assert.True(t, lastApplied.Equal(recorded), "expected %v, got %v", lastApplied, recorded)
+}
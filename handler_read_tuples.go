@@ -26,7 +26,7 @@ func (h *HandlerService) readTuplesHandler(ctx context.Context, message INatsMsg
 
 	// Unmarshal the JSON request payload.
 	var req types.ReadTuplesRequest
-	if err := json.Unmarshal(message.Data(), &req); err != nil {
+	if err := decodePayload(message.Data(), &req); err != nil {
 		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal read tuples request")
 		return h.respondReadTuplesError(ctx, message, "invalid request payload")
 	}
@@ -97,15 +97,5 @@ func (h *HandlerService) readTuplesHandler(ctx context.Context, message INatsMsg
 // handlers. This helper does not log — callers are responsible for logging
 // before calling it.
 func (h *HandlerService) respondReadTuplesError(_ context.Context, message INatsMsg, errMsg string) error {
-	if message.Reply() != "" {
-		resp := types.ReadTuplesResponse{Error: errMsg}
-		data, err := json.Marshal(resp)
-		if err != nil {
-			return fmt.Errorf("read tuples: %s (marshal error response: %w)", errMsg, err)
-		}
-		if errRespond := message.Respond(data); errRespond != nil {
-			return fmt.Errorf("read tuples: %s (send error reply: %w)", errMsg, errRespond)
-		}
-	}
-	return fmt.Errorf("read tuples: %s", errMsg)
+	return respondJSONError(message, "read tuples", types.ReadTuplesResponse{Error: errMsg}, errMsg)
 }
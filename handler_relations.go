@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// relationsTimeout is the maximum time allowed for reading the authorization
+// model.
+const relationsTimeout = 10 * time.Second
+
+// relationsHandler handles requests to list the relations defined on an
+// object type in the current authorization model, so producers can check
+// which relations an update_access payload may use. It responds with a
+// JSON-encoded RelationsResponse.
+func (h *HandlerService) relationsHandler(ctx context.Context, message INatsMsg) error {
+	ctx, cancel := context.WithTimeout(ctx, relationsTimeout)
+	defer cancel()
+
+	var req types.RelationsRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal relations request")
+		return h.respondRelationsError(ctx, message, "invalid request payload")
+	}
+
+	if req.ObjectType == "" {
+		logger.WarnContext(ctx, "relations request missing object_type")
+		return h.respondRelationsError(ctx, message, "object_type is required")
+	}
+
+	model, err := h.fgaService.AuthorizationModel(ctx)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to read authorization model")
+		return h.respondRelationsError(ctx, message, "failed to read authorization model")
+	}
+
+	relations, ok := model.relationsFor(req.ObjectType)
+	if !ok {
+		logger.With("object_type", req.ObjectType).WarnContext(ctx, "relations request for unknown object_type")
+		errMsg := fmt.Sprintf("object_type %q is not defined in the model", req.ObjectType)
+		return h.respondRelationsError(ctx, message, errMsg)
+	}
+
+	data, err := json.Marshal(types.RelationsResponse{Relations: relations})
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal relations response")
+		return h.respondRelationsError(ctx, message, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send relations reply")
+			return errRespond
+		}
+		logger.With(
+			"object_type", req.ObjectType,
+			"count", len(relations),
+		).InfoContext(ctx, "sent relations response")
+	}
+
+	return nil
+}
+
+// respondRelationsError replies to a relations request with a
+// RelationsResponse carrying errMsg.
+func (h *HandlerService) respondRelationsError(_ context.Context, message INatsMsg, errMsg string) error {
+	return respondJSONError(message, "relations", types.RelationsResponse{Error: errMsg}, errMsg)
+}
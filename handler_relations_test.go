@@ -0,0 +1,88 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestRelationsHandler tests the relationsHandler method of HandlerService.
+func TestRelationsHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		messageData       []byte
+		modelErr          error
+		expectedRelations []string
+		expectedError     string
+	}{
+		{
+			name:              "known object type returns sorted relations",
+			messageData:       []byte(`{"object_type":"meeting"}`),
+			expectedRelations: []string{"project", "viewer"},
+		},
+		{
+			name:          "unknown object type returns error",
+			messageData:   []byte(`{"object_type":"unknown"}`),
+			expectedError: `object_type "unknown" is not defined in the model`,
+		},
+		{
+			name:          "missing object type returns error",
+			messageData:   []byte(`{}`),
+			expectedError: "object_type is required",
+		},
+		{
+			name:          "invalid JSON payload returns error",
+			messageData:   []byte(`not-json`),
+			expectedError: "invalid request payload",
+		},
+		{
+			name:          "model read failure returns generic error",
+			messageData:   []byte(`{"object_type":"meeting"}`),
+			modelErr:      errors.New("store unavailable"),
+			expectedError: "failed to read authorization model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			if tt.modelErr != nil {
+				fgaClient.On("ReadAuthorizationModel", mock.Anything).Return(
+					(*client.ClientReadAuthorizationModelResponse)(nil), tt.modelErr,
+				)
+			} else {
+				fgaClient.On("ReadAuthorizationModel", mock.Anything).Return(testAuthorizationModelResponse(), nil)
+			}
+
+			msg := CreateMockNatsMsg(tt.messageData)
+			msg.reply = "reply.relations"
+			var resp types.RelationsResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.relationsHandler(context.Background(), msg)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedError, resp.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRelations, resp.Relations)
+			}
+			msg.AssertExpectations(t)
+		})
+	}
+}
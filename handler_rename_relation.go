@@ -0,0 +1,141 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+const (
+	// renameRelationTimeout is the maximum time allowed for migrating every
+	// object of a rename request.
+	renameRelationTimeout = 2 * time.Minute
+	// maxRenameRelationObjects bounds the objects migrated by one request, so
+	// a request finishes well within renameRelationTimeout.
+	maxRenameRelationObjects = 1000
+)
+
+// renameRelationHandler handles requests to migrate the tuples of a relation
+// renamed in the authorization model. For each listed object, tuples with the
+// old relation are rewritten with the new one and the old tuples deleted.
+// Objects are migrated in order and independently: on failure, the objects
+// already migrated stay migrated and are listed in the response, so the
+// request can be retried with the remaining UIDs. It responds with a
+// JSON-encoded RenameRelationResponse.
+func (h *HandlerService) renameRelationHandler(ctx context.Context, message INatsMsg) error {
+	ctx, cancel := context.WithTimeout(ctx, renameRelationTimeout)
+	defer cancel()
+
+	var req types.RenameRelationRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal rename relation request")
+		return h.respondRenameRelationError(ctx, message, nil, "invalid request payload")
+	}
+
+	objects, err := h.validateRenameRelation(ctx, &req)
+	if err != nil {
+		return h.respondRenameRelationError(ctx, message, nil, err.Error())
+	}
+
+	resp := types.RenameRelationResponse{Renamed: make(map[string]int, len(objects))}
+	for _, object := range objects {
+		renamed, err := h.fgaService.RenameRelation(ctx, object, req.OldRelation, req.NewRelation)
+		if err != nil {
+			logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to rename relation")
+			errMsg := withFgaRequestID("failed to rename relation on "+object, err)
+			return h.respondRenameRelationError(ctx, message, resp.Renamed, errMsg)
+		}
+		resp.Renamed[object] = renamed
+		logger.With(
+			"object", object,
+			"old_relation", req.OldRelation,
+			"new_relation", req.NewRelation,
+			"renamed", renamed,
+		).InfoContext(ctx, "renamed relation")
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal rename relation response")
+		return h.respondRenameRelationError(ctx, message, resp.Renamed, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send rename relation reply")
+			return errRespond
+		}
+	}
+
+	return nil
+}
+
+// validateRenameRelation checks a rename request and returns the IDs of the
+// objects to migrate. The new relation must be defined in the current
+// authorization model; the old one usually no longer is.
+func (h *HandlerService) validateRenameRelation(
+	ctx context.Context,
+	req *types.RenameRelationRequest,
+) ([]string, error) {
+	objectType, err := canonicalObjectType(ctx, req.ObjectType)
+	if err != nil {
+		return nil, err
+	}
+	if req.OldRelation == "" || req.NewRelation == "" || len(req.ObjectUIDs) == 0 {
+		logger.WarnContext(ctx, "rename relation request missing fields")
+		return nil, errors.New("old_relation, new_relation, and object_uids are required")
+	}
+	if req.OldRelation == req.NewRelation {
+		logger.WarnContext(ctx, "rename relation request renames a relation to itself", "relation", req.OldRelation)
+		return nil, errors.New("old_relation and new_relation must differ")
+	}
+	if len(req.ObjectUIDs) > maxRenameRelationObjects {
+		logger.With("count", len(req.ObjectUIDs)).WarnContext(ctx, "rename relation request lists too many objects")
+		return nil, fmt.Errorf("at most %d object_uids may be renamed per request", maxRenameRelationObjects)
+	}
+
+	model, err := h.fgaService.AuthorizationModel(ctx)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to read authorization model")
+		return nil, errors.New("failed to read authorization model")
+	}
+	if !model.hasRelation(objectType, req.NewRelation) {
+		logger.With("object_type", objectType, "relation", req.NewRelation).
+			WarnContext(ctx, "rename relation request targets an undefined relation")
+		return nil, fmt.Errorf("relation %q is not defined on %q in the model", req.NewRelation, objectType)
+	}
+
+	objects := make([]string, 0, len(req.ObjectUIDs))
+	for _, uid := range req.ObjectUIDs {
+		if uid == "" {
+			logger.WarnContext(ctx, "rename relation request contains an empty uid")
+			return nil, errors.New("object_uids must not contain empty values")
+		}
+		if err := validateUID(uid); err != nil {
+			logger.With(errKey, err).WarnContext(ctx, "rename relation request contains an invalid uid")
+			return nil, err
+		}
+		objects = append(objects, buildObjectID(objectType, uid))
+	}
+	return objects, nil
+}
+
+// respondRenameRelationError replies to a rename with errMsg, listing the
+// objects renamed before it failed.
+func (h *HandlerService) respondRenameRelationError(
+	_ context.Context,
+	message INatsMsg,
+	renamed map[string]int,
+	errMsg string,
+) error {
+	resp := types.RenameRelationResponse{Renamed: renamed, Error: errMsg}
+	return respondJSONError(message, "rename relation", resp, errMsg)
+}
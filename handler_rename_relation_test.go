@@ -0,0 +1,135 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestRenameRelationHandler tests the renameRelationHandler method of
+// HandlerService.
+func TestRenameRelationHandler(t *testing.T) {
+	condition := &openfga.RelationshipCondition{Name: "in_business_hours"}
+	existing := map[string][]openfga.Tuple{
+		"project:p1": {
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "owner", Object: "project:p1"}},
+			{Key: openfga.TupleKey{User: "user:bob", Relation: "owner", Object: "project:p1", Condition: condition}},
+			{Key: openfga.TupleKey{User: "user:carol", Relation: "viewer", Object: "project:p1"}},
+		},
+		"project:p2": {
+			// alice already holds the new relation, so only the old tuple goes.
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "owner", Object: "project:p2"}},
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "project:p2"}},
+		},
+		"project:p3": {
+			{Key: openfga.TupleKey{User: "user:dave", Relation: "viewer", Object: "project:p3"}},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		messageData    string
+		writeErr       error
+		expectedResp   types.RenameRelationResponse
+		expectedWrites map[string][]client.ClientTupleKey
+		expectNoWrite  bool
+	}{
+		{
+			name: "renames the relation on every listed object",
+			messageData: `{"object_type":"project","old_relation":"owner","new_relation":"writer",` +
+				`"object_uids":["p1","p2","p3"]}`,
+			expectedResp: types.RenameRelationResponse{Renamed: map[string]int{"project:p1": 2, "project:p2": 1, "project:p3": 0}},
+			expectedWrites: map[string][]client.ClientTupleKey{
+				"project:p1": {
+					{User: "user:alice", Relation: "writer", Object: "project:p1"},
+					{User: "user:bob", Relation: "writer", Object: "project:p1", Condition: condition},
+				},
+				"project:p2": nil,
+			},
+		},
+		{
+			name: "failure reports the objects already renamed",
+			messageData: `{"object_type":"project","old_relation":"owner","new_relation":"writer",` +
+				`"object_uids":["p1","p2"]}`,
+			writeErr: errors.New("store unavailable"),
+			expectedResp: types.RenameRelationResponse{
+				Renamed: map[string]int{},
+				Error:   "failed to rename relation on project:p1",
+			},
+		},
+		{
+			name:          "new relation absent from the model is rejected",
+			messageData:   `{"object_type":"project","old_relation":"owner","new_relation":"admin","object_uids":["p1"]}`,
+			expectedResp:  types.RenameRelationResponse{Error: `relation "admin" is not defined on "project" in the model`},
+			expectNoWrite: true,
+		},
+		{
+			name:          "renaming to the same relation is rejected",
+			messageData:   `{"object_type":"project","old_relation":"writer","new_relation":"writer","object_uids":["p1"]}`,
+			expectedResp:  types.RenameRelationResponse{Error: "old_relation and new_relation must differ"},
+			expectNoWrite: true,
+		},
+		{
+			name:          "missing object UIDs are rejected",
+			messageData:   `{"object_type":"project","old_relation":"owner","new_relation":"writer"}`,
+			expectedResp:  types.RenameRelationResponse{Error: "old_relation, new_relation, and object_uids are required"},
+			expectNoWrite: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("ReadAuthorizationModel", mock.Anything).Return(testAuthorizationModelResponse(), nil)
+			for object, tuples := range existing {
+				fgaClient.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+					return req.Object != nil && *req.Object == object
+				}), mock.Anything).Return(&client.ClientReadResponse{Tuples: tuples}, nil).Maybe()
+			}
+			writes := make(map[string][]client.ClientTupleKey)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				req := args.Get(1).(client.ClientWriteRequest)
+				for _, del := range req.Deletes {
+					assert.Equal(t, "owner", del.Relation, "only old relation tuples are deleted")
+				}
+				object := req.Deletes[0].Object
+				writes[object] = append(writes[object], req.Writes...)
+			}).Return(&client.ClientWriteResponse{}, tt.writeErr)
+
+			msg := CreateMockNatsMsg([]byte(tt.messageData))
+			msg.reply = "reply.rename_relation"
+			var resp types.RenameRelationResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.renameRelationHandler(context.Background(), msg)
+
+			if tt.expectedResp.Error != "" {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedResp, resp)
+			if tt.expectNoWrite {
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			}
+			if tt.expectedWrites != nil {
+				assert.Equal(t, tt.expectedWrites, writes)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// resyncObjectHandler reconciles a single object's tuples against the desired
+// state supplied in the request, for targeted remediation. The service keeps
+// no desired state of its own, so the request is taken as the truth: it is
+// applied like an update_access for the object, except that the public flag
+// is always authoritative. It responds with a JSON-encoded
+// ResyncObjectResponse listing the tuples written and deleted.
+func (h *HandlerService) resyncObjectHandler(ctx context.Context, message INatsMsg) error {
+	var req types.ResyncObjectRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal resync object request")
+		return h.respondResyncError(ctx, message, "invalid request payload")
+	}
+
+	result := h.resyncObject(ctx, req)
+	if result.Error != "" {
+		return h.respondResyncError(ctx, message, result.Error)
+	}
+
+	data, err := json.Marshal(types.ResyncObjectResponse{Writes: result.Writes, Deletes: result.Deletes})
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal resync object response")
+		return h.respondResyncError(ctx, message, "failed to marshal response")
+	}
+
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send resync object reply")
+			return errRespond
+		}
+	}
+
+	return nil
+}
+
+// resyncObject reconciles one object against the desired state in req,
+// returning the tuples changed, or the error to report, in its result.
+func (h *HandlerService) resyncObject(ctx context.Context, req types.ResyncObjectRequest) types.ResyncObjectResult {
+	if req.ObjectType == "" {
+		logger.WarnContext(ctx, "resync object request missing object_type")
+		return types.ResyncObjectResult{Object: ":" + req.UID, Error: "object_type is required"}
+	}
+
+	object, tuples, err := h.standardAccessTuples(ctx, &standardAccessStub{
+		UID:        req.UID,
+		ObjectType: req.ObjectType,
+		Public:     req.Public,
+		Relations:  req.Relations,
+		References: req.References,
+	})
+	if err != nil {
+		return types.ResyncObjectResult{Object: req.ObjectType + ":" + req.UID, Error: err.Error()}
+	}
+
+	writes, deletes, _, err := h.fgaService.SyncObjectTuples(ctx, object, tuples)
+	if err != nil {
+		logger.With(errKey, err, "object", object).ErrorContext(ctx, "failed to resync object")
+		return types.ResyncObjectResult{Object: object, Error: withFgaRequestID("failed to sync tuples", err)}
+	}
+
+	result := types.ResyncObjectResult{
+		Object:  object,
+		Writes:  make([]string, 0, len(writes)),
+		Deletes: make([]string, 0, len(deletes)),
+	}
+	for _, t := range writes {
+		result.Writes = append(result.Writes, fmt.Sprintf("%s#%s@%s", t.Object, t.Relation, t.User))
+	}
+	for _, t := range deletes {
+		result.Deletes = append(result.Deletes, fmt.Sprintf("%s#%s@%s", t.Object, t.Relation, t.User))
+	}
+
+	logger.With(
+		"object", object,
+		"writes", result.Writes,
+		"deletes", result.Deletes,
+	).InfoContext(ctx, "resynced object")
+	return result
+}
+
+// respondResyncError replies to a resync request with a
+// ResyncObjectResponse carrying errMsg.
+func (h *HandlerService) respondResyncError(_ context.Context, message INatsMsg, errMsg string) error {
+	return respondJSONError(message, "resync object", types.ResyncObjectResponse{Error: errMsg}, errMsg)
+}
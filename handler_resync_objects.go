@@ -0,0 +1,116 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+const (
+	// resyncObjectsTimeout is the maximum time allowed for resyncing every
+	// object of a resync_objects request.
+	resyncObjectsTimeout = 2 * time.Minute
+	// maxResyncObjects bounds the objects resynced by one request, so a
+	// request finishes well within resyncObjectsTimeout.
+	maxResyncObjects = 500
+	// defaultResyncConcurrency is the number of objects of a resync_objects
+	// request read and reconciled at once.
+	defaultResyncConcurrency = 4
+)
+
+// resyncObjectsHandler reconciles a list of objects against the desired
+// states supplied in the request, e.g. a project's whole meeting set, as a
+// resync_object for each. Up to resyncConcurrency objects are read and
+// reconciled at once, every OpenFGA call going through the same client, and
+// so the same circuit breaker, as other handlers. Objects are resynced
+// independently: an object that fails, or is invalid, is reported in its
+// result and the remaining objects are still resynced. It responds with a
+// JSON-encoded ResyncObjectsResponse.
+func (h *HandlerService) resyncObjectsHandler(ctx context.Context, message INatsMsg) error {
+	ctx, cancel := context.WithTimeout(ctx, resyncObjectsTimeout)
+	defer cancel()
+
+	var req types.ResyncObjectsRequest
+	if err := decodePayload(message.Data(), &req); err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to unmarshal resync objects request")
+		return h.respondResyncObjectsError(ctx, message, "invalid request payload")
+	}
+	if len(req.Objects) == 0 {
+		logger.WarnContext(ctx, "resync objects request lists no objects")
+		return h.respondResyncObjectsError(ctx, message, "objects is required")
+	}
+	if len(req.Objects) > maxResyncObjects {
+		logger.With("count", len(req.Objects)).WarnContext(ctx, "resync objects request lists too many objects")
+		return h.respondResyncObjectsError(ctx, message,
+			fmt.Sprintf("at most %d objects may be resynced per request", maxResyncObjects))
+	}
+
+	resp := types.ResyncObjectsResponse{Results: h.resyncObjects(ctx, req.Objects)}
+	for _, result := range resp.Results {
+		resp.Writes += len(result.Writes)
+		resp.Deletes += len(result.Deletes)
+		if result.Error != "" {
+			resp.Failed++
+		}
+	}
+	logger.With(
+		"objects", len(req.Objects),
+		"writes", resp.Writes,
+		"deletes", resp.Deletes,
+		"failed", resp.Failed,
+	).InfoContext(ctx, "handled resync objects request")
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.With(errKey, err).ErrorContext(ctx, "failed to marshal resync objects response")
+		return h.respondResyncObjectsError(ctx, message, "failed to marshal response")
+	}
+	if message.Reply() != "" {
+		if errRespond := message.Respond(data); errRespond != nil {
+			logger.With(errKey, errRespond).WarnContext(ctx, "failed to send resync objects reply")
+			return errRespond
+		}
+	}
+
+	if resp.Failed > 0 {
+		return fmt.Errorf("resync objects: %d of %d objects failed", resp.Failed, len(req.Objects))
+	}
+	return nil
+}
+
+// resyncObjects resyncs every object of reqs, up to resyncConcurrency at
+// once, returning their results in request order.
+func (h *HandlerService) resyncObjects(ctx context.Context, reqs []types.ResyncObjectRequest) []types.ResyncObjectResult {
+	limit := h.resyncConcurrency
+	if limit <= 0 {
+		limit = defaultResyncConcurrency
+	}
+
+	results := make([]types.ResyncObjectResult, len(reqs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i, req := range reqs {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			results[i] = h.resyncObject(ctx, req)
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// respondResyncObjectsError replies to a resync of several objects that
+// failed as a whole, with no per-object results.
+func (h *HandlerService) respondResyncObjectsError(_ context.Context, message INatsMsg, errMsg string) error {
+	resp := types.ResyncObjectsResponse{Results: []types.ResyncObjectResult{}, Error: errMsg}
+	return respondJSONError(message, "resync objects", resp, errMsg)
+}
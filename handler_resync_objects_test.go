@@ -0,0 +1,110 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestResyncObjectsHandler tests that a batch of objects is read and
+// reconciled concurrently, and that the response reports each object in
+// request order along with the totals.
+func TestResyncObjectsHandler(t *testing.T) {
+	// Every valid object must be read before any of them is reconciled, which
+	// only completes if the three reads run at once.
+	barrier := new(sync.WaitGroup)
+	barrier.Add(3)
+	store := &memoryFgaClient{
+		MockFgaClient: new(MockFgaClient),
+		tuples: map[client.ClientTupleKeyWithoutCondition]bool{
+			{User: "user:mallory", Relation: "organizer", Object: "meeting:m1"}: true,
+			{User: "user:alice", Relation: "organizer", Object: "meeting:m3"}:   true,
+		},
+		readBarrier: barrier,
+	}
+	service := setupService()
+	service.fgaService.client = store
+	service.resyncConcurrency = 3
+
+	msg := CreateMockNatsMsg([]byte(`{"objects":[` +
+		`{"object_type":"meeting","uid":"m1","relations":{"organizer":["alice"]}},` +
+		`{"object_type":"meeting","uid":"m2","relations":{"organizer":["bob"]}},` +
+		`{"uid":"m4"},` +
+		`{"object_type":"meeting","uid":"m3","relations":{"organizer":["alice"]}}]}`))
+	msg.reply = "reply.resync_objects"
+	var resp types.ResyncObjectsResponse
+	msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+		assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+	}).Return(nil).Once()
+
+	err := service.resyncObjectsHandler(context.Background(), msg)
+	assert.ErrorContains(t, err, "1 of 4 objects failed")
+
+	assert.Equal(t, types.ResyncObjectsResponse{
+		Results: []types.ResyncObjectResult{
+			{
+				Object:  "meeting:m1",
+				Writes:  []string{"meeting:m1#organizer@user:alice"},
+				Deletes: []string{"meeting:m1#organizer@user:mallory"},
+			},
+			{Object: "meeting:m2", Writes: []string{"meeting:m2#organizer@user:bob"}, Deletes: []string{}},
+			{Object: ":m4", Error: "object_type is required"},
+			{Object: "meeting:m3", Writes: []string{}, Deletes: []string{}},
+		},
+		Writes:  2,
+		Deletes: 1,
+		Failed:  1,
+	}, resp)
+	assert.Equal(t, map[client.ClientTupleKeyWithoutCondition]bool{
+		{User: "user:alice", Relation: "organizer", Object: "meeting:m1"}: true,
+		{User: "user:bob", Relation: "organizer", Object: "meeting:m2"}:   true,
+		{User: "user:alice", Relation: "organizer", Object: "meeting:m3"}: true,
+	}, store.tuples)
+}
+
+// TestResyncObjectsHandler_RejectedRequests tests that requests listing no
+// objects, or too many, are rejected as a whole.
+func TestResyncObjectsHandler_RejectedRequests(t *testing.T) {
+	tooMany := `{"objects":[` + strings.Repeat(`{"object_type":"meeting","uid":"m1"},`, maxResyncObjects) +
+		`{"object_type":"meeting","uid":"m1"}]}`
+
+	tests := []struct {
+		name          string
+		messageData   string
+		expectedError string
+	}{
+		{name: "no objects", messageData: `{"objects":[]}`, expectedError: "objects is required"},
+		{name: "too many objects", messageData: tooMany, expectedError: "at most 500 objects may be resynced per request"},
+		{name: "invalid JSON payload", messageData: `not-json`, expectedError: "invalid request payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+
+			msg := CreateMockNatsMsg([]byte(tt.messageData))
+			msg.reply = "reply.resync_objects"
+			var resp types.ResyncObjectsResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			assert.Error(t, service.resyncObjectsHandler(context.Background(), msg))
+			assert.Equal(t, tt.expectedError, resp.Error)
+			assert.Empty(t, resp.Results)
+			fgaClient.AssertNotCalled(t, "Read", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// TestResyncObjectHandler tests the resyncObjectHandler method of HandlerService.
+func TestResyncObjectHandler(t *testing.T) {
+	existing := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "project:p1"}},
+		{Key: openfga.TupleKey{User: "user:mallory", Relation: "writer", Object: "project:p1"}},
+	}
+
+	tests := []struct {
+		name            string
+		messageData     []byte
+		writeErr        error
+		expectedWrites  []string
+		expectedDeletes []string
+		expectedError   string
+	}{
+		{
+			name:            "reconciles against the supplied desired state",
+			messageData:     []byte(`{"object_type":"project","uid":"p1","relations":{"writer":["alice","bob"]}}`),
+			expectedWrites:  []string{"project:p1#writer@user:bob"},
+			expectedDeletes: []string{"project:p1#writer@user:mallory"},
+		},
+		{
+			name:          "missing object type returns error",
+			messageData:   []byte(`{"uid":"p1"}`),
+			expectedError: "object_type is required",
+		},
+		{
+			name:          "missing uid returns error",
+			messageData:   []byte(`{"object_type":"project"}`),
+			expectedError: "project ID not found",
+		},
+		{
+			name:          "invalid JSON payload returns error",
+			messageData:   []byte(`not-json`),
+			expectedError: "invalid request payload",
+		},
+		{
+			name:          "write failure returns error",
+			messageData:   []byte(`{"object_type":"project","uid":"p1"}`),
+			writeErr:      errors.New("store unavailable"),
+			expectedError: "failed to sync tuples",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: existing}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, tt.writeErr)
+
+			msg := CreateMockNatsMsg(tt.messageData)
+			msg.reply = "reply.resync"
+			var resp types.ResyncObjectResponse
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+			}).Return(nil).Once()
+
+			err := service.resyncObjectHandler(context.Background(), msg)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedError, resp.Error)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, resp.Error)
+			assert.Equal(t, tt.expectedWrites, resp.Writes)
+			assert.Equal(t, tt.expectedDeletes, resp.Deletes)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"sync"
+)
+
+// objectTupleCountBuckets are the upper bounds of the object tuple count
+// histogram buckets.
+var objectTupleCountBuckets = []int{10, 100, 1000, 10000, 100000}
+
+// objectTupleCounts records the number of existing tuples read for each
+// synced object, keyed by object type, so that objects with unbounded tuple
+// growth can be alerted on.
+var objectTupleCounts = newLabeledHistogram(objectTupleCountBuckets)
+
+func init() {
+	expvar.Publish("fga_sync_object_tuple_count", objectTupleCounts)
+}
+
+// histogram is a cumulative bucketed histogram of integer observations.
+type histogram struct {
+	bounds  []int
+	buckets []uint64
+	count   uint64
+	sum     uint64
+	max     int
+}
+
+// observe records value in the histogram.
+func (h *histogram) observe(value int) {
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += uint64(max(value, 0)) //nolint:gosec // clamped to be non-negative
+	h.max = max(h.max, value)
+}
+
+// snapshot returns the histogram in its published form.
+func (h *histogram) snapshot() map[string]any {
+	buckets := make(map[string]uint64, len(h.bounds)+1)
+	for i, bound := range h.bounds {
+		buckets["le_"+strconv.Itoa(bound)] = h.buckets[i]
+	}
+	buckets["le_inf"] = h.count
+	return map[string]any{
+		"buckets": buckets,
+		"count":   h.count,
+		"sum":     h.sum,
+		"max":     h.max,
+	}
+}
+
+// labeledHistogram is a set of histograms keyed by a label value. It
+// implements [expvar.Var].
+type labeledHistogram struct {
+	mu         sync.Mutex
+	bounds     []int
+	histograms map[string]*histogram
+}
+
+// newLabeledHistogram returns an empty labeled histogram with the given
+// bucket upper bounds.
+func newLabeledHistogram(bounds []int) *labeledHistogram {
+	return &labeledHistogram{bounds: bounds, histograms: make(map[string]*histogram)}
+}
+
+// observe records value in the histogram for label.
+func (l *labeledHistogram) observe(label string, value int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.histograms[label]
+	if !ok {
+		h = &histogram{bounds: l.bounds, buckets: make([]uint64, len(l.bounds))}
+		l.histograms[label] = h
+	}
+	h.observe(value)
+}
+
+// get returns the snapshot of the histogram for label, or nil if nothing
+// has been observed for it.
+func (l *labeledHistogram) get(label string) map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.histograms[label]
+	if !ok {
+		return nil
+	}
+	return h.snapshot()
+}
+
+// String implements [expvar.Var].
+func (l *labeledHistogram) String() string {
+	l.mu.Lock()
+	snapshots := make(map[string]any, len(l.histograms))
+	for label, h := range l.histograms {
+		snapshots[label] = h.snapshot()
+	}
+	l.mu.Unlock()
+
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
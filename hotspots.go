@@ -0,0 +1,115 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+)
+
+const (
+	// defaultHotspotCapacity is the number of objects tracked by the check
+	// hotspot tracker.
+	defaultHotspotCapacity = 100
+	// defaultHotspotSampleRate records 1 in every N checked objects.
+	defaultHotspotSampleRate = 10
+)
+
+// checkHotspots tracks the most frequently checked objects. It is exposed at
+// /debug/vars as check_hotspots.
+var checkHotspots = newHotspotTracker(defaultHotspotCapacity, defaultHotspotSampleRate)
+
+func init() {
+	expvar.Publish("check_hotspots", expvar.Func(func() any {
+		return checkHotspots.top()
+	}))
+}
+
+// hotspotEntry is an object's approximate check volume. Count may overestimate
+// the true volume by at most Error; both are scaled by the sample rate.
+type hotspotEntry struct {
+	Object string `json:"object"`
+	Count  uint64 `json:"count"`
+	Error  uint64 `json:"error"`
+}
+
+// hotspotTracker is an approximate top-K counter using the space-saving
+// algorithm: memory is bounded by capacity no matter how many distinct
+// objects are observed, and heavy hitters are guaranteed to be retained.
+type hotspotTracker struct {
+	mu       sync.Mutex
+	capacity int
+	sampler  *logSampler
+	entries  map[string]*hotspotEntry
+}
+
+// newHotspotTracker returns a tracker holding up to capacity objects and
+// recording 1 in every sampleRate observations.
+func newHotspotTracker(capacity int, sampleRate uint64) *hotspotTracker {
+	return &hotspotTracker{
+		capacity: capacity,
+		sampler:  &logSampler{rate: sampleRate},
+		entries:  make(map[string]*hotspotEntry, capacity),
+	}
+}
+
+// record observes a check against object, subject to sampling.
+func (t *hotspotTracker) record(object string) {
+	if !t.sampler.sample() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.entries[object]; ok {
+		entry.Count++
+		return
+	}
+	if len(t.entries) < t.capacity {
+		t.entries[object] = &hotspotEntry{Object: object, Count: 1}
+		return
+	}
+
+	// Replace the entry with the lowest count; the newcomer inherits that
+	// count as its error bound.
+	var minEntry *hotspotEntry
+	for _, entry := range t.entries {
+		if minEntry == nil || entry.Count < minEntry.Count {
+			minEntry = entry
+		}
+	}
+	delete(t.entries, minEntry.Object)
+	t.entries[object] = &hotspotEntry{Object: object, Count: minEntry.Count + 1, Error: minEntry.Count}
+}
+
+// top returns the tracked objects ordered by descending count, with counts
+// scaled by the sample rate to estimate the true volume.
+func (t *hotspotTracker) top() []hotspotEntry {
+	scale := t.sampler.rate
+	if scale == 0 {
+		scale = 1
+	}
+
+	t.mu.Lock()
+	result := make([]hotspotEntry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		result = append(result, hotspotEntry{
+			Object: entry.Object,
+			Count:  entry.Count * scale,
+			Error:  entry.Error * scale,
+		})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Object < result[j].Object
+	})
+	return result
+}
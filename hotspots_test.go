@@ -0,0 +1,40 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotspotTracker(t *testing.T) {
+	tracker := newHotspotTracker(3, 1)
+
+	// A heavy hitter interleaved with a long tail of distinct objects.
+	for i := 0; i < 50; i++ {
+		tracker.record("project:hot")
+		tracker.record(fmt.Sprintf("project:tail-%d", i))
+	}
+
+	top := tracker.top()
+	assert.Len(t, top, 3, "tracker should stay within capacity")
+	assert.Equal(t, "project:hot", top[0].Object)
+	assert.GreaterOrEqual(t, top[0].Count, uint64(50))
+	assert.LessOrEqual(t, top[0].Count-top[0].Error, uint64(50))
+}
+
+func TestHotspotTracker_Sampling(t *testing.T) {
+	tracker := newHotspotTracker(10, 5)
+
+	for i := 0; i < 100; i++ {
+		tracker.record("project:hot")
+	}
+
+	top := tracker.top()
+	assert.Len(t, top, 1)
+	assert.Equal(t, uint64(100), top[0].Count, "sampled counts should be scaled by the sample rate")
+}
@@ -0,0 +1,80 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// inflight tracks the messages received and not yet handled by this replica.
+var inflight = newInflightTracker(time.Now)
+
+func init() {
+	expvar.Publish("fga_sync_oldest_inflight_age_seconds", expvar.Func(func() any {
+		return inflight.oldestAges()
+	}))
+}
+
+// inflightTracker records when each in-flight message was published, so the
+// age of the oldest one per subject shows whether the service is falling
+// behind: throughput can look healthy while a backlog grows.
+type inflightTracker struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	next     uint64
+	messages map[string]map[uint64]time.Time
+}
+
+// newInflightTracker returns an empty tracker reading the time from now.
+func newInflightTracker(now func() time.Time) *inflightTracker {
+	return &inflightTracker{now: now, messages: make(map[string]map[uint64]time.Time)}
+}
+
+// track records a message on subject published at timestamp as in flight.
+// The returned function marks it handled.
+func (t *inflightTracker) track(subject string, timestamp time.Time) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	id := t.next
+	if t.messages[subject] == nil {
+		t.messages[subject] = make(map[uint64]time.Time)
+	}
+	t.messages[subject][id] = timestamp
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(t.messages[subject], id)
+			if len(t.messages[subject]) == 0 {
+				delete(t.messages, subject)
+			}
+		})
+	}
+}
+
+// oldestAges returns the age in seconds of the oldest in-flight message of
+// each subject with messages in flight.
+func (t *inflightTracker) oldestAges() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	ages := make(map[string]float64, len(t.messages))
+	for subject, messages := range t.messages {
+		var oldest time.Time
+		for _, timestamp := range messages {
+			if oldest.IsZero() || timestamp.Before(oldest) {
+				oldest = timestamp
+			}
+		}
+		ages[subject] = now.Sub(oldest).Seconds()
+	}
+	return ages
+}
@@ -0,0 +1,73 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInflightTracker_OldestAges(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracker := newInflightTracker(func() time.Time { return now })
+
+	doneOld := tracker.track("lfx.fga-sync.update_access", now.Add(-30*time.Second))
+	doneNew := tracker.track("lfx.fga-sync.update_access", now.Add(-2*time.Second))
+	doneOther := tracker.track("lfx.fga-sync.member_put", now.Add(-time.Second))
+	assert.Equal(t, map[string]float64{
+		"lfx.fga-sync.update_access": 30,
+		"lfx.fga-sync.member_put":    1,
+	}, tracker.oldestAges())
+
+	doneOld()
+	doneOld()
+	doneOther()
+	assert.Equal(t, map[string]float64{"lfx.fga-sync.update_access": 2}, tracker.oldestAges())
+
+	doneNew()
+	assert.Empty(t, tracker.oldestAges())
+}
+
+// TestSubscribeToDispatchTable_InflightAge asserts that a message delayed
+// before it is received counts towards the oldest in-flight age of its
+// subject while it is handled, and no longer once it is.
+func TestSubscribeToDispatchTable_InflightAge(t *testing.T) {
+	var process func(context.Context, INatsMsg)
+	original := queueSubscribe
+	queueSubscribe = func(_, _ string, p func(context.Context, INatsMsg)) error {
+		process = p
+		return nil
+	}
+	t.Cleanup(func() { queueSubscribe = original })
+
+	const subject = "lfx.fga-sync.update_access"
+	var during map[string]float64
+	table := dispatchTable{subject: {
+		subject:     subject,
+		description: "generic update access",
+		handler: func(context.Context, INatsMsg) error {
+			during = inflight.oldestAges()
+			return nil
+		},
+	}}
+	assert.NoError(t, subscribeToDispatchTable("lfx.fga-sync.>", "queue", table))
+
+	msg := CreateMockNatsMsg([]byte("{}"))
+	msg.subject = subject
+	msg.timestamp = time.Now().Add(-time.Minute)
+	process(context.Background(), msg)
+
+	assert.GreaterOrEqual(t, during[subject], time.Minute.Seconds())
+	assert.NotContains(t, inflight.oldestAges(), subject)
+}
+
+func TestNatsMsg_Timestamp(t *testing.T) {
+	received := time.Now()
+	msg := &NatsMsg{Msg: nats.NewMsg("lfx.fga-sync.update_access"), received: received}
+	assert.Equal(t, received, msg.Timestamp(), "messages without JetStream metadata use the receive time")
+}
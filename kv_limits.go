@@ -0,0 +1,41 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxKVValueSize bounds the values written to the cache bucket. KV values are
+// published as NATS messages, so they are limited by the server's
+// max_payload, 1 MiB by default.
+const maxKVValueSize = 1 << 20
+
+// invalidationMarker is the value of every cache invalidation marker. Only
+// the marker's KV timestamp is read, so the value never grows: invalidation
+// state is one fixed-size key per scope, and any finer-grained invalidation
+// must likewise add keys rather than accumulate entries in a value.
+var invalidationMarker = []byte("1")
+
+// checkKVValueSize rejects a value for key that the cache bucket would not
+// accept, so that the write fails with a clear error rather than a NATS
+// payload error.
+func checkKVValueSize(key string, value []byte) error {
+	if len(value) > maxKVValueSize {
+		return fmt.Errorf("value of %s is %d bytes, exceeding the %d byte KV value limit", key, len(value), maxKVValueSize)
+	}
+	return nil
+}
+
+// putInvalidationMarker writes the invalidation marker at key, invalidating
+// the cache entries it covers as of now.
+func (s FgaService) putInvalidationMarker(ctx context.Context, key string) error {
+	if err := checkKVValueSize(key, invalidationMarker); err != nil {
+		return err
+	}
+	_, err := s.cacheBucket.Put(ctx, key, invalidationMarker)
+	return err
+}
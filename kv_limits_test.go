@@ -0,0 +1,63 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestInvalidationState_Bounded asserts that invalidating the cache for an
+// object with many relations, over several write batches and cache scopes,
+// leaves one fixed-size marker per scope rather than growing values.
+func TestInvalidationState_Bounded(t *testing.T) {
+	fgaClient := new(MockFgaClient)
+	fgaClient.On("Write", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+	bucket := NewMockKeyValue()
+	service := FgaService{client: fgaClient, cacheBucket: bucket, useCache: true}
+
+	var writes []client.ClientTupleKey
+	for i := range 1000 {
+		writes = append(writes, client.ClientTupleKey{
+			User:     fmt.Sprintf("user:u%d", i),
+			Relation: fmt.Sprintf("relation_%d", i%50),
+			Object:   "committee:c1",
+		})
+	}
+	assert.NoError(t, service.WriteAndDeleteTuples(context.Background(), writes, nil))
+	for i := range 100 {
+		assert.NoError(t, service.RefreshCacheScope(context.Background(), fmt.Sprintf("scope-%d", i)))
+	}
+
+	markers := 0
+	for key, value := range bucket.data {
+		if key == "inv" || strings.HasPrefix(key, "inv.") {
+			markers++
+			assert.Equal(t, invalidationMarker, value, key)
+		}
+	}
+	assert.Equal(t, 101, markers, "expected the unscoped marker plus one per scope")
+}
+
+func TestSchedulePendingDelete_ValueSizeLimit(t *testing.T) {
+	bucket := NewMockKeyValue()
+	service := FgaService{stateBucket: bucket}
+
+	cascade := make([]string, maxKVValueSize/16)
+	for i := range cascade {
+		cascade[i] = fmt.Sprintf("meeting:%010d", i)
+	}
+	err := service.SchedulePendingDelete(context.Background(), pendingDelete{Object: "project:p1", Cascade: cascade})
+	assert.ErrorContains(t, err, "KV value limit")
+	assert.NotContains(t, bucket.data, pendingDeleteKey("project:p1"))
+
+	err = service.SchedulePendingDelete(context.Background(), pendingDelete{Object: "project:p1", Cascade: cascade[:100]})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,123 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// maxSampledOutRecords bounds the log records held for one sampled-out
+// message; later records are dropped.
+const maxSampledOutRecords = 100
+
+// logSampledOutKey marks a message context whose happy-path logs should be
+// dropped.
+type logSampledOutKey struct{}
+
+// sampledOutRecord is a log record held back for a sampled-out message, with
+// the handler that would have written it.
+type sampledOutRecord struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+// sampledOutLogs holds the Debug and Info records of a sampled-out message,
+// so that they are still written if the message fails or is slow.
+type sampledOutLogs struct {
+	mu      sync.Mutex
+	records []sampledOutRecord
+	flushed bool
+}
+
+// withLogSampledOut returns a context whose Debug and Info log records are
+// held back by [samplingHandler], and dropped unless [flushSampledOutLogs] is
+// called. Warn and Error records are always kept.
+func withLogSampledOut(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logSampledOutKey{}, &sampledOutLogs{})
+}
+
+// sampledOutLogsFrom returns the records held back for ctx, or nil if ctx
+// was not marked by [withLogSampledOut].
+func sampledOutLogsFrom(ctx context.Context) *sampledOutLogs {
+	if ctx == nil {
+		return nil
+	}
+	logs, _ := ctx.Value(logSampledOutKey{}).(*sampledOutLogs)
+	return logs
+}
+
+// flushSampledOutLogs writes the records held back for ctx, for a message
+// that failed or was slow after being sampled out. Records logged after the
+// flush are written directly.
+func flushSampledOutLogs(ctx context.Context) {
+	logs := sampledOutLogsFrom(ctx)
+	if logs == nil {
+		return
+	}
+	logs.mu.Lock()
+	records := logs.records
+	logs.records, logs.flushed = nil, true
+	logs.mu.Unlock()
+	for _, held := range records {
+		_ = held.handler.Handle(ctx, held.record)
+	}
+}
+
+// logSampler keeps 1 in every rate messages. A rate of 0 or 1 keeps every
+// message.
+type logSampler struct {
+	rate    uint64
+	counter atomic.Uint64
+}
+
+// sample reports whether the next message should be logged in full.
+func (s *logSampler) sample() bool {
+	if s == nil || s.rate <= 1 {
+		return true
+	}
+	return s.counter.Add(1)%s.rate == 1
+}
+
+// samplingHandler is a [slog.Handler] that holds back records below Warn for
+// contexts marked by [withLogSampledOut], so errors and warnings are never
+// sampled out.
+type samplingHandler struct {
+	next slog.Handler
+}
+
+// Enabled implements [slog.Handler.Enabled].
+func (h samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements [slog.Handler.Handle].
+func (h samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	logs := sampledOutLogsFrom(ctx)
+	if logs == nil || record.Level >= slog.LevelWarn {
+		return h.next.Handle(ctx, record)
+	}
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	switch {
+	case logs.flushed:
+		return h.next.Handle(ctx, record)
+	case len(logs.records) < maxSampledOutRecords:
+		logs.records = append(logs.records, sampledOutRecord{handler: h.next, record: record.Clone()})
+	}
+	return nil
+}
+
+// WithAttrs implements [slog.Handler.WithAttrs].
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup implements [slog.Handler.WithGroup].
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{next: h.next.WithGroup(name)}
+}
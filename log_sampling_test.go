@@ -0,0 +1,80 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDispatchMessage_LogSampling asserts that errors and slow handlers are
+// never sampled out while happy-path info logs are reduced, and that the info
+// logs of a failing message are written even when it was sampled out.
+func TestDispatchMessage_LogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	origLogger, origSampler, origThreshold := logger, dispatchLogSampler, slowHandlerThreshold
+	logger = slog.New(samplingHandler{next: slog.NewTextHandler(&buf, nil)})
+	dispatchLogSampler = &logSampler{rate: 5}
+	slowHandlerThreshold = time.Hour
+	defer func() {
+		logger, dispatchLogSampler, slowHandlerThreshold = origLogger, origSampler, origThreshold
+	}()
+
+	happy := func(ctx context.Context, _ INatsMsg) error {
+		logger.InfoContext(ctx, "happy path detail")
+		return nil
+	}
+	failing := func(ctx context.Context, _ INatsMsg) error {
+		logger.InfoContext(ctx, "failing path detail")
+		logger.ErrorContext(ctx, "failing path error")
+		return errors.New("boom")
+	}
+
+	for i := 0; i < 10; i++ {
+		dispatchMessage(context.Background(), "test.subject", "happy", "queue", happy, CreateMockNatsMsg(nil))
+	}
+	for i := 0; i < 10; i++ {
+		dispatchMessage(context.Background(), "test.subject", "failing", "queue", failing, CreateMockNatsMsg(nil))
+	}
+
+	out := buf.String()
+	assert.Equal(t, 2, strings.Count(out, "happy path detail"), "happy-path logs should be sampled 1 in 5")
+	assert.Equal(t, 2, strings.Count(out, "handled happy request"), "happy-path summaries should be sampled 1 in 5")
+	assert.Equal(t, 10, strings.Count(out, "failing path detail"), "failing messages must keep their info logs")
+	assert.Equal(t, 10, strings.Count(out, "failing path error"), "error logs must never be sampled out")
+	assert.Equal(t, 10, strings.Count(out, "error handling failing request"), "dispatch errors must never be sampled out")
+}
+
+// TestDispatchMessage_SlowHandler asserts that slow handlers are always
+// logged, along with their info logs.
+func TestDispatchMessage_SlowHandler(t *testing.T) {
+	var buf bytes.Buffer
+	origLogger, origSampler, origThreshold := logger, dispatchLogSampler, slowHandlerThreshold
+	logger = slog.New(samplingHandler{next: slog.NewTextHandler(&buf, nil)})
+	dispatchLogSampler = &logSampler{rate: 1000}
+	slowHandlerThreshold = time.Millisecond
+	defer func() {
+		logger, dispatchLogSampler, slowHandlerThreshold = origLogger, origSampler, origThreshold
+	}()
+
+	slow := func(ctx context.Context, _ INatsMsg) error {
+		logger.InfoContext(ctx, "slow path detail")
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		dispatchMessage(context.Background(), "test.subject", "slow", "queue", slow, CreateMockNatsMsg(nil))
+	}
+
+	assert.Equal(t, 3, strings.Count(buf.String(), "slow slow request"))
+	assert.Equal(t, 3, strings.Count(buf.String(), "slow path detail"))
+}
@@ -6,12 +6,14 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -36,6 +38,14 @@ const (
 	// request timeout, and lower than the pod or liveness probe's
 	// terminationGracePeriodSeconds.
 	gracefulShutdownSeconds = 25
+	// defaultSlowHandlerThreshold is the default for SLOW_HANDLER_THRESHOLD.
+	defaultSlowHandlerThreshold = time.Second
+	// maxUnhandledSubjectLabels bounds the subjects counted separately in
+	// unhandledMessages.
+	maxUnhandledSubjectLabels = 50
+	// unhandledSubjectOther is the unhandledMessages key counting unhandled
+	// subjects past maxUnhandledSubjectLabels.
+	unhandledSubjectOther = "other"
 )
 
 // Build-time variables set via ldflags
@@ -46,31 +56,23 @@ var (
 )
 
 var (
-	logger          *slog.Logger
-	httpServer      *http.Server
-	natsURL         string
-	natsConn        *nats.Conn
-	jetstreamConn   jetstream.JetStream
-	cacheBucketName string
-	// TODO: improve the configuration of the service to use dependency injection instead of global variables
-	useCache bool
+	logger        *slog.Logger
+	httpServer    *http.Server
+	natsConn      *nats.Conn
+	jetstreamConn jetstream.JetStream
+	// dispatchLogSampler samples the happy-path logs of dispatched messages.
+	dispatchLogSampler = &logSampler{rate: 1}
+	// slowHandlerThreshold is the handler duration above which a message is
+	// always logged, regardless of sampling.
+	slowHandlerThreshold = defaultSlowHandlerThreshold
+	// maxMessageSize is the largest payload, in bytes, passed to a handler.
+	// Zero means no limit beyond the NATS server's own.
+	maxMessageSize int
+	// messageBudget bounds the total time a handler spends on one message.
+	// Zero means no bound beyond each handler's own timeouts.
+	messageBudget time.Duration
 )
 
-func init() {
-	natsURL = os.Getenv("NATS_URL")
-	if natsURL == "" {
-		natsURL = "nats://nats:4222"
-	}
-	cacheBucketName = os.Getenv("CACHE_BUCKET")
-	if cacheBucketName == "" {
-		cacheBucketName = "fga-sync-cache"
-	}
-	useCacheStr := os.Getenv("USE_CACHE")
-	if useCacheStr == trueString {
-		useCache = true
-	}
-}
-
 // main parses optional flags and starts the NATS subscribers.
 func main() {
 	// Allow overriding the port by environmental variable as well as command
@@ -100,7 +102,7 @@ func main() {
 	// Create JSON handler and wrap with slog-otel to add trace_id and span_id from context
 	jsonHandler := slog.NewJSONHandler(os.Stdout, logOptions)
 	otelHandler := slogotel.OtelHandler{Next: jsonHandler}
-	logger = slog.New(otelHandler)
+	logger = slog.New(samplingHandler{next: otelHandler})
 	slog.SetDefault(logger)
 
 	if err := run(*bind, *port); err != nil {
@@ -113,6 +115,24 @@ func main() {
 // deferred cleanup functions (e.g. OpenTelemetry shutdown) run before
 // main() calls os.Exit on error.
 func run(bind, port string) error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	// Dispatch-wide settings are shared by every subscription.
+	dispatchLogSampler = &logSampler{rate: cfg.LogSampleRate}
+	slowHandlerThreshold = cfg.SlowHandlerThreshold
+	checkHotspots = newHotspotTracker(defaultHotspotCapacity, cfg.CheckHotspotSampleRate)
+	if cfg.CaptureSampleRate > 0 {
+		captureSampler = &logSampler{rate: cfg.CaptureSampleRate}
+	}
+	successReply = cfg.Reply
+	maintenance = newMaintenanceMode(cfg.MaintenanceMaxParked, cfg.Maintenance, time.Now)
+	maxMessageSize = cfg.MaxMessageSize
+	messageBudget = cfg.MessageBudget
+	objectTypes = cfg.ObjectTypes
+	strictDecoding = cfg.StrictDecoding
+
 	// Set up OpenTelemetry SDK.
 	// Command-line/environment OTEL_SERVICE_VERSION takes precedence over
 	// the build-time Version variable.
@@ -134,12 +154,23 @@ func run(bind, port string) error {
 	}()
 
 	// Create an OpenFGA client.
-	fgaClient, err := connectFga()
+	fgaClient, err := connectFga(cfg.Fga)
 	if err != nil {
 		return fmt.Errorf("error creating OpenFGA client: %w", err)
 	}
 
-	logger.With("url", os.Getenv("OPENFGA_API_URL")).Info("OpenFGA client created")
+	logger.With("url", redactURL(cfg.Fga.apiURL)).Info("OpenFGA client created")
+
+	// Verify the configured store and authorization model can be resolved
+	// before accepting any messages, retrying while OpenFGA starts up.
+	retryCfg := cfg.StartupRetry
+	err = retryWithBackoff(context.Background(), "openfga", retryCfg, func(ctx context.Context) error {
+		_, errModel := fgaClient.ReadAuthorizationModel(ctx)
+		return errModel
+	})
+	if err != nil {
+		return fmt.Errorf("error resolving OpenFGA authorization model: %w", err)
+	}
 
 	// Create HTTP handlers for health checks.
 	createHTTPHandlers()
@@ -158,9 +189,8 @@ func run(bind, port string) error {
 
 	// Create NATS connection.
 	gracefulCloseWG.Add(1)
-	natsConn, err = nats.Connect(
-		natsURL,
-		nats.DrainTimeout(gracefulShutdownSeconds*time.Second),
+	natsOpts := []nats.Option{
+		nats.DrainTimeout(gracefulShutdownSeconds * time.Second),
 		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
 			if err != nil {
 				logger.With(errKey, err).Warn("NATS disconnected with error")
@@ -200,26 +230,76 @@ func run(bind, port string) error {
 			// Exit with an error instead of decrementing the wait group.
 			os.Exit(1)
 		}),
-	)
+	}
+	err = retryWithBackoff(context.Background(), "nats", retryCfg, func(_ context.Context) error {
+		var errConnect error
+		natsConn, errConnect = nats.Connect(cfg.NatsURL, natsOpts...)
+		return errConnect
+	})
 	if err != nil {
 		return fmt.Errorf("error creating NATS client: %w", err)
 	}
-	logger.With("url", natsURL).Info("NATS client created")
+	logger.With("url", cfg.NatsURL).Info("NATS client created")
 
 	jetstreamConn, err = jetstream.New(natsConn)
 	if err != nil {
 		return fmt.Errorf("error creating JetStream client: %w", err)
 	}
-	cacheBucket, err := jetstreamConn.KeyValue(context.Background(), cacheBucketName)
+	cacheBucket, err := jetstreamConn.KeyValue(context.Background(), cfg.CacheBucket)
 	if err != nil {
 		return fmt.Errorf("error binding to cache bucket: %w", err)
 	}
+	stateBucket, err := jetstreamConn.KeyValue(context.Background(), cfg.StateBucket)
+	if err != nil {
+		return fmt.Errorf("error binding to state bucket: %w", err)
+	}
 
-	handlerService := HandlerService{
-		fgaService: FgaService{
-			client:      fgaClient,
-			cacheBucket: cacheBucket,
-		},
+	shadowClient, err := connectShadowFga(cfg.ShadowFga)
+	if err != nil {
+		return fmt.Errorf("error creating shadow OpenFGA client: %w", err)
+	}
+	if shadowClient != nil {
+		logger.With("store_id", cfg.ShadowFga.storeID).Info("shadow OpenFGA client created")
+	}
+
+	handlerService := newHandlerService(cfg, fgaClient, shadowClient, cacheBucket, stateBucket)
+	if cfg.HTTPCheck {
+		// The health check listener is already serving; /check answers 404
+		// until it is registered here.
+		http.Handle("/check", handlerService.httpCheckHandler())
+	}
+
+	if cfg.UseCache {
+		warmCache(ctx, handlerService.fgaService)
+	}
+
+	if cfg.WatchdogWindow > 0 {
+		workWatchdog = newWatchdog(cfg.WatchdogWindow, cfg.WatchdogActiveFrom, cfg.WatchdogActiveTo, time.Now)
+		if cfg.WatchdogAlertSubject != "" {
+			workWatchdog.alert = publishWatchdogAlert(cfg.WatchdogAlertSubject)
+		}
+		go workWatchdog.run(ctx)
+	}
+
+	if cfg.StatsSummaryInterval > 0 {
+		processingSummary = newStatsSummary(cfg.StatsSummaryInterval, time.Now)
+		go processingSummary.run(ctx)
+	}
+
+	go handlerService.runGrantExpirySweeper(ctx, cfg.GrantExpirySweepInterval)
+
+	auditSubject = cfg.AuditSubject
+	if cfg.DeadLetterSubject != "" {
+		deadLetter = publishDeadLetter(cfg.DeadLetterSubject)
+	}
+	// Deferred deletes are audited, so sweep them only once auditing is set up.
+	go handlerService.runPendingDeleteSweeper(ctx, pendingDeleteSweepInterval)
+
+	if cfg.PartitionedWorkers > 0 {
+		dispatchWorkers = newPartitionedWorkers(cfg.PartitionedWorkers)
+	}
+	if len(cfg.BackfillSubjects) > 0 {
+		backfillWorkers = newPartitionedWorkers(cfg.BackfillWorkers)
 	}
 
 	if err = createQueueSubscriptions(handlerService); err != nil {
@@ -244,6 +324,15 @@ func run(bind, port string) error {
 	// Wait for the graceful shutdown steps to complete.
 	gracefulCloseWG.Wait()
 
+	// Finish any messages still queued on the partitioned workers. Their
+	// writes are applied, but replies can no longer be sent.
+	if dispatchWorkers != nil {
+		dispatchWorkers.stop()
+	}
+	if backfillWorkers != nil {
+		backfillWorkers.stop()
+	}
+
 	// Immediately close the HTTP server after graceful shutdown has finished.
 	if err = httpServer.Close(); err != nil {
 		logger.With(errKey, err).Error("http listener error on close")
@@ -252,6 +341,27 @@ func run(bind, port string) error {
 	return nil
 }
 
+// warmCache pre-populates the cache from the configured warm set. Failures
+// are logged and otherwise ignored, since a cold cache is still correct.
+func warmCache(ctx context.Context, fgaService FgaService) {
+	warmSet, err := cacheWarmSetFromEnv()
+	if err != nil {
+		logger.With(errKey, err).Warn("failed to read cache warm set")
+		return
+	}
+	if len(warmSet) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, cacheWarmTimeout)
+	defer cancel()
+	warmed, err := fgaService.WarmCache(ctx, warmSet)
+	if err != nil {
+		logger.With(errKey, err).Warn("cache warming failed")
+		return
+	}
+	logger.With("tuples", warmed).Info("cache warmed")
+}
+
 func startHTTPListener(bind, port string) {
 	// Add an http listener for health checks. This server does NOT participate
 	// in the graceful shutdown process; we want it to stay up until the process
@@ -266,7 +376,7 @@ func startHTTPListener(bind, port string) {
 	handler := otelhttp.NewHandler(http.DefaultServeMux, "fga-sync",
 		otelhttp.WithFilter(func(r *http.Request) bool {
 			p := r.URL.Path
-			return p != "/livez" && p != "/readyz"
+			return p != "/livez" && p != "/readyz" && p != "/workz" && p != "/maintenancez"
 		}),
 	)
 
@@ -298,6 +408,34 @@ func createHTTPHandlers() {
 		}
 	})
 
+	// Work liveness: fails when the work watchdog has seen no messages within
+	// its window. Intended for alerting, not for Kubernetes probes.
+	http.HandleFunc("/workz", func(w http.ResponseWriter, _ *http.Request) {
+		if !workWatchdog.healthy() {
+			http.Error(w, "no messages processed within watchdog window", http.StatusServiceUnavailable)
+			return
+		}
+		_, err := fmt.Fprintf(w, "OK\n")
+		if err != nil {
+			logger.With(errKey, err).Error("error writing to response writer")
+		}
+	})
+
+	// Maintenance mode: fails while writes are being parked, so planned
+	// maintenance is visible to monitoring. Checks are still served, so it is
+	// not a readiness check.
+	http.HandleFunc("/maintenancez", func(w http.ResponseWriter, _ *http.Request) {
+		if active, since, parked := maintenance.status(); active {
+			http.Error(w, fmt.Sprintf("maintenance mode since %s; %d writes parked",
+				since.UTC().Format(time.RFC3339), parked), http.StatusServiceUnavailable)
+			return
+		}
+		_, err := fmt.Fprintf(w, "OK\n")
+		if err != nil {
+			logger.With(errKey, err).Error("error writing to response writer")
+		}
+	})
+
 	// Basic health check.
 	http.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
 		if natsConn == nil {
@@ -323,35 +461,156 @@ type subscriptionConfig struct {
 	subject     string
 	handler     HandlerFunc
 	description string
+	// writes is set for subjects whose handler writes to OpenFGA, so their
+	// messages are parked in maintenance mode.
+	writes bool
+	// broadcast is set for subjects every replica handles, rather than one
+	// replica of the queue group.
+	broadcast bool
 }
 
-// subscribeToSubject subscribes to a single NATS subject with error handling and logging.
-func subscribeToSubject(subject, description, queue string, handler HandlerFunc) error {
-	if _, err := natsConn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+// dispatchTable maps exact subjects to their subscription configuration.
+type dispatchTable map[string]subscriptionConfig
+
+// dispatch routes msg to the handler registered for its subject, or records
+// it as unhandled when no handler is registered.
+func (t dispatchTable) dispatch(ctx context.Context, queue string, msg INatsMsg) {
+	config, ok := t[msg.Subject()]
+	if !ok {
+		handleUnhandledSubject(ctx, queue, msg)
+		return
+	}
+	if config.broadcast {
+		// Handled by every replica's own subscription to the subject.
+		return
+	}
+	dispatchSubscription(ctx, config, queue, msg)
+}
+
+// dispatchSubscription dispatches msg to the handler of config, first
+// parking it if its handler writes to OpenFGA and maintenance mode is on.
+func dispatchSubscription(ctx context.Context, config subscriptionConfig, queue string, msg INatsMsg) {
+	run := func() { dispatchMessage(ctx, config.subject, config.description, queue, config.handler, msg) }
+	if config.writes {
+		parked, err := maintenance.park(run)
+		if err != nil {
+			rejectParkedWrite(ctx, config.subject, queue, msg, err)
+			return
+		}
+		if parked {
+			logger.DebugContext(ctx, "parked "+config.description+" request for maintenance",
+				"subject", config.subject,
+				"queue", queue,
+			)
+			return
+		}
+	}
+	run()
+}
+
+// rejectParkedWrite logs and dead-letters a write that could not be parked
+// for maintenance. If the sender is waiting on a reply, an error reply is
+// sent.
+func rejectParkedWrite(ctx context.Context, subject, queue string, msg INatsMsg, err error) {
+	logger.With(errKey, err).ErrorContext(ctx, "rejected write during maintenance",
+		"subject", subject,
+		"queue", queue,
+	)
+	if deadLetter != nil {
+		deadLetter(ctx, msg, err.Error())
+	}
+	respondError(ctx, msg, err)
+}
+
+// handleUnhandledSubject logs, counts and dead-letters a message that arrived
+// on a subject with no registered handler. If the sender is waiting on a
+// reply, an error reply is sent so the request fails fast rather than timing
+// out.
+func handleUnhandledSubject(ctx context.Context, queue string, msg INatsMsg) {
+	unhandledMessages.Add(unhandledSubjectLabel(msg.Subject()), 1)
+	logger.WarnContext(ctx, "received message on unhandled subject",
+		"subject", msg.Subject(),
+		"queue", queue,
+	)
+	err := fmt.Errorf("unhandled subject: %s", msg.Subject())
+	if deadLetter != nil {
+		deadLetter(ctx, msg, err.Error())
+	}
+	respondError(ctx, msg, err)
+}
+
+// unhandledSubjectLabel returns the unhandledMessages key counting subject.
+// Publishers choose the subjects, so past maxUnhandledSubjectLabels distinct
+// ones, new subjects are counted under unhandledSubjectOther, keeping the
+// expvar map bounded.
+func unhandledSubjectLabel(subject string) string {
+	if unhandledMessages.Get(subject) != nil {
+		return subject
+	}
+	labels := 0
+	unhandledMessages.Do(func(expvar.KeyValue) { labels++ })
+	if labels >= maxUnhandledSubjectLabels {
+		return unhandledSubjectOther
+	}
+	return subject
+}
+
+// queueSubscribe subscribes to subject in the given queue group, extracting
+// the trace context from each message before passing it to process. It is a
+// variable so tests can record subscriptions without a NATS connection.
+var queueSubscribe = func(subject, queue string, process func(context.Context, INatsMsg)) error {
+	_, err := natsConn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
 		// Extract trace context from message headers, handling nil header gracefully
 		var hdr nats.Header
 		if msg.Header != nil {
 			hdr = msg.Header
 		}
 		msgCtx := otel.GetTextMapPropagator().Extract(context.Background(), natsHeaderCarrier(hdr))
-		msgCtx, span := tracer.Start(msgCtx, "nats.process",
-			trace.WithSpanKind(trace.SpanKindConsumer),
-			trace.WithAttributes(
-				attribute.String("messaging.system", "nats"),
-				attribute.String("messaging.destination.name", subject),
-				attribute.String("messaging.operation.type", "process"),
-			),
-		)
-		defer span.End()
-		if errHandler := handler(msgCtx, &NatsMsg{msg}); errHandler != nil {
-			span.RecordError(errHandler)
-			span.SetStatus(codes.Error, errHandler.Error())
-			logger.Error("error handling "+description+" request",
-				errKey, errHandler,
-				"subject", subject,
-				"queue", queue,
-			)
+		process(msgCtx, &NatsMsg{Msg: msg, received: time.Now()})
+	})
+	return err
+}
+
+// subscribeToDispatchTable subscribes to a wildcard subject and routes each
+// message through table.
+func subscribeToDispatchTable(wildcard, queue string, table dispatchTable) error {
+	if err := queueSubscribe(wildcard, queue, func(ctx context.Context, msg INatsMsg) {
+		// Messages waiting for a partitioned worker are in flight too.
+		done := inflight.track(msg.Subject(), msg.Timestamp())
+		if dispatchWorkers == nil {
+			defer done()
+			table.dispatch(ctx, queue, msg)
+			return
 		}
+		dispatchWorkers.submit(partitionKey(msg), func() {
+			defer done()
+			table.dispatch(ctx, queue, msg)
+		})
+	}); err != nil {
+		logger.Error("error subscribing to NATS subject",
+			errKey, err,
+			"subject", wildcard,
+			"queue", queue,
+		)
+		return err
+	}
+	for subject := range table {
+		logger.Info("subscribed to NATS subject",
+			"subject", subject,
+			"wildcard", wildcard,
+			"queue", queue,
+		)
+	}
+	return nil
+}
+
+// subscribeToSubject subscribes to a single NATS subject with error handling
+// and logging. An empty queue subscribes outside any queue group.
+func subscribeToSubject(config subscriptionConfig, queue string) error {
+	subject := config.subject
+	if err := queueSubscribe(subject, queue, func(ctx context.Context, msg INatsMsg) {
+		defer inflight.track(msg.Subject(), msg.Timestamp())()
+		dispatchSubscription(ctx, config, queue, msg)
 	}); err != nil {
 		logger.Error("error subscribing to NATS subject",
 			errKey, err,
@@ -367,51 +626,242 @@ func subscribeToSubject(subject, description, queue string, handler HandlerFunc)
 	return nil
 }
 
+// dispatchMessage runs handler for a single message inside a consumer span.
+// Errors and slow handlers are always logged; the happy-path info logs
+// emitted while handling the message are sampled per LOG_SAMPLE_RATE. Those
+// of a sampled-out message are held back, and written after all if it fails
+// or is slow.
+func dispatchMessage(
+	ctx context.Context,
+	subject, description, queue string,
+	handler HandlerFunc,
+	msg INatsMsg,
+) {
+	ctx, span := tracer.Start(ctx, "nats.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", subject),
+			attribute.String("messaging.operation.type", "process"),
+		),
+	)
+	defer span.End()
+
+	// Captured messages are always logged in full.
+	ctx, captured := withMessageCapture(ctx, subject, msg)
+	if !captured && !dispatchLogSampler.sample() {
+		ctx = withLogSampledOut(ctx)
+	}
+	ctx = withFgaRequestIDs(ctx)
+	ctx = withOpenfgaCalls(ctx)
+	ctx = withAuditTrail(ctx, subject, msg)
+
+	if maxMessageSize > 0 && len(msg.Data()) > maxMessageSize {
+		workWatchdog.record()
+		flushSampledOutLogs(ctx)
+		err := rejectOversizedMessage(ctx, subject, queue, msg)
+		processingSummary.recordMessage(subject, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	budgetCtx, cancel := withMessageBudget(ctx)
+	defer cancel()
+	start := time.Now()
+	tracked := &replyTracker{INatsMsg: msg}
+	errHandler := handler(budgetCtx, tracked)
+	duration := time.Since(start)
+	replyHandlerError(ctx, tracked, errHandler)
+	workWatchdog.record()
+	processingSummary.recordMessage(subject, errHandler)
+	// Changes applied before a failure are audited too: a redelivery finds
+	// them already in place and won't write them again.
+	publishAuditTrail(ctx)
+	logMessageCapture(ctx, errHandler)
+
+	attrs := []any{
+		"subject", subject,
+		"queue", queue,
+		"duration_ms", duration.Milliseconds(),
+	}
+	if ids := fgaRequestIDsFrom(ctx); len(ids) > 0 {
+		attrs = append(attrs, "openfga_request_ids", ids)
+	}
+	calls, totalCalls := openfgaCallsFrom(ctx)
+	openfgaCallsPerMessage.observe(subject, totalCalls)
+	if totalCalls > 0 {
+		attrs = append(attrs, "openfga_calls", calls)
+	}
+
+	switch {
+	case budgetExhausted(budgetCtx, subject, msg, errHandler):
+		flushSampledOutLogs(ctx)
+		span.RecordError(errHandler)
+		span.SetStatus(codes.Error, errHandler.Error())
+		logger.ErrorContext(ctx, description+" request exhausted its message budget",
+			append([]any{errKey, errHandler, "budget", messageBudget.String()}, attrs...)...)
+	case errHandler != nil:
+		flushSampledOutLogs(ctx)
+		span.RecordError(errHandler)
+		span.SetStatus(codes.Error, errHandler.Error())
+		logger.ErrorContext(ctx, "error handling "+description+" request", append([]any{errKey, errHandler}, attrs...)...)
+	case duration >= slowHandlerThreshold:
+		flushSampledOutLogs(ctx)
+		logger.WarnContext(ctx, "slow "+description+" request", attrs...)
+	default:
+		logger.InfoContext(ctx, "handled "+description+" request", attrs...)
+	}
+}
+
+// rejectOversizedMessage logs, counts, and dead-letters a message whose
+// payload exceeds maxMessageSize, without unmarshaling it. If the sender is
+// waiting on a reply, an error reply is sent.
+func rejectOversizedMessage(ctx context.Context, subject, queue string, msg INatsMsg) error {
+	err := fmt.Errorf("message size %d bytes exceeds limit of %d bytes", len(msg.Data()), maxMessageSize)
+	oversizedMessages.Add(subject, 1)
+	logger.With(errKey, err).ErrorContext(ctx, "rejected oversized message",
+		"subject", subject,
+		"queue", queue,
+	)
+	if deadLetter != nil {
+		deadLetter(ctx, msg, err.Error())
+	}
+	respondError(ctx, msg, err)
+	return err
+}
+
 // createQueueSubscriptions creates queue subscriptions for the NATS subjects.
 func createQueueSubscriptions(handlerService HandlerService) error {
-	queue := constants.FgaSyncQueue
+	subjects := newSubjectSet(handlerService.config.SubjectPrefix)
+	queue := subjects.of(constants.FgaSyncQueue)
 
 	// Define all subscriptions in a slice for easy maintenance
 	subscriptions := []subscriptionConfig{
 		{
-			subject:     constants.AccessCheckSubject,
+			subject:     subjects.of(constants.AccessCheckSubject),
 			handler:     handlerService.accessCheckHandler,
 			description: "access check",
 		},
 		{
-			subject:     constants.ReadTuplesSubject,
+			subject:     subjects.of(constants.ReadTuplesSubject),
 			handler:     handlerService.readTuplesHandler,
 			description: "read tuples",
 		},
+		{
+			subject:     subjects.of(constants.ListObjectsSubject),
+			handler:     handlerService.listObjectsHandler,
+			description: "list objects",
+		},
+		{
+			subject:     subjects.of(constants.RelationsSubject),
+			handler:     handlerService.relationsHandler,
+			description: "relations",
+		},
+		{
+			subject:     subjects.of(constants.ResyncObjectSubject),
+			handler:     handlerService.resyncObjectHandler,
+			description: "resync object",
+			writes:      true,
+		},
+		{
+			subject:     subjects.of(constants.ResyncObjectsSubject),
+			handler:     handlerService.resyncObjectsHandler,
+			description: "resync objects",
+			writes:      true,
+		},
+		{
+			subject:     subjects.of(constants.EnsureSubject),
+			handler:     handlerService.ensureHandler,
+			description: "ensure",
+			writes:      true,
+		},
+		{
+			subject:     subjects.of(constants.ExplainAccessSubject),
+			handler:     handlerService.explainAccessHandler,
+			description: "explain access",
+		},
+		{
+			subject:     subjects.of(constants.ConfigSubject),
+			handler:     handlerService.configHandler,
+			description: "config",
+		},
+		{
+			subject:     subjects.of(constants.MaintenanceSubject),
+			handler:     handlerService.maintenanceHandler,
+			description: "maintenance",
+			broadcast:   true,
+		},
+		{
+			subject:     subjects.of(constants.RenameRelationSubject),
+			handler:     handlerService.renameRelationHandler,
+			description: "rename relation",
+			writes:      true,
+		},
+		{
+			subject:     subjects.of(constants.DeleteAccessBulkSubject),
+			handler:     handlerService.deleteAccessBulkHandler,
+			description: "delete access bulk",
+			writes:      true,
+		},
 		// Generic handlers (resource-agnostic)
 		{
-			subject:     constants.GenericUpdateAccessSubject,
+			subject:     subjects.of(constants.GenericUpdateAccessSubject),
 			handler:     handlerService.genericUpdateAccessHandler,
 			description: "generic update access",
+			writes:      true,
 		},
 		{
-			subject:     constants.GenericDeleteAccessSubject,
+			subject:     subjects.of(constants.GenericDeleteAccessSubject),
 			handler:     handlerService.genericDeleteAccessHandler,
 			description: "generic delete access",
+			writes:      true,
 		},
 		{
-			subject:     constants.GenericMemberPutSubject,
+			subject:     subjects.of(constants.GenericMemberPutSubject),
 			handler:     handlerService.genericMemberPutHandler,
 			description: "generic member put",
+			writes:      true,
 		},
 		{
-			subject:     constants.GenericMemberRemoveSubject,
+			subject:     subjects.of(constants.GenericMemberRemoveSubject),
 			handler:     handlerService.genericMemberRemoveHandler,
 			description: "generic member remove",
+			writes:      true,
+		},
+		{
+			subject:     subjects.of(constants.GenericMemberBatchSubject),
+			handler:     handlerService.genericMemberBatchHandler,
+			description: "generic member batch",
+			writes:      true,
 		},
 	}
 
-	// Subscribe to each subject using the helper function
+	// Subjects in the FGA sync namespace are routed through a single wildcard
+	// subscription so that messages on unregistered subjects are surfaced;
+	// other subjects are subscribed to individually.
+	table := make(dispatchTable)
 	for _, config := range subscriptions {
-		if err := subscribeToSubject(config.subject, config.description, queue, config.handler); err != nil {
+		if config.broadcast {
+			if err := subscribeToSubject(config, ""); err != nil {
+				return err
+			}
+		}
+		if strings.HasPrefix(config.subject, subjects.of(constants.FgaSyncSubjectPrefix)) {
+			table[config.subject] = config
+			continue
+		}
+		if config.broadcast {
+			continue
+		}
+		if err := subscribeToSubject(config, queue); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if err := subscribeToBackfill(handlerService.config.BackfillSubjects, subjects, queue, subscriptions); err != nil {
+		return err
+	}
+
+	return subscribeToDispatchTable(subjects.of(constants.FgaSyncSubjectWildcard), queue, table)
 }
@@ -0,0 +1,129 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// defaultMaintenanceMaxParked is the default number of writes held during
+// maintenance before further writes are rejected.
+const defaultMaintenanceMaxParked = 10000
+
+// errMaintenanceQueueFull is returned for a write arriving in maintenance
+// mode once the parked writes have reached their limit.
+var errMaintenanceQueueFull = errors.New("maintenance in progress and write queue is full")
+
+// maintenance is the maintenance mode of this replica. It is off until run
+// applies the configuration.
+var maintenance = newMaintenanceMode(defaultMaintenanceMaxParked, false, time.Now)
+
+// maintenanceRejected counts writes rejected because the parked writes had
+// reached their limit.
+var maintenanceRejected = expvar.NewInt("fga_sync_maintenance_rejected_total")
+
+func init() {
+	expvar.Publish("fga_sync_maintenance_parked", expvar.Func(func() any {
+		_, _, parked := maintenance.status()
+		return parked
+	}))
+}
+
+// maintenanceMode holds writes in memory while OpenFGA is down for planned
+// maintenance, so they are neither dropped nor failed, while checks keep
+// being served. Parked writes are processed in arrival order once
+// maintenance ends; writes parked at shutdown are not applied.
+type maintenanceMode struct {
+	maxParked int
+	now       func() time.Time
+
+	mu     sync.Mutex
+	active bool
+	since  time.Time
+	parked []func()
+	// draining is set while parked writes are being processed after
+	// maintenance ended. New writes keep being parked behind them, so writes
+	// are still applied in arrival order.
+	draining bool
+}
+
+// newMaintenanceMode returns a maintenance mode holding up to maxParked
+// writes, starting in maintenance if active.
+func newMaintenanceMode(maxParked int, active bool, now func() time.Time) *maintenanceMode {
+	m := &maintenanceMode{maxParked: maxParked, now: now, active: active}
+	if active {
+		m.since = now()
+	}
+	return m
+}
+
+// park holds run until maintenance ends and reports whether it did. Outside
+// maintenance, run is not held and must be called by the caller. If the
+// parked writes are at their limit, run is not held and
+// errMaintenanceQueueFull is returned.
+func (m *maintenanceMode) park(run func()) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.active && !m.draining {
+		return false, nil
+	}
+	if len(m.parked) >= m.maxParked {
+		maintenanceRejected.Add(1)
+		return false, errMaintenanceQueueFull
+	}
+	m.parked = append(m.parked, run)
+	return true, nil
+}
+
+// set turns maintenance mode on or off. Turning it off processes the parked
+// writes in the background.
+func (m *maintenanceMode) set(active bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if active == m.active {
+		return
+	}
+	m.active = active
+	if active {
+		m.since = m.now()
+		logger.Warn("maintenance mode enabled; parking writes")
+		return
+	}
+	m.since = time.Time{}
+	logger.With("parked", len(m.parked)).Info("maintenance mode disabled; processing parked writes")
+	if !m.draining && len(m.parked) > 0 {
+		m.draining = true
+		go m.drain()
+	}
+}
+
+// drain processes parked writes in order until none remain or maintenance
+// is enabled again.
+func (m *maintenanceMode) drain() {
+	for {
+		m.mu.Lock()
+		if m.active || len(m.parked) == 0 {
+			m.draining = false
+			m.mu.Unlock()
+			return
+		}
+		run := m.parked[0]
+		m.parked[0] = nil
+		m.parked = m.parked[1:]
+		m.mu.Unlock()
+		run()
+	}
+}
+
+// status returns whether maintenance mode is on, since when, and the
+// number of parked writes.
+func (m *maintenanceMode) status() (bool, time.Time, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active, m.since, len(m.parked)
+}
@@ -0,0 +1,133 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// useMaintenance replaces the maintenance mode for the duration of the test.
+func useMaintenance(t *testing.T, m *maintenanceMode) {
+	t.Helper()
+	original := maintenance
+	maintenance = m
+	t.Cleanup(func() { maintenance = original })
+}
+
+// TestDispatchSubscription_Maintenance asserts that writes arriving in
+// maintenance mode are parked while checks are served, and processed in
+// order once maintenance ends.
+func TestDispatchSubscription_Maintenance(t *testing.T) {
+	useMaintenance(t, newMaintenanceMode(10, true, time.Now))
+
+	var mu sync.Mutex
+	var handled []string
+	record := func(_ context.Context, msg INatsMsg) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, string(msg.Data()))
+		return nil
+	}
+	handledSoFar := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), handled...)
+	}
+	write := subscriptionConfig{subject: "lfx.fga-sync.update_access", handler: record, description: "write", writes: true}
+	check := subscriptionConfig{subject: "lfx.access_check.request", handler: record, description: "check"}
+
+	dispatchSubscription(context.Background(), write, "queue", CreateMockNatsMsg([]byte("write 1")))
+	dispatchSubscription(context.Background(), check, "queue", CreateMockNatsMsg([]byte("check")))
+	dispatchSubscription(context.Background(), write, "queue", CreateMockNatsMsg([]byte("write 2")))
+
+	assert.Equal(t, []string{"check"}, handledSoFar(), "only the check is served during maintenance")
+	active, since, parked := maintenance.status()
+	assert.True(t, active)
+	assert.False(t, since.IsZero())
+	assert.Equal(t, 2, parked)
+
+	maintenance.set(false)
+	assert.Eventually(t, func() bool { return len(handledSoFar()) == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"check", "write 1", "write 2"}, handledSoFar())
+	active, _, parked = maintenance.status()
+	assert.False(t, active)
+	assert.Equal(t, 0, parked)
+
+	dispatchSubscription(context.Background(), write, "queue", CreateMockNatsMsg([]byte("write 3")))
+	assert.Equal(t, "write 3", handledSoFar()[3], "writes are handled directly after maintenance")
+}
+
+// TestDispatchSubscription_MaintenanceQueueFull asserts that writes beyond
+// the parked limit are rejected with an error reply rather than dropped
+// silently.
+func TestDispatchSubscription_MaintenanceQueueFull(t *testing.T) {
+	useMaintenance(t, newMaintenanceMode(1, true, time.Now))
+	rejectedBefore := maintenanceRejected.Value()
+
+	handler := func(context.Context, INatsMsg) error {
+		t.Error("handler called during maintenance")
+		return nil
+	}
+	write := subscriptionConfig{subject: "lfx.fga-sync.update_access", handler: handler, description: "write", writes: true}
+
+	dispatchSubscription(context.Background(), write, "queue", CreateMockNatsMsg([]byte("parked")))
+	msg := CreateMockNatsMsg([]byte("rejected"))
+	msg.reply = "reply.update_access"
+	msg.On("Respond", []byte(`{"status":"error","error":"`+errMaintenanceQueueFull.Error()+`"}`)).Return(nil).Once()
+	dispatchSubscription(context.Background(), write, "queue", msg)
+
+	msg.AssertExpectations(t)
+	_, _, parked := maintenance.status()
+	assert.Equal(t, 1, parked)
+	assert.Equal(t, rejectedBefore+1, maintenanceRejected.Value())
+}
+
+// TestMaintenanceHandler asserts that the maintenance subject sets and
+// reports maintenance mode.
+func TestMaintenanceHandler(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	useMaintenance(t, newMaintenanceMode(10, false, func() time.Time { return now }))
+	service := setupService()
+
+	request := func(payload string) (types.MaintenanceResponse, error) {
+		msg := CreateMockNatsMsg([]byte(payload))
+		msg.reply = "reply.maintenance"
+		var resp types.MaintenanceResponse
+		msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &resp))
+		}).Return(nil).Once()
+		err := service.maintenanceHandler(context.Background(), msg)
+		return resp, err
+	}
+
+	resp, err := request("")
+	assert.NoError(t, err)
+	assert.Equal(t, types.MaintenanceResponse{}, resp)
+
+	resp, err = request(`{"enabled": true}`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.MaintenanceResponse{Enabled: true, Since: &now}, resp)
+
+	parked, _ := maintenance.park(func() {})
+	assert.True(t, parked)
+	resp, err = request(`{}`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.MaintenanceResponse{Enabled: true, Since: &now, Parked: 1}, resp)
+
+	resp, err = request(`{"enabled": false}`)
+	assert.NoError(t, err)
+	assert.False(t, resp.Enabled)
+
+	resp, err = request(`{"enabled": "yes"}`)
+	assert.Error(t, err)
+	assert.Equal(t, "invalid request payload", resp.Error)
+}
@@ -0,0 +1,39 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+)
+
+// budgetExhaustedMessages counts messages whose handler ran out of
+// MESSAGE_BUDGET, keyed by subject.
+var budgetExhaustedMessages = expvar.NewMap("fga_sync_budget_exhausted_total")
+
+// withMessageBudget bounds the total time spent handling a message, across
+// every OpenFGA call and retry it makes, to messageBudget. A zero budget
+// leaves ctx unbounded.
+func withMessageBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if messageBudget <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, messageBudget)
+}
+
+// budgetExhausted reports whether errHandler failed the message because ctx
+// ran out of its message budget, and if so counts and dead-letters it.
+func budgetExhausted(ctx context.Context, subject string, msg INatsMsg, errHandler error) bool {
+	if messageBudget <= 0 || errHandler == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	budgetExhaustedMessages.Add(subject, 1)
+	if deadLetter != nil {
+		deadLetter(ctx, msg, fmt.Sprintf("message budget of %s exhausted: %s", messageBudget, errHandler))
+	}
+	return true
+}
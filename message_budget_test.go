@@ -0,0 +1,67 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDispatchMessage_MessageBudget asserts that the message budget caps the
+// total time a handler spends retrying failing calls, and that a message
+// exhausting it is dead-lettered.
+func TestDispatchMessage_MessageBudget(t *testing.T) {
+	origBudget, origDeadLetter := messageBudget, deadLetter
+	messageBudget = 50 * time.Millisecond
+	var deadLettered []string
+	deadLetter = func(_ context.Context, _ INatsMsg, reason string) {
+		deadLettered = append(deadLettered, reason)
+	}
+	defer func() { messageBudget, deadLetter = origBudget, origDeadLetter }()
+	budgetExhaustedMessages.Init()
+
+	// The handler retries a failing call every 5ms for up to 10s, as a
+	// handler making several retried OpenFGA calls might.
+	attempts := 0
+	retrying := func(ctx context.Context, _ INatsMsg) error {
+		for range 2000 {
+			attempts++
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+		return errors.New("still failing")
+	}
+
+	msg := CreateMockNatsMsg([]byte(`{}`))
+	msg.subject = constants.GenericUpdateAccessSubject
+	start := time.Now()
+	dispatchMessage(context.Background(), msg.subject, "generic update access", constants.FgaSyncQueue, retrying, msg)
+	elapsed := time.Since(start)
+
+	assert.GreaterOrEqual(t, elapsed, messageBudget)
+	assert.Less(t, elapsed, time.Second, "the budget should cap the total retry time")
+	assert.Less(t, attempts, 2000)
+	if assert.Len(t, deadLettered, 1) {
+		assert.True(t, strings.HasPrefix(deadLettered[0], "message budget of 50ms exhausted"), deadLettered[0])
+	}
+	if v, ok := budgetExhaustedMessages.Get(constants.GenericUpdateAccessSubject).(*expvar.Int); assert.True(t, ok) {
+		assert.Equal(t, int64(1), v.Value())
+	}
+
+	// Failures within the budget go through the usual error path.
+	failing := func(_ context.Context, _ INatsMsg) error { return errors.New("invalid payload") }
+	dispatchMessage(context.Background(), "test.subject", "failing", constants.FgaSyncQueue, failing, CreateMockNatsMsg(nil))
+	assert.Len(t, deadLettered, 1)
+	assert.Nil(t, budgetExhaustedMessages.Get("test.subject"))
+}
@@ -6,6 +6,8 @@ package main
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -47,8 +49,9 @@ func (m *MockFgaClient) Write(
 func (m *MockFgaClient) BatchCheck(
 	ctx context.Context,
 	request ClientBatchCheckRequest,
+	options BatchCheckOptions,
 ) (*openfga.BatchCheckResponse, error) {
-	args := m.Called(ctx, request)
+	args := m.Called(ctx, request, options)
 	//nolint:errcheck // the error is passed through to the caller
 	return args.Get(0).(*openfga.BatchCheckResponse), args.Error(1)
 }
@@ -64,12 +67,21 @@ func (m *MockFgaClient) ListObjects(
 	return args.Get(0).(*ClientListObjectsResponse), args.Error(1)
 }
 
+// ReadAuthorizationModel implements the IFgaClient interface
+func (m *MockFgaClient) ReadAuthorizationModel(ctx context.Context) (*ClientReadAuthorizationModelResponse, error) {
+	args := m.Called(ctx)
+	//nolint:errcheck // the error is passed through to the caller
+	return args.Get(0).(*ClientReadAuthorizationModelResponse), args.Error(1)
+}
+
 // MockNatsMsg is a mock implementation of the INatsMsg interface
 type MockNatsMsg struct {
 	mock.Mock
-	reply   string
-	data    []byte
-	subject string
+	reply     string
+	data      []byte
+	subject   string
+	header    nats.Header
+	timestamp time.Time
 }
 
 // Reply implements the INatsMsg interface
@@ -83,6 +95,12 @@ func (m *MockNatsMsg) Respond(data []byte) error {
 	return args.Error(0)
 }
 
+// RespondMsg implements the INatsMsg interface
+func (m *MockNatsMsg) RespondMsg(msg *nats.Msg) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
 // Data implements the INatsMsg interface
 func (m *MockNatsMsg) Data() []byte {
 	return m.data
@@ -95,13 +113,22 @@ func (m *MockNatsMsg) Subject() string {
 
 // Header implements the INatsMsg interface
 func (m *MockNatsMsg) Header() nats.Header {
-	return nats.Header{}
+	if m.header == nil {
+		return nats.Header{}
+	}
+	return m.header
+}
+
+// Timestamp implements the INatsMsg interface
+func (m *MockNatsMsg) Timestamp() time.Time {
+	return m.timestamp
 }
 
 // CreateMockNatsMsg creates a mock NATS message that can be used in tests
 func CreateMockNatsMsg(data []byte) *MockNatsMsg {
 	msg := MockNatsMsg{
-		data: data,
+		data:      data,
+		timestamp: time.Now(),
 	}
 	return &msg
 }
@@ -114,6 +141,8 @@ type MockKeyValue struct {
 	createdTimes map[string]time.Time
 	returnError  error
 	notFoundKeys map[string]bool
+	revisions    map[string]uint64
+	revision     uint64
 }
 
 // NewMockKeyValue creates a new MockKeyValue instance
@@ -122,6 +151,7 @@ func NewMockKeyValue() *MockKeyValue {
 		data:         make(map[string][]byte),
 		createdTimes: make(map[string]time.Time),
 		notFoundKeys: make(map[string]bool),
+		revisions:    make(map[string]uint64),
 	}
 }
 
@@ -138,9 +168,10 @@ func (m *MockKeyValue) Get(_ context.Context, key string) (jetstream.KeyValueEnt
 	}
 	if data, exists := m.data[key]; exists {
 		return &MockKeyValueEntry{
-			key:     key,
-			value:   data,
-			created: m.createdTimes[key],
+			key:      key,
+			value:    data,
+			created:  m.createdTimes[key],
+			revision: m.revisions[key],
 		}, nil
 	}
 	return nil, jetstream.ErrKeyNotFound
@@ -154,9 +185,7 @@ func (m *MockKeyValue) Put(_ context.Context, key string, value []byte) (uint64,
 	if m.returnError != nil {
 		return 0, m.returnError
 	}
-	m.data[key] = value
-	m.createdTimes[key] = time.Now()
-	return 1, nil
+	return m.storeLocked(key, value), nil
 }
 
 // PutString implements the jetstream.KeyValue interface
@@ -164,6 +193,72 @@ func (m *MockKeyValue) PutString(ctx context.Context, key, value string) (uint64
 	return m.Put(ctx, key, []byte(value))
 }
 
+// Create implements the jetstream.KeyValue interface
+func (m *MockKeyValue) Create(_ context.Context, key string, value []byte, _ ...jetstream.KVCreateOpt) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.returnError != nil {
+		return 0, m.returnError
+	}
+	if _, exists := m.data[key]; exists {
+		return 0, jetstream.ErrKeyExists
+	}
+	return m.storeLocked(key, value), nil
+}
+
+// Update implements the jetstream.KeyValue interface
+func (m *MockKeyValue) Update(_ context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.returnError != nil {
+		return 0, m.returnError
+	}
+	if m.revisions[key] != revision {
+		return 0, &jetstream.APIError{ErrorCode: jetstream.JSErrCodeStreamWrongLastSequence, Code: 400}
+	}
+	return m.storeLocked(key, value), nil
+}
+
+// ListKeysFiltered implements the jetstream.KeyValue interface. Filters
+// match keys exactly or, ending in ">", by prefix.
+func (m *MockKeyValue) ListKeysFiltered(_ context.Context, filters ...string) (jetstream.KeyLister, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.returnError != nil {
+		return nil, m.returnError
+	}
+	var keys []string
+	for key := range m.data {
+		for _, filter := range filters {
+			prefix, wildcard := strings.CutSuffix(filter, ">")
+			if key == filter || wildcard && strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	slices.Sort(keys)
+	lister := &MockKeyLister{keys: make(chan string, len(keys))}
+	for _, key := range keys {
+		lister.keys <- key
+	}
+	close(lister.keys)
+	return lister, nil
+}
+
+// storeLocked stores value under key and returns its new revision. The
+// caller must hold m.mu.
+func (m *MockKeyValue) storeLocked(key string, value []byte) uint64 {
+	m.revision++
+	m.data[key] = value
+	m.createdTimes[key] = time.Now()
+	m.revisions[key] = m.revision
+	return m.revision
+}
+
 // SetNotFound implements the jetstream.KeyValue interface
 func (m *MockKeyValue) SetNotFound(key string) {
 	m.mu.Lock()
@@ -178,6 +273,17 @@ func (m *MockKeyValue) SetError(err error) {
 	m.returnError = err
 }
 
+// MockKeyLister is a mock implementation of jetstream.KeyLister
+type MockKeyLister struct {
+	keys chan string
+}
+
+// Keys implements the jetstream.KeyLister interface
+func (m *MockKeyLister) Keys() <-chan string { return m.keys }
+
+// Stop implements the jetstream.KeyLister interface
+func (m *MockKeyLister) Stop() error { return nil }
+
 // MockKeyValueEntry is a mock implementation of jetstream.KeyValueEntry
 type MockKeyValueEntry struct {
 	key      string
@@ -0,0 +1,128 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+)
+
+// builtinObjectTypePrefixes are the object ID prefixes of the object types
+// defined in pkg/constants.
+var builtinObjectTypePrefixes = []string{
+	constants.ObjectTypeUser,
+	constants.ObjectTypeProject,
+	constants.ObjectTypeCommittee,
+	constants.ObjectTypeTeam,
+	constants.ObjectTypeMeeting,
+	constants.ObjectTypeMeetingAttachment,
+	constants.ObjectTypePastMeeting,
+	constants.ObjectTypePastMeetingAttachment,
+	constants.ObjectTypePastMeetingRecording,
+	constants.ObjectTypePastMeetingTranscript,
+	constants.ObjectTypePastMeetingSummary,
+	constants.ObjectTypeGroupsIOService,
+	constants.ObjectTypeGroupsIOMailingList,
+	constants.ObjectTypeB2BOrg,
+	constants.ObjectTypeProjectMembership,
+	constants.ObjectTypeV1Meeting,
+	constants.ObjectTypeV1PastMeeting,
+	constants.ObjectTypeV1PastMeetingRecording,
+	constants.ObjectTypeV1PastMeetingTranscript,
+	constants.ObjectTypeV1PastMeetingSummary,
+}
+
+// objectIDPrefixPattern matches a valid object ID prefix: an OpenFGA type
+// name followed by the type separator.
+var objectIDPrefixPattern = regexp.MustCompile(`^[a-z0-9_]+:$`)
+
+// objectTypes is the registry used to build object IDs. It holds the
+// built-in types until run replaces it with the configured registry.
+var objectTypes = newObjectTypeRegistry(nil, false)
+
+// objectTypeRegistry maps the object types named in messages to the prefix
+// of their OpenFGA object IDs. A prefix normally repeats the type name, but
+// may name another type, letting a message's object_type alias it.
+type objectTypeRegistry struct {
+	prefixes map[string]string
+	// overrides are the configured entries, kept for the effective
+	// configuration.
+	overrides map[string]string
+	// strict rejects object types that are not registered; otherwise they
+	// are prefixed with their own name.
+	strict bool
+}
+
+// newObjectTypeRegistry returns a registry of the built-in object types plus
+// overrides, which may add types or replace built-in prefixes.
+func newObjectTypeRegistry(overrides map[string]string, strict bool) objectTypeRegistry {
+	prefixes := make(map[string]string, len(builtinObjectTypePrefixes)+len(overrides))
+	for _, prefix := range builtinObjectTypePrefixes {
+		prefixes[strings.TrimSuffix(prefix, ":")] = prefix
+	}
+	maps.Copy(prefixes, overrides)
+	return objectTypeRegistry{prefixes: prefixes, overrides: overrides, strict: strict}
+}
+
+// resolve returns the OpenFGA type that objects of objectType are stored
+// under, or an error if objectType is not registered and the registry is
+// strict.
+func (r objectTypeRegistry) resolve(objectType string) (string, error) {
+	prefix, ok := r.prefixes[objectType]
+	if !ok {
+		if r.strict {
+			return "", fmt.Errorf("object_type %q is not registered", objectType)
+		}
+		return objectType, nil
+	}
+	return strings.TrimSuffix(prefix, ":"), nil
+}
+
+// objectID returns the OpenFGA object ID of uid under objectType.
+func (r objectTypeRegistry) objectID(objectType, uid string) string {
+	if prefix, ok := r.prefixes[objectType]; ok {
+		return prefix + uid
+	}
+	return objectType + ":" + uid
+}
+
+// String formats the configured overrides as they are configured, for the
+// effective configuration.
+func (r objectTypeRegistry) String() string {
+	entries := make([]string, 0, len(r.overrides))
+	for _, objectType := range slices.Sorted(maps.Keys(r.overrides)) {
+		entries = append(entries, objectType+"="+r.overrides[objectType])
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseObjectTypePrefixes parses a comma-separated list of type=prefix
+// entries, e.g. recording=v1_past_meeting_recording:.
+func parseObjectTypePrefixes(v string) (map[string]string, error) {
+	prefixes := make(map[string]string)
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		objectType, prefix, found := strings.Cut(entry, "=")
+		if !found || !objectIDPrefixPattern.MatchString(objectType+":") {
+			return nil, fmt.Errorf("expected type=prefix with a lowercase type, got %q", entry)
+		}
+		if !objectIDPrefixPattern.MatchString(prefix) {
+			return nil, fmt.Errorf("%s: prefix %q must be a lowercase type name followed by ':'", objectType, prefix)
+		}
+		if _, dup := prefixes[objectType]; dup {
+			return nil, errors.New(objectType + " is listed more than once")
+		}
+		prefixes[objectType] = prefix
+	}
+	return prefixes, nil
+}
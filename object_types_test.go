@@ -0,0 +1,122 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestObjectTypeRegistry tests object type resolution and object ID
+// construction for built-in, configured and unregistered types.
+func TestObjectTypeRegistry(t *testing.T) {
+	registry := newObjectTypeRegistry(map[string]string{
+		"recording": "v1_past_meeting_recording:",
+		"survey":    "survey:",
+	}, false)
+
+	tests := []struct {
+		name         string
+		objectType   string
+		expectType   string
+		expectObject string
+	}{
+		{name: "built-in type", objectType: "committee", expectType: "committee", expectObject: "committee:123"},
+		{name: "configured type", objectType: "survey", expectType: "survey", expectObject: "survey:123"},
+		{
+			name:         "alias of another type",
+			objectType:   "recording",
+			expectType:   "v1_past_meeting_recording",
+			expectObject: "v1_past_meeting_recording:123",
+		},
+		{name: "unregistered type", objectType: "widget", expectType: "widget", expectObject: "widget:123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objectType, err := registry.resolve(tt.objectType)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectType, objectType)
+			assert.Equal(t, tt.expectObject, registry.objectID(tt.objectType, "123"))
+		})
+	}
+
+	t.Run("strict registry rejects unregistered type", func(t *testing.T) {
+		strict := newObjectTypeRegistry(nil, true)
+		_, err := strict.resolve("widget")
+		assert.ErrorContains(t, err, "widget")
+		objectType, err := strict.resolve("project")
+		assert.NoError(t, err)
+		assert.Equal(t, "project", objectType)
+	})
+}
+
+// TestParseObjectTypePrefixes tests parsing of OBJECT_TYPE_PREFIXES.
+func TestParseObjectTypePrefixes(t *testing.T) {
+	prefixes, err := parseObjectTypePrefixes(" survey=survey: , recording=v1_past_meeting_recording:,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"survey":    "survey:",
+		"recording": "v1_past_meeting_recording:",
+	}, prefixes)
+
+	for _, invalid := range []string{
+		"survey",
+		"Survey=survey:",
+		"survey=survey",
+		"survey=sur#vey:",
+		"survey=survey:,survey=poll:",
+	} {
+		t.Run(invalid, func(t *testing.T) {
+			_, err := parseObjectTypePrefixes(invalid)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestGenericUpdateAccess_StrictObjectTypes tests that, with a strict
+// registry, generic messages for unregistered object types are rejected
+// before anything is written.
+func TestGenericUpdateAccess_StrictObjectTypes(t *testing.T) {
+	previous := objectTypes
+	t.Cleanup(func() { objectTypes = previous })
+	objectTypes = newObjectTypeRegistry(map[string]string{"survey": "survey:"}, true)
+
+	tests := []struct {
+		name        string
+		objectType  string
+		expectError bool
+	}{
+		{name: "built-in type", objectType: "project"},
+		{name: "configured type", objectType: "survey"},
+		{name: "unregistered type", objectType: "widget", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, tt.objectType, "update_access", fgatypes.GenericAccessData{
+				UID:    "obj-1",
+				Public: true,
+			})
+			err := service.genericUpdateAccessHandler(context.Background(), msg)
+
+			if tt.expectError {
+				assert.ErrorContains(t, err, "not registered")
+				fgaClient.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"expvar"
+	"sync"
+
+	openfga "github.com/openfga/go-sdk"
+
+	. "github.com/openfga/go-sdk/client"
+)
+
+// openfgaCallBuckets are the upper bounds of the OpenFGA calls per message
+// histogram buckets.
+var openfgaCallBuckets = []int{0, 1, 2, 5, 10, 25, 50, 100}
+
+// openfgaCallsPerMessage records the number of OpenFGA calls each handled
+// message made, keyed by subject, so that handlers chattier than expected
+// stand out.
+var openfgaCallsPerMessage = newLabeledHistogram(openfgaCallBuckets)
+
+func init() {
+	expvar.Publish("fga_sync_openfga_calls_per_message", openfgaCallsPerMessage)
+}
+
+// openfgaCallsKey is the context key for the OpenFGA calls counted while
+// handling a message.
+type openfgaCallsKey struct{}
+
+// openfgaCalls counts the OpenFGA calls made while handling a message, by
+// kind of call.
+type openfgaCalls struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// withOpenfgaCalls returns a context that counts the OpenFGA calls made with
+// it.
+func withOpenfgaCalls(ctx context.Context) context.Context {
+	return context.WithValue(ctx, openfgaCallsKey{}, &openfgaCalls{counts: make(map[string]int)})
+}
+
+// openfgaCallsFrom returns the OpenFGA calls counted in ctx by kind, and
+// their total.
+func openfgaCallsFrom(ctx context.Context) (map[string]int, int) {
+	counter, ok := ctx.Value(openfgaCallsKey{}).(*openfgaCalls)
+	if !ok {
+		return nil, 0
+	}
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	counts := make(map[string]int, len(counter.counts))
+	total := 0
+	for kind, n := range counter.counts {
+		counts[kind] = n
+		total += n
+	}
+	return counts, total
+}
+
+// countOpenfgaCall counts one call of kind in ctx, if it is counting.
+func countOpenfgaCall(ctx context.Context, kind string) {
+	if counter, ok := ctx.Value(openfgaCallsKey{}).(*openfgaCalls); ok {
+		counter.mu.Lock()
+		counter.counts[kind]++
+		counter.mu.Unlock()
+	}
+}
+
+// countingFgaClient is an IFgaClient that counts every call in the calling
+// context.
+type countingFgaClient struct {
+	client IFgaClient
+}
+
+// Read implements [IFgaClient.Read].
+func (c countingFgaClient) Read(
+	ctx context.Context,
+	req ClientReadRequest,
+	options ClientReadOptions,
+) (*ClientReadResponse, error) {
+	countOpenfgaCall(ctx, "read")
+	return c.client.Read(ctx, req, options)
+}
+
+// Write implements [IFgaClient.Write].
+func (c countingFgaClient) Write(ctx context.Context, req ClientWriteRequest) (*ClientWriteResponse, error) {
+	countOpenfgaCall(ctx, "write")
+	return c.client.Write(ctx, req)
+}
+
+// BatchCheck implements [IFgaClient.BatchCheck].
+func (c countingFgaClient) BatchCheck(
+	ctx context.Context,
+	request ClientBatchCheckRequest,
+	options BatchCheckOptions,
+) (*openfga.BatchCheckResponse, error) {
+	countOpenfgaCall(ctx, "check")
+	return c.client.BatchCheck(ctx, request, options)
+}
+
+// ListObjects implements [IFgaClient.ListObjects].
+func (c countingFgaClient) ListObjects(
+	ctx context.Context,
+	body ClientListObjectsRequest,
+	options ClientListObjectsOptions,
+) (*ClientListObjectsResponse, error) {
+	countOpenfgaCall(ctx, "list_objects")
+	return c.client.ListObjects(ctx, body, options)
+}
+
+// ReadAuthorizationModel implements [IFgaClient.ReadAuthorizationModel].
+func (c countingFgaClient) ReadAuthorizationModel(ctx context.Context) (*ClientReadAuthorizationModelResponse, error) {
+	countOpenfgaCall(ctx, "read_model")
+	return c.client.ReadAuthorizationModel(ctx)
+}
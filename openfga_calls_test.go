@@ -0,0 +1,55 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+func TestDispatchMessage_CountsOpenfgaCalls(t *testing.T) {
+	const subject = "test.openfga_calls"
+
+	mockClient := new(MockFgaClient)
+	mockClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+	mockClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+	service := setupService()
+	service.fgaService.client = countingFgaClient{client: mockClient}
+
+	msg := buildGenericMessage(t, "committee", "update_access", fgatypes.GenericAccessData{
+		UID:       "c1",
+		Relations: map[string][]string{"writer": {"alice"}},
+	})
+	var counted map[string]int
+	handler := func(ctx context.Context, msg INatsMsg) error {
+		err := service.genericUpdateAccessHandler(ctx, msg)
+		counted, _ = openfgaCallsFrom(ctx)
+		return err
+	}
+
+	dispatchMessage(context.Background(), subject, "generic update access", constants.FgaSyncQueue, handler, msg)
+
+	// One read of the object's existing tuples and one write of the diff.
+	assert.Equal(t, map[string]int{"read": 1, "write": 1}, counted)
+	if snapshot := openfgaCallsPerMessage.get(subject); assert.NotNil(t, snapshot) {
+		assert.Equal(t, uint64(1), snapshot["count"])
+		assert.Equal(t, uint64(2), snapshot["sum"])
+	}
+}
+
+func TestCountOpenfgaCall_WithoutCounter(t *testing.T) {
+	ctx := context.Background()
+	countOpenfgaCall(ctx, "read")
+
+	counts, total := openfgaCallsFrom(ctx)
+	assert.Nil(t, counts)
+	assert.Zero(t, total)
+}
@@ -0,0 +1,54 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+)
+
+// checkParentCycle rejects setting parents on object if object is already
+// among their ancestors, which would close a cycle that OpenFGA resolves
+// through indefinitely. The existing parent chain is read level by level,
+// up to h.parentCycleDepth levels; a chain deeper than that is rejected too,
+// since it cannot be shown to be free of object.
+func (h *HandlerService) checkParentCycle(ctx context.Context, object string, parents []string) error {
+	visited := map[string]bool{object: true}
+	level := make([]string, 0, len(parents))
+	for _, parent := range parents {
+		if !visited[parent] {
+			visited[parent] = true
+			level = append(level, parent)
+		}
+	}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth == h.parentCycleDepth {
+			return newFieldError("references", reasonInvalid,
+				fmt.Sprintf("parent chain of %s is deeper than %d levels", object, h.parentCycleDepth))
+		}
+		var next []string
+		for _, ancestor := range level {
+			tuples, err := h.fgaService.GetTuplesByRelation(ctx, ancestor, constants.RelationParent)
+			if err != nil {
+				return fmt.Errorf("failed to read parents of %s: %w", ancestor, err)
+			}
+			for _, tuple := range tuples {
+				if tuple.Key.User == object {
+					logger.ErrorContext(ctx, "parent would create a cycle", "object", object, "ancestor", ancestor)
+					return newFieldError("references", reasonInvalid,
+						fmt.Sprintf("setting the parent of %s would create a cycle", object))
+				}
+				if !visited[tuple.Key.User] {
+					visited[tuple.Key.User] = true
+					next = append(next, tuple.Key.User)
+				}
+			}
+		}
+		level = next
+	}
+	return nil
+}
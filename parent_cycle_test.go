@@ -0,0 +1,101 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockParents serves reads of the parent tuples in parents, keyed by child.
+// Objects without an entry have no tuples.
+func mockParents(m *MockFgaClient, parents map[string][]string) {
+	for child, ancestors := range parents {
+		var tuples []openfga.Tuple
+		for _, parent := range ancestors {
+			tuples = append(tuples, openfga.Tuple{Key: openfga.TupleKey{User: parent, Relation: "parent", Object: child}})
+		}
+		m.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+			return req.Object != nil && *req.Object == child
+		}), mock.Anything).Return(&client.ClientReadResponse{Tuples: tuples}, nil)
+	}
+	m.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+}
+
+func TestStandardAccessTuples_ParentCycle(t *testing.T) {
+	tests := []struct {
+		name        string
+		depth       int
+		parents     map[string][]string
+		expectError string
+	}{
+		{
+			name:    "acyclic chain",
+			depth:   8,
+			parents: map[string][]string{"committee:b": {"committee:c"}},
+		},
+		{
+			name:        "two-committee cycle",
+			depth:       8,
+			parents:     map[string][]string{"committee:b": {"committee:a"}},
+			expectError: "setting the parent of committee:a would create a cycle",
+		},
+		{
+			name:  "longer cycle",
+			depth: 8,
+			parents: map[string][]string{
+				"committee:b": {"committee:c"},
+				"committee:c": {"committee:d"},
+				"committee:d": {"committee:a"},
+			},
+			expectError: "setting the parent of committee:a would create a cycle",
+		},
+		{
+			name:  "existing cycle not involving the object",
+			depth: 8,
+			parents: map[string][]string{
+				"committee:b": {"committee:c"},
+				"committee:c": {"committee:b"},
+			},
+		},
+		{
+			name:  "chain deeper than the bound",
+			depth: 2,
+			parents: map[string][]string{
+				"committee:b": {"committee:c"},
+				"committee:c": {"committee:d"},
+			},
+			expectError: "parent chain of committee:a is deeper than 2 levels",
+		},
+		{
+			name:    "detection disabled",
+			parents: map[string][]string{"committee:b": {"committee:a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.parentCycleDepth = tt.depth
+			mockParents(service.fgaService.client.(*MockFgaClient), tt.parents)
+
+			_, _, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+				UID:        "a",
+				ObjectType: "committee",
+				References: map[string][]string{"parent": {"b"}},
+			})
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectError)
+			assert.Contains(t, string(errorReply(err)), `"field":"references"`)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// partitionQueueDepth is the number of messages a worker buffers before the
+// subscription blocks on it.
+const partitionQueueDepth = 32
+
+// dispatchWorkers, when set, processes FGA sync messages on a fixed set of
+// workers partitioned by object. It is nil when PARTITIONED_WORKERS is unset,
+// in which case messages are processed one at a time on the subscription.
+var dispatchWorkers *partitionedWorkers
+
+// partitionedWorkers runs jobs on a fixed set of workers, always routing jobs
+// with the same key to the same worker. Jobs for one key therefore run one at
+// a time in submission order, while jobs for different keys run in parallel.
+type partitionedWorkers struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+// newPartitionedWorkers starts n workers.
+func newPartitionedWorkers(n int) *partitionedWorkers {
+	p := &partitionedWorkers{queues: make([]chan func(), n)}
+	for i := range p.queues {
+		queue := make(chan func(), partitionQueueDepth)
+		p.queues[i] = queue
+		p.wg.Go(func() {
+			for job := range queue {
+				job()
+			}
+		})
+	}
+	return p
+}
+
+// submit queues job on the worker owning key, blocking while that worker's
+// queue is full.
+func (p *partitionedWorkers) submit(key string, job func()) {
+	p.queueFor(key) <- job
+}
+
+// queueFor returns the queue of the worker owning key.
+func (p *partitionedWorkers) queueFor(key string) chan func() {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return p.queues[h.Sum32()%uint32(len(p.queues))] //nolint:gosec // len(p.queues) is a small positive worker count
+}
+
+// stop waits for every queued job to finish and stops the workers. No job
+// may be submitted after stop is called.
+func (p *partitionedWorkers) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// partitionKey returns the object a message is about, so that messages for
+// one object are processed in order. Generic sync messages carry the object
+// as object_type plus data.uid, and resync requests as top-level object_type
+// plus uid. Messages naming no object, and oversized messages, which are
+// rejected unread, are keyed by subject.
+func partitionKey(msg INatsMsg) string {
+	if maxMessageSize > 0 && len(msg.Data()) > maxMessageSize {
+		return msg.Subject()
+	}
+	var envelope struct {
+		ObjectType string `json:"object_type"`
+		UID        string `json:"uid"`
+		Data       struct {
+			UID string `json:"uid"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Data(), &envelope); err == nil && envelope.ObjectType != "" {
+		// Handlers canonicalize the object type's casing, so route on the
+		// canonical form too.
+		objectType := strings.ToLower(envelope.ObjectType)
+		switch {
+		case envelope.Data.UID != "":
+			return buildObjectID(objectType, envelope.Data.UID)
+		case envelope.UID != "":
+			return buildObjectID(objectType, envelope.UID)
+		}
+	}
+	return msg.Subject()
+}
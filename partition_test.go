@@ -0,0 +1,121 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionedWorkers_SerializesSameKey(t *testing.T) {
+	workers := newPartitionedWorkers(4)
+
+	var running, maxRunning atomic.Int32
+	var order []int
+	var mu sync.Mutex
+	for i := range 5 {
+		workers.submit("meeting:m1", func() {
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			running.Add(-1)
+		})
+	}
+	workers.stop()
+
+	assert.Equal(t, int32(1), maxRunning.Load(), "messages for the same object should not run concurrently")
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, order, "messages for the same object should run in submission order")
+}
+
+func TestPartitionedWorkers_ParallelizesDifferentKeys(t *testing.T) {
+	workers := newPartitionedWorkers(4)
+
+	// Find two keys owned by different workers, then block each job until
+	// both have started: this only completes if they run in parallel.
+	keyA, keyB := "meeting:m1", ""
+	for i := range 100 {
+		candidate := buildObjectID("meeting", string(rune('a'+i)))
+		if workers.queueFor(candidate) != workers.queueFor(keyA) {
+			keyB = candidate
+			break
+		}
+	}
+	assert.NotEmpty(t, keyB)
+
+	var started sync.WaitGroup
+	started.Add(2)
+	both := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(both)
+	}()
+	for _, key := range []string{keyA, keyB} {
+		workers.submit(key, func() {
+			started.Done()
+			select {
+			case <-both:
+			case <-time.After(time.Second):
+				t.Error("messages for different objects did not run concurrently")
+			}
+		})
+	}
+	workers.stop()
+}
+
+func TestPartitionKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		data    string
+		want    string
+	}{
+		{
+			name:    "generic sync message",
+			subject: "lfx.fga-sync.member_put",
+			data:    `{"object_type":"meeting","operation":"member_put","data":{"uid":"m1","username":"alice"}}`,
+			want:    "meeting:m1",
+		},
+		{
+			name:    "mixed-case object type",
+			subject: "lfx.fga-sync.member_put",
+			data:    `{"object_type":"Meeting","operation":"member_put","data":{"uid":"m1","username":"alice"}}`,
+			want:    "meeting:m1",
+		},
+		{
+			name:    "resync request",
+			subject: "lfx.fga-sync.resync_object",
+			data:    `{"object_type":"committee","uid":"c1"}`,
+			want:    "committee:c1",
+		},
+		{
+			name:    "message without an object",
+			subject: "lfx.fga-sync.relations",
+			data:    `{"object_type":"meeting"}`,
+			want:    "lfx.fga-sync.relations",
+		},
+		{
+			name:    "invalid JSON",
+			subject: "lfx.fga-sync.update_access",
+			data:    `not-json`,
+			want:    "lfx.fga-sync.update_access",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := CreateMockNatsMsg([]byte(tt.data))
+			msg.subject = tt.subject
+			assert.Equal(t, tt.want, partitionKey(msg))
+		})
+	}
+}
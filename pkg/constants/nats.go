@@ -19,6 +19,58 @@ const (
 	// ReadTuplesSubject is the subject for reading a user's direct tuples by object type.
 	// The subject is of the form: lfx.access_check.read_tuples
 	ReadTuplesSubject = "lfx.access_check.read_tuples"
+
+	// ListObjectsSubject is the subject for listing the objects of a type a user has a relation on.
+	// The subject is of the form: lfx.access_check.list_objects
+	ListObjectsSubject = "lfx.access_check.list_objects"
+)
+
+// Admin NATS subjects for inspecting the service's view of the authorization model
+// and for targeted remediation.
+const (
+	// RelationsSubject is the subject for listing the relations defined on an object type.
+	// The subject is of the form: lfx.fga-sync.relations
+	RelationsSubject = "lfx.fga-sync.relations"
+
+	// ResyncObjectSubject is the subject for reconciling a single object against an
+	// operator-supplied desired state.
+	// The subject is of the form: lfx.fga-sync.resync_object
+	ResyncObjectSubject = "lfx.fga-sync.resync_object"
+
+	// ResyncObjectsSubject is the subject for reconciling a list of objects
+	// against operator-supplied desired states in one request.
+	// The subject is of the form: lfx.fga-sync.resync_objects
+	ResyncObjectsSubject = "lfx.fga-sync.resync_objects"
+
+	// EnsureSubject is the subject for writing the missing tuples of an
+	// object's baseline access without deleting any.
+	// The subject is of the form: lfx.fga-sync.ensure
+	EnsureSubject = "lfx.fga-sync.ensure"
+
+	// ExplainAccessSubject is the subject for attributing a user's access to the
+	// direct grants that provide it.
+	// The subject is of the form: lfx.fga-sync.explain_access
+	ExplainAccessSubject = "lfx.fga-sync.explain_access"
+
+	// ConfigSubject is the subject for reading the service's effective, non-secret
+	// configuration.
+	// The subject is of the form: lfx.fga-sync.config
+	ConfigSubject = "lfx.fga-sync.config"
+
+	// MaintenanceSubject is the subject for reading and setting maintenance
+	// mode. Every replica handles it, rather than one of the queue group.
+	// The subject is of the form: lfx.fga-sync.maintenance
+	MaintenanceSubject = "lfx.fga-sync.maintenance"
+
+	// RenameRelationSubject is the subject for migrating the tuples of a renamed
+	// relation on a list of objects.
+	// The subject is of the form: lfx.fga-sync.rename_relation
+	RenameRelationSubject = "lfx.fga-sync.rename_relation"
+
+	// DeleteAccessBulkSubject is the subject for deleting all access to a list of
+	// objects in one request.
+	// The subject is of the form: lfx.fga-sync.delete_access_bulk
+	DeleteAccessBulkSubject = "lfx.fga-sync.delete_access_bulk"
 )
 
 // NATS queue subjects that the FGA sync service handles messages about.
@@ -28,6 +80,17 @@ const (
 	FgaSyncQueue = "lfx.fga-sync.queue"
 )
 
+// FGA sync subject namespace owned by this service.
+const (
+	// FgaSyncSubjectPrefix is the prefix shared by all subjects in the FGA sync namespace.
+	FgaSyncSubjectPrefix = "lfx.fga-sync."
+
+	// FgaSyncSubjectWildcard matches every subject in the FGA sync namespace. Messages
+	// on subjects that have no registered handler are logged and counted as unhandled.
+	// The subject is of the form: lfx.fga-sync.>
+	FgaSyncSubjectWildcard = FgaSyncSubjectPrefix + ">"
+)
+
 // Generic NATS subjects for resource-agnostic FGA operations.
 // These subjects accept a GenericFGAMessage envelope and route based on object_type.
 const (
@@ -46,4 +109,9 @@ const (
 	// GenericMemberRemoveSubject is the subject for generic member remove operations.
 	// The subject is of the form: lfx.fga-sync.member_remove
 	GenericMemberRemoveSubject = "lfx.fga-sync.member_remove"
+
+	// GenericMemberBatchSubject is the subject for generic member operations
+	// applying several users' changes to one object together.
+	// The subject is of the form: lfx.fga-sync.member_batch
+	GenericMemberBatchSubject = "lfx.fga-sync.member_batch"
 )
@@ -0,0 +1,22 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+import "time"
+
+// AuditRecord is the JSON record published to AUDIT_SUBJECT for every object
+// whose access an operation changed. Writes and Deletes list the applied
+// tuples as object#relation@user. Actor is the Fga-Actor header of the
+// message, if set, and CorrelationID ties the records of one message
+// together.
+type AuditRecord struct {
+	Object        string    `json:"object"`
+	Operation     string    `json:"operation"`
+	Writes        []string  `json:"writes"`
+	Deletes       []string  `json:"deletes"`
+	Actor         string    `json:"actor,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	CorrelationID string    `json:"correlation_id"`
+}
@@ -0,0 +1,34 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// CaptureRecord is a sampled message as received, with the tuples handling
+// it wrote and deleted, logged when CAPTURE_SAMPLE_RATE is set. Publishing
+// Payload with Headers to Subject against a test store reproduces the same
+// mutations. Error is set if the handler failed; the tuples written before
+// the failure are still listed.
+type CaptureRecord struct {
+	Subject string              `json:"subject"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Payload string              `json:"payload"`
+	Writes  []CapturedTuple     `json:"writes"`
+	Deletes []CapturedTuple     `json:"deletes"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// CapturedTuple is a tuple written or deleted by a captured message, exactly
+// as sent to OpenFGA.
+type CapturedTuple struct {
+	User      string             `json:"user"`
+	Relation  string             `json:"relation"`
+	Object    string             `json:"object"`
+	Condition *CapturedCondition `json:"condition,omitempty"`
+}
+
+// CapturedCondition is the condition of a captured tuple.
+type CapturedCondition struct {
+	Name    string         `json:"name"`
+	Context map[string]any `json:"context,omitempty"`
+}
@@ -0,0 +1,27 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// CheckRequest is the JSON body accepted by the HTTP POST /check endpoint.
+// Checks are tuple-strings in the object#relation@user format.
+type CheckRequest struct {
+	Checks []string `json:"checks"`
+}
+
+// CheckResult is the result of one check in a CheckResponse. Guarded is set
+// on a check denied because OpenFGA did not resolve it within the check
+// guard timeout, rather than because access is not granted.
+type CheckResult struct {
+	Check   string `json:"check"`
+	Allowed bool   `json:"allowed"`
+	Guarded bool   `json:"guarded,omitempty"`
+}
+
+// CheckResponse is the JSON response of the HTTP POST /check endpoint.
+// Results are not guaranteed to be in request order. Error is set on failure.
+type CheckResponse struct {
+	Results []CheckResult `json:"results"`
+	Error   string        `json:"error,omitempty"`
+}
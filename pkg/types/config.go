@@ -0,0 +1,14 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// ConfigResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.config subject. Config is the effective configuration keyed
+// by environment variable name, with credentials redacted. Error is set on
+// failure.
+type ConfigResponse struct {
+	Config map[string]any `json:"config,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// DeleteAccessResponse is the JSON reply sent back over NATS for a
+// delete_access request with verbose set, recording what was removed.
+// Deleted counts every tuple removed from the object and its cascade; Tuples
+// lists them as object#relation@user, truncated to a sample for objects with
+// many tuples, in which case Truncated is set.
+type DeleteAccessResponse struct {
+	Deleted   int      `json:"deleted"`
+	Tuples    []string `json:"tuples"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
@@ -0,0 +1,37 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// DeleteAccessBulkRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.delete_access_bulk subject: a delete_access for each listed
+// object, applied in order.
+type DeleteAccessBulkRequest struct {
+	Objects []DeleteAccessBulkObject `json:"objects"`
+}
+
+// DeleteAccessBulkObject names one object of a DeleteAccessBulkRequest.
+type DeleteAccessBulkObject struct {
+	ObjectType string `json:"object_type"`
+	UID        string `json:"uid"`
+}
+
+// DeleteAccessBulkResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.delete_access_bulk subject. Results has one entry per
+// requested object, in request order. Error is set when the request as a
+// whole was rejected, in which case nothing was deleted.
+type DeleteAccessBulkResponse struct {
+	Results []DeleteAccessBulkResult `json:"results"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// DeleteAccessBulkResult is the outcome of deleting one object's access.
+// Deleted counts the tuples removed; Scheduled is set instead when deletes are
+// deferred by a grace period. Error is set if this object failed.
+type DeleteAccessBulkResult struct {
+	Object    string `json:"object"`
+	Deleted   int    `json:"deleted"`
+	Scheduled bool   `json:"scheduled,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
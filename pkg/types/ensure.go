@@ -0,0 +1,27 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// EnsureRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.ensure subject. The public, relations, and references fields
+// have the same meaning as in GenericAccessData, but list tuples the object
+// must have rather than its complete state.
+type EnsureRequest struct {
+	ObjectType string              `json:"object_type"`
+	UID        string              `json:"uid"`
+	Public     bool                `json:"public"`
+	Relations  map[string][]string `json:"relations,omitempty"`
+	References map[string][]string `json:"references,omitempty"`
+}
+
+// EnsureResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.ensure subject. Writes are the tuples that were missing and
+// have been written, as tuple-strings in the canonical object#relation@user
+// format. Error is set on failure.
+type EnsureResponse struct {
+	Object string   `json:"object,omitempty"`
+	Writes []string `json:"writes"`
+	Error  string   `json:"error,omitempty"`
+}
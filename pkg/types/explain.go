@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// ExplainAccessRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.explain_access subject.
+type ExplainAccessRequest struct {
+	Object   string `json:"object"`
+	Relation string `json:"relation"`
+	User     string `json:"user"`
+}
+
+// AccessGrant is a direct tuple contributing to a user's access. Kind is
+// "direct" for a tuple naming the user, "public" for a wildcard tuple, or
+// "userset" for a tuple granting a userset the user belongs to. Tuple is in
+// the canonical object#relation@user format.
+type AccessGrant struct {
+	Kind  string `json:"kind"`
+	Tuple string `json:"tuple"`
+}
+
+// ExplainAccessResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.explain_access subject. Grants lists every direct tuple on
+// the object and relation that grants the user access; it is empty when
+// access is only inherited through the model. Error is set on failure.
+type ExplainAccessResponse struct {
+	Allowed bool          `json:"allowed"`
+	Grants  []AccessGrant `json:"grants"`
+	Error   string        `json:"error,omitempty"`
+}
@@ -6,7 +6,11 @@
 // the message envelope and data payloads stay consistent across the platform.
 package types
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
 
 // GenericFGAMessage is the universal message format for all FGA operations.
 // This allows clients to send resource-agnostic messages without needing
@@ -27,6 +31,18 @@ func (m *GenericFGAMessage) UnmarshalData(v any) error {
 	return json.Unmarshal(b, v)
 }
 
+// UnmarshalDataStrict is UnmarshalData, but fails if the Data field has a
+// field v does not define, such as a misspelled one.
+func (m *GenericFGAMessage) UnmarshalDataStrict(v any) error {
+	b, err := json.Marshal(m.Data)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
 // GenericAccessData is the Data payload for update_access operations.
 // This is a full sync — any relations not listed (and not excluded) will be removed.
 type GenericAccessData struct {
@@ -35,11 +51,22 @@ type GenericAccessData struct {
 	Relations        map[string][]string `json:"relations"`         // relation_name → [usernames]
 	References       map[string][]string `json:"references"`        // relation_name → [object_uids]
 	ExcludeRelations []string            `json:"exclude_relations"` // relations managed elsewhere
+	// ExpectedVersion is the object version this update is based on. It is
+	// required for object types with optimistic concurrency enabled, and
+	// ignored otherwise.
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
 }
 
 // GenericDeleteData is the Data payload for delete_access operations.
 type GenericDeleteData struct {
 	UID string `json:"uid"`
+	// Cascade lists dependent objects, in "type:uid" form (e.g.
+	// "meeting_attachment:123"), whose tuples are deleted along with the
+	// object's own so they are not left pointing at a deleted parent.
+	Cascade []string `json:"cascade,omitempty"`
+	// Verbose requests a DeleteAccessResponse reply listing the removed
+	// tuples, instead of the usual success reply.
+	Verbose bool `json:"verbose,omitempty"`
 }
 
 // GenericMemberData is the Data payload for member_put and member_remove operations.
@@ -48,5 +75,33 @@ type GenericMemberData struct {
 	UID                   string   `json:"uid"`
 	Username              string   `json:"username"`
 	Relations             []string `json:"relations"`               // relations to add or remove
-	MutuallyExclusiveWith []string `json:"mutually_exclusive_with"` // on member_put: remove these
+	MutuallyExclusiveWith []string `json:"mutually_exclusive_with"` // on put: remove these; on remove: repair
+	// Email identifies the user when Username is empty. It is resolved to a
+	// username by the service's configured email resolver.
+	Email string `json:"email,omitempty"`
+	// UpdatedAt is optional. When set, operations older than the last one
+	// applied for the same object and user are skipped.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// ExpiresAt is optional, on put only. When set, the relations granted
+	// are removed once it passes.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// GenericMemberBatchData is the Data payload for member_batch operations,
+// which apply the puts and removes of several users to one object together.
+type GenericMemberBatchData struct {
+	UID     string                `json:"uid"`
+	Puts    []GenericMemberChange `json:"puts"`
+	Removes []GenericMemberChange `json:"removes"`
+}
+
+// GenericMemberChange is one user's change in a member_batch operation. On a
+// put, Relations are granted; on a remove, they are revoked, and an empty
+// list revokes every relation the user holds.
+type GenericMemberChange struct {
+	Username  string   `json:"username"`
+	Relations []string `json:"relations"`
+	// UpdatedAt is optional. When set, the change is skipped if it is older
+	// than the last member operation applied for the same object and user.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
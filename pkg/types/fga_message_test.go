@@ -91,3 +91,24 @@ func TestGenericFGAMessage_UnmarshalData_NilData(t *testing.T) {
 		t.Errorf("expected zero-value UID, got %q", decoded.UID)
 	}
 }
+
+func TestGenericFGAMessage_UnmarshalDataStrict_UnknownField(t *testing.T) {
+	msg := GenericFGAMessage{
+		ObjectType: "committee",
+		Operation:  "update_access",
+		Data:       map[string]any{"uid": "committee-123", "comittees": []string{"committee-456"}},
+	}
+
+	var lenient GenericAccessData
+	if err := msg.UnmarshalData(&lenient); err != nil {
+		t.Fatalf("UnmarshalData returned unexpected error: %v", err)
+	}
+	if lenient.UID != "committee-123" {
+		t.Errorf("UID: got %q, want %q", lenient.UID, "committee-123")
+	}
+
+	var strict GenericAccessData
+	if err := msg.UnmarshalDataStrict(&strict); err == nil {
+		t.Error("UnmarshalDataStrict: expected an error for the unknown field, got nil")
+	}
+}
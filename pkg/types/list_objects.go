@@ -0,0 +1,37 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// ListObjectsRequest is the JSON payload received over NATS for the
+// lfx.access_check.list_objects subject. When ChunkSubject is set the
+// objects are streamed to it as ListObjectsChunk messages of up to ChunkSize
+// objects instead of being returned in the reply.
+type ListObjectsRequest struct {
+	User         string `json:"user"`
+	Relation     string `json:"relation"`
+	ObjectType   string `json:"object_type"`
+	ChunkSubject string `json:"chunk_subject,omitempty"`
+	ChunkSize    int    `json:"chunk_size,omitempty"`
+}
+
+// ListObjectsResponse is the JSON response sent back over NATS for the
+// lfx.access_check.list_objects subject. Objects is omitted when the result
+// was streamed to a chunk subject; Total is always the number of objects
+// found. Error is set on failure.
+type ListObjectsResponse struct {
+	Objects []string `json:"objects,omitempty"`
+	Total   int      `json:"total"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ListObjectsChunk is one message of a streamed list_objects result.
+// Sequence starts at 0 and increases by one per message. The final message
+// has Done set, carries no objects, and reports the Total streamed.
+type ListObjectsChunk struct {
+	Sequence int      `json:"sequence"`
+	Objects  []string `json:"objects,omitempty"`
+	Done     bool     `json:"done,omitempty"`
+	Total    int      `json:"total,omitempty"`
+}
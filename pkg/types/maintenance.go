@@ -0,0 +1,25 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+import "time"
+
+// MaintenanceRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.maintenance subject. Enabled turns maintenance mode on or
+// off; an empty payload, or no Enabled, only reads the current mode.
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// MaintenanceResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.maintenance subject by each replica. Since is when
+// maintenance mode was enabled, and Parked counts the writes waiting for it
+// to end. Error is set on failure.
+type MaintenanceResponse struct {
+	Enabled bool       `json:"enabled"`
+	Since   *time.Time `json:"since,omitempty"`
+	Parked  int        `json:"parked"`
+	Error   string     `json:"error,omitempty"`
+}
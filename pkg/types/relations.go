@@ -0,0 +1,20 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// RelationsRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.relations subject.
+type RelationsRequest struct {
+	ObjectType string `json:"object_type"`
+}
+
+// RelationsResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.relations subject. Relations are the relation names defined
+// on the object type in the current authorization model, sorted
+// alphabetically. Error is set on failure.
+type RelationsResponse struct {
+	Relations []string `json:"relations"`
+	Error     string   `json:"error,omitempty"`
+}
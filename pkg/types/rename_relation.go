@@ -0,0 +1,24 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// RenameRelationRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.rename_relation subject. OpenFGA cannot enumerate the objects
+// of a type, so the objects to migrate are listed by UID.
+type RenameRelationRequest struct {
+	ObjectType  string   `json:"object_type"`
+	OldRelation string   `json:"old_relation"`
+	NewRelation string   `json:"new_relation"`
+	ObjectUIDs  []string `json:"object_uids"`
+}
+
+// RenameRelationResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.rename_relation subject. Renamed maps each migrated object to
+// the number of tuples moved to the new relation. Error is set on failure, in
+// which case Renamed lists the objects migrated before the failure.
+type RenameRelationResponse struct {
+	Renamed map[string]int `json:"renamed"`
+	Error   string         `json:"error,omitempty"`
+}
@@ -0,0 +1,57 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// ResyncObjectRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.resync_object subject. The public, relations, and references
+// fields have the same meaning as in GenericAccessData and are treated as the
+// complete desired state of the object.
+type ResyncObjectRequest struct {
+	ObjectType string              `json:"object_type"`
+	UID        string              `json:"uid"`
+	Public     bool                `json:"public"`
+	Relations  map[string][]string `json:"relations,omitempty"`
+	References map[string][]string `json:"references,omitempty"`
+}
+
+// ResyncObjectResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.resync_object subject. Writes and Deletes are the tuples
+// changed to reconcile the object, as tuple-strings in the canonical
+// object#relation@user format. Error is set on failure.
+type ResyncObjectResponse struct {
+	Writes  []string `json:"writes"`
+	Deletes []string `json:"deletes"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ResyncObjectsRequest is the JSON payload received over NATS for the
+// lfx.fga-sync.resync_objects subject: a resync_object for each listed
+// object.
+type ResyncObjectsRequest struct {
+	Objects []ResyncObjectRequest `json:"objects"`
+}
+
+// ResyncObjectsResponse is the JSON response sent back over NATS for the
+// lfx.fga-sync.resync_objects subject. Results has one entry per requested
+// object, in request order; Writes, Deletes and Failed total them. Error is
+// set when the request as a whole was rejected, in which case nothing was
+// resynced.
+type ResyncObjectsResponse struct {
+	Results []ResyncObjectResult `json:"results"`
+	Writes  int                  `json:"writes"`
+	Deletes int                  `json:"deletes"`
+	Failed  int                  `json:"failed"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// ResyncObjectResult is the outcome of resyncing one object of a
+// ResyncObjectsRequest, with the tuples changed as in ResyncObjectResponse.
+// Error is set if this object failed.
+type ResyncObjectResult struct {
+	Object  string   `json:"object"`
+	Writes  []string `json:"writes"`
+	Deletes []string `json:"deletes"`
+	Error   string   `json:"error,omitempty"`
+}
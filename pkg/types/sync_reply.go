@@ -0,0 +1,36 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package types contains shared message types for the fga-sync service.
+package types
+
+// Statuses of the replies sent by the sync handlers.
+const (
+	// SyncReplyStatusOK is the status of a successful SyncReply.
+	SyncReplyStatusOK = "ok"
+	// SyncReplyStatusError is the status of an ErrorReply.
+	SyncReplyStatusError = "error"
+)
+
+// SyncReply is the JSON success reply sent back over NATS by the sync
+// handlers when REPLY_STRUCTURED is enabled, in place of "OK". Writes and
+// Deletes count the tuples the message changed on Object.
+type SyncReply struct {
+	Status  string `json:"status"`
+	Object  string `json:"object"`
+	Writes  int    `json:"writes"`
+	Deletes int    `json:"deletes"`
+}
+
+// ErrorReply is the JSON error reply sent back over NATS for a sync message
+// that failed. Error is the human-readable reason. For a message rejected
+// because of one of its fields, Field names the field as it appears in the
+// message, e.g. "uid" or "object_type", and Reason is one of "required",
+// "invalid" or "not_allowed"; both are omitted for other failures, such as an
+// OpenFGA write error.
+type ErrorReply struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
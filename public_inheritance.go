@@ -0,0 +1,97 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+
+	. "github.com/openfga/go-sdk/client"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+)
+
+// publicInheritance maps object types to the reference relation of the
+// object, such as an attachment's meeting, whose public status they inherit.
+type publicInheritance map[string]string
+
+// String formats the inheritance as it is configured, for the effective
+// configuration.
+func (p publicInheritance) String() string {
+	entries := make([]string, 0, len(p))
+	for _, objectType := range slices.Sorted(maps.Keys(p)) {
+		entries = append(entries, objectType+"="+p[objectType])
+	}
+	return strings.Join(entries, ",")
+}
+
+// parsePublicInheritance parses a comma-separated list of type=reference
+// entries, e.g. meeting_attachment=meeting.
+func parsePublicInheritance(v string) (publicInheritance, error) {
+	inheritance := make(publicInheritance)
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		objectType, reference, found := strings.Cut(entry, "=")
+		if !found || objectType == "" || reference == "" {
+			return nil, fmt.Errorf("expected type=reference, got %q", entry)
+		}
+		if _, dup := inheritance[objectType]; dup {
+			return nil, errors.New(objectType + " is listed more than once")
+		}
+		inheritance[objectType] = reference
+	}
+	return inheritance, nil
+}
+
+// inheritPublic adds the public viewer to the tuples of an access update of
+// object, of objectType, when the type inherits its public status and an
+// object it references through the configured relation is public. The status
+// is read when the object is synced: a referenced object made public or
+// private later does not change it until the object is synced again.
+func (h *HandlerService) inheritPublic(
+	ctx context.Context,
+	objectType, object string,
+	tuples []ClientTupleKey,
+) ([]ClientTupleKey, error) {
+	reference, ok := h.publicInheritance[objectType]
+	if !ok || slices.ContainsFunc(tuples, isWildcardTuple) {
+		return tuples, nil
+	}
+	for _, tuple := range tuples {
+		if tuple.Relation != reference {
+			continue
+		}
+		public, err := h.fgaService.isPublic(ctx, tuple.User)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public status of %s: %w", tuple.User, err)
+		}
+		if public {
+			logger.With("object", object, "inherited_from", tuple.User).DebugContext(ctx, "inherited public access")
+			return append(tuples, h.fgaService.TupleKey(constants.UserWildcard, constants.RelationViewer, object)), nil
+		}
+	}
+	return tuples, nil
+}
+
+// isPublic reports whether object grants the viewer relation to every user.
+func (s FgaService) isPublic(ctx context.Context, object string) (bool, error) {
+	resp, err := s.client.Read(ctx, ClientReadRequest{
+		User:     openfga.PtrString(constants.UserWildcard),
+		Relation: openfga.PtrString(constants.RelationViewer),
+		Object:   openfga.PtrString(object),
+	}, ClientReadOptions{PageSize: openfga.PtrInt32(1)})
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Tuples) > 0, nil
+}
@@ -0,0 +1,123 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+)
+
+// mockPublicObjects serves public viewer reads of the objects in public with
+// the wildcard viewer tuple. Other objects are private.
+func mockPublicObjects(m *MockFgaClient, public ...string) {
+	m.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+		return req.User != nil && *req.User == constants.UserWildcard && slices.Contains(public, *req.Object)
+	}), mock.Anything).Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+		{Key: openfga.TupleKey{User: constants.UserWildcard, Relation: constants.RelationViewer, Object: public[0]}},
+	}}, nil)
+	m.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+}
+
+func TestStandardAccessTuples_PublicInheritance(t *testing.T) {
+	wildcard := client.ClientTupleKey{
+		User: constants.UserWildcard, Relation: constants.RelationViewer, Object: "meeting_attachment:a1",
+	}
+	tests := []struct {
+		name         string
+		inheritance  publicInheritance
+		meeting      string
+		public       bool
+		expectPublic bool
+		expectReads  int
+	}{
+		{
+			name:         "attachment of a public meeting is public",
+			inheritance:  publicInheritance{"meeting_attachment": "meeting"},
+			meeting:      "public-meeting",
+			expectPublic: true,
+			expectReads:  1,
+		},
+		{
+			name:        "attachment of a private meeting is not public",
+			inheritance: publicInheritance{"meeting_attachment": "meeting"},
+			meeting:     "private-meeting",
+			expectReads: 1,
+		},
+		{
+			name:    "inheritance is off by default",
+			meeting: "public-meeting",
+		},
+		{
+			name:         "attachment already public is not looked up",
+			inheritance:  publicInheritance{"meeting_attachment": "meeting"},
+			meeting:      "private-meeting",
+			public:       true,
+			expectPublic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.publicInheritance = tt.inheritance
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			mockPublicObjects(fgaClient, "meeting:public-meeting")
+
+			_, tuples, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+				UID:        "a1",
+				ObjectType: "meeting_attachment",
+				Public:     tt.public,
+				References: map[string][]string{"meeting": {tt.meeting}},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectPublic, slices.Contains(tuples, wildcard))
+			wildcardTuples := 0
+			for _, tuple := range tuples {
+				if tuple == wildcard {
+					wildcardTuples++
+				}
+			}
+			assert.LessOrEqual(t, wildcardTuples, 1)
+			fgaClient.AssertNumberOfCalls(t, "Read", tt.expectReads)
+		})
+	}
+}
+
+func TestStandardAccessTuples_PublicInheritanceReadError(t *testing.T) {
+	service := setupService()
+	service.publicInheritance = publicInheritance{"meeting_attachment": "meeting"}
+	service.fgaService.client.(*MockFgaClient).On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), errors.New("store unavailable"))
+
+	_, _, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+		UID:        "a1",
+		ObjectType: "meeting_attachment",
+		References: map[string][]string{"meeting": {"m1"}},
+	})
+	assert.EqualError(t, err, "failed to read public status of meeting:m1: store unavailable")
+}
+
+func TestParsePublicInheritance(t *testing.T) {
+	inheritance, err := parsePublicInheritance("meeting_attachment=meeting, past_meeting_recording=past_meeting")
+	assert.NoError(t, err)
+	assert.Equal(t, publicInheritance{
+		"meeting_attachment":     "meeting",
+		"past_meeting_recording": "past_meeting",
+	}, inheritance)
+	assert.Equal(t, "meeting_attachment=meeting,past_meeting_recording=past_meeting", inheritance.String())
+
+	for _, v := range []string{"meeting_attachment", "=meeting", "meeting_attachment=meeting,meeting_attachment=project"} {
+		_, err := parsePublicInheritance(v)
+		assert.Error(t, err, v)
+	}
+}
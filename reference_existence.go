@@ -0,0 +1,149 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+
+	. "github.com/openfga/go-sdk/client"
+)
+
+// referenceExistenceMode controls how references to objects that have no
+// tuples in OpenFGA are handled.
+type referenceExistenceMode string
+
+const (
+	// referenceExistenceOff writes references without checking their
+	// objects.
+	referenceExistenceOff referenceExistenceMode = ""
+	// referenceExistenceWarn logs references to missing objects and writes
+	// them anyway.
+	referenceExistenceWarn referenceExistenceMode = "warn"
+	// referenceExistenceStrict rejects the sync if any reference points at a
+	// missing object.
+	referenceExistenceStrict referenceExistenceMode = "strict"
+)
+
+// parseReferenceExistenceMode parses a mode as configured. "off" is accepted
+// so that a per-type override can disable the check.
+func parseReferenceExistenceMode(v string) (referenceExistenceMode, error) {
+	switch mode := referenceExistenceMode(v); mode {
+	case referenceExistenceOff, referenceExistenceWarn, referenceExistenceStrict:
+		return mode, nil
+	case "off":
+		return referenceExistenceOff, nil
+	}
+	return "", fmt.Errorf("mode must be off, %q or %q, got %q", referenceExistenceWarn, referenceExistenceStrict, v)
+}
+
+// referenceExistencePolicy maps object types to the mode applied to the
+// references of their access updates, falling back to a model-wide mode for
+// types without one.
+type referenceExistencePolicy struct {
+	fallback referenceExistenceMode
+	byType   map[string]referenceExistenceMode
+}
+
+// forType returns the mode applied to references of objects of objectType.
+func (p referenceExistencePolicy) forType(objectType string) referenceExistenceMode {
+	if mode, ok := p.byType[objectType]; ok {
+		return mode
+	}
+	return p.fallback
+}
+
+// String formats the per-type overrides as they are configured, for the
+// effective configuration.
+func (p referenceExistencePolicy) String() string {
+	entries := make([]string, 0, len(p.byType))
+	for _, objectType := range slices.Sorted(maps.Keys(p.byType)) {
+		mode := p.byType[objectType]
+		if mode == referenceExistenceOff {
+			mode = "off"
+		}
+		entries = append(entries, objectType+"="+string(mode))
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseReferenceExistenceOverrides parses a comma-separated list of
+// type=mode entries, e.g. meeting=strict,project=off.
+func parseReferenceExistenceOverrides(v string) (map[string]referenceExistenceMode, error) {
+	overrides := make(map[string]referenceExistenceMode)
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		objectType, modeStr, found := strings.Cut(entry, "=")
+		if !found || objectType == "" || modeStr == "" {
+			return nil, fmt.Errorf("expected type=mode, got %q", entry)
+		}
+		mode, err := parseReferenceExistenceMode(modeStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", objectType, err)
+		}
+		if _, dup := overrides[objectType]; dup {
+			return nil, errors.New(objectType + " is listed more than once")
+		}
+		overrides[objectType] = mode
+	}
+	return overrides, nil
+}
+
+// checkReferenceExistence checks that every object referenced by refs, the
+// reference tuples of an access update of object, exists in OpenFGA, as the
+// mode configured for objectType requires. References to missing objects are
+// rejected in strict mode and logged in warn mode.
+func (h *HandlerService) checkReferenceExistence(
+	ctx context.Context,
+	objectType, object string,
+	refs []ClientTupleKey,
+) error {
+	mode := h.referenceExistence.forType(objectType)
+	if mode == referenceExistenceOff {
+		return nil
+	}
+	checked := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		if checked[ref.User] {
+			continue
+		}
+		checked[ref.User] = true
+		exists, err := h.fgaService.objectExists(ctx, ref.User)
+		if err != nil {
+			return fmt.Errorf("failed to read referenced object %s: %w", ref.User, err)
+		}
+		if exists {
+			continue
+		}
+		if mode == referenceExistenceStrict {
+			logger.ErrorContext(ctx, "reference to missing object", "object", object, "reference", ref.User)
+			return newFieldError("references", reasonInvalid,
+				fmt.Sprintf("%s references %s as %s, which does not exist", object, ref.User, ref.Relation))
+		}
+		logger.WarnContext(ctx, "reference to missing object", "object", object, "reference", ref.User)
+	}
+	return nil
+}
+
+// objectExists reports whether object has any tuples in OpenFGA. The service
+// keeps no record of objects of its own, so an object whose access was never
+// synced, or has been deleted, does not exist.
+func (s FgaService) objectExists(ctx context.Context, object string) (bool, error) {
+	resp, err := s.client.Read(ctx, ClientReadRequest{Object: openfga.PtrString(object)}, ClientReadOptions{
+		PageSize: openfga.PtrInt32(1),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Tuples) > 0, nil
+}
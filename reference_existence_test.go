@@ -0,0 +1,129 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockExistingObjects serves reads of the objects in existing with one tuple
+// each. Other objects have no tuples.
+func mockExistingObjects(m *MockFgaClient, existing ...string) {
+	for _, object := range existing {
+		m.On("Read", mock.Anything, mock.MatchedBy(func(req client.ClientReadRequest) bool {
+			return req.Object != nil && *req.Object == object
+		}), mock.Anything).Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: object}},
+		}}, nil)
+	}
+	m.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+}
+
+func TestStandardAccessTuples_ReferenceExistence(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      referenceExistencePolicy
+		objectType  string
+		expectError string
+	}{
+		{
+			name:       "off by default",
+			objectType: "meeting",
+		},
+		{
+			name:        "global strict rejects a missing reference",
+			policy:      referenceExistencePolicy{fallback: referenceExistenceStrict},
+			objectType:  "meeting",
+			expectError: "meeting:m1 references committee:missing as committee, which does not exist",
+		},
+		{
+			name:       "global warn writes a missing reference",
+			policy:     referenceExistencePolicy{fallback: referenceExistenceWarn},
+			objectType: "meeting",
+		},
+		{
+			name: "per-type override disables strict",
+			policy: referenceExistencePolicy{
+				fallback: referenceExistenceStrict,
+				byType:   map[string]referenceExistenceMode{"meeting": referenceExistenceOff},
+			},
+			objectType: "meeting",
+		},
+		{
+			name: "per-type override enables strict",
+			policy: referenceExistencePolicy{
+				byType: map[string]referenceExistenceMode{"meeting": referenceExistenceStrict},
+			},
+			objectType:  "meeting",
+			expectError: "meeting:m1 references committee:missing as committee, which does not exist",
+		},
+		{
+			name: "per-type override applies to its type only",
+			policy: referenceExistencePolicy{
+				byType: map[string]referenceExistenceMode{"past_meeting": referenceExistenceStrict},
+			},
+			objectType: "meeting",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			service.referenceExistence = tt.policy
+			mockExistingObjects(service.fgaService.client.(*MockFgaClient), "project:p1")
+
+			_, tuples, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+				UID:        "m1",
+				ObjectType: tt.objectType,
+				References: map[string][]string{"project": {"p1"}, "committee": {"missing"}},
+			})
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+				assert.Len(t, tuples, 2)
+				return
+			}
+			assert.EqualError(t, err, tt.expectError)
+			assert.Contains(t, string(errorReply(err)), `"field":"references"`)
+		})
+	}
+}
+
+func TestStandardAccessTuples_ReferenceExistenceReadError(t *testing.T) {
+	service := setupService()
+	service.referenceExistence = referenceExistencePolicy{fallback: referenceExistenceStrict}
+	service.fgaService.client.(*MockFgaClient).On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return((*client.ClientReadResponse)(nil), errors.New("store unavailable"))
+
+	_, _, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+		UID:        "m1",
+		ObjectType: "meeting",
+		References: map[string][]string{"project": {"p1"}},
+	})
+	assert.ErrorContains(t, err, "failed to read referenced object project:p1")
+	assert.NotContains(t, string(errorReply(err)), `"field"`)
+}
+
+func TestParseReferenceExistenceOverrides(t *testing.T) {
+	overrides, err := parseReferenceExistenceOverrides("meeting=strict, project=off,committee=warn")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]referenceExistenceMode{
+		"meeting":   referenceExistenceStrict,
+		"project":   referenceExistenceOff,
+		"committee": referenceExistenceWarn,
+	}, overrides)
+	assert.Equal(t, "committee=warn,meeting=strict,project=off",
+		referenceExistencePolicy{byType: overrides}.String())
+
+	for _, v := range []string{"meeting", "meeting=", "meeting=lenient", "meeting=strict,meeting=warn"} {
+		_, err := parseReferenceExistenceOverrides(v)
+		assert.Error(t, err, v)
+	}
+}
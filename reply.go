@@ -0,0 +1,136 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+// defaultSuccessReply is the reply payload sent by sync handlers on success.
+const defaultSuccessReply = "OK"
+
+// replyConfig is the success reply sent by sync handlers when the publisher
+// provides a reply inbox.
+type replyConfig struct {
+	payload []byte
+	// contentType, when set, is sent as the Content-Type header of the reply.
+	contentType string
+	// structured replies with a JSON SyncReply recording the tuples changed,
+	// in place of payload.
+	structured bool
+}
+
+// successReply is the configured success reply for sync handlers.
+var successReply = replyConfig{payload: []byte(defaultSuccessReply)}
+
+// replyConfigFromEnv reads the success reply from REPLY_SUCCESS_PAYLOAD,
+// REPLY_CONTENT_TYPE and REPLY_STRUCTURED, defaulting to a plain "OK" with no
+// content type.
+func replyConfigFromEnv() replyConfig {
+	cfg := replyConfig{payload: []byte(defaultSuccessReply)}
+	if v := os.Getenv("REPLY_SUCCESS_PAYLOAD"); v != "" {
+		cfg.payload = []byte(v)
+	}
+	cfg.contentType = os.Getenv("REPLY_CONTENT_TYPE")
+	cfg.structured = os.Getenv("REPLY_STRUCTURED") == trueString
+	return cfg
+}
+
+// sendReplyIfNeeded sends the configured success reply if the message has a
+// reply inbox. object, writes and deletes record what the message changed,
+// for structured replies.
+func (h *HandlerService) sendReplyIfNeeded(ctx context.Context, message INatsMsg, object string, writes, deletes int) error {
+	if message.Reply() == "" {
+		return nil
+	}
+
+	payload := successReply.payload
+	if successReply.structured {
+		data, err := json.Marshal(types.SyncReply{
+			Status:  types.SyncReplyStatusOK,
+			Object:  object,
+			Writes:  writes,
+			Deletes: deletes,
+		})
+		if err != nil {
+			logger.With(errKey, err).ErrorContext(ctx, "failed to marshal reply")
+			return err
+		}
+		payload = data
+	}
+
+	var err error
+	if successReply.contentType == "" {
+		err = message.Respond(payload)
+	} else {
+		reply := nats.NewMsg(message.Reply())
+		reply.Data = payload
+		reply.Header.Set("Content-Type", successReply.contentType)
+		err = message.RespondMsg(reply)
+	}
+	if err != nil {
+		logger.With(errKey, err).WarnContext(ctx, "failed to send reply")
+		return err
+	}
+	return nil
+}
+
+// sendErrorReplyIfNeeded sends err as a reply if the message has a reply
+// inbox, and returns err so the caller can propagate it.
+func (h *HandlerService) sendErrorReplyIfNeeded(ctx context.Context, message INatsMsg, err error) error {
+	respondError(ctx, message, err)
+	return err
+}
+
+// respondError sends err as a JSON ErrorReply if msg has a reply inbox. Every
+// error reply to a sync message goes through it, so publishers can parse them
+// all the same way.
+func respondError(ctx context.Context, msg INatsMsg, err error) {
+	if msg.Reply() == "" {
+		return
+	}
+	if errRespond := msg.Respond(errorReply(err)); errRespond != nil {
+		logger.With(errKey, errRespond).WarnContext(ctx, "failed to send reply")
+	}
+}
+
+// errorReply returns the JSON ErrorReply for err. A fieldError also names the
+// field and the reason it was rejected; other errors include the OpenFGA
+// request ID, if there is one.
+func errorReply(err error) []byte {
+	reply := types.ErrorReply{Status: types.SyncReplyStatusError, Error: withFgaRequestID(err.Error(), err)}
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		reply.Error, reply.Field, reply.Reason = fieldErr.msg, fieldErr.field, fieldErr.reason
+	}
+	// An ErrorReply holds only strings, so it always marshals.
+	data, _ := json.Marshal(reply)
+	return data
+}
+
+// respondJSONError sends resp, the JSON response of a request/reply subject
+// carrying errMsg, if message has a reply inbox, and returns errMsg prefixed
+// with operation as an error so the subscription loop can log it. It does not
+// log; callers are responsible for logging before calling it.
+func respondJSONError[R any](message INatsMsg, operation string, resp R, errMsg string) error {
+	if message.Reply() != "" {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return fmt.Errorf("%s: %s (marshal error response: %w)", operation, errMsg, err)
+		}
+		if errRespond := message.Respond(data); errRespond != nil {
+			return fmt.Errorf("%s: %s (send error reply: %w)", operation, errMsg, errRespond)
+		}
+	}
+	return fmt.Errorf("%s: %s", operation, errMsg)
+}
@@ -0,0 +1,185 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	nats "github.com/nats-io/nats.go"
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	fgatypes "github.com/linuxfoundation/lfx-v2-fga-sync/pkg/types"
+)
+
+func TestReplyConfigFromEnv(t *testing.T) {
+	t.Setenv("REPLY_SUCCESS_PAYLOAD", "")
+	t.Setenv("REPLY_CONTENT_TYPE", "")
+	t.Setenv("REPLY_STRUCTURED", "")
+	cfg := replyConfigFromEnv()
+	assert.Equal(t, []byte("OK"), cfg.payload)
+	assert.Empty(t, cfg.contentType)
+	assert.False(t, cfg.structured)
+
+	t.Setenv("REPLY_SUCCESS_PAYLOAD", `{"status":"ok"}`)
+	t.Setenv("REPLY_CONTENT_TYPE", "application/json")
+	cfg = replyConfigFromEnv()
+	assert.Equal(t, []byte(`{"status":"ok"}`), cfg.payload)
+	assert.Equal(t, "application/json", cfg.contentType)
+
+	t.Setenv("REPLY_STRUCTURED", "true")
+	assert.True(t, replyConfigFromEnv().structured)
+}
+
+// TestSyncHandlers_ConfiguredReply asserts that every sync handler sends the
+// configured success reply.
+func TestSyncHandlers_ConfiguredReply(t *testing.T) {
+	previous := successReply
+	successReply = replyConfig{payload: []byte(`{"status":"ok"}`), contentType: "application/json"}
+	defer func() { successReply = previous }()
+
+	tests := []struct {
+		name      string
+		operation string
+		data      any
+		handle    func(*HandlerService, context.Context, INatsMsg) error
+	}{
+		{
+			name:      "update_access",
+			operation: "update_access",
+			data:      fgatypes.GenericAccessData{UID: "committee-1", Public: true},
+			handle:    (*HandlerService).genericUpdateAccessHandler,
+		},
+		{
+			name:      "delete_access",
+			operation: "delete_access",
+			data:      fgatypes.GenericDeleteData{UID: "committee-1"},
+			handle:    (*HandlerService).genericDeleteAccessHandler,
+		},
+		{
+			name:      "member_put",
+			operation: "member_put",
+			data:      fgatypes.GenericMemberData{UID: "committee-1", Username: "alice", Relations: []string{"member"}},
+			handle:    (*HandlerService).genericMemberPutHandler,
+		},
+		{
+			name:      "member_remove",
+			operation: "member_remove",
+			data:      fgatypes.GenericMemberData{UID: "committee-1", Username: "alice"},
+			handle:    (*HandlerService).genericMemberRemoveHandler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "committee", tt.operation, tt.data)
+			msg.reply = "reply.inbox"
+			msg.On("RespondMsg", mock.MatchedBy(func(reply *nats.Msg) bool {
+				return string(reply.Data) == `{"status":"ok"}` &&
+					reply.Header.Get("Content-Type") == "application/json"
+			})).Return(nil).Once()
+
+			assert.NoError(t, tt.handle(service, context.Background(), msg))
+			msg.AssertExpectations(t)
+		})
+	}
+}
+
+// TestSyncHandlers_StructuredReply asserts that with structured replies
+// enabled, every sync handler replies with the tuples it changed.
+func TestSyncHandlers_StructuredReply(t *testing.T) {
+	previous := successReply
+	successReply = replyConfig{payload: []byte(defaultSuccessReply), structured: true}
+	defer func() { successReply = previous }()
+
+	existing := []openfga.Tuple{
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "member", Object: "committee:committee-1"}},
+		{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: "committee:committee-1"}},
+		{Key: openfga.TupleKey{User: "user:bob", Relation: "member", Object: "committee:committee-1"}},
+	}
+	tests := []struct {
+		name      string
+		operation string
+		data      any
+		handle    func(*HandlerService, context.Context, INatsMsg) error
+		expected  fgatypes.SyncReply
+	}{
+		{
+			name:      "update_access",
+			operation: "update_access",
+			data: fgatypes.GenericAccessData{
+				UID:       "committee-1",
+				Public:    true,
+				Relations: map[string][]string{"member": {"alice", "carol"}},
+			},
+			handle:   (*HandlerService).genericUpdateAccessHandler,
+			expected: fgatypes.SyncReply{Writes: 2, Deletes: 2},
+		},
+		{
+			name:      "delete_access",
+			operation: "delete_access",
+			data:      fgatypes.GenericDeleteData{UID: "committee-1"},
+			handle:    (*HandlerService).genericDeleteAccessHandler,
+			expected:  fgatypes.SyncReply{Deletes: 3},
+		},
+		{
+			name:      "member_put",
+			operation: "member_put",
+			data:      fgatypes.GenericMemberData{UID: "committee-1", Username: "carol", Relations: []string{"member"}},
+			handle:    (*HandlerService).genericMemberPutHandler,
+			expected:  fgatypes.SyncReply{Writes: 1},
+		},
+		{
+			name:      "member_remove",
+			operation: "member_remove",
+			data:      fgatypes.GenericMemberData{UID: "committee-1", Username: "alice"},
+			handle:    (*HandlerService).genericMemberRemoveHandler,
+			expected:  fgatypes.SyncReply{Deletes: 2},
+		},
+		{
+			name:      "member_remove of relations not held",
+			operation: "member_remove",
+			data: fgatypes.GenericMemberData{
+				UID:       "committee-1",
+				Username:  "bob",
+				Relations: []string{"member", "viewer", "writer"},
+			},
+			handle:   (*HandlerService).genericMemberRemoveHandler,
+			expected: fgatypes.SyncReply{Deletes: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+			fgaClient := service.fgaService.client.(*MockFgaClient)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: existing}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+
+			msg := buildGenericMessage(t, "committee", tt.operation, tt.data)
+			msg.reply = "reply.inbox"
+			var reply fgatypes.SyncReply
+			msg.On("Respond", mock.Anything).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &reply))
+			}).Return(nil).Once()
+
+			assert.NoError(t, tt.handle(service, context.Background(), msg))
+			msg.AssertExpectations(t)
+			tt.expected.Status = fgatypes.SyncReplyStatusOK
+			tt.expected.Object = "committee:committee-1"
+			assert.Equal(t, tt.expected, reply)
+		})
+	}
+}
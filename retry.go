@@ -0,0 +1,75 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultStartupRetryTimeout is the total time allowed for establishing the
+	// NATS connection and verifying OpenFGA connectivity at startup.
+	defaultStartupRetryTimeout = 60 * time.Second
+	// defaultStartupRetryBackoff is the delay before the first retry; each
+	// subsequent retry doubles the delay up to maxStartupRetryBackoff.
+	defaultStartupRetryBackoff = time.Second
+	maxStartupRetryBackoff     = 10 * time.Second
+)
+
+// retryConfig controls the connect-retry loop used during startup.
+type retryConfig struct {
+	// timeout is the overall deadline for all attempts. Zero disables retries
+	// (a single attempt is made).
+	timeout time.Duration
+	// initialBackoff is the delay after the first failed attempt.
+	initialBackoff time.Duration
+	// maxBackoff caps the exponential backoff between attempts.
+	maxBackoff time.Duration
+}
+
+// retryWithBackoff calls fn until it succeeds, the configured timeout
+// elapses, or ctx is canceled. Each failed attempt is logged. The last error
+// from fn is returned when attempts are exhausted.
+func retryWithBackoff(ctx context.Context, operation string, cfg retryConfig, fn func(context.Context) error) error {
+	if cfg.timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	backoff := cfg.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.With("operation", operation, "attempt", attempt).InfoContext(ctx, "startup connection succeeded after retry")
+			}
+			return nil
+		}
+
+		logger.With(
+			errKey, err,
+			"operation", operation,
+			"attempt", attempt,
+			"backoff", backoff.String(),
+		).WarnContext(ctx, "startup connection attempt failed")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: giving up after %d attempts: %w", operation, attempt, err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if cfg.maxBackoff > 0 && backoff > cfg.maxBackoff {
+			backoff = cfg.maxBackoff
+		}
+	}
+}
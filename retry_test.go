@@ -0,0 +1,102 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestRetryWithBackoff tests the [retryWithBackoff] function.
+func TestRetryWithBackoff(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           retryConfig
+		failures      int
+		expectedCalls int
+		expectError   bool
+	}{
+		{
+			name:          "succeeds on first attempt",
+			cfg:           retryConfig{timeout: time.Second, initialBackoff: time.Millisecond, maxBackoff: 5 * time.Millisecond},
+			failures:      0,
+			expectedCalls: 1,
+			expectError:   false,
+		},
+		{
+			name:          "initial failures followed by success",
+			cfg:           retryConfig{timeout: time.Second, initialBackoff: time.Millisecond, maxBackoff: 5 * time.Millisecond},
+			failures:      3,
+			expectedCalls: 4,
+			expectError:   false,
+		},
+		{
+			name:          "retries disabled makes a single attempt",
+			cfg:           retryConfig{timeout: 0, initialBackoff: time.Millisecond},
+			failures:      1,
+			expectedCalls: 1,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryWithBackoff(context.Background(), "test", tt.cfg, func(_ context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("connection refused")
+				}
+				return nil
+			})
+
+			assert.Equal(t, tt.expectedCalls, calls)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestRetryWithBackoff_Timeout tests that retries stop once the timeout elapses.
+func TestRetryWithBackoff_Timeout(t *testing.T) {
+	cfg := retryConfig{timeout: 20 * time.Millisecond, initialBackoff: 5 * time.Millisecond, maxBackoff: 5 * time.Millisecond}
+	errDown := errors.New("connection refused")
+
+	start := time.Now()
+	err := retryWithBackoff(context.Background(), "test", cfg, func(_ context.Context) error {
+		return errDown
+	})
+
+	assert.ErrorIs(t, err, errDown)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestRetryWithBackoff_OpenFGA simulates OpenFGA being unavailable for the
+// first attempts while the authorization model is resolved at startup.
+func TestRetryWithBackoff_OpenFGA(t *testing.T) {
+	mockClient := new(MockFgaClient)
+	mockClient.On("ReadAuthorizationModel", mock.Anything).
+		Return((*ClientReadAuthorizationModelResponse)(nil), errors.New("dial tcp: connection refused")).Twice()
+	mockClient.On("ReadAuthorizationModel", mock.Anything).
+		Return(&ClientReadAuthorizationModelResponse{}, nil).Once()
+
+	cfg := retryConfig{timeout: time.Second, initialBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+	err := retryWithBackoff(context.Background(), "openfga", cfg, func(ctx context.Context) error {
+		_, errModel := mockClient.ReadAuthorizationModel(ctx)
+		return errModel
+	})
+
+	assert.NoError(t, err)
+	mockClient.AssertNumberOfCalls(t, "ReadAuthorizationModel", 3)
+}
@@ -0,0 +1,82 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// sharedTupleReadTimeout bounds a tuple read shared through a tupleReadGroup,
+// which runs detached from the context of the caller that started it.
+const sharedTupleReadTimeout = 30 * time.Second
+
+// tupleReadCall is an in-flight or completed tuple read shared by every
+// caller that asked for the same key while it was running.
+type tupleReadCall struct {
+	done   chan struct{}
+	tuples []openfga.Tuple
+	err    error
+	// waiters counts the callers waiting for the read, guarded by the
+	// group's mutex.
+	waiters int
+}
+
+// tupleReadGroup deduplicates concurrent identical tuple reads: while a read
+// for a key is in flight, further callers for that key wait for it and share
+// its result instead of starting their own.
+type tupleReadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*tupleReadCall
+}
+
+// newTupleReadGroup returns an empty read group.
+func newTupleReadGroup() *tupleReadGroup {
+	return &tupleReadGroup{calls: make(map[string]*tupleReadCall)}
+}
+
+// do runs read for key unless a read for key is already in flight, in which
+// case it waits for that read. Each caller receives its own copy of the
+// tuples so the shared result can't be mutated through another caller.
+//
+// read is passed a context detached from ctx, bounded by
+// sharedTupleReadTimeout, so that the first caller ending does not fail the
+// read for the others. A waiting caller whose ctx ends stops waiting and
+// returns its context's error.
+func (g *tupleReadGroup) do(
+	ctx context.Context,
+	key string,
+	read func(context.Context) ([]openfga.Tuple, error),
+) ([]openfga.Tuple, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return slices.Clone(call.tuples), call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &tupleReadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	readCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedTupleReadTimeout)
+	defer cancel()
+	call.tuples, call.err = read(readCtx)
+	return slices.Clone(call.tuples), call.err
+}
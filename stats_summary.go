@@ -0,0 +1,127 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// processingSummary, when set, periodically logs a summary of processing
+// since the previous one. It is nil when no summary interval is configured.
+var processingSummary *statsSummary
+
+// statsSummary counts the messages handled and tuples changed, and logs the
+// counts every interval before resetting them, for deployments that read
+// logs but do not scrape metrics.
+type statsSummary struct {
+	interval time.Duration
+	now      func() time.Time
+
+	// messages holds an *atomic.Int64 per subject.
+	messages sync.Map
+	errors   atomic.Int64
+	writes   atomic.Int64
+	deletes  atomic.Int64
+
+	mu   sync.Mutex
+	last time.Time
+	// cacheHits and cacheMisses are the cache counters at the last summary;
+	// the cache keeps its own totals, which are not reset.
+	cacheHits, cacheMisses int64
+}
+
+// newStatsSummary returns a summary whose first interval starts now.
+func newStatsSummary(interval time.Duration, now func() time.Time) *statsSummary {
+	s := &statsSummary{interval: interval, now: now, last: now()}
+	s.cacheHits, s.cacheMisses = cacheCounts()
+	return s
+}
+
+// cacheCounts returns the access check cache hits and misses so far. Stale
+// hits are rechecked in OpenFGA, so they count as misses.
+func cacheCounts() (hits, misses int64) {
+	return cacheHits.Value(), cacheMisses.Value() + cacheStaleHits.Value()
+}
+
+// recordMessage counts a message handled on subject, and whether its handler
+// failed.
+func (s *statsSummary) recordMessage(subject string, err error) {
+	if s == nil {
+		return
+	}
+	counter, _ := s.messages.LoadOrStore(subject, new(atomic.Int64))
+	counter.(*atomic.Int64).Add(1)
+	if err != nil {
+		s.errors.Add(1)
+	}
+}
+
+// recordChanges counts tuples written and deleted in OpenFGA.
+func (s *statsSummary) recordChanges(writes, deletes int) {
+	if s == nil {
+		return
+	}
+	s.writes.Add(int64(writes))
+	s.deletes.Add(int64(deletes))
+}
+
+// check logs the summary and resets the counts once the interval has elapsed
+// since the previous summary.
+func (s *statsSummary) check(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.last) < s.interval {
+		return
+	}
+	elapsed := now.Sub(s.last)
+	s.last = now
+
+	messages := make(map[string]int64)
+	var total int64
+	s.messages.Range(func(subject, counter any) bool {
+		if n := counter.(*atomic.Int64).Swap(0); n > 0 {
+			messages[subject.(string)] = n
+			total += n
+		}
+		return true
+	})
+	hits, misses := cacheCounts()
+	intervalHits, intervalMisses := hits-s.cacheHits, misses-s.cacheMisses
+	s.cacheHits, s.cacheMisses = hits, misses
+	hitRate := 0.0
+	if lookups := intervalHits + intervalMisses; lookups > 0 {
+		hitRate = float64(intervalHits) / float64(lookups)
+	}
+
+	logger.InfoContext(ctx, "processing summary",
+		"interval", elapsed.String(),
+		"messages", total,
+		"messages_by_subject", messages,
+		"errors", s.errors.Swap(0),
+		"writes", s.writes.Swap(0),
+		"deletes", s.deletes.Swap(0),
+		"cache_hits", intervalHits,
+		"cache_misses", intervalMisses,
+		"cache_hit_rate", hitRate,
+	)
+}
+
+// run logs the summary periodically until ctx is done.
+func (s *statsSummary) run(ctx context.Context) {
+	ticker := time.NewTicker(max(s.interval/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
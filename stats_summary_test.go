@@ -0,0 +1,107 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/linuxfoundation/lfx-v2-fga-sync/pkg/constants"
+)
+
+// summaryEntry is the logged processing summary.
+type summaryEntry struct {
+	Messages          int64            `json:"messages"`
+	MessagesBySubject map[string]int64 `json:"messages_by_subject"`
+	Errors            int64            `json:"errors"`
+	Writes            int64            `json:"writes"`
+	Deletes           int64            `json:"deletes"`
+	CacheHits         int64            `json:"cache_hits"`
+	CacheMisses       int64            `json:"cache_misses"`
+	CacheHitRate      float64          `json:"cache_hit_rate"`
+}
+
+func TestStatsSummary(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	origSummary, origLogger := processingSummary, logger
+	t.Cleanup(func() { processingSummary, logger = origSummary, origLogger })
+	var buf bytes.Buffer
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	processingSummary = newStatsSummary(time.Minute, clock.now)
+	summaries := func() []summaryEntry {
+		var entries []summaryEntry
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var record struct {
+				Msg string `json:"msg"`
+			}
+			if !assert.NoError(t, json.Unmarshal(line, &record)) || record.Msg != "processing summary" {
+				continue
+			}
+			var entry summaryEntry
+			assert.NoError(t, json.Unmarshal(line, &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	ctx := context.Background()
+
+	dispatch := func(subject string, err error) {
+		dispatchMessage(ctx, subject, "test", constants.FgaSyncQueue,
+			func(context.Context, INatsMsg) error { return err }, CreateMockNatsMsg([]byte("{}")))
+	}
+	dispatch(constants.GenericUpdateAccessSubject, nil)
+	dispatch(constants.GenericUpdateAccessSubject, errors.New("store unavailable"))
+	dispatch(constants.GenericMemberPutSubject, nil)
+
+	fgaClient := new(MockFgaClient)
+	fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+	service := FgaService{client: fgaClient, cacheBucket: NewMockKeyValue()}
+	assert.NoError(t, service.WriteAndDeleteTuples(ctx,
+		[]client.ClientTupleKey{
+			{User: "user:alice", Relation: "writer", Object: "project:p1"},
+			{User: "user:bob", Relation: "writer", Object: "project:p1"},
+		},
+		[]client.ClientTupleKeyWithoutCondition{{User: "user:mallory", Relation: "writer", Object: "project:p1"}},
+	))
+
+	cacheHits.Add(3)
+	cacheMisses.Add(1)
+
+	// Nothing is logged before the interval elapses.
+	clock.advance(59 * time.Second)
+	processingSummary.check(ctx)
+	assert.Empty(t, summaries())
+
+	clock.advance(time.Second)
+	processingSummary.check(ctx)
+	assert.Equal(t, []summaryEntry{{
+		Messages: 3,
+		MessagesBySubject: map[string]int64{
+			constants.GenericUpdateAccessSubject: 2,
+			constants.GenericMemberPutSubject:    1,
+		},
+		Errors:       1,
+		Writes:       2,
+		Deletes:      1,
+		CacheHits:    3,
+		CacheMisses:  1,
+		CacheHitRate: 0.75,
+	}}, summaries())
+
+	// The counts are reset for the next interval.
+	clock.advance(time.Minute)
+	processingSummary.check(ctx)
+	entries := summaries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, summaryEntry{MessagesBySubject: map[string]int64{}}, entries[1])
+}
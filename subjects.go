@@ -0,0 +1,46 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// defaultSubjectPrefix is the namespace of the subject constants, used
+// unless SUBJECT_PREFIX moves the service under another one.
+const defaultSubjectPrefix = "lfx"
+
+// subjectSet derives the NATS subjects the service uses from the configured
+// prefix, so that several deployments can share one NATS cluster.
+type subjectSet struct {
+	prefix string
+}
+
+// newSubjectSet returns the subjects under prefix, or under
+// defaultSubjectPrefix when prefix is empty.
+func newSubjectSet(prefix string) subjectSet {
+	if prefix == "" {
+		prefix = defaultSubjectPrefix
+	}
+	return subjectSet{prefix: prefix}
+}
+
+// of returns subject, one of the lfx-prefixed subject constants, under the
+// configured prefix.
+func (s subjectSet) of(subject string) string {
+	return s.prefix + strings.TrimPrefix(subject, defaultSubjectPrefix)
+}
+
+// validateSubjectPrefix checks that prefix is a literal NATS subject of one
+// or more tokens.
+func validateSubjectPrefix(prefix string) error {
+	for _, token := range strings.Split(prefix, ".") {
+		if token == "" || strings.ContainsAny(token, "*> \t") {
+			return errors.New("must be dot-separated tokens without wildcards or whitespace")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestCreateQueueSubscriptions_SubjectPrefix asserts that every subscription,
+// including the FGA sync wildcard and its dispatch table, uses the
+// configured subject prefix.
+func TestCreateQueueSubscriptions_SubjectPrefix(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		expectQueue  string
+		expectSubs   []string
+		expectRouted string
+		// expectBroadcast is subscribed to outside the queue group.
+		expectBroadcast string
+	}{
+		{
+			name:        "default prefix",
+			expectQueue: "lfx.fga-sync.queue",
+			expectSubs: []string{
+				"lfx.access_check.request",
+				"lfx.access_check.read_tuples",
+				"lfx.access_check.list_objects",
+				"lfx.fga-sync.maintenance",
+				"lfx.fga-sync.>",
+			},
+			expectRouted:    "lfx.fga-sync.update_access",
+			expectBroadcast: "lfx.fga-sync.maintenance",
+		},
+		{
+			name:        "custom prefix",
+			prefix:      "staging.lfx",
+			expectQueue: "staging.lfx.fga-sync.queue",
+			expectSubs: []string{
+				"staging.lfx.access_check.request",
+				"staging.lfx.access_check.read_tuples",
+				"staging.lfx.access_check.list_objects",
+				"staging.lfx.fga-sync.maintenance",
+				"staging.lfx.fga-sync.>",
+			},
+			expectRouted:    "staging.lfx.fga-sync.update_access",
+			expectBroadcast: "staging.lfx.fga-sync.maintenance",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subscribed []string
+			processes := make(map[string]func(context.Context, INatsMsg))
+			original := queueSubscribe
+			queueSubscribe = func(subject, queue string, process func(context.Context, INatsMsg)) error {
+				if subject == tt.expectBroadcast {
+					assert.Empty(t, queue)
+				} else {
+					assert.Equal(t, tt.expectQueue, queue)
+				}
+				subscribed = append(subscribed, subject)
+				processes[subject] = process
+				return nil
+			}
+			t.Cleanup(func() { queueSubscribe = original })
+
+			service := setupService()
+			service.config.SubjectPrefix = tt.prefix
+			assert.NoError(t, createQueueSubscriptions(*service))
+			assert.Equal(t, tt.expectSubs, subscribed)
+
+			// A message on a prefixed sync subject reaches its handler rather
+			// than being counted as unhandled.
+			unhandledMessages.Init()
+			wildcard := tt.expectSubs[len(tt.expectSubs)-1]
+			msg := CreateMockNatsMsg([]byte(`{}`))
+			msg.subject = tt.expectRouted
+			processes[wildcard](context.Background(), msg)
+			assert.Nil(t, unhandledMessages.Get(tt.expectRouted))
+
+			// The broadcast subject also reaches the wildcard subscription of
+			// the queue group, which leaves it to the broadcast subscription.
+			msg = CreateMockNatsMsg([]byte(`{}`))
+			msg.subject = tt.expectBroadcast
+			processes[wildcard](context.Background(), msg)
+			assert.Nil(t, unhandledMessages.Get(tt.expectBroadcast))
+			msg.AssertNotCalled(t, "Respond", mock.Anything)
+		})
+	}
+}
+
+func TestValidateSubjectPrefix(t *testing.T) {
+	for _, prefix := range []string{"lfx", "staging.lfx", "tenant-a"} {
+		assert.NoError(t, validateSubjectPrefix(prefix), prefix)
+	}
+	for _, prefix := range []string{"", ".lfx", "lfx.", "lfx..staging", "lfx.*", "lfx.>", "lf x"} {
+		err := validateSubjectPrefix(prefix)
+		assert.Error(t, err, prefix)
+		if err != nil {
+			assert.True(t, strings.HasPrefix(err.Error(), "must be"), err.Error())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// maxObjectTupleCacheEntries bounds the objects whose tuples are cached at
+// once. Once full, expired entries are dropped, and reads of further objects
+// are not cached until there is room.
+const maxObjectTupleCacheEntries = 10000
+
+// objectTupleEntry is the cached tuple set of one object.
+type objectTupleEntry struct {
+	tuples  []openfga.Tuple
+	fetched time.Time
+}
+
+// objectTupleCache briefly caches the full tuple set of recently read
+// objects, so hot objects aren't paginated through on every fingerprint or
+// read of them. Entries are dropped when this process writes to their object.
+// Writes made by other replicas are not seen until the entry expires, so the
+// TTL should be kept short, and reads deciding what to write bypass it.
+type objectTupleCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]objectTupleEntry
+	// generation counts invalidations, so a read that overlapped a write is
+	// not cached with the tuples from before it.
+	generation uint64
+}
+
+// newObjectTupleCache returns an empty cache whose entries expire after ttl.
+func newObjectTupleCache(ttl time.Duration, now func() time.Time) *objectTupleCache {
+	return &objectTupleCache{ttl: ttl, now: now, entries: make(map[string]objectTupleEntry)}
+}
+
+// get returns a copy of the cached tuples of object, if fresh, and otherwise
+// the generation to pass to put once the tuples have been read.
+func (c *objectTupleCache) get(object string) ([]openfga.Tuple, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[object]
+	if !ok || c.now().Sub(entry.fetched) >= c.ttl {
+		return nil, c.generation, false
+	}
+	return slices.Clone(entry.tuples), c.generation, true
+}
+
+// put caches the tuples of object read since generation was returned by get,
+// unless an invalidation has happened in the meantime.
+func (c *objectTupleCache) put(object string, tuples []openfga.Tuple, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	now := c.now()
+	if _, ok := c.entries[object]; !ok && len(c.entries) >= maxObjectTupleCacheEntries {
+		for key, entry := range c.entries {
+			if now.Sub(entry.fetched) >= c.ttl {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxObjectTupleCacheEntries {
+			return
+		}
+	}
+	c.entries[object] = objectTupleEntry{tuples: slices.Clone(tuples), fetched: now}
+}
+
+// invalidate drops the cached tuples of objects.
+func (c *objectTupleCache) invalidate(objects ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, object := range objects {
+		delete(c.entries, object)
+	}
+}
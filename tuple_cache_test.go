@@ -0,0 +1,114 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+	. "github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestReadObjectTuples_Cache asserts that a cached read skips OpenFGA until
+// it expires or a write to the object invalidates it.
+func TestReadObjectTuples_Cache(t *testing.T) {
+	const object = "project:hot"
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client := new(MockFgaClient)
+	client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{
+		Tuples: []openfga.Tuple{{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: object}}},
+	}, nil)
+	client.On("Write", mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil)
+	service := FgaService{
+		client:       client,
+		cacheBucket:  NewMockKeyValue(),
+		objectTuples: newObjectTupleCache(time.Minute, clock.now),
+	}
+	ctx := context.Background()
+
+	read := func() {
+		t.Helper()
+		tuples, err := service.ReadObjectTuples(ctx, object)
+		assert.NoError(t, err)
+		assert.Len(t, tuples, 1)
+	}
+
+	read()
+	read()
+	client.AssertNumberOfCalls(t, "Read", 1)
+
+	// A write to another object leaves the entry in place.
+	assert.NoError(t, service.WriteAndDeleteTuples(ctx,
+		[]ClientTupleKey{{User: "user:bob", Relation: "viewer", Object: "project:other"}}, nil))
+	read()
+	client.AssertNumberOfCalls(t, "Read", 1)
+
+	// A write to the object invalidates it.
+	assert.NoError(t, service.WriteAndDeleteTuples(ctx, nil,
+		[]ClientTupleKeyWithoutCondition{{User: "user:alice", Relation: "viewer", Object: object}}))
+	read()
+	client.AssertNumberOfCalls(t, "Read", 2)
+
+	// Entries expire after the TTL.
+	clock.advance(time.Minute)
+	read()
+	client.AssertNumberOfCalls(t, "Read", 3)
+}
+
+// TestSyncObjectTuples_BypassesCache asserts that a sync diffs against
+// OpenFGA's current tuples, not an entry cached before another replica's
+// write.
+func TestSyncObjectTuples_BypassesCache(t *testing.T) {
+	const object = "project:hot"
+	client := new(MockFgaClient)
+	client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{
+		Tuples: []openfga.Tuple{{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: object}}},
+	}, nil).Once()
+	// Another replica has since deleted alice's tuple.
+	client.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&ClientReadResponse{}, nil)
+	client.On("Write", mock.Anything, mock.Anything).Return(&ClientWriteResponse{}, nil)
+	service := FgaService{
+		client:       client,
+		cacheBucket:  NewMockKeyValue(),
+		objectTuples: newObjectTupleCache(time.Minute, time.Now),
+	}
+	ctx := context.Background()
+
+	_, err := service.ReadObjectTuples(ctx, object)
+	assert.NoError(t, err)
+
+	desired := []ClientTupleKey{{User: "user:alice", Relation: "viewer", Object: object}}
+	writes, deletes, _, err := service.SyncObjectTuples(ctx, object, desired)
+	assert.NoError(t, err)
+	assert.Equal(t, desired, writes)
+	assert.Empty(t, deletes)
+	client.AssertNumberOfCalls(t, "Read", 2)
+}
+
+// TestObjectTupleCache_StaleRead asserts that a read overlapping a write is
+// not cached, and that callers cannot mutate cached tuples.
+func TestObjectTupleCache_StaleRead(t *testing.T) {
+	const object = "project:hot"
+	cache := newObjectTupleCache(time.Minute, time.Now)
+	tuples := []openfga.Tuple{{Key: openfga.TupleKey{User: "user:alice", Relation: "viewer", Object: object}}}
+
+	_, generation, ok := cache.get(object)
+	assert.False(t, ok)
+	cache.invalidate(object)
+	cache.put(object, tuples, generation)
+	_, _, ok = cache.get(object)
+	assert.False(t, ok, "a read started before the write must not be cached")
+
+	_, generation, _ = cache.get(object)
+	cache.put(object, tuples, generation)
+	cached, _, ok := cache.get(object)
+	assert.True(t, ok)
+	cached[0].Key.User = "user:mallory"
+	cached, _, _ = cache.get(object)
+	assert.Equal(t, "user:alice", cached[0].Key.User)
+}
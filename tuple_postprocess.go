@@ -0,0 +1,29 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	. "github.com/openfga/go-sdk/client"
+)
+
+// tuplePostProcessor adjusts the desired tuples built for an object of one
+// type before they are synced, for rules that the generic update_access build
+// cannot express. It returns the tuples to sync, and may add to, remove from
+// or reorder the slice it is given.
+type tuplePostProcessor func(object string, tuples []ClientTupleKey) []ClientTupleKey
+
+// tuplePostProcessors are the post-processors run by default, keyed by object
+// type. Object types without an entry are synced exactly as built.
+var tuplePostProcessors = map[string]tuplePostProcessor{}
+
+// withMandatoryRelation returns a post-processor that always grants relation
+// on the object to user, e.g. a system account that must keep access however
+// the producer's payload changes. Duplicates of a tuple the payload already
+// produced are harmless: they collapse into one desired tuple in the sync.
+func withMandatoryRelation(relation, user string) tuplePostProcessor {
+	return func(object string, tuples []ClientTupleKey) []ClientTupleKey {
+		return append(tuples, ClientTupleKey{User: user, Relation: relation, Object: object})
+	}
+}
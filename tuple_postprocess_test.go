@@ -0,0 +1,54 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProcessStandardAccessUpdate_TuplePostProcessor(t *testing.T) {
+	service := setupService()
+	service.tuplePostProcessors = map[string]tuplePostProcessor{
+		"committee": withMandatoryRelation("auditor", "user:lfx-system"),
+	}
+	fgaClient := service.fgaService.client.(*MockFgaClient)
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).Return(&client.ClientReadResponse{}, nil)
+	var written []string
+	fgaClient.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		for _, tuple := range args.Get(1).(client.ClientWriteRequest).Writes {
+			written = append(written, tuple.Relation+"@"+tuple.User)
+		}
+	}).Return(&client.ClientWriteResponse{}, nil).Once()
+
+	msg := CreateMockNatsMsg([]byte(`{}`))
+	err := service.processStandardAccessUpdate(context.Background(), msg, &standardAccessStub{
+		UID:        "c1",
+		ObjectType: "committee",
+		Relations:  map[string][]string{"writer": {"alice"}},
+	})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"writer@user:alice", "auditor@user:lfx-system"}, written)
+}
+
+func TestStandardAccessTuples_PostProcessorScopedToType(t *testing.T) {
+	service := setupService()
+	service.tuplePostProcessors = map[string]tuplePostProcessor{
+		"committee": withMandatoryRelation("auditor", "user:lfx-system"),
+	}
+
+	_, tuples, err := service.standardAccessTuples(context.Background(), &standardAccessStub{
+		UID:        "p1",
+		ObjectType: "project",
+		Relations:  map[string][]string{"writer": {"alice"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, tuples, 1, "post-processors for other object types must not run")
+}
@@ -0,0 +1,136 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// workWatchdog, when set, alerts if no messages are processed within its
+// window. It is nil when no watchdog window is configured.
+var workWatchdog *watchdog
+
+// watchdog is a dead-man's switch over message processing: if no message of
+// any subject is processed within window during active hours, it logs an
+// error, reports unhealthy, and optionally publishes an alert.
+type watchdog struct {
+	window time.Duration
+	// activeFrom and activeTo bound the UTC hours [from, to) during which
+	// inactivity is alerted on. Equal values mean all hours.
+	activeFrom, activeTo int
+	now                  func() time.Time
+	// alert, when set, is called once each time the watchdog trips.
+	alert func(ctx context.Context, idle time.Duration)
+
+	mu           sync.Mutex
+	lastActivity time.Time
+	tripped      bool
+}
+
+// newWatchdog returns a watchdog that starts its window now.
+func newWatchdog(window time.Duration, activeFrom, activeTo int, now func() time.Time) *watchdog {
+	return &watchdog{
+		window:       window,
+		activeFrom:   activeFrom,
+		activeTo:     activeTo,
+		now:          now,
+		lastActivity: now(),
+	}
+}
+
+// record notes that a message was processed.
+func (w *watchdog) record() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastActivity = w.now()
+	if w.tripped {
+		w.tripped = false
+		logger.Info("message processing resumed")
+	}
+}
+
+// healthy reports whether messages have been processed within the window.
+func (w *watchdog) healthy() bool {
+	if w == nil {
+		return true
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.tripped
+}
+
+// active reports whether t falls in the watchdog's active hours.
+func (w *watchdog) active(t time.Time) bool {
+	if w.activeFrom == w.activeTo {
+		return true
+	}
+	hour := t.UTC().Hour()
+	if w.activeFrom < w.activeTo {
+		return hour >= w.activeFrom && hour < w.activeTo
+	}
+	// The active range wraps around midnight.
+	return hour >= w.activeFrom || hour < w.activeTo
+}
+
+// check trips the watchdog if the window has elapsed without activity during
+// active hours.
+func (w *watchdog) check(ctx context.Context) {
+	w.mu.Lock()
+	now := w.now()
+	idle := now.Sub(w.lastActivity)
+	if w.tripped || idle < w.window || !w.active(now) {
+		w.mu.Unlock()
+		return
+	}
+	w.tripped = true
+	w.mu.Unlock()
+
+	logger.ErrorContext(ctx, "no messages processed within watchdog window",
+		"window", w.window.String(),
+		"idle", idle.String(),
+	)
+	if w.alert != nil {
+		w.alert(ctx, idle)
+	}
+}
+
+// run checks the watchdog periodically until ctx is done.
+func (w *watchdog) run(ctx context.Context) {
+	ticker := time.NewTicker(max(w.window/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+// publishWatchdogAlert returns an alert function publishing a JSON event to
+// subject on the NATS connection.
+func publishWatchdogAlert(subject string) func(context.Context, time.Duration) {
+	return func(ctx context.Context, idle time.Duration) {
+		event, err := json.Marshal(map[string]any{
+			"service": "fga-sync",
+			"alert":   "no_messages_processed",
+			"idle_ms": idle.Milliseconds(),
+		})
+		if err != nil {
+			logger.With(errKey, err).ErrorContext(ctx, "failed to marshal watchdog alert")
+			return
+		}
+		if err = natsConn.Publish(subject, event); err != nil {
+			logger.With(errKey, err, "subject", subject).ErrorContext(ctx, "failed to publish watchdog alert")
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for watchdog tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestWatchdog_TripsAfterWindowWithoutActivity(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	w := newWatchdog(10*time.Minute, 0, 0, clock.now)
+	alerts := 0
+	w.alert = func(context.Context, time.Duration) { alerts++ }
+	ctx := context.Background()
+
+	clock.advance(9 * time.Minute)
+	w.check(ctx)
+	assert.True(t, w.healthy(), "should stay healthy inside the window")
+
+	clock.advance(2 * time.Minute)
+	w.check(ctx)
+	assert.False(t, w.healthy(), "should trip once the window elapses without activity")
+	assert.Equal(t, 1, alerts)
+
+	clock.advance(10 * time.Minute)
+	w.check(ctx)
+	assert.Equal(t, 1, alerts, "should alert once per trip")
+
+	w.record()
+	assert.True(t, w.healthy(), "activity should clear the trip")
+}
+
+func TestWatchdog_ActivityResetsWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	w := newWatchdog(10*time.Minute, 0, 0, clock.now)
+	ctx := context.Background()
+
+	clock.advance(8 * time.Minute)
+	w.record()
+	clock.advance(8 * time.Minute)
+	w.check(ctx)
+
+	assert.True(t, w.healthy())
+}
+
+func TestWatchdog_ActiveHours(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, to    int
+		hour        int
+		wantTripped bool
+	}{
+		{name: "inside hours", from: 8, to: 20, hour: 12, wantTripped: true},
+		{name: "outside hours", from: 8, to: 20, hour: 22, wantTripped: false},
+		{name: "wrapping range inside", from: 22, to: 6, hour: 2, wantTripped: true},
+		{name: "wrapping range outside", from: 22, to: 6, hour: 12, wantTripped: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Date(2024, 1, 1, tt.hour, 0, 0, 0, time.UTC).Add(-time.Hour)}
+			w := newWatchdog(30*time.Minute, tt.from, tt.to, clock.now)
+
+			clock.advance(time.Hour)
+			w.check(context.Background())
+
+			assert.Equal(t, tt.wantTripped, !w.healthy())
+		})
+	}
+}
+
+func TestWatchdog_DisabledIsHealthy(t *testing.T) {
+	var w *watchdog
+	w.record()
+	assert.True(t, w.healthy(), "a disabled watchdog is always healthy")
+}
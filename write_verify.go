@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package main provides the fga-sync service entry point and supporting types.
+package main
+
+import (
+	"context"
+	"expvar"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+	. "github.com/openfga/go-sdk/client"
+)
+
+// writeVerificationFailures counts synced objects whose read-back did not
+// show the sync's changes, keyed by object type.
+var writeVerificationFailures = expvar.NewMap("fga_sync_write_verification_failures_total")
+
+// verifyObjectTuples re-reads object after a sync wrote writes and deleted
+// deletes, if its type is listed in verifyWriteTypes, and reports any write
+// missing or delete still present. The read bypasses the object tuple cache
+// and asks for higher consistency, so it sees the write rather than a stale
+// replica. Discrepancies are logged and counted, not returned: the write
+// itself succeeded, and the read-back is there to surface silent failures.
+func (s FgaService) verifyObjectTuples(
+	ctx context.Context,
+	object string,
+	writes []ClientTupleKey,
+	deletes []ClientTupleKeyWithoutCondition,
+) {
+	objectType, _, _ := strings.Cut(object, ":")
+	if !s.verifyWriteTypes[objectType] {
+		return
+	}
+
+	options := s.readOptions()
+	consistency := openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY
+	options.Consistency = &consistency
+	tuples, err := s.readObjectTuples(ctx, object, "", options)
+	if err != nil {
+		logger.With(errKey, err, "object", object).WarnContext(ctx, "failed to read back synced tuples")
+		return
+	}
+
+	present := make(map[string]bool, len(tuples))
+	for _, tuple := range tuples {
+		present[tuple.Key.Relation+"@"+tuple.Key.User] = true
+	}
+	var missing, remaining []string
+	for _, tuple := range writes {
+		if !present[tuple.Relation+"@"+tuple.User] {
+			missing = append(missing, tuple.Object+"#"+tuple.Relation+"@"+tuple.User)
+		}
+	}
+	for _, tuple := range deletes {
+		if present[tuple.Relation+"@"+tuple.User] {
+			remaining = append(remaining, tuple.Object+"#"+tuple.Relation+"@"+tuple.User)
+		}
+	}
+	if len(missing) == 0 && len(remaining) == 0 {
+		return
+	}
+	writeVerificationFailures.Add(objectType, 1)
+	logger.With(
+		"object", object,
+		"missing_writes", missing,
+		"remaining_deletes", remaining,
+	).ErrorContext(ctx, "synced tuples not found on read-back")
+}
@@ -0,0 +1,143 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"expvar"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestSyncObjectTuples_VerifyWrites asserts that syncs of listed object types
+// are read back with higher consistency, and that a write missing from the
+// read-back, or a delete still present, is counted.
+func TestSyncObjectTuples_VerifyWrites(t *testing.T) {
+	desired := func(object string) []client.ClientTupleKey {
+		return []client.ClientTupleKey{
+			{User: "user:alice", Relation: "writer", Object: object},
+			{User: "user:bob", Relation: "auditor", Object: object},
+		}
+	}
+	tests := []struct {
+		name       string
+		objectType string
+		verify     bool
+		readBack   []openfga.Tuple
+		expectFail bool
+	}{
+		{
+			name:       "all changes applied",
+			objectType: "verify_ok",
+			verify:     true,
+			readBack: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "verify_ok:1"}},
+				{Key: openfga.TupleKey{User: "user:bob", Relation: "auditor", Object: "verify_ok:1"}},
+			},
+		},
+		{
+			name:       "missing write",
+			objectType: "verify_missing",
+			verify:     true,
+			readBack: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "verify_missing:1"}},
+			},
+			expectFail: true,
+		},
+		{
+			name:       "delete not applied",
+			objectType: "verify_remaining",
+			verify:     true,
+			readBack: []openfga.Tuple{
+				{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "verify_remaining:1"}},
+				{Key: openfga.TupleKey{User: "user:bob", Relation: "auditor", Object: "verify_remaining:1"}},
+				{Key: openfga.TupleKey{User: "user:old", Relation: "writer", Object: "verify_remaining:1"}},
+			},
+			expectFail: true,
+		},
+		{
+			name:       "type not listed",
+			objectType: "verify_off",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := tt.objectType + ":1"
+			fgaClient := new(MockFgaClient)
+			isReadBack := func(options client.ClientReadOptions) bool {
+				return options.Consistency != nil &&
+					*options.Consistency == openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY
+			}
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.MatchedBy(isReadBack)).
+				Return(&client.ClientReadResponse{Tuples: tt.readBack}, nil)
+			fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+					{Key: openfga.TupleKey{User: "user:old", Relation: "writer", Object: object}},
+				}}, nil)
+			fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil)
+			service := FgaService{client: fgaClient, cacheBucket: NewMockKeyValue()}
+			if tt.verify {
+				service.verifyWriteTypes = map[string]bool{tt.objectType: true}
+			}
+
+			_, _, _, err := service.SyncObjectTuples(context.Background(), object, desired(object))
+
+			assert.NoError(t, err, "a failed verification does not fail the sync")
+			expectReads := 1
+			if tt.verify {
+				expectReads = 2
+			}
+			fgaClient.AssertNumberOfCalls(t, "Read", expectReads)
+			assert.Equal(t, tt.expectFail, writeVerificationFailures.Get(tt.objectType) != nil)
+		})
+	}
+}
+
+// TestSyncObjectsTuples_VerifyWrites asserts that a sync of several objects
+// reads back each changed object of a listed type, and counts a write missing
+// from one of them.
+func TestSyncObjectsTuples_VerifyWrites(t *testing.T) {
+	fgaClient := new(MockFgaClient)
+	isReadBack := func(options client.ClientReadOptions) bool {
+		return options.Consistency != nil &&
+			*options.Consistency == openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY
+	}
+	readBackOf := func(object string) interface{} {
+		return mock.MatchedBy(func(body client.ClientReadRequest) bool { return *body.Object == object })
+	}
+	fgaClient.On("Read", mock.Anything, readBackOf("verify_multi:1"), mock.MatchedBy(isReadBack)).
+		Return(&client.ClientReadResponse{Tuples: []openfga.Tuple{
+			{Key: openfga.TupleKey{User: "user:alice", Relation: "writer", Object: "verify_multi:1"}},
+		}}, nil).Once()
+	fgaClient.On("Read", mock.Anything, readBackOf("verify_multi:2"), mock.MatchedBy(isReadBack)).
+		Return(&client.ClientReadResponse{}, nil).Once()
+	fgaClient.On("Read", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.ClientReadResponse{}, nil).Twice()
+	fgaClient.On("Write", mock.Anything, mock.Anything).Return(&client.ClientWriteResponse{}, nil).Once()
+	service := FgaService{
+		client:           fgaClient,
+		cacheBucket:      NewMockKeyValue(),
+		verifyWriteTypes: map[string]bool{"verify_multi": true},
+	}
+
+	_, _, err := service.SyncObjectsTuples(context.Background(), []objectSync{
+		{object: "verify_multi:1", relations: []client.ClientTupleKey{
+			{User: "user:alice", Relation: "writer", Object: "verify_multi:1"},
+		}},
+		{object: "verify_multi:2", relations: []client.ClientTupleKey{
+			{User: "user:bob", Relation: "writer", Object: "verify_multi:2"},
+		}},
+	})
+
+	assert.NoError(t, err)
+	fgaClient.AssertExpectations(t)
+	if failures, ok := writeVerificationFailures.Get("verify_multi").(*expvar.Int); assert.True(t, ok) {
+		assert.Equal(t, int64(1), failures.Value())
+	}
+}